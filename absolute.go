@@ -0,0 +1,67 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+)
+
+// Absolute returns an attribute which the handler emits without the names of
+// the enclosing groups (started with WithGroup or group values), e.g. a
+// correlation ID which must keep a fixed key.  If the value is a group, its
+// attributes are prefixed only with its own key.  Other handlers see the
+// original attribute.
+func Absolute(a slog.Attr) slog.Attr {
+	return slog.Any(a.Key, absoluteValue{a.Value})
+}
+
+type absoluteValue struct {
+	value slog.Value
+}
+
+func (v absoluteValue) LogValue() slog.Value {
+	return v.value
+}
+
+// newGlobalKeys returns the GlobalKeys as a set, or nil.
+func newGlobalKeys(keys []string) map[string]struct{} {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+	return set
+}
+
+// absoluteAttr reports whether an attribute is emitted without the group
+// prefix (see Absolute and GlobalKeys).  The attribute is returned unwrapped.
+func (h *Handler) absoluteAttr(a slog.Attr) (slog.Attr, bool) {
+	if a.Value.Kind() == slog.KindLogValuer {
+		if v, ok := a.Value.Any().(absoluteValue); ok {
+			a.Value = v.value
+			return a, true
+		}
+	}
+	if len(h.globalKeys) > 0 {
+		if _, ok := h.globalKeys[a.Key]; ok {
+			return a, true
+		}
+	}
+	return a, false
+}
+
+// appendAbsoluteAttr without the current group prefix, which must not be
+// empty.
+func (s *handleState) appendAbsoluteAttr(a slog.Attr) {
+	// Nested groups are appended after the current prefix within the same
+	// array, so it stays intact.
+	prefix := *s.prefix
+	*s.prefix = prefix[len(prefix):]
+	s.appendAttr(a)
+	*s.prefix = prefix
+}
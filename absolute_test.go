@@ -0,0 +1,55 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestAbsolute(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter:  ColonDelimiter,
+		GlobalKeys: []string{"request_id"},
+	})
+
+	logger := slog.New(h).WithGroup("a").WithGroup("b")
+
+	logger.Info("msg",
+		slog.Group("c",
+			Absolute(slog.String("trace_id", "t1")),
+			"x", 1,
+			"request_id", "r1",
+		),
+		"y", 2,
+	)
+	logger.With(Absolute(slog.String("trace_id", "t2")), "x", 3).WithGroup("c").Info("msg", "request_id", "r2", "y", 4)
+	logger.Info("msg", slog.Group("c", Absolute(slog.Group("span", "id", 5, slog.Group("d", "e", 6))), "z", 7))
+	slog.New(h).Info("msg", Absolute(slog.String("trace_id", "t3")), "request_id", "r3")
+
+	expect := []string{
+		"msg: trace_id=t1 a.b.c.x=1 request_id=r1 a.b.y=2",
+		"msg: trace_id=t2 a.b.x=3 request_id=r2 a.b.c.y=4",
+		"msg: span.id=5 span.d.e=6 a.b.c.z=7",
+		"msg: trace_id=t3 request_id=r3",
+	}
+
+	for i, m := range recv.wait(t, len(expect)) {
+		if s := m["MESSAGE"]; s != expect[i] {
+			t.Errorf("entry %d: %q", i, s)
+		}
+	}
+}
+
+func TestAbsoluteOtherHandler(t *testing.T) {
+	var b bytes.Buffer
+	slog.New(slog.NewTextHandler(&b, nil)).WithGroup("g").Info("msg", Absolute(slog.String("trace_id", "t")))
+
+	if s := b.String(); !strings.Contains(s, " g.trace_id=t\n") {
+		t.Error(s)
+	}
+}
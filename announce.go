@@ -0,0 +1,94 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"os"
+	"runtime/debug"
+	"sync"
+)
+
+const modulePath = "import.name/sjournal"
+
+// moduleVersion is the version of this package according to the build info,
+// or "(devel)".
+var moduleVersion = sync.OnceValue(func() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == modulePath && info.Main.Version != "" {
+			return info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				if dep.Replace != nil && dep.Replace.Version != "" {
+					return dep.Replace.Version
+				}
+				return dep.Version
+			}
+		}
+	}
+	return "(devel)"
+})
+
+// Announce sends an informational entry describing the logging configuration,
+// unless it has already been sent by the handler or a related handler (see
+// HandlerOptions.Announce).  The entry has the fields SJOURNAL_VERSION (the
+// package version), SJOURNAL_CONFIG (see String), SJOURNAL_SOCKET (unless
+// Uploader or Syslog is used), SJOURNAL_LARGE_MESSAGES (see
+// LargeMessageSupport) and SJOURNAL_INVOCATION_ID (if the INVOCATION_ID
+// environment variable is set).  The context is used like with Handle in
+// asynchronous mode.  The entry is not sent again even if sending fails.
+func (h *Handler) Announce(ctx context.Context) error {
+	if !h.root.announced.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	b := newBuffer()
+	defer b.Free()
+	h.appendAnnouncement(b)
+
+	if q := h.root.queue; q != nil {
+		e := queueEntry{
+			data:     append([]byte(nil), *b...),
+			keyLen:   b.Len(),
+			priority: priorityInfo,
+			time:     h.root.clock.Now(),
+		}
+		return q.put(ctx, e)
+	}
+
+	return h.root.sendEntry(*b, nil)
+}
+
+// announce the configuration after the first successful send if Announce is
+// enabled.  Errors are ignored.
+func (r *root) announce() {
+	if r.announcer == nil || r.announced.Load() || !r.announced.CompareAndSwap(false, true) {
+		return
+	}
+
+	b := newBuffer()
+	defer b.Free()
+	r.announcer.appendAnnouncement(b)
+
+	r.sendEntry(*b, nil)
+}
+
+func (h *Handler) appendAnnouncement(b *buffer) {
+	b.WriteString("PRIORITY=6\nMESSAGE=sjournal: logging started\n")
+	*b = appendField(*b, "SJOURNAL_VERSION", moduleVersion())
+	*b = appendField(*b, "SJOURNAL_CONFIG", h.String())
+	if _, ok := h.root.transport.(*journalTransport); ok {
+		*b = appendField(*b, "SJOURNAL_SOCKET", h.root.addr.Load().Name)
+	}
+	if LargeMessageSupport {
+		b.WriteString("SJOURNAL_LARGE_MESSAGES=1\n")
+	} else {
+		b.WriteString("SJOURNAL_LARGE_MESSAGES=0\n")
+	}
+	if id := os.Getenv("INVOCATION_ID"); id != "" {
+		*b = appendField(*b, "SJOURNAL_INVOCATION_ID", id)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAnnounce(t *testing.T) {
+	t.Setenv("INVOCATION_ID", "0123456789abcdef0123456789abcdef")
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Announce: true,
+	})
+
+	const count = 10
+
+	var wg sync.WaitGroup
+	for i := range count {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0))
+			if i%2 == 0 {
+				h.Announce(context.Background())
+			}
+		}()
+	}
+	wg.Wait()
+
+	slog.New(h).With("a", 1).Info("last")
+
+	ms := recv.wait(t, count+2)
+	if len(ms) != count+2 {
+		t.Fatalf("%d entries", len(ms))
+	}
+
+	var announced []map[string]string
+	for _, m := range ms {
+		if m["MESSAGE"] == "sjournal: logging started" {
+			announced = append(announced, m)
+		}
+	}
+	if len(announced) != 1 {
+		t.Fatalf("%d announcements", len(announced))
+	}
+
+	m := announced[0]
+	if ms[0]["MESSAGE"] != "msg" {
+		t.Errorf("first entry: %q", ms[0]["MESSAGE"])
+	}
+	if s := m["PRIORITY"]; s != "6" {
+		t.Errorf("priority: %q", s)
+	}
+	if s := m["SJOURNAL_VERSION"]; s != moduleVersion() || s == "" {
+		t.Errorf("version: %q", s)
+	}
+	if s := m["SJOURNAL_CONFIG"]; s != h.String() || !strings.Contains(s, " announce") {
+		t.Errorf("config: %q", s)
+	}
+	if s := m["SJOURNAL_SOCKET"]; s != recv.path {
+		t.Errorf("socket: %q", s)
+	}
+	if s := m["SJOURNAL_LARGE_MESSAGES"]; s != "1" && s != "0" {
+		t.Errorf("large messages: %q", s)
+	}
+	if s := m["SJOURNAL_INVOCATION_ID"]; s != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("invocation id: %q", s)
+	}
+}
+
+func TestAnnounceExplicit(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+
+	h2 := slog.New(h).With("a", 1).Handler().(*Handler)
+	if err := h2.Announce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Announce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	slog.New(h).Info("msg")
+
+	ms := recv.wait(t, 2)
+	if len(ms) != 2 {
+		t.Fatalf("%d entries", len(ms))
+	}
+	if s := ms[0]["SJOURNAL_CONFIG"]; s != h2.String() {
+		t.Errorf("config: %q", s)
+	}
+	if s := ms[1]["MESSAGE"]; s != "msg" {
+		t.Errorf("message: %q", s)
+	}
+}
+
+func TestAnnounceError(t *testing.T) {
+	h, err := NewHandler(&HandlerOptions{Announce: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	errAnnounce := errors.New("announce failed")
+
+	var sent [][]byte
+	h.root.sink = func(b []byte) error {
+		if bytes.Contains(b, []byte("SJOURNAL_VERSION=")) {
+			return errAnnounce
+		}
+		sent = append(sent, b)
+		return nil
+	}
+
+	logger := slog.New(h)
+	for range 2 {
+		if err := logger.Handler().Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(sent) != 2 {
+		t.Errorf("%d entries", len(sent))
+	}
+	if err := h.Announce(context.Background()); err != nil {
+		t.Errorf("repeated announcement: %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultBudgetInterval = time.Hour
+
+// Budget limits the total encoded size of the entries of records within a
+// level range during each interval.  Records which would exceed the budget
+// are dropped (counted in Stats with reason DropBudget).  When the budget has
+// been used up, records are dropped before their attributes are resolved, and
+// only their counts (not sizes) are included in the summary.
+type Budget struct {
+	MinLevel slog.Leveler  // Nil means no lower bound.
+	MaxLevel slog.Leveler  // Inclusive.  Nil means no upper bound.
+	Bytes    int           // Budget for each interval.
+	Interval time.Duration // Defaults to one hour.
+}
+
+func (b *Budget) matches(l slog.Level) bool {
+	return (b.MinLevel == nil || l >= b.MinLevel.Level()) && (b.MaxLevel == nil || l <= b.MaxLevel.Level())
+}
+
+// budgets tracks the usage of Budgets.
+type budgets struct {
+	exempt          slog.Leveler // Nil means no exemption.
+	summaryInterval time.Duration
+
+	mu             sync.Mutex
+	budgets        []Budget
+	windows        []budgetWindow
+	summaryStart   time.Time // Zero until the first record.
+	droppedBytes   [numPriorities]int
+	droppedEntries [numPriorities]int
+	dropped        int
+}
+
+type budgetWindow struct {
+	start time.Time
+	used  int
+}
+
+// advance starts a new window if the interval has elapsed.
+func (w *budgetWindow) advance(now time.Time, interval time.Duration) {
+	if elapsed := now.Sub(w.start); w.start.IsZero() || elapsed < 0 {
+		w.start = now
+		w.used = 0
+	} else if elapsed >= interval {
+		w.start = w.start.Add(elapsed - elapsed%interval)
+		w.used = 0
+	}
+}
+
+// newBudgets returns nil unless Budgets is set.
+func newBudgets(opts *HandlerOptions) *budgets {
+	if len(opts.Budgets) == 0 {
+		return nil
+	}
+
+	b := &budgets{
+		exempt:          opts.BudgetExempt,
+		summaryInterval: opts.BudgetSummaryInterval,
+		budgets:         make([]Budget, len(opts.Budgets)),
+		windows:         make([]budgetWindow, len(opts.Budgets)),
+	}
+	copy(b.budgets, opts.Budgets)
+
+	var shortest time.Duration
+	for i := range b.budgets {
+		if b.budgets[i].Interval <= 0 {
+			b.budgets[i].Interval = defaultBudgetInterval
+		}
+		if shortest == 0 || b.budgets[i].Interval < shortest {
+			shortest = b.budgets[i].Interval
+		}
+	}
+	if b.summaryInterval <= 0 {
+		b.summaryInterval = shortest
+	}
+	return b
+}
+
+// allow reports whether an entry of a record at the level fits in the
+// budgets, and uses them if it does.  Records at or above the exemption
+// level are always allowed, but they use the budgets.  A summary entry is
+// returned if it's due.
+func (b *budgets) allow(now time.Time, level slog.Level, priority, size int) (bool, []byte) {
+	exempt := b.exempt != nil && level >= b.exempt.Level()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.summaryStart.IsZero() {
+		b.summaryStart = now
+	}
+
+	ok := true
+	for i := range b.budgets {
+		budget := &b.budgets[i]
+		if !budget.matches(level) {
+			continue
+		}
+		w := &b.windows[i]
+		w.advance(now, budget.Interval)
+		if !exempt && w.used+size > budget.Bytes {
+			ok = false
+		}
+	}
+
+	if ok {
+		for i := range b.budgets {
+			if b.budgets[i].matches(level) {
+				b.windows[i].used += size
+			}
+		}
+	} else {
+		b.droppedBytes[priority] += size
+		b.droppedEntries[priority]++
+		b.dropped++
+	}
+
+	return ok, b.dueSummary(now)
+}
+
+// exhausted reports whether a budget matching the level has been used up, so
+// that a record at the level can be dropped without encoding it.  The record
+// is counted in the summary, but its size is unknown.  A summary entry is
+// returned if it's due.
+func (b *budgets) exhausted(now time.Time, level slog.Level, priority int) (bool, []byte) {
+	if b.exempt != nil && level >= b.exempt.Level() {
+		return false, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.summaryStart.IsZero() {
+		b.summaryStart = now
+	}
+
+	for i := range b.budgets {
+		budget := &b.budgets[i]
+		if !budget.matches(level) {
+			continue
+		}
+		w := &b.windows[i]
+		w.advance(now, budget.Interval)
+		if w.used >= budget.Bytes {
+			b.droppedEntries[priority]++
+			b.dropped++
+			return true, b.dueSummary(now)
+		}
+	}
+	return false, nil
+}
+
+// dueSummary returns the summary if the summary interval has passed and
+// entries have been dropped.  The mutex must be held.
+func (b *budgets) dueSummary(now time.Time) []byte {
+	var summary []byte
+	if now.Sub(b.summaryStart) >= b.summaryInterval {
+		if b.dropped > 0 {
+			summary = b.summary()
+		}
+		b.summaryStart = now
+	}
+	return summary
+}
+
+// summary of the dropped entries, which are reset.
+func (b *budgets) summary() []byte {
+	total := 0
+	for _, n := range b.droppedBytes {
+		total += n
+	}
+
+	e := []byte("PRIORITY=4\nMESSAGE=sjournal: entries dropped due to budgets: ")
+	e = strconv.AppendInt(e, int64(b.dropped), 10)
+	e = append(e, " entries, "...)
+	e = strconv.AppendInt(e, int64(total), 10)
+	e = append(e, " bytes\nDROPPED_ENTRIES="...)
+	e = strconv.AppendInt(e, int64(b.dropped), 10)
+	e = append(e, "\nDROPPED_BYTES="...)
+	e = strconv.AppendInt(e, int64(total), 10)
+	e = append(e, '\n')
+	for p, n := range b.droppedEntries {
+		if n > 0 {
+			name := strings.ToUpper(priorityNames[p][0])
+			e = append(e, "DROPPED_ENTRIES_"...)
+			e = append(e, name...)
+			e = append(e, '=')
+			e = strconv.AppendInt(e, int64(n), 10)
+			e = append(e, '\n')
+			if size := b.droppedBytes[p]; size > 0 {
+				e = append(e, "DROPPED_BYTES_"...)
+				e = append(e, name...)
+				e = append(e, '=')
+				e = strconv.AppendInt(e, int64(size), 10)
+				e = append(e, '\n')
+			}
+		}
+	}
+
+	b.droppedBytes = [numPriorities]int{}
+	b.droppedEntries = [numPriorities]int{}
+	b.dropped = 0
+	return e
+}
+
+// checkBudgets reports whether an entry fits in the budgets (see
+// HandlerOptions.Budgets).  A due summary is sent (or queued in asynchronous
+// mode) before the entry; its errors are ignored.
+func (r *root) checkBudgets(ctx context.Context, level slog.Level, priority int, b []byte) bool {
+	now := r.clock.Now()
+	ok, summary := r.budgets.allow(now, level, priority, len(b))
+	r.sendBudgetSummary(ctx, now, summary)
+	if !ok {
+		r.stats.drop(dropBudget, priority, 1)
+	}
+	return ok
+}
+
+// budgetExhausted reports whether a record can be dropped before encoding it,
+// as a budget has been used up.  A due summary is sent like in checkBudgets.
+func (r *root) budgetExhausted(ctx context.Context, level slog.Level, priority int) bool {
+	now := r.clock.Now()
+	exhausted, summary := r.budgets.exhausted(now, level, priority)
+	r.sendBudgetSummary(ctx, now, summary)
+	if exhausted {
+		r.stats.drop(dropBudget, priority, 1)
+	}
+	return exhausted
+}
+
+// sendBudgetSummary sends or queues the summary, if any.
+func (r *root) sendBudgetSummary(ctx context.Context, now time.Time, summary []byte) {
+	if summary == nil {
+		return
+	}
+	if q := r.queue; q != nil {
+		q.put(ctx, queueEntry{
+			data:     summary,
+			keyLen:   len(summary),
+			priority: priorityWarning,
+			time:     now,
+		})
+	} else {
+		r.send(summary)
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type budgetClock struct {
+	t time.Time
+}
+
+func (c *budgetClock) Now() time.Time           { return c.t }
+func (c *budgetClock) Monotonic() time.Duration { return c.t.Sub(time.Unix(0, 0)) }
+
+func TestBudgets(t *testing.T) {
+	clock := &budgetClock{time.Unix(1700000000, 0)}
+
+	h, err := NewHandler(&HandlerOptions{
+		Level:                 slog.LevelDebug,
+		Clock:                 clock,
+		Budgets:               []Budget{{Bytes: 2500, Interval: time.Minute}},
+		BudgetExempt:          slog.LevelError,
+		BudgetSummaryInterval: 10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	var sent [][]byte
+	h.root.sink = func(b []byte) error {
+		sent = append(sent, slices.Clone(b))
+		return nil
+	}
+
+	logger := slog.New(h)
+	message := func(i int) string {
+		return strconv.Itoa(i) + strings.Repeat("x", 1000)
+	}
+	messages := func() (ms []string) {
+		for _, b := range sent {
+			rangeFields(b, func(name string, value []byte) {
+				if name == "MESSAGE" {
+					ms = append(ms, string(value))
+				}
+			})
+		}
+		return
+	}
+
+	logger.Info(message(0))
+	logger.Info(message(1))
+	logger.Info(message(2))  // Dropped.
+	logger.Debug(message(3)) // Dropped.
+	logger.Error(message(4)) // Exempt.
+	logger.Warn(message(5))  // Dropped before encoding.
+
+	if ms := messages(); !slices.Equal(ms, []string{message(0), message(1), message(4)}) {
+		t.Errorf("messages: %d", len(ms))
+	}
+	if n := h.Stats().Dropped[DropBudget]; n != 3 {
+		t.Errorf("dropped: %d", n)
+	}
+
+	size := len(sent[0])
+
+	clock.t = clock.t.Add(90 * time.Second)
+	logger.Info(message(6))
+	logger.Info(message(7))
+	logger.Info(message(8)) // Dropped.
+
+	if ms := messages(); len(ms) != 5 || ms[3] != message(6) || ms[4] != message(7) {
+		t.Errorf("messages after rollover: %d", len(ms))
+	}
+
+	// The window started 30 seconds ago.
+	clock.t = clock.t.Add(30 * time.Second)
+	sent = nil
+	logger.Info(message(9))
+	if ms := messages(); !slices.Equal(ms, []string{message(9)}) {
+		t.Errorf("messages after second rollover: %d", len(ms))
+	}
+
+	clock.t = clock.t.Add(10 * time.Minute)
+	sent = nil
+	logger.Info(message(10))
+
+	if len(sent) != 2 {
+		t.Fatalf("entries: %d", len(sent))
+	}
+
+	summary := map[string]string{}
+	rangeFields(sent[0], func(name string, value []byte) {
+		summary[name] = string(value)
+	})
+
+	for name, value := range map[string]int{
+		"PRIORITY":                4,
+		"DROPPED_ENTRIES":         4,
+		"DROPPED_BYTES":           3 * size,
+		"DROPPED_BYTES_INFO":      2 * size,
+		"DROPPED_BYTES_DEBUG":     size,
+		"DROPPED_ENTRIES_INFO":    2,
+		"DROPPED_ENTRIES_DEBUG":   1,
+		"DROPPED_ENTRIES_WARNING": 1, // Dropped before encoding.
+	} {
+		if s := summary[name]; s != strconv.Itoa(value) {
+			t.Errorf("%s: %q", name, s)
+		}
+	}
+	for _, name := range []string{"DROPPED_BYTES_ERR", "DROPPED_BYTES_WARNING", "DROPPED_ENTRIES_ERR"} {
+		if _, found := summary[name]; found {
+			t.Error(name)
+		}
+	}
+	if s := summary["MESSAGE"]; !strings.Contains(s, "4 entries") {
+		t.Errorf("message: %q", s)
+	}
+
+	// The counts were reset.
+	clock.t = clock.t.Add(10 * time.Minute)
+	sent = nil
+	logger.Info(message(11))
+	if len(sent) != 1 {
+		t.Errorf("entries: %d", len(sent))
+	}
+}
+
+// TestBudgetSummaryExhausted checks that summaries are sent at the summary
+// interval while records are being dropped before encoding.
+func TestBudgetSummaryExhausted(t *testing.T) {
+	clock := &budgetClock{time.Unix(1700000000, 0)}
+
+	h, err := NewHandler(&HandlerOptions{
+		Clock:                 clock,
+		Budgets:               []Budget{{Bytes: 1000, Interval: time.Hour}},
+		BudgetExempt:          slog.LevelError,
+		BudgetSummaryInterval: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	var summaries []map[string]string
+	h.root.sink = func(b []byte) error {
+		m := map[string]string{}
+		rangeFields(b, func(name string, value []byte) {
+			m[name] = string(value)
+		})
+		if m["DROPPED_ENTRIES"] != "" {
+			summaries = append(summaries, m)
+		}
+		return nil
+	}
+
+	logger := slog.New(h)
+	logger.Error(strings.Repeat("x", 1000)) // Uses up the budget.
+
+	for range 10 {
+		clock.t = clock.t.Add(30 * time.Second)
+		logger.Info("dropped")
+		logger.Warn("dropped")
+	}
+
+	if len(summaries) != 5 {
+		t.Fatalf("summaries: %d", len(summaries))
+	}
+	for i, m := range summaries {
+		entries := "4"
+		if i == 0 {
+			entries = "3"
+		}
+		if m["DROPPED_ENTRIES"] != entries || m["DROPPED_BYTES"] != "0" {
+			t.Errorf("summary %d: %q", i, m)
+		}
+		if m["DROPPED_ENTRIES_WARNING"] == "" || m["DROPPED_ENTRIES_INFO"] == "" {
+			t.Errorf("summary %d: %q", i, m)
+		}
+	}
+	if n := h.Stats().Dropped[DropBudget]; n != 20 {
+		t.Errorf("dropped: %d", n)
+	}
+}
+
+func TestBudgetSummaryQueued(t *testing.T) {
+	const summaryInterval = 50 * time.Millisecond
+
+	h, err := NewHandler(&HandlerOptions{
+		Budgets:               []Budget{{Bytes: 1500, Interval: time.Hour}},
+		BudgetSummaryInterval: summaryInterval,
+		QueueSize:             10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	release := make(chan struct{})
+	unblock := sync.OnceFunc(func() { close(release) })
+	defer unblock()
+
+	var (
+		mu       sync.Mutex
+		messages []string
+	)
+	h.root.sink = func(b []byte) error {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		rangeFields(b, func(name string, value []byte) {
+			if name == "MESSAGE" {
+				messages = append(messages, string(value))
+			}
+		})
+		return nil
+	}
+
+	logger := slog.New(h)
+	logger.Info(strings.Repeat("x", 1000))
+	logger.Info(strings.Repeat("y", 1000)) // Dropped.
+
+	// The sender is blocked, so the summary must not be sent synchronously.
+	time.Sleep(2 * summaryInterval)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Info("after")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("summary was not queued")
+	}
+
+	unblock()
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// The last one is the shutdown summary.
+	if len(messages) != 4 || !strings.HasPrefix(messages[1], "sjournal: entries dropped due to budgets: 1 entries") || messages[2] != "after" {
+		t.Errorf("messages: %.40q", messages)
+	}
+}
+
+func TestBudgetLevelRange(t *testing.T) {
+	h, err := NewHandler(&HandlerOptions{
+		Level:   slog.LevelDebug,
+		Budgets: []Budget{{MaxLevel: slog.LevelInfo, Bytes: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	sent := 0
+	h.root.sink = func([]byte) error {
+		sent++
+		return nil
+	}
+
+	logger := slog.New(h)
+	logger.Debug("dropped")
+	logger.Info("dropped")
+	logger.Warn("sent")
+	logger.Error("sent")
+
+	if sent != 2 {
+		t.Errorf("sent: %d", sent)
+	}
+	if s := h.Stats(); s.Dropped[DropBudget] != 2 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+func TestBudgetMunger(t *testing.T) {
+	h, err := NewHandler(&HandlerOptions{
+		Budgets: []Budget{{Bytes: 10}},
+		Mungers: []func(context.Context, []byte) ([]byte, error){prependMunger},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	h.root.sink = func([]byte) error {
+		t.Error("sent")
+		return nil
+	}
+
+	slog.New(h).Warn("dropped")
+
+	if s := h.Stats(); s.Dropped[DropBudget] != 1 || s.DroppedByPriority[4] != 1 {
+		t.Errorf("stats: %+v", s)
+	}
+}
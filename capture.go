@@ -0,0 +1,156 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+const (
+	defaultCaptureEntries = 1000
+	defaultCaptureBytes   = 1 << 20
+)
+
+const replayedField = "REPLAYED=1\n"
+
+// captureRing holds encoded entries of records which were below the handler
+// level.  Its memory use is bounded by the entry count and byte limits.
+type captureRing struct {
+	level      slog.Leveler
+	trigger    slog.Leveler
+	maxEntries int
+	maxBytes   int
+
+	replayMu sync.Mutex // Keeps the replayed batches in order.
+
+	mu      sync.Mutex
+	entries []capturedEntry // Circular; len is maxEntries once allocated.
+	start   int             // Index of the oldest entry.
+	n       int             // Number of entries.
+	bytes   int             // Total size of the entries.
+}
+
+type capturedEntry struct {
+	data     []byte
+	priority int // Not parsed from the data, which may have been munged.
+	meta     *entryMeta
+}
+
+// newCaptureRing returns nil unless CaptureLevel is set.
+func newCaptureRing(opts *HandlerOptions) *captureRing {
+	if opts.CaptureLevel == nil {
+		return nil
+	}
+
+	c := &captureRing{
+		level:      opts.CaptureLevel,
+		trigger:    opts.CaptureTrigger,
+		maxEntries: opts.CaptureEntries,
+		maxBytes:   opts.CaptureBytes,
+	}
+	if c.trigger == nil {
+		c.trigger = slog.LevelError
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = defaultCaptureEntries
+	}
+	if c.maxBytes <= 0 {
+		c.maxBytes = defaultCaptureBytes
+	}
+	return c
+}
+
+// captures reports whether records at the level are captured when they are
+// below the handler level.
+func (c *captureRing) captures(l slog.Level) bool {
+	return c != nil && l >= c.level.Level()
+}
+
+// triggers reports whether a record at the level causes the captured entries
+// to be replayed.
+func (c *captureRing) triggers(l slog.Level) bool {
+	return c != nil && l >= c.trigger.Level()
+}
+
+// put a copy of an entry with the REPLAYED field into the ring, discarding the
+// oldest entries if necessary.  An entry which is larger than the byte limit is
+// not captured.
+func (c *captureRing) put(b []byte, priority int, meta *entryMeta) {
+	size := len(b) + len(replayedField)
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make([]capturedEntry, c.maxEntries)
+	}
+
+	var reuse []byte
+	for c.n == c.maxEntries || c.bytes+size > c.maxBytes {
+		reuse = c.entries[c.start].data
+		c.entries[c.start] = capturedEntry{}
+		c.bytes -= len(reuse)
+		c.start = (c.start + 1) % c.maxEntries
+		c.n--
+	}
+
+	data := append(append(reuse[:0], b...), replayedField...)
+	c.entries[(c.start+c.n)%c.maxEntries] = capturedEntry{data, priority, meta}
+	c.n++
+	c.bytes += size
+}
+
+// take the captured entries in the order in which they were put, and clear
+// the ring.
+func (c *captureRing) take() []capturedEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.n == 0 {
+		return nil
+	}
+
+	entries := make([]capturedEntry, 0, c.n)
+	for i := range c.n {
+		j := (c.start + i) % c.maxEntries
+		entries = append(entries, c.entries[j])
+		c.entries[j] = capturedEntry{}
+	}
+	c.start = 0
+	c.n = 0
+	c.bytes = 0
+	return entries
+}
+
+// replay sends the captured entries (see CaptureLevel).
+func (r *root) replay(ctx context.Context) error {
+	r.capture.replayMu.Lock()
+	defer r.capture.replayMu.Unlock()
+
+	var errs []error
+
+	for _, c := range r.capture.take() {
+		if q := r.queue; q != nil {
+			e := queueEntry{
+				data:     c.data,
+				keyLen:   len(c.data),
+				priority: c.priority,
+				time:     r.clock.Now(),
+				meta:     c.meta,
+			}
+			errs = append(errs, q.put(ctx, e))
+		} else {
+			errs = append(errs, r.sendRecord(c.data, c.meta))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,204 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCapture(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level:        slog.LevelInfo,
+		CaptureLevel: slog.LevelDebug,
+	})
+
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug level is not enabled")
+	}
+	if h.Enabled(context.Background(), LevelTrace) {
+		t.Error("trace level is enabled")
+	}
+
+	base := time.Unix(1700000000, 0)
+	for i, x := range []struct {
+		level slog.Level
+		msg   string
+	}{
+		{slog.LevelDebug, "d0"},
+		{LevelTrace, "t"},
+		{slog.LevelDebug, "d1"},
+		{slog.LevelInfo, "i"},
+		{slog.LevelError, "e0"},
+		{slog.LevelError, "e1"},
+		{slog.LevelDebug, "d2"},
+	} {
+		r := slog.NewRecord(base.Add(time.Duration(i)*time.Second), x.level, x.msg, 0)
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var summary []string
+	for _, m := range recv.wait(t, 5) {
+		s := m["MESSAGE"] + "@" + m["SYSLOG_TIMESTAMP"]
+		if m["REPLAYED"] != "" {
+			s += "+" + m["REPLAYED"]
+		}
+		summary = append(summary, s)
+	}
+	if s := strings.Join(summary, " "); s != "i@1700000003 d0@1700000000+1 d1@1700000002+1 e0@1700000004 e1@1700000005" {
+		t.Error(s)
+	}
+}
+
+func TestCaptureWrapAround(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level:          slog.LevelInfo,
+		CaptureLevel:   slog.LevelDebug,
+		CaptureTrigger: slog.LevelWarn,
+		CaptureEntries: 3,
+	})
+
+	logger := slog.New(h)
+	for i := range 5 {
+		logger.Debug("d" + strconv.Itoa(i))
+	}
+	logger.Warn("w")
+
+	var messages []string
+	for _, m := range recv.wait(t, 4) {
+		messages = append(messages, m["MESSAGE"])
+	}
+	if s := strings.Join(messages, " "); s != "d2 d3 d4 w" {
+		t.Error(s)
+	}
+}
+
+func TestCaptureRingBytes(t *testing.T) {
+	c := newCaptureRing(&HandlerOptions{
+		CaptureLevel: slog.LevelDebug,
+		CaptureBytes: 3 * (4 + len(replayedField)),
+	})
+
+	c.put([]byte("aaaa"), priorityDebug, nil)
+	c.put([]byte("bbbb"), priorityDebug, nil)
+	c.put([]byte("cccc"), priorityDebug, nil)
+	c.put([]byte("dd"), priorityDebug, nil)
+	c.put([]byte(strings.Repeat("x", c.maxBytes)), priorityDebug, nil) // Too large.
+	c.put([]byte("eeee"), priorityDebug, nil)
+
+	var entries []string
+	for _, e := range c.take() {
+		entries = append(entries, strings.TrimSuffix(string(e.data), replayedField))
+	}
+	if s := strings.Join(entries, " "); s != "cccc dd eeee" {
+		t.Error(s)
+	}
+	if c.bytes != 0 || c.n != 0 {
+		t.Errorf("not cleared: %d bytes, %d entries", c.bytes, c.n)
+	}
+	if entries := c.take(); entries != nil {
+		t.Error(entries)
+	}
+
+	// The entries are reused.
+	for i := range 1000 {
+		c.put([]byte(fmt.Sprintf("%04d", i)), priorityDebug, nil)
+		if c.n > 3 || c.bytes > c.maxBytes {
+			t.Fatalf("%d entries, %d bytes", c.n, c.bytes)
+		}
+	}
+}
+
+func TestCaptureConcurrent(t *testing.T) {
+	const (
+		goroutines = 8
+		records    = 50
+	)
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level:          slog.LevelInfo,
+		CaptureLevel:   slog.LevelDebug,
+		CaptureEntries: goroutines * records,
+		CaptureBytes:   1 << 30,
+	})
+
+	logger := slog.New(h)
+
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range records {
+				logger.Debug("debug", "g", g, "i", i)
+				if i%10 == 9 {
+					logger.Error("error", "g", g, "i", i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	logger.Error("last")
+
+	total := goroutines*records + goroutines*records/10 + 1
+	entries := recv.wait(t, total)
+
+	next := make(map[string]int) // Per goroutine.
+	var replayed int
+	for _, m := range entries {
+		msg := m["MESSAGE"]
+		if msg == "last" {
+			continue
+		}
+		var kind string
+		var g, i int
+		if _, err := fmt.Sscanf(msg, "%s g=%d i=%d", &kind, &g, &i); err != nil {
+			t.Fatalf("%q: %v", msg, err)
+		}
+		if kind == "debug" {
+			if m["REPLAYED"] != "1" {
+				t.Errorf("not replayed: %q", msg)
+			}
+			replayed++
+			key := strconv.Itoa(g)
+			if i != next[key] {
+				t.Errorf("goroutine %d: entry %d out of order (expected %d)", g, i, next[key])
+			}
+			next[key] = i + 1
+		}
+	}
+	if replayed != goroutines*records {
+		t.Errorf("%d replayed entries", replayed)
+	}
+}
+
+func TestCaptureMungerQueued(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level:        slog.LevelInfo,
+		CaptureLevel: slog.LevelDebug,
+		QueueSize:    10,
+		Mungers:      []func(context.Context, []byte) ([]byte, error){prependMunger},
+	})
+
+	logger := slog.New(h)
+	logger.Debug("debug")
+	logger.Error("error")
+
+	ms := recv.wait(t, 2)
+	if m := ms[0]; m["MESSAGE"] != "debug" || m["PRIORITY"] != "7" || m["REPLAYED"] != "1" {
+		t.Errorf("entry 0: %q", m)
+	}
+	if m := ms[1]; m["MESSAGE"] != "error" {
+		t.Errorf("entry 1: %q", m)
+	}
+}
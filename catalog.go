@@ -0,0 +1,108 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type catalogEntry struct {
+	subject string
+	body    string
+	headers map[string]string
+}
+
+var (
+	catalogMu sync.Mutex
+	catalog   = make(map[string]catalogEntry)
+)
+
+// RegisterCatalogEntry for a MESSAGE_ID.  The fields are written as
+// additional headers, e.g. "Defined-By", "Support" or "Documentation".  The
+// subject and body may refer to entry fields using the @FIELD@ syntax.  It
+// panics if the id is not 32 lowercase hexadecimal digits, if an entry has
+// already been registered for the id, or if the text would break the catalog
+// format.  See MessageID and WriteCatalog.
+func RegisterCatalogEntry(id, subject, body string, fields map[string]string) {
+	if !validMessageID(id) {
+		panic(fmt.Sprintf("sjournal: invalid catalog message id: %q", id))
+	}
+	if strings.ContainsRune(subject, '\n') {
+		panic(fmt.Sprintf("sjournal: catalog entry %s: newline in subject", id))
+	}
+	for key, value := range fields {
+		if key == "" || strings.ContainsAny(key, ":\n") || strings.ContainsRune(value, '\n') {
+			panic(fmt.Sprintf("sjournal: catalog entry %s: invalid field: %q", id, key))
+		}
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "-- ") {
+			panic(fmt.Sprintf("sjournal: catalog entry %s: body line starts with \"-- \"", id))
+		}
+	}
+
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	if _, found := catalog[id]; found {
+		panic(fmt.Sprintf("sjournal: catalog entry %s registered twice", id))
+	}
+	catalog[id] = catalogEntry{subject, strings.TrimRight(body, "\n"), maps.Clone(fields)}
+}
+
+// WriteCatalog writes the registered entries in the systemd message catalog
+// format, sorted by id.  The output is suitable for installing as
+// /usr/lib/systemd/catalog/*.catalog.  A program could support it like this:
+//
+//	if *dumpCatalog {
+//		if err := sjournal.WriteCatalog(os.Stdout); err != nil {
+//			log.Fatal(err)
+//		}
+//		return
+//	}
+func WriteCatalog(w io.Writer) error {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	b := bufio.NewWriter(w)
+
+	for i, id := range slices.Sorted(maps.Keys(catalog)) {
+		e := catalog[id]
+
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(b, "-- %s\n", id)
+		fmt.Fprintf(b, "Subject: %s\n", e.subject)
+		for _, key := range slices.Sorted(maps.Keys(e.headers)) {
+			fmt.Fprintf(b, "%s: %s\n", key, e.headers[key])
+		}
+		b.WriteString("\n")
+		if e.body != "" {
+			b.WriteString(e.body)
+			b.WriteString("\n")
+		}
+	}
+
+	return b.Flush()
+}
+
+func validMessageID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	for _, c := range []byte(id) {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func resetCatalog() {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	clear(catalog)
+}
+
+func TestWriteCatalog(t *testing.T) {
+	resetCatalog()
+	defer resetCatalog()
+
+	RegisterCatalogEntry("0123456789abcdef0123456789abcdef", "Database @DB@ opened", "", map[string]string{
+		"Defined-By": "example",
+	})
+	RegisterCatalogEntry(MessageID("user %s logged in from %s"), "User logged in", "A user logged in.\n\nThe address is in @ADDR@.\n", map[string]string{
+		"Defined-By":    "example",
+		"Documentation": "man:example(8)",
+		"Support":       "https://example.invalid/support",
+	})
+
+	var b bytes.Buffer
+	if err := WriteCatalog(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	golden, err := os.ReadFile("testdata/catalog.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != string(golden) {
+		t.Errorf("output:\n%s", b.Bytes())
+	}
+}
+
+func TestRegisterCatalogEntryInvalid(t *testing.T) {
+	resetCatalog()
+	defer resetCatalog()
+
+	RegisterCatalogEntry("00000000000000000000000000000000", "Duplicate", "", nil)
+
+	for _, c := range []struct {
+		id      string
+		subject string
+		body    string
+		fields  map[string]string
+	}{
+		{"0123456789ABCDEF0123456789ABCDEF", "Uppercase", "", nil},
+		{"0123456789abcdef0123456789abcde", "Short", "", nil},
+		{"0123-456789abcdef0123456789abcd", "Dash", "", nil},
+		{"00000000000000000000000000000000", "Duplicate", "", nil},
+		{"11111111111111111111111111111111", "Multi\nline", "", nil},
+		{"11111111111111111111111111111111", "Separator", "text\n-- 22222222222222222222222222222222\n", nil},
+		{"11111111111111111111111111111111", "Field", "", map[string]string{"Bad:Key": "x"}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%q did not panic", c.subject)
+				}
+			}()
+			RegisterCatalogEntry(c.id, c.subject, c.body, c.fields)
+		}()
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultChunkSize = 32 << 10
+
+// chunkSize returns the maximum MESSAGE size of a chunk, or zero if entries
+// are not chunked.
+func chunkSize(opts *HandlerOptions) int {
+	switch {
+	case opts != nil && (opts.ChunkMessages || !LargeMessageSupport):
+		return cmp.Or(opts.ChunkSize, defaultChunkSize)
+	case !LargeMessageSupport:
+		return defaultChunkSize
+	}
+	return 0
+}
+
+// splitMessage at UTF-8 boundaries into parts which are at most size bytes
+// long (unless a character is longer than size).
+func splitMessage(message []byte, size int) [][]byte {
+	var parts [][]byte
+
+	for len(message) > size {
+		n := size
+		for n > 0 && !utf8.RuneStart(message[n]) {
+			n--
+		}
+		if n == 0 {
+			_, n = utf8.DecodeRune(message)
+		}
+		parts = append(parts, message[:n])
+		message = message[n:]
+	}
+
+	if len(message) > 0 || len(parts) == 0 {
+		parts = append(parts, message)
+	}
+	return parts
+}
+
+// chunkEntry splits the MESSAGE of an entry across multiple entries.  The
+// first chunk contains the other fields; the subsequent ones contain only
+// PRIORITY and CODE_* fields.  Nil is returned if the MESSAGE is not longer
+// than size.
+func chunkEntry(b []byte, size int) [][]byte {
+	var (
+		message []byte
+		common  []byte // PRIORITY and CODE_* fields.
+	)
+
+	rangeFields(b, func(name string, value []byte) {
+		switch {
+		case name == "MESSAGE":
+			if message == nil {
+				message = value
+			}
+		case name == "PRIORITY", strings.HasPrefix(name, "CODE_"):
+			common = appendField(common, name, value)
+		}
+	})
+	if len(message) <= size {
+		return nil
+	}
+
+	parts := splitMessage(message, size)
+
+	var id [16]byte
+	rand.Read(id[:])
+	idField := "CHUNK_ID=" + hex.EncodeToString(id[:]) + "\n"
+	count := "/" + strconv.Itoa(len(parts)) + "\n"
+
+	chunks := make([][]byte, 0, len(parts))
+
+	var first []byte
+	messageDone := false
+	rangeFields(b, func(name string, value []byte) {
+		if name == "MESSAGE" && !messageDone {
+			value = parts[0]
+			messageDone = true
+		}
+		first = appendField(first, name, value)
+	})
+	first = append(first, idField...)
+	first = append(first, "CHUNK=1"+count...)
+	chunks = append(chunks, first)
+
+	for i, part := range parts[1:] {
+		var c []byte
+		c = append(c, common...)
+		c = appendField(c, "MESSAGE", part)
+		c = append(c, idField...)
+		c = append(c, "CHUNK="...)
+		c = strconv.AppendInt(c, int64(i+2), 10)
+		c = append(c, count...)
+		chunks = append(chunks, c)
+	}
+
+	return chunks
+}
+
+// sendChunks sends an entry which was too large for a datagram as multiple
+// entries.  The original error is returned if the entry can't be chunked.
+func (r *root) sendChunks(err error, b []byte, sock *net.UnixConn, addr *net.UnixAddr) error {
+	chunks := chunkEntry(b, r.chunkSize)
+	if chunks == nil {
+		return err
+	}
+
+	var errs []error
+	for _, c := range chunks {
+		if _, _, err := sock.WriteMsgUnix(c, nil, addr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessage(t *testing.T) {
+	for _, x := range []struct {
+		message string
+		size    int
+		expect  []string
+	}{
+		{"", 3, []string{""}},
+		{"abc", 3, []string{"abc"}},
+		{"abcdefg", 3, []string{"abc", "def", "g"}},
+		{"aäöb", 3, []string{"aä", "öb"}},
+		{"a€", 3, []string{"a", "€"}},
+		{"€€", 2, []string{"€", "€"}}, // Longer than size.
+	} {
+		var parts []string
+		for _, p := range splitMessage([]byte(x.message), x.size) {
+			parts = append(parts, string(p))
+		}
+		if !slices.Equal(parts, x.expect) {
+			t.Errorf("%q/%d: %q", x.message, x.size, parts)
+		}
+	}
+}
+
+func TestChunkMessages(t *testing.T) {
+	const size = 16 << 10
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		ChunkMessages: true,
+		ChunkSize:     size,
+		Identifier:    "chunky",
+	})
+
+	message := strings.Repeat("line of text ÄÖ€\n", 20000)
+	logger := slog.New(h)
+	logger.Warn("small")
+	logger.Warn(message)
+
+	_, count, _ := strings.Cut(recv.wait(t, 2)[1]["CHUNK"], "/")
+	n, err := strconv.Atoi(count)
+	if err != nil || n < len(message)/size {
+		t.Fatalf("chunk count: %q", count)
+	}
+	entries := recv.wait(t, 1+n)
+	if len(entries) != 1+n {
+		t.Fatalf("%d entries", len(entries))
+	}
+	if m := entries[0]; m["MESSAGE"] != "small" || m["CHUNK_ID"] != "" {
+		t.Errorf("small entry: %q", m)
+	}
+	chunks := entries[1:]
+
+	id := chunks[0]["CHUNK_ID"]
+	if len(id) != 32 {
+		t.Errorf("CHUNK_ID: %q", id)
+	}
+
+	var reassembled strings.Builder
+
+	for i, m := range chunks {
+		if m["CHUNK_ID"] != id {
+			t.Errorf("chunk %d: CHUNK_ID: %q", i, m["CHUNK_ID"])
+		}
+		if s := strconv.Itoa(i+1) + "/" + count; m["CHUNK"] != s {
+			t.Errorf("chunk %d: CHUNK: %q", i, m["CHUNK"])
+		}
+		if m["PRIORITY"] != "4" || !strings.HasSuffix(m["CODE_FILE"], "chunk_test.go") || m["CODE_LINE"] == "" || m["CODE_FUNC"] == "" {
+			t.Errorf("chunk %d: common fields: %q %q %q %q", i, m["PRIORITY"], m["CODE_FILE"], m["CODE_LINE"], m["CODE_FUNC"])
+		}
+		if identifier := m["SYSLOG_IDENTIFIER"]; (i == 0) != (identifier == "chunky") {
+			t.Errorf("chunk %d: SYSLOG_IDENTIFIER: %q", i, identifier)
+		}
+
+		part := m["MESSAGE"]
+		if len(part) > size || !utf8.ValidString(part) {
+			t.Errorf("chunk %d: MESSAGE of %d bytes", i, len(part))
+		}
+		reassembled.WriteString(part)
+	}
+
+	if reassembled.String() != message {
+		t.Error("reassembled message differs")
+	}
+}
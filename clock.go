@@ -0,0 +1,29 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"time"
+)
+
+// Clock is a source of the current time.  It can be replaced for testing or
+// for running on a synthetic clock.  Implementations must be safe for
+// concurrent use.
+type Clock interface {
+	// Now returns the current wall clock time.
+	Now() time.Time
+
+	// Monotonic returns the time elapsed since an arbitrary fixed point.  It
+	// must not decrease.
+	Monotonic() time.Duration
+}
+
+// SystemClock is the default Clock.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time           { return time.Now() }
+func (systemClock) Monotonic() time.Duration { return time.Since(monotonicStart) }
@@ -0,0 +1,143 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Program sjournal-cat sends lines read from standard input to the journal.
+//
+// Usage:
+//
+//	sjournal-cat [flags] [KEY=value...]
+//
+// The optional arguments are added as journal fields to every entry.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"import.name/sjournal"
+)
+
+const defaultSocket = "/run/systemd/journal/socket"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stderr))
+}
+
+// run the program and return the exit status.
+func run(args []string, stdin io.Reader, stderr io.Writer) int {
+	var (
+		priority   = 6
+		level      = slog.LevelInfo
+		identifier string
+		prefix     string
+		socket     = defaultSocket
+		fallback   bool
+	)
+
+	flags := flag.NewFlagSet("sjournal-cat", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s [flags] [KEY=value...]\n\nFlags:\n", flags.Name())
+		flags.PrintDefaults()
+	}
+
+	parsePriority := func(s string) (err error) {
+		if priority, err = sjournal.ParsePriority(s); err == nil {
+			level, err = sjournal.ParseLevel(s)
+		}
+		return
+	}
+	flags.Func("priority", "journald priority name or number (default info)", parsePriority)
+	flags.Func("level", "alias for -priority", parsePriority)
+	flags.StringVar(&identifier, "identifier", identifier, "SYSLOG_IDENTIFIER field")
+	flags.StringVar(&prefix, "prefix", prefix, "message prefix")
+	flags.StringVar(&socket, "socket", socket, "journald socket path")
+	flags.BoolVar(&fallback, "stderr-fallback", fallback, "write to stderr if the socket doesn't exist")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	fields := make(map[string]string)
+	for _, arg := range flags.Args() {
+		key, value, found := strings.Cut(arg, "=")
+		if !found || key == "" {
+			fmt.Fprintf(stderr, "%s: invalid field: %q\n", flags.Name(), arg)
+			return 2
+		}
+		fields[key] = value
+	}
+
+	if fallback {
+		if _, err := os.Stat(socket); err != nil {
+			return copyLines(stdin, stderr, prefix, stderr)
+		}
+	}
+
+	h, err := sjournal.NewHandler(&sjournal.HandlerOptions{
+		Level:      slog.LevelDebug,
+		Prefix:     prefix,
+		Identifier: identifier,
+		Fields:     fields,
+		Socket:     socket,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", flags.Name(), err)
+		return 2
+	}
+	defer h.Close()
+
+	var (
+		ctx    = context.Background()
+		attr   = sjournal.Priority(priority)
+		status = 0
+	)
+
+	err = scanLines(stdin, func(line string) {
+		r := slog.NewRecord(time.Now(), level, line, 0)
+		r.AddAttrs(attr)
+		if err := h.Handle(ctx, r); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", flags.Name(), err)
+			status = 1
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", flags.Name(), err)
+		status = 1
+	}
+
+	return status
+}
+
+// copyLines from r to w with a prefix.
+func copyLines(r io.Reader, w io.Writer, prefix string, stderr io.Writer) int {
+	err := scanLines(r, func(line string) {
+		fmt.Fprintf(w, "%s%s\n", prefix, line)
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "sjournal-cat: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func scanLines(r io.Reader, f func(string)) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		f(s.Text())
+	}
+	return s.Err()
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"import.name/sjournal/sjournaltest"
+)
+
+func TestRun(t *testing.T) {
+	sock := sjournaltest.NewSocket(t)
+
+	var stderr bytes.Buffer
+	args := []string{"-socket", sock.Path, "-priority", "warning", "-identifier", "test", "-prefix", "pre: ", "FOO=bar"}
+	if status := run(args, strings.NewReader("hello\nworld\n"), &stderr); status != 0 {
+		t.Fatalf("status %d: %s", status, stderr.String())
+	}
+
+	entries := sock.Receive(2)
+	for i, msg := range []string{"hello", "world"} {
+		e := entries[i]
+		for name, want := range map[string]string{
+			"PRIORITY":          "4",
+			"SYSLOG_IDENTIFIER": "test",
+			"FOO":               "bar",
+			"MESSAGE":           "pre: " + msg,
+		} {
+			if s := e.Get(name); s != want {
+				t.Errorf("entry %d: %s=%q", i, name, s)
+			}
+		}
+	}
+}
+
+func TestRunLevel(t *testing.T) {
+	sock := sjournaltest.NewSocket(t)
+
+	var stderr bytes.Buffer
+	if status := run([]string{"-socket", sock.Path, "-level", "3"}, strings.NewReader("x"), &stderr); status != 0 {
+		t.Fatalf("status %d: %s", status, stderr.String())
+	}
+
+	if e := sock.Receive(1)[0]; e.Get("PRIORITY") != "3" {
+		t.Errorf("entry: %q", e)
+	}
+}
+
+func TestRunSendFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing")
+
+	var stderr bytes.Buffer
+	if status := run([]string{"-socket", path}, strings.NewReader("hello\n"), &stderr); status != 1 {
+		t.Errorf("status %d: %s", status, stderr.String())
+	}
+}
+
+func TestRunFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing")
+
+	var stderr bytes.Buffer
+	args := []string{"-socket", path, "-stderr-fallback", "-prefix", "> "}
+	if status := run(args, strings.NewReader("hello\nworld\n"), &stderr); status != 0 {
+		t.Fatalf("status %d: %s", status, stderr.String())
+	}
+	if s := stderr.String(); s != "> hello\n> world\n" {
+		t.Errorf("stderr: %q", s)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{"-priority", "loud"},
+		{"-level", "8"},
+		{"FOO"},
+		{"=bar"},
+	} {
+		var stderr bytes.Buffer
+		if status := run(args, strings.NewReader(""), &stderr); status != 2 {
+			t.Errorf("%q: status %d", args, status)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func callerPCForTest() uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:])
+	return pcs[0]
+}
+
+func TestCodeLocationSuffix(t *testing.T) {
+	a, b := callerPCForTest(), callerPCForTest() // Same line.
+	c := callerPCForTest()
+
+	for _, pc := range []uintptr{0, a, b, c} {
+		f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		expect := fmt.Sprintf("\nCODE_FILE=%s\nCODE_LINE=%d\nCODE_FUNC=%s\n", f.File, f.Line, f.Function)
+
+		loc := lookupCodeLocation(pc)
+		if string(loc.suffix) != expect {
+			t.Errorf("suffix %q; expected %q", loc.suffix, expect)
+		}
+		if loc.file != f.File || loc.line != f.Line || loc.function != f.Function {
+			t.Errorf("location %v", *loc)
+		}
+		if lookupCodeLocation(pc) != loc {
+			t.Error("location not cached")
+		}
+	}
+
+	if a == b {
+		t.Fatal("same pc")
+	}
+	if lookupCodeLocation(a) != lookupCodeLocation(b) {
+		t.Error("location not shared")
+	}
+	if lookupCodeLocation(a) == lookupCodeLocation(c) {
+		t.Error("different lines share location")
+	}
+}
+
+func BenchmarkCodeLocation(b *testing.B) {
+	pc := callerPCForTest()
+
+	b.Run("cold", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			b.StopTimer()
+			codeLocationCache.Clear()
+			clear(codeLocationShared)
+			b.StartTimer()
+			lookupCodeLocation(pc)
+		}
+	})
+
+	b.Run("warm", func(b *testing.B) {
+		lookupCodeLocation(pc)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for range b.N {
+			lookupCodeLocation(pc)
+		}
+	})
+}
+
+func BenchmarkHandle(b *testing.B) {
+	h, err := NewHandler(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer h.Close()
+	h.root.sink = func([]byte) error { return nil }
+
+	ctx := context.Background()
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "message", callerPCForTest())
+	r.AddAttrs(slog.String("key", "value"), slog.Int("n", 1))
+
+	b.Run("cold", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			b.StopTimer()
+			codeLocationCache.Clear()
+			clear(codeLocationShared)
+			b.StartTimer()
+			h.Handle(ctx, r)
+		}
+	})
+
+	b.Run("warm", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			h.Handle(ctx, r)
+		}
+	})
+}
+
+func TestSourceLevel(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{SourceLevel: LevelWarn})
+
+	pc := callerPCForTest()
+	if _, found := codeLocationCache.Load(pc); found {
+		t.Fatal("location cached already")
+	}
+
+	ctx := context.Background()
+	for _, level := range []slog.Level{LevelDebug, LevelWarn - 1} {
+		if err := h.Handle(ctx, slog.NewRecord(time.Now(), level, "without", pc)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, found := codeLocationCache.Load(pc); found {
+		t.Error("location cached for skipped records")
+	}
+	for _, level := range []slog.Level{LevelWarn, LevelError} {
+		if err := h.Handle(ctx, slog.NewRecord(time.Now(), level, "with", pc)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, found := codeLocationCache.Load(pc); !found {
+		t.Error("location not cached")
+	}
+
+	for i, m := range recv.wait(t, 4) {
+		_, file := m["CODE_FILE"]
+		_, line := m["CODE_LINE"]
+		_, fn := m["CODE_FUNC"]
+
+		if with := m["MESSAGE"] == "with"; file != with || line != with || fn != with {
+			t.Errorf("entry %d: %q", i, m)
+		}
+		if m["MESSAGE"] == "with" && !strings.HasSuffix(m["CODE_FUNC"], ".TestSourceLevel") {
+			t.Errorf("entry %d: CODE_FUNC=%q", i, m["CODE_FUNC"])
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	osHostname        = os.Hostname
+	osExecutable      = os.Executable
+	osGetwd           = os.Getwd
+	procContainerInfo = containerInfoFromProc
+)
+
+// maxArgvLen limits the size of the ARGV field.
+const maxArgvLen = 4096
+
+// config is the part of the options which can be changed using Reload.  It
+// is shared by all handlers derived from the same NewHandler call.
+type config struct {
+	level        slog.Leveler
+	msgPrefix    string
+	timeFormat   string
+	timeLocation *time.Location
+	fields       []byte // Encoded Identifier and Fields.
+	fieldList    []configField
+}
+
+// configField is an encoded field in config.fields.
+type configField struct {
+	name  string
+	value string
+	data  []byte
+}
+
+// appendField encodes a field and records it in fieldList.
+func (c *config) appendField(name, value string) {
+	start := len(c.fields)
+	c.fields = appendField(c.fields, name, value)
+	c.fieldList = append(c.fieldList, configField{name, value, c.fields[start:len(c.fields):len(c.fields)]})
+}
+
+// appendFields writes the fields to b, except the ones which are overridden.
+func (c *config) appendFields(b *buffer, override map[string]string) {
+	if len(override) == 0 {
+		b.Write(c.fields)
+		return
+	}
+	for _, f := range c.fieldList {
+		if _, found := override[f.name]; !found {
+			b.Write(f.data)
+		}
+	}
+}
+
+// newConfig encodes the reloadable options.  Reserved field names in Fields
+// and invalid UTF-8 are treated according to the handler's policies, which
+// cannot be reloaded.
+func newConfig(opts *HandlerOptions, reserved reservedFields, policy UTF8Policy) (*config, error) {
+	c := &config{
+		level:        opts.Level,
+		msgPrefix:    opts.Prefix,
+		timeFormat:   opts.TimeFormat,
+		timeLocation: opts.TimeLocation,
+	}
+
+	if opts.Identifier != "" {
+		c.appendField("SYSLOG_IDENTIFIER", policy.apply(opts.Identifier))
+	}
+
+	if hostname := opts.Hostname; hostname != "" || opts.IncludeHostname {
+		if hostname == "" {
+			hostname, _ = osHostname()
+		}
+		if hostname != "" {
+			c.appendField("HOSTNAME", policy.apply(hostname))
+		}
+	}
+
+	if opts.IncludeContainer {
+		info := procContainerInfo()
+		if info.id != "" {
+			c.appendField("CONTAINER_ID", info.id[:containerShortIDLen])
+			c.appendField("CONTAINER_ID_FULL", info.id)
+		}
+		if info.podUID != "" {
+			c.appendField("KUBERNETES_POD_UID", info.podUID)
+		}
+	}
+
+	if opts.IncludeProcessInfo {
+		if exe, err := osExecutable(); err == nil && exe != "" {
+			c.appendField("EXECUTABLE", policy.apply(exe))
+		}
+		if cwd, err := osGetwd(); err == nil && cwd != "" {
+			c.appendField("CWD", policy.apply(cwd))
+		}
+		if argv := processArgv(os.Args); argv != "" {
+			c.appendField("ARGV", policy.apply(argv))
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(opts.Fields)) {
+		name, ok := fieldName(key)
+		if !ok {
+			return nil, fmt.Errorf("sjournal: invalid field name: %q", key)
+		}
+		if name, ok = reserved.name(name); ok {
+			c.appendField(name, policy.apply(opts.Fields[key]))
+		}
+	}
+
+	return c, nil
+}
+
+// processArgv joins the arguments, truncating the result to maxArgvLen
+// bytes without splitting a UTF-8 sequence.
+func processArgv(args []string) string {
+	s := strings.Join(args, " ")
+	if len(s) <= maxArgvLen {
+		return s
+	}
+	n := maxArgvLen
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func (c *config) enabled(l slog.Level) bool {
+	minLevel := slog.LevelDebug
+	if c.level != nil {
+		minLevel = c.level.Level()
+	}
+	return l >= minLevel
+}
+
+// Reload replaces the Level, Prefix, TimeFormat, TimeLocation, Identifier,
+// hostname, container and process fields and Fields of the handler and all
+// handlers derived from the same NewHandler call.  Each record is handled
+// either with the old or the new configuration.  Prefixes added with
+// ExtendPrefix are retained.  Time values added using WithAttrs before the
+// Reload call keep their old format.
+//
+// Socket and QueueSize cannot be changed live; an error is returned if they
+// differ from the current configuration (empty Socket is not considered a
+// change).  (SetSocket can be used to change the socket.)  The options are
+// validated like in NewHandler.  Other options are ignored; e.g. Fields are
+// subject to the ReservedFields and UTF8Policy given to NewHandler.
+func (h *Handler) Reload(opts *HandlerOptions) error {
+	if opts == nil {
+		opts = new(HandlerOptions)
+	}
+
+	if opts.Socket != "" && opts.Socket != h.root.addr.Load().Name {
+		return errors.New("sjournal: socket cannot be changed by Reload")
+	}
+
+	queueSize := 0
+	if h.root.queue != nil {
+		queueSize = h.root.queue.size
+	}
+	if max(opts.QueueSize, 0) != queueSize {
+		return errors.New("sjournal: queue size cannot be changed by Reload")
+	}
+
+	if err := errors.Join(validateConfig(opts, h.reserved.policy)...); err != nil {
+		return err
+	}
+
+	c, err := newConfig(opts, h.reserved, h.utf8Policy)
+	if err != nil {
+		return err
+	}
+
+	h.root.config.Store(c)
+
+	for _, v := range h.variants {
+		vopts := *opts
+		v.root.override(&vopts)
+		if err := v.Reload(&vopts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestStaticFields(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Identifier:   "test",
+		Fields:       map[string]string{"unit_name": "a", "Other": "b\nc"},
+		AllowInvalid: true,
+	})
+
+	slog.New(h).WithGroup("g").Info("hello")
+
+	m := recv.wait(t, 1)[0]
+	for key, value := range map[string]string{
+		"SYSLOG_IDENTIFIER": "test",
+		"UNIT_NAME":         "a",
+		"OTHER":             "b\nc",
+	} {
+		if s := m[key]; s != value {
+			t.Errorf("%s: %q", key, s)
+		}
+	}
+
+	if _, err := NewHandler(&HandlerOptions{Fields: map[string]string{"_bad": ""}}); err == nil {
+		t.Error("invalid field name accepted")
+	}
+}
+
+func TestReload(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level:      slog.LevelInfo,
+		Prefix:     "old: ",
+		TimeFormat: time.DateOnly,
+		Identifier: "old",
+	})
+
+	logger := slog.New(h.ExtendPrefix("ext: ")).With("x", 1)
+	ts := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	logger.Debug("hidden")
+	logger.Info("before", "t", ts)
+
+	err := h.Reload(&HandlerOptions{
+		Level:      slog.LevelDebug,
+		Prefix:     "new: ",
+		TimeFormat: time.TimeOnly,
+		Identifier: "new",
+		Fields:     map[string]string{"FOO": "bar"},
+		Socket:     recv.path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Debug("after", "t", ts)
+
+	ms := recv.wait(t, 2)
+	if len(ms) != 2 {
+		t.Fatalf("%d entries", len(ms))
+	}
+
+	if s := ms[0]["MESSAGE"]; s != "old: ext: before x=1 t=2006-01-02" {
+		t.Errorf("old message: %q", s)
+	}
+	if s := ms[0]["SYSLOG_IDENTIFIER"]; s != "old" {
+		t.Errorf("old identifier: %q", s)
+	}
+	if _, found := ms[0]["FOO"]; found {
+		t.Error("old entry has FOO")
+	}
+
+	if s := ms[1]["MESSAGE"]; s != "new: ext: after x=1 t=15:04:05" {
+		t.Errorf("new message: %q", s)
+	}
+	if s := ms[1]["SYSLOG_IDENTIFIER"]; s != "new" {
+		t.Errorf("new identifier: %q", s)
+	}
+	if s := ms[1]["FOO"]; s != "bar" {
+		t.Errorf("new FOO: %q", s)
+	}
+
+	if err := h.Reload(&HandlerOptions{Socket: "/nonexistent"}); err == nil {
+		t.Error("socket change accepted")
+	}
+	if err := h.Reload(&HandlerOptions{QueueSize: 10}); err == nil {
+		t.Error("queue size change accepted")
+	}
+}
+
+func TestReloadReserved(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		ReservedFields: RenameReserved,
+		ReservedPrefix: "X_",
+	})
+
+	// The reserved field policy is not reloaded.
+	err := h.Reload(&HandlerOptions{
+		Fields: map[string]string{"SYSLOG_FOO": "bar"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Reload(&HandlerOptions{
+		ReservedFields: RejectReserved,
+		Fields:         map[string]string{"SYSLOG_FOO": "bar"},
+	}); err != nil {
+		t.Error("reserved field rejected:", err)
+	}
+
+	slog.New(h).Info("test")
+
+	m := recv.wait(t, 1)[0]
+	if s := m["X_SYSLOG_FOO"]; s != "bar" {
+		t.Errorf("X_SYSLOG_FOO: %q", s)
+	}
+	if s, found := m["SYSLOG_FOO"]; found {
+		t.Errorf("SYSLOG_FOO: %q", s)
+	}
+}
+
+func TestReloadConcurrent(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{Prefix: "a "})
+	logger := slog.New(h)
+
+	const (
+		numWorkers = 4
+		numRecords = 50
+	)
+
+	var wg sync.WaitGroup
+	for range numWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range numRecords {
+				logger.Info("msg")
+			}
+		}()
+	}
+
+	for i := range 20 {
+		prefix := "a "
+		if i%2 == 0 {
+			prefix = "b "
+		}
+		if err := h.Reload(&HandlerOptions{Prefix: prefix, Identifier: prefix[:1]}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+
+	wg.Wait()
+
+	for _, m := range recv.wait(t, numWorkers*numRecords) {
+		msg := m["MESSAGE"]
+		if msg != "a msg" && msg != "b msg" {
+			t.Fatalf("message: %q", msg)
+		}
+		if id := m["SYSLOG_IDENTIFIER"]; id != "" && id != msg[:1] {
+			t.Errorf("message %q with identifier %q", msg, id)
+		}
+	}
+}
+
+func TestHostname(t *testing.T) {
+	resolved, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	for _, x := range []struct {
+		opts   HandlerOptions
+		expect string
+	}{
+		{HandlerOptions{}, ""},
+		{HandlerOptions{IncludeHostname: true}, resolved},
+		{HandlerOptions{Hostname: "override"}, "override"},
+		{HandlerOptions{IncludeHostname: true, Hostname: "override"}, "override"},
+	} {
+		h, recv := newTestHandler(t, &x.opts)
+		slog.New(h).Info("hello")
+
+		if s, found := recv.wait(t, 1)[0]["HOSTNAME"]; s != x.expect || found != (x.expect != "") {
+			t.Errorf("%#v: hostname %q", x.opts, s)
+		}
+	}
+}
+
+func TestHostnameError(t *testing.T) {
+	orig := osHostname
+	defer func() { osHostname = orig }()
+	osHostname = func() (string, error) { return "", errors.New("test") }
+
+	h, recv := newTestHandler(t, &HandlerOptions{IncludeHostname: true})
+	slog.New(h).Info("hello")
+
+	if s, found := recv.wait(t, 1)[0]["HOSTNAME"]; found {
+		t.Errorf("hostname %q", s)
+	}
+}
+
+func TestProcessInfo(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, recv := newTestHandler(t, &HandlerOptions{IncludeProcessInfo: true})
+	slog.New(h).Info("hello")
+
+	m := recv.wait(t, 1)[0]
+	if s := m["EXECUTABLE"]; s != exe {
+		t.Errorf("executable %q", s)
+	}
+	if s := m["CWD"]; s != cwd {
+		t.Errorf("cwd %q", s)
+	}
+	if s := m["ARGV"]; s != strings.Join(os.Args, " ") {
+		t.Errorf("argv %q", s)
+	}
+}
+
+func TestProcessInfoError(t *testing.T) {
+	origExecutable, origGetwd := osExecutable, osGetwd
+	defer func() { osExecutable, osGetwd = origExecutable, origGetwd }()
+	osExecutable = func() (string, error) { return "", errors.New("test") }
+	osGetwd = func() (string, error) { return "", errors.New("test") }
+
+	h, recv := newTestHandler(t, &HandlerOptions{IncludeProcessInfo: true})
+	slog.New(h).Info("hello")
+
+	m := recv.wait(t, 1)[0]
+	for _, name := range []string{"EXECUTABLE", "CWD"} {
+		if s, found := m[name]; found {
+			t.Errorf("%s %q", name, s)
+		}
+	}
+	if _, found := m["ARGV"]; !found {
+		t.Error("no argv")
+	}
+}
+
+func TestProcessArgv(t *testing.T) {
+	if s := processArgv([]string{"prog", "-v", "arg"}); s != "prog -v arg" {
+		t.Errorf("short: %q", s)
+	}
+	if s := processArgv(nil); s != "" {
+		t.Errorf("empty: %q", s)
+	}
+
+	long := processArgv([]string{"prog", strings.Repeat("x", maxArgvLen)})
+	if long != "prog "+strings.Repeat("x", maxArgvLen-5) {
+		t.Errorf("long: %d bytes", len(long))
+	}
+
+	multibyte := processArgv([]string{"pp", strings.Repeat("ä", maxArgvLen)})
+	if len(multibyte) != maxArgvLen-1 || !utf8.ValidString(multibyte) {
+		t.Errorf("multibyte: %d bytes", len(multibyte))
+	}
+}
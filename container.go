@@ -0,0 +1,174 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+)
+
+// containerShortIDLen is the length of the CONTAINER_ID field, like in
+// Docker's journald logging driver.
+const containerShortIDLen = 12
+
+// containerInfo is derived from the cgroup and mount information of the
+// process.  The fields are empty if they can't be determined.
+type containerInfo struct {
+	id     string // 64 hexadecimal digits.
+	podUID string
+}
+
+// containerInfoFromProc is cached, as the container can't change.
+var containerInfoFromProc = sync.OnceValue(func() containerInfo {
+	return readContainerInfo("/proc/self/cgroup", "/proc/self/mountinfo")
+})
+
+// readContainerInfo parses the files; missing files are ignored.
+func readContainerInfo(cgroupPath, mountinfoPath string) containerInfo {
+	var info containerInfo
+	if data, err := os.ReadFile(cgroupPath); err == nil {
+		info = parseCgroup(data)
+	}
+	if info.id == "" || info.podUID == "" {
+		if data, err := os.ReadFile(mountinfoPath); err == nil {
+			m := parseMountinfo(data)
+			if info.id == "" {
+				info.id = m.id
+			}
+			if info.podUID == "" {
+				info.podUID = m.podUID
+			}
+		}
+	}
+	return info
+}
+
+// parseCgroup looks for container ids and pod uids in the cgroup paths of
+// /proc/self/cgroup (both cgroup v1 and v2 layouts).  Inside a cgroup
+// namespace the paths don't have them.
+func parseCgroup(data []byte) containerInfo {
+	var info containerInfo
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(s.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		for _, name := range strings.Split(fields[2], "/") {
+			if id := containerIDFromCgroup(name); id != "" {
+				info.id = id
+			} else if uid := podUIDFromCgroup(name); uid != "" {
+				info.podUID = uid
+			}
+		}
+
+		if info.id != "" {
+			break
+		}
+	}
+
+	return info
+}
+
+// containerIDFromCgroup recognizes cgroup names such as
+// "<id>", "docker-<id>.scope", "cri-containerd-<id>.scope", "crio-<id>.scope"
+// and "libpod-<id>.scope".
+func containerIDFromCgroup(name string) string {
+	name = strings.TrimSuffix(name, ".scope")
+	if i := strings.LastIndexAny(name, "-:"); i >= 0 {
+		name = name[i+1:]
+	}
+	if isContainerID(name) {
+		return name
+	}
+	return ""
+}
+
+// podUIDFromCgroup recognizes cgroup names such as "pod<uid>" (cgroupfs
+// driver) and "kubepods-besteffort-pod<uid>.slice" (systemd driver, with
+// underscores instead of dashes).
+func podUIDFromCgroup(name string) string {
+	name = strings.TrimSuffix(name, ".slice")
+	i := strings.LastIndex(name, "pod")
+	if i < 0 {
+		return ""
+	}
+	uid := strings.ReplaceAll(name[i+len("pod"):], "_", "-")
+	if isUUID(uid) {
+		return uid
+	}
+	return ""
+}
+
+// parseMountinfo looks for container ids and pod uids in the roots of the
+// mounts listed in /proc/self/mountinfo.  Container runtimes bind-mount files
+// such as /etc/hostname from directories named after them, e.g.
+// /var/lib/docker/containers/<id>/hostname and
+// /var/lib/kubelet/pods/<uid>/etc-hosts.
+func parseMountinfo(data []byte) containerInfo {
+	var info containerInfo
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		// mount-ID parent-ID major:minor root mount-point ...
+		fields := strings.Fields(s.Text())
+		if len(fields) < 5 {
+			continue
+		}
+
+		names := strings.Split(fields[3], "/")
+		for i := 1; i < len(names); i++ {
+			switch names[i-1] {
+			case "containers":
+				if info.id == "" && isContainerID(names[i]) {
+					info.id = names[i]
+				}
+			case "pods":
+				if info.podUID == "" && isUUID(names[i]) {
+					info.podUID = names[i]
+				}
+			}
+		}
+	}
+
+	return info
+}
+
+func isContainerID(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range []byte(s) {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range []byte(s) {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+				return false
+			}
+		}
+	}
+	return true
+}
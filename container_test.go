@@ -0,0 +1,59 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"path"
+	"testing"
+)
+
+const (
+	testDockerID = "3f4a1b2c9d8e7f60123456789abcdef0123456789abcdef0123456789abcdef0"
+	testK8sID    = "9c1e2d3f4a5b6c7d8e9f00112233445566778899aabbccddeeff001122334455"
+	testPodUID   = "1b2c3d4e-5f60-4a7b-8c9d-0e1f2a3b4c5d"
+)
+
+func TestContainerInfo(t *testing.T) {
+	for name, expect := range map[string]containerInfo{
+		"docker-v1":      {id: testDockerID},
+		"docker-v2":      {id: testDockerID},
+		"docker-systemd": {id: testDockerID},
+		"k8s-systemd":    {id: testK8sID, podUID: testPodUID},
+		"k8s-cgroupfs":   {id: testK8sID, podUID: testPodUID},
+		"k8s-namespaced": {podUID: testPodUID},
+		"host-v1":        {},
+		"host-v2":        {},
+		"missing":        {},
+	} {
+		dir := path.Join("testdata", "container")
+		info := readContainerInfo(path.Join(dir, name+".cgroup"), path.Join(dir, name+".mountinfo"))
+		if info != expect {
+			t.Errorf("%s: %+v", name, info)
+		}
+	}
+}
+
+func TestIncludeContainer(t *testing.T) {
+	orig := procContainerInfo
+	defer func() { procContainerInfo = orig }()
+
+	procContainerInfo = func() containerInfo {
+		return containerInfo{id: testK8sID, podUID: testPodUID}
+	}
+	h, recv := newTestHandler(t, &HandlerOptions{IncludeContainer: true})
+	slog.New(h).Info("container")
+
+	procContainerInfo = func() containerInfo { return containerInfo{} }
+	h, recv2 := newTestHandler(t, &HandlerOptions{IncludeContainer: true})
+	slog.New(h).Info("host")
+
+	if m := recv.wait(t, 1)[0]; m["CONTAINER_ID"] != testK8sID[:12] || m["CONTAINER_ID_FULL"] != testK8sID || m["KUBERNETES_POD_UID"] != testPodUID {
+		t.Errorf("container entry: %q", m)
+	}
+	if m := recv2.wait(t, 1)[0]; m["CONTAINER_ID"] != "" || m["CONTAINER_ID_FULL"] != "" || m["KUBERNETES_POD_UID"] != "" {
+		t.Errorf("host entry: %q", m)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+type contextFieldsKey struct{}
+
+// contextFields is immutable; nested ContextWithFields calls create merged
+// copies.
+type contextFields struct {
+	values   map[string]string // By field name.
+	encoded  []byte            // Fields in name order.
+	invalid  []slog.Attr       // Keys which are not valid field names.
+	reserved bool              // Some values have reserved names.
+}
+
+// ContextWithFields returns a context which carries journal fields.  The
+// handler emits them with every record which is logged using the context,
+// after the fields added by the Fields option and WithFields; if the names
+// are the same, the context's value replaces the other one.  The field names
+// are derived from the keys like with Field; the keys are checked when the
+// fields are attached, and the ones which are not valid field names are
+// included in the message as normal attributes (outside of groups) instead.
+// The fields are merged with the ones already carried by the context: if the
+// names (or the keys of the invalid ones) are the same, the new value
+// replaces the old one.  Other handlers ignore the fields.
+func ContextWithFields(ctx context.Context, fields map[string]string) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+
+	parent := fieldsFromContext(ctx)
+
+	cf := &contextFields{
+		values: make(map[string]string, len(fields)),
+	}
+	if parent != nil {
+		maps.Copy(cf.values, parent.values)
+		for _, a := range parent.invalid {
+			if _, found := fields[a.Key]; !found {
+				cf.invalid = append(cf.invalid, a)
+			}
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		if name, ok := fieldName(key); ok {
+			cf.values[name] = fields[key]
+		} else {
+			cf.invalid = append(cf.invalid, slog.String(key, fields[key]))
+		}
+	}
+
+	cf.encode()
+	return context.WithValue(ctx, contextFieldsKey{}, cf)
+}
+
+func (cf *contextFields) encode() {
+	for _, name := range slices.Sorted(maps.Keys(cf.values)) {
+		cf.encoded = appendField(cf.encoded, name, cf.values[name])
+		if reservedFieldName(name) {
+			cf.reserved = true
+		}
+	}
+}
+
+// reserveContextFields applies the ReservedFields policy to context fields.
+func (s *handleState) reserveContextFields(cf *contextFields) *contextFields {
+	cf2 := &contextFields{
+		values:  make(map[string]string, len(cf.values)),
+		invalid: cf.invalid,
+	}
+	for _, name := range slices.Sorted(maps.Keys(cf.values)) {
+		if field, ok := s.h.reserved.name(name); ok {
+			cf2.values[field] = cf.values[name]
+		} else {
+			s.rejectField(name)
+		}
+	}
+	cf2.encode()
+	return cf2
+}
+
+func fieldsFromContext(ctx context.Context) *contextFields {
+	if ctx == nil {
+		return nil
+	}
+	cf, _ := ctx.Value(contextFieldsKey{}).(*contextFields)
+	return cf
+}
+
+// appendFields writes the static, per-handler and context fields.
+func (h *Handler) appendFields(b *buffer, cfg *config, cf *contextFields) {
+	if cf == nil {
+		cfg.appendFields(b, h.fields)
+		b.Write(h.encodedFields)
+		return
+	}
+
+	for _, f := range cfg.fieldList {
+		_, handler := h.fields[f.name]
+		_, context := cf.values[f.name]
+		if !handler && !context {
+			b.Write(f.data)
+		}
+	}
+
+	if cf.overrides(h.fields) {
+		for _, name := range slices.Sorted(maps.Keys(h.fields)) {
+			if _, found := cf.values[name]; !found {
+				*b = appendField(*b, name, h.fields[name])
+			}
+		}
+	} else {
+		b.Write(h.encodedFields)
+	}
+
+	b.Write(cf.encoded)
+}
+
+func (cf *contextFields) overrides(fields map[string]string) bool {
+	for name := range fields {
+		if _, found := cf.values[name]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// appendContextAttrs appends the invalid context fields outside of any groups.
+func (s *handleState) appendContextAttrs(attrs []slog.Attr) {
+	prefix := slices.Clone(*s.prefix)
+	*s.prefix = (*s.prefix)[:0]
+	for _, a := range attrs {
+		s.appendAttr(a)
+	}
+	*s.prefix = append((*s.prefix)[:0], prefix...)
+}
@@ -0,0 +1,101 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestContextWithFields(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Fields: map[string]string{"TENANT": "default", "APP": "test"},
+	})
+	logger := slog.New(h.WithFields(map[string]string{"REGION": "eu"})).WithGroup("g")
+
+	outer := ContextWithFields(context.Background(), map[string]string{
+		"request_id": "r1",
+		"TENANT":     "acme",
+		"bad key":    "x",
+	})
+	inner := ContextWithFields(outer, map[string]string{
+		"REQUEST_ID": "r2",
+		"REGION":     "us",
+		"bad key":    "y",
+	})
+
+	logger.InfoContext(context.Background(), "background")
+	logger.InfoContext(outer, "outer", "a", 1)
+	logger.InfoContext(inner, "inner")
+
+	ms := recv.wait(t, 3)
+
+	expect := []map[string]string{
+		{"TENANT": "default", "APP": "test", "REGION": "eu", "REQUEST_ID": ""},
+		{"TENANT": "acme", "APP": "test", "REGION": "eu", "REQUEST_ID": "r1"},
+		{"TENANT": "acme", "APP": "test", "REGION": "us", "REQUEST_ID": "r2"},
+	}
+	for i, m := range ms {
+		for name, value := range expect[i] {
+			if m[name] != value {
+				t.Errorf("entry %d: %s=%q", i, name, m[name])
+			}
+		}
+	}
+
+	if s := ms[1]["MESSAGE"]; s != `outer g.a=1 "bad key"=x` {
+		t.Errorf("message: %q", s)
+	}
+	if s := ms[2]["MESSAGE"]; s != `inner "bad key"=y` {
+		t.Errorf("message: %q", s)
+	}
+
+	// Each field appears once.
+	for i, b := range recv.datagrams() {
+		fields, err := parseProtocolFields(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := make(map[string]bool)
+		for _, f := range fields {
+			if seen[f.key] {
+				t.Errorf("entry %d: duplicate %s", i, f.key)
+			}
+			seen[f.key] = true
+		}
+	}
+
+	if ContextWithFields(outer, nil) != outer {
+		t.Error("empty fields created a new context")
+	}
+}
+
+func TestContextWithFieldsConcurrent(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+	logger := slog.New(h)
+
+	const n = 20
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := ContextWithFields(context.Background(), map[string]string{"REQUEST_ID": fmt.Sprint(i)})
+			logger.InfoContext(ctx, fmt.Sprint("request ", i))
+		}()
+	}
+	wg.Wait()
+
+	for _, m := range recv.wait(t, n) {
+		if id := strings.TrimPrefix(m["MESSAGE"], "request "); m["REQUEST_ID"] != id {
+			t.Errorf("message %q has REQUEST_ID=%q", m["MESSAGE"], m["REQUEST_ID"])
+		}
+	}
+}
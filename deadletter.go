@@ -0,0 +1,202 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	defaultDeadLetterBytes = 16 << 20
+	deadLetterBacklog      = 64
+)
+
+const deadLetterErrorField = "SJOURNAL_ERROR"
+
+// deadLetter appends entries which couldn't be sent to a file in the Journal
+// Export Format.  The file is written by a background goroutine.
+type deadLetter struct {
+	path     string
+	maxBytes int
+	stats    *stats
+
+	mu      sync.Mutex
+	closed  bool
+	entries chan []byte
+	done    chan struct{}
+
+	// Accessed by the background goroutine.
+	file *os.File
+	size int
+}
+
+// newDeadLetter returns nil unless DeadLetterPath is set.
+func newDeadLetter(opts *HandlerOptions, s *stats) *deadLetter {
+	if opts.DeadLetterPath == "" {
+		return nil
+	}
+
+	d := &deadLetter{
+		path:     opts.DeadLetterPath,
+		maxBytes: opts.DeadLetterBytes,
+		stats:    s,
+		entries:  make(chan []byte, deadLetterBacklog),
+		done:     make(chan struct{}),
+	}
+	if d.maxBytes <= 0 {
+		d.maxBytes = defaultDeadLetterBytes
+	}
+	go d.run()
+	return d
+}
+
+// deadLetter writes a copy of an entry which couldn't be sent to the
+// dead-letter file.  It doesn't block: if the background goroutine is behind,
+// the entry is counted in Stats.DeadLetterErrors.
+func (r *root) deadLetter(b []byte, err error) {
+	d := r.deadLetters
+	if d == nil {
+		return
+	}
+
+	msg := err.Error()
+	entry := make([]byte, 0, len(b)+len(deadLetterErrorField)+len(msg)+10)
+	entry = append(entry, b...)
+	if len(b) > 0 && b[len(b)-1] != '\n' {
+		entry = append(entry, '\n')
+	}
+	entry = appendField(entry, deadLetterErrorField, msg)
+	e := appendExportEntry(make([]byte, 0, len(entry)+48), entry, r.clock.Now())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.closed {
+		select {
+		case d.entries <- e:
+			return
+		default:
+		}
+	}
+	d.stats.deadLetterErrors.Add(1)
+}
+
+// close waits until the queued entries have been written.
+func (d *deadLetter) close() {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.entries)
+	}
+	d.mu.Unlock()
+
+	<-d.done
+}
+
+func (d *deadLetter) run() {
+	defer close(d.done)
+
+	for e := range d.entries {
+		if err := d.write(e); err != nil {
+			d.stats.deadLetterErrors.Add(1)
+		} else {
+			d.stats.deadLettered.Add(1)
+		}
+	}
+
+	if d.file != nil {
+		d.file.Close()
+	}
+}
+
+func (d *deadLetter) write(e []byte) error {
+	if len(e) > d.maxBytes {
+		return errors.New("sjournal: entry is larger than DeadLetterBytes")
+	}
+
+	if d.file == nil {
+		if err := d.open(); err != nil {
+			return err
+		}
+	}
+
+	if d.size+len(e) > d.maxBytes {
+		if err := d.rotate(len(e)); err != nil {
+			return err
+		}
+	}
+
+	n, err := d.file.Write(e)
+	d.size += n
+	return err
+}
+
+func (d *deadLetter) open() error {
+	f, err := os.OpenFile(d.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	d.file = f
+	d.size = int(info.Size())
+	return nil
+}
+
+// rotate discards the oldest entries so that at most half of maxBytes is
+// kept, leaving room for an entry of the given size.
+func (d *deadLetter) rotate(need int) error {
+	d.file.Close()
+	d.file = nil
+
+	data, err := os.ReadFile(d.path)
+	if err != nil {
+		return err
+	}
+
+	keep := min(d.maxBytes/2, d.maxBytes-need)
+	data = data[exportTail(data, keep):]
+
+	tmp := d.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, d.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return d.open()
+}
+
+// exportTail returns the offset of the first entry in export format data
+// after which there are at most limit bytes.  Data which cannot be parsed is
+// discarded.
+func exportTail(data []byte, limit int) int {
+	r := bytes.NewReader(data)
+	dec := NewDecoder(r)
+
+	offset := 0
+	for len(data)-offset > limit {
+		if _, err := dec.Next(); err != nil {
+			if err == io.EOF {
+				return offset
+			}
+			return len(data)
+		}
+		offset = len(data) - r.Len() - dec.r.Buffered()
+	}
+	return offset
+}
@@ -0,0 +1,198 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	file := path.Join(dir, "dead.export")
+
+	h, err := NewHandler(&HandlerOptions{
+		Delimiter:      ColonDelimiter,
+		Socket:         path.Join(dir, "nonexistent"),
+		DeadLetterPath: file,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(h)
+	logger.Info("first", "n", 1)
+	logger.Warn("second\nline", "n", 2)
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := h.Stats(); s.DeadLettered != 2 || s.DeadLetterErrors != 0 {
+		t.Errorf("stats: %+v", s)
+	}
+
+	entries := readDeadLetters(t, file)
+	if len(entries) != 2 {
+		t.Fatalf("%d entries", len(entries))
+	}
+
+	for i, expect := range []struct {
+		message  string
+		priority string
+	}{
+		{"first: n=1", "6"},
+		{"second\nline: n=2", "4"},
+	} {
+		e := entries[i]
+		if s := string(e["MESSAGE"]); s != expect.message {
+			t.Errorf("entry %d: message %q", i, s)
+		}
+		if s := string(e["PRIORITY"]); s != expect.priority {
+			t.Errorf("entry %d: priority %q", i, s)
+		}
+		if s := string(e["SJOURNAL_ERROR"]); !strings.Contains(s, "nonexistent") {
+			t.Errorf("entry %d: error %q", i, s)
+		}
+		if _, err := strconv.ParseInt(string(e["__REALTIME_TIMESTAMP"]), 10, 64); err != nil {
+			t.Errorf("entry %d: timestamp: %v", i, err)
+		}
+	}
+}
+
+func TestDeadLetterRotation(t *testing.T) {
+	const maxBytes = 4096
+
+	file := path.Join(t.TempDir(), "dead.export")
+
+	h, err := NewHandler(&HandlerOptions{
+		DeadLetterPath:  file,
+		DeadLetterBytes: maxBytes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.root.sink = func([]byte) error { return io.ErrClosedPipe }
+
+	const count = 100
+
+	for i := range count {
+		if err := h.Handle(context.Background(), slog.NewRecord(h.root.clock.Now(), slog.LevelInfo, strconv.Itoa(i), 0)); err != io.ErrClosedPipe {
+			t.Fatal(err)
+		}
+		for s := h.Stats(); s.DeadLettered+s.DeadLetterErrors <= uint64(i); s = h.Stats() {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	h.Close()
+
+	if s := h.Stats(); s.DeadLettered != count || s.DeadLetterErrors != 0 {
+		t.Errorf("stats: %+v", s)
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > maxBytes {
+		t.Errorf("file size %d", info.Size())
+	}
+
+	entries := readDeadLetters(t, file)
+	if len(entries) == 0 || len(entries) == count {
+		t.Fatalf("%d entries", len(entries))
+	}
+
+	last := entries[len(entries)-1]
+	if s := string(last["MESSAGE"]); s != strconv.Itoa(count-1) {
+		t.Errorf("last message %q", s)
+	}
+
+	first, _ := strconv.Atoi(string(entries[0]["MESSAGE"]))
+	for i, e := range entries {
+		if s := string(e["MESSAGE"]); s != strconv.Itoa(first+i) {
+			t.Errorf("entry %d: message %q", i, s)
+		}
+		if s := string(e["SJOURNAL_ERROR"]); s != io.ErrClosedPipe.Error() {
+			t.Errorf("entry %d: error %q", i, s)
+		}
+	}
+}
+
+func TestDeadLetterTooLarge(t *testing.T) {
+	file := path.Join(t.TempDir(), "dead.export")
+
+	h, err := NewHandler(&HandlerOptions{
+		DeadLetterPath:  file,
+		DeadLetterBytes: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.root.sink = func([]byte) error { return io.ErrClosedPipe }
+
+	slog.New(h).Info(strings.Repeat("x", 100))
+	h.Close()
+
+	if s := h.Stats(); s.DeadLettered != 0 || s.DeadLetterErrors != 1 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+func TestExportTail(t *testing.T) {
+	var data []byte
+	var ends []int
+	for i := range 5 {
+		data = appendField(data, "MESSAGE", strings.Repeat("\n", i))
+		data = append(data, '\n')
+		ends = append(ends, len(data))
+	}
+
+	if n := exportTail(data, len(data)); n != 0 {
+		t.Errorf("no limit: %d", n)
+	}
+	if n := exportTail(data, len(data)-ends[0]); n != ends[0] {
+		t.Errorf("exact: %d", n)
+	}
+	if n := exportTail(data, len(data)-ends[1]+1); n != ends[1] {
+		t.Errorf("inexact: %d", n)
+	}
+	if n := exportTail(data, 0); n != len(data) {
+		t.Errorf("zero: %d", n)
+	}
+	if n := exportTail([]byte("MESSAGE\nxx"), 1); n != 10 {
+		t.Errorf("malformed: %d", n)
+	}
+}
+
+func readDeadLetters(t *testing.T, file string) (entries []map[string][]byte) {
+	t.Helper()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewDecoder(bytes.NewReader(data))
+	for {
+		e, err := dec.Next()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"math"
+	"unicode/utf8"
+)
+
+const (
+	defaultMaxGroupDepth     = 64
+	defaultMaxKeyPrefixLen   = 1024
+	depthLimitedValueLen     = 256
+	depthLimitedField        = "DEPTH_LIMITED=1\n"
+	depthLimitedValueTrailer = "..."
+)
+
+// depthLimit interprets a MaxGroupDepth or MaxKeyPrefixLen option.
+func depthLimit(n, defaultValue int) int {
+	switch {
+	case n == 0:
+		return defaultValue
+	case n < 0:
+		return math.MaxInt
+	default:
+		return n
+	}
+}
+
+// appendDepthLimited appends a group which is nested too deeply (see
+// MaxGroupDepth and MaxKeyPrefixLen) as a single attribute with a truncated
+// rendering of the value, and marks the entry with a DEPTH_LIMITED field.
+func (s *handleState) appendDepthLimited(a slog.Attr) {
+	if !s.depthLimited {
+		s.depthLimited = true
+		s.fields.WriteString(depthLimitedField)
+	}
+	s.appendAttr(slog.String(a.Key, limitedString(a.Value, depthLimitedValueLen)))
+}
+
+// limitedString renders a value like slog.Value.String, but at most n bytes
+// of it.  Groups are traversed iteratively and LogValuers are resolved on the
+// way, so the work is bounded even if the structure isn't.
+func limitedString(v slog.Value, n int) string {
+	type group struct {
+		attrs  []slog.Attr // Remaining attributes.
+		first  bool
+		inline bool // Group with an empty key.
+	}
+
+	var (
+		b     []byte
+		stack []group
+	)
+
+	appendValue := func(v slog.Value) {
+		v = v.Resolve()
+		if v.Kind() == slog.KindGroup {
+			b = append(b, '[')
+			stack = append(stack, group{v.Group(), true, false})
+		} else {
+			b = append(b, v.String()...)
+		}
+	}
+
+	appendValue(v)
+
+	for len(stack) > 0 && len(b) <= n {
+		g := &stack[len(stack)-1]
+		if len(g.attrs) == 0 {
+			stack = stack[:len(stack)-1]
+			if g.inline {
+				if !g.first && len(stack) > 0 {
+					stack[len(stack)-1].first = false
+				}
+			} else {
+				b = append(b, ']')
+			}
+			continue
+		}
+		a := g.attrs[0]
+		g.attrs = g.attrs[1:]
+		if a.Key == "" {
+			// Inline a group with an empty key.
+			if v := a.Value.Resolve(); v.Kind() == slog.KindGroup {
+				stack = append(stack, group{v.Group(), g.first, true})
+				continue
+			}
+		}
+		if !g.first {
+			b = append(b, ' ')
+		}
+		g.first = false
+		b = append(b, a.Key...)
+		b = append(b, '=')
+		appendValue(a.Value)
+	}
+
+	if len(b) > n {
+		for n > 0 && !utf8.RuneStart(b[n]) {
+			n--
+		}
+		return string(b[:n]) + depthLimitedValueTrailer
+	}
+	return string(b)
+}
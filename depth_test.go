@@ -0,0 +1,138 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestDepthLimitDeepGroup(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter: DefaultDelimiter,
+	})
+
+	v := slog.IntValue(1)
+	for range 10000 {
+		v = slog.GroupValue(slog.Attr{Key: "g", Value: v})
+	}
+	slog.New(h).Info("deep", slog.Attr{Key: "g", Value: v}, "after", 2)
+
+	m := recv.wait(t, 1)[0]
+	s := m["MESSAGE"]
+	prefix := "deep " + strings.Repeat("g.", defaultMaxGroupDepth) + "g=\"[g=[g=["
+	if !strings.HasPrefix(s, prefix) {
+		t.Errorf("message: %q", s)
+	}
+	if !strings.HasSuffix(s, "...\" after=2") {
+		t.Errorf("message: %q", s)
+	}
+	if len(s) > len(prefix)+depthLimitedValueLen+32 {
+		t.Errorf("message length: %d", len(s))
+	}
+	if s := m["DEPTH_LIMITED"]; s != "1" {
+		t.Errorf("DEPTH_LIMITED: %q", s)
+	}
+}
+
+type recursiveValuer struct {
+	name string
+}
+
+func (r recursiveValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("name", r.name), slog.Any("self", r))
+}
+
+func TestDepthLimitRecursiveValuer(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter:     DefaultDelimiter,
+		MaxGroupDepth: 2,
+	})
+
+	logger := slog.New(h.WithAttrs([]slog.Attr{slog.Any("r", recursiveValuer{"x"})}))
+	logger.Info("one")
+	logger.Info("two", "r", recursiveValuer{"y"})
+
+	ms := recv.wait(t, 2)
+
+	const limited = `r.self.name=x r.self.self="[name=x self=[name=x self=[`
+	if s := ms[0]["MESSAGE"]; !strings.HasPrefix(s, "one r.name=x "+limited) || len(s) > 300 {
+		t.Errorf("message: %q", s)
+	}
+	if s := ms[1]["MESSAGE"]; !strings.Contains(s, `" r.name=y r.self.name=y r.self.self="[name=y self=[`) || len(s) > 600 {
+		t.Errorf("message: %q", s)
+	}
+	for i, m := range ms {
+		if s := m["DEPTH_LIMITED"]; s != "1" {
+			t.Errorf("entry %d: DEPTH_LIMITED: %q", i, s)
+		}
+	}
+	if n := strings.Count(string(recv.datagrams()[0]), "DEPTH_LIMITED"); n != 1 {
+		t.Errorf("%d DEPTH_LIMITED fields", n)
+	}
+}
+
+func TestDepthLimitKeyPrefix(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter:       DefaultDelimiter,
+		MaxKeyPrefixLen: 8,
+	})
+
+	slog.New(h).WithGroup("abc").Info("wide",
+		slog.Group("de", "x", 1),
+		slog.Group("defg", slog.Group("", "y", 2)),
+	)
+
+	m := recv.wait(t, 1)[0]
+	if s := m["MESSAGE"]; s != `wide abc.de.x=1 abc.defg="[y=2]"` {
+		t.Errorf("message: %q", s)
+	}
+	if s := m["DEPTH_LIMITED"]; s != "1" {
+		t.Errorf("DEPTH_LIMITED: %q", s)
+	}
+}
+
+func TestDepthLimitUnlimited(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter:     DefaultDelimiter,
+		MaxGroupDepth: -1,
+	})
+
+	v := slog.IntValue(1)
+	for range 100 {
+		v = slog.GroupValue(slog.Attr{Key: "g", Value: v})
+	}
+	slog.New(h).Info("deep", slog.Attr{Key: "g", Value: v})
+
+	m := recv.wait(t, 1)[0]
+	if s := m["MESSAGE"]; s != "deep "+strings.Repeat("g.", 100)+"g=1" {
+		t.Errorf("message: %q", s)
+	}
+	if _, found := m["DEPTH_LIMITED"]; found {
+		t.Error("DEPTH_LIMITED")
+	}
+}
+
+func TestLimitedString(t *testing.T) {
+	for _, c := range []struct {
+		v      slog.Value
+		n      int
+		expect string
+	}{
+		{slog.IntValue(123), 10, "123"},
+		{slog.StringValue("äääää"), 3, "ä..."},
+		{slog.GroupValue(slog.Int("a", 1), slog.Group("b", "c", 2, "d", 3)), 100, "[a=1 b=[c=2 d=3]]"},
+		{slog.GroupValue(slog.String("a", "x["), slog.Int("b", 2)), 100, "[a=x[ b=2]"},
+		{slog.GroupValue(), 100, "[]"},
+		{slog.GroupValue(slog.Int("a", 1), slog.Group("", "b", 2), slog.Group(""), slog.Int("c", 3)), 100, "[a=1 b=2 c=3]"},
+		{slog.GroupValue(slog.Group("", "a", 1), slog.Int("b", 2)), 100, "[a=1 b=2]"},
+		{slog.GroupValue(slog.Int("a", 1), slog.Int("b", 2)), 5, "[a=1 ..."},
+	} {
+		if s := limitedString(c.v, c.n); s != c.expect {
+			t.Errorf("%v: %q", c.v, s)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"errors"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+)
+
+// AttrMode determines where attributes are written.
+type AttrMode int
+
+const (
+	// DefaultAttrs is MessageAttrs in HandlerOptions.  In a Destination it
+	// means that the handler's AttrMode is used.
+	DefaultAttrs AttrMode = iota
+
+	// MessageAttrs causes attributes to be formatted into MESSAGE as
+	// key=value pairs.
+	MessageAttrs
+
+	// FieldAttrs causes attributes to be written as journal fields named
+	// after their keys.  Group names and keys are joined by underscores and
+	// converted to uppercase, so that "req.id" becomes REQ_ID.  Attributes
+	// whose keys can't be converted to field names are formatted into
+	// MESSAGE.
+	FieldAttrs
+)
+
+// Destination is an additional recipient of the entries of a handler.  The
+// formatting options of a destination override the handler's options; the
+// zero values mean that the handler's options are used.  Records are encoded
+// once for the handler and the destinations with the same formatting, and
+// separately for each distinct formatting.  The other options (including
+// levels, queueing and budgets) apply also to the destinations, except for
+// Announce, DeadLetterPath, CaptureLevel and MirrorToStderr, which apply only
+// to the handler's own destination.  Stats don't include the destinations
+// with distinct formatting.
+type Destination struct {
+	// Socket path of a journald-compatible receiver.
+	Socket string
+
+	// Transport is used instead of Socket if set.
+	Transport Transport
+
+	AttrMode   AttrMode
+	Identifier string
+	Fields     map[string]string // Replaces the handler's Fields.
+}
+
+func (d *Destination) sameFormat(opts *HandlerOptions) bool {
+	return (d.AttrMode == DefaultAttrs || d.AttrMode == max(opts.AttrMode, MessageAttrs)) &&
+		(d.Identifier == "" || d.Identifier == opts.Identifier) &&
+		(d.Fields == nil || maps.Equal(d.Fields, opts.Fields))
+}
+
+func (d *Destination) sameFormatAs(other *Destination) bool {
+	return d.AttrMode == other.AttrMode && d.Identifier == other.Identifier &&
+		(d.Fields == nil) == (other.Fields == nil) && maps.Equal(d.Fields, other.Fields)
+}
+
+// override the formatting options of a destination variant.
+func (d *Destination) override(opts *HandlerOptions) {
+	if d.AttrMode != DefaultAttrs {
+		opts.AttrMode = d.AttrMode
+	}
+	if d.Identifier != "" {
+		opts.Identifier = d.Identifier
+	}
+	if d.Fields != nil {
+		opts.Fields = d.Fields
+	}
+	opts.Socket = ""
+	opts.SendSockets = 0
+	opts.Uploader = nil
+	opts.Syslog = nil
+	opts.Destinations = nil
+	opts.Announce = false
+	opts.DeadLetterPath = ""
+	opts.CaptureLevel = nil
+	opts.MirrorToStderr = nil
+	opts.Middleware = nil
+	opts.ReplaceRecord = nil
+}
+
+// socketTransport sends entries to a socket using the sockets of a handler.
+type socketTransport struct {
+	r    *root
+	addr *net.UnixAddr
+}
+
+func (t *socketTransport) Send(b []byte, needsFile bool) error {
+	sock := t.r.socket()
+	_, _, err := sock.WriteMsgUnix(b, nil, t.addr)
+	if err != nil {
+		_, err = sendViaFileIfTooLarge(err, b, t.r.largeEntryField, sock, t.addr)
+	}
+	return err
+}
+
+// fanout sends entries to multiple transports.
+type fanout []Transport
+
+func (ts fanout) Send(b []byte, needsFile bool) error {
+	var errs []error
+	for _, t := range ts {
+		if err := t.Send(b, needsFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// initDestinations sets up the transports of the destinations with the same
+// formatting as the handler, and variant handlers for the others.
+func (h *Handler) initDestinations(opts *HandlerOptions) error {
+	var (
+		dests []*Destination
+		sends []fanout
+	)
+
+	for i := range opts.Destinations {
+		d := &opts.Destinations[i]
+
+		t := d.Transport
+		if t == nil {
+			t = &socketTransport{h.root, &net.UnixAddr{Net: "unixgram", Name: d.Socket}}
+		}
+
+		if d.sameFormat(opts) {
+			h.root.fanout = append(h.root.fanout, t)
+			continue
+		}
+
+		j := slices.IndexFunc(dests, d.sameFormatAs)
+		if j < 0 {
+			j = len(dests)
+			dests = append(dests, d)
+			sends = append(sends, nil)
+		}
+		sends[j] = append(sends[j], t)
+	}
+
+	for i, d := range dests {
+		vopts := *opts
+		d.override(&vopts)
+		vopts.Transport = sends[i]
+
+		v, err := NewHandler(&vopts)
+		if err != nil {
+			return err
+		}
+		v.root.override = d.override
+		h.variants = append(h.variants, v)
+		h.root.variants = append(h.root.variants, v.root)
+	}
+
+	return nil
+}
+
+// deriveVariants applies a derivation to the destination variants of a new
+// handler.
+func (h *Handler) deriveVariants(f func(*Handler) *Handler) {
+	if len(h.variants) == 0 {
+		return
+	}
+	vs := make([]*Handler, len(h.variants))
+	for i, v := range h.variants {
+		vs[i] = f(v)
+	}
+	h.variants = vs
+}
+
+// appendAttrField writes an attribute as a journal field (see FieldAttrs).  It
+// returns false if the key can't be converted to a field name.
+func (s *handleState) appendAttrField(key, value string) bool {
+	name, ok := fieldName(strings.ReplaceAll(s.fullKey(key), string(keyComponentSep), "_"))
+	if !ok {
+		return false
+	}
+	if field, ok := s.h.reserved.name(name); ok {
+		*s.fields = appendField(*s.fields, field, value)
+	} else {
+		s.rejectField(name)
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestDestinations(t *testing.T) {
+	recvSame := newTestReceiver(t)
+	recvText := newTestReceiver(t)
+	recvText2 := newTestReceiver(t)
+
+	legacy := Destination{
+		AttrMode:   MessageAttrs,
+		Identifier: "legacy",
+	}
+	text := legacy
+	text.Socket = recvText.path
+	text2 := legacy
+	text2.Socket = recvText2.path
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter:  ColonDelimiter,
+		AttrMode:   FieldAttrs,
+		Identifier: "app",
+		Fields:     map[string]string{"STATIC": "a"},
+		Destinations: []Destination{
+			{Socket: recvSame.path, Identifier: "app"},
+			text,
+			text2,
+		},
+	})
+	if n := len(h.variants); n != 1 {
+		t.Fatalf("%d variants", n)
+	}
+
+	logger := slog.New(h).With("req", 7).WithGroup("g")
+	logger.Info("hello", "n", 1, "bad-key", "x")
+
+	m := recv.wait(t, 1)[0]
+	for name, expect := range map[string]string{
+		"MESSAGE":           "hello: g.bad-key=x",
+		"REQ":               "7",
+		"G_N":               "1",
+		"SYSLOG_IDENTIFIER": "app",
+		"STATIC":            "a",
+	} {
+		if s := m[name]; s != expect {
+			t.Errorf("field mode: %s=%q", name, s)
+		}
+	}
+
+	recvSame.wait(t, 1)
+	if !bytes.Equal(recvSame.datagrams()[0], recv.datagrams()[0]) {
+		t.Errorf("same format: %q", recvSame.datagrams()[0])
+	}
+
+	m = recvText.wait(t, 1)[0]
+	for name, expect := range map[string]string{
+		"MESSAGE":           "hello: req=7 g.n=1 g.bad-key=x",
+		"SYSLOG_IDENTIFIER": "legacy",
+		"STATIC":            "a",
+	} {
+		if s := m[name]; s != expect {
+			t.Errorf("text mode: %s=%q", name, s)
+		}
+	}
+	for _, name := range []string{"REQ", "G_N"} {
+		if _, found := m[name]; found {
+			t.Errorf("text mode: %s field", name)
+		}
+	}
+
+	recvText2.wait(t, 1)
+	if !bytes.Equal(recvText2.datagrams()[0], recvText.datagrams()[0]) {
+		t.Errorf("shared variant: %q", recvText2.datagrams()[0])
+	}
+
+	if err := h.Reload(&HandlerOptions{Identifier: "app2", Level: slog.LevelInfo}); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("filtered")
+	logger.Info("reloaded")
+
+	if s := recv.wait(t, 2)[1]["SYSLOG_IDENTIFIER"]; s != "app2" {
+		t.Errorf("reloaded identifier: %q", s)
+	}
+	ms := recvText.wait(t, 2)
+	if s := ms[1]["SYSLOG_IDENTIFIER"]; s != "legacy" {
+		t.Errorf("reloaded variant identifier: %q", s)
+	}
+	if s := ms[1]["MESSAGE"]; s != "reloaded: req=7" {
+		t.Errorf("reloaded variant message: %q", s)
+	}
+
+	h.Close()
+	if !h.variants[0].root.closed.Load() {
+		t.Error("variant not closed")
+	}
+}
+
+func TestFieldAttrs(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		AttrMode:       FieldAttrs,
+		ReservedFields: RenameReserved,
+	})
+
+	slog.New(h).Info("msg", "user_id", 42, slog.Group("http", "status", 200), "message", "x")
+
+	m := recv.wait(t, 1)[0]
+	for name, expect := range map[string]string{
+		"MESSAGE":     "msg",
+		"USER_ID":     "42",
+		"HTTP_STATUS": "200",
+		"X_MESSAGE":   "x",
+	} {
+		if s := m[name]; s != expect {
+			t.Errorf("%s=%q", name, s)
+		}
+	}
+}
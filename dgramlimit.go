@@ -0,0 +1,34 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+// DatagramLimit returns the estimated maximum size of an encoded entry which
+// can be sent to journald as a datagram; larger entries are passed via files
+// or chunked (see ChunkMessages).  The estimate is initially the send buffer
+// size of the socket (SO_SNDBUF), and it's lowered when sending fails because
+// of the size of an entry.  Zero means that the limit is unknown.  See also
+// Stats.MaxEntryBytes.
+func (h *Handler) DatagramLimit() int {
+	return int(h.root.dgramMax.Load())
+}
+
+// learnDatagramLimit lowers the limit if a send error was caused by the size
+// of the entry.
+func (r *root) learnDatagramLimit(err error, size int) {
+	if !LargeMessageSupport || !tooLarge(err) {
+		return // Other errors don't tell anything about the size.
+	}
+
+	limit := int64(size - 1)
+	for {
+		old := r.dgramMax.Load()
+		if old != 0 && old <= limit {
+			return
+		}
+		if r.dgramMax.CompareAndSwap(old, limit) {
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || unix
+
+package sjournal
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func TestDatagramLimit(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+
+	initial := h.DatagramLimit()
+	if initial <= 0 {
+		t.Fatalf("initial limit: %d", initial)
+	}
+
+	h.root.learnDatagramLimit(errors.New("other"), 100)
+	h.root.learnDatagramLimit(syscall.EMSGSIZE, initial+100)
+	if n := h.DatagramLimit(); n != initial {
+		t.Errorf("limit changed to %d", n)
+	}
+
+	h.root.learnDatagramLimit(syscall.EMSGSIZE, initial-100)
+	if n := h.DatagramLimit(); n != initial-101 {
+		t.Errorf("limit: %d", n)
+	}
+	h.root.learnDatagramLimit(syscall.ENOBUFS, 1000)
+	h.root.learnDatagramLimit(syscall.EMSGSIZE, 2000)
+	if n := h.DatagramLimit(); n != 999 {
+		t.Errorf("limit: %d", n)
+	}
+
+	// Linux rejects datagrams which are almost as large as the send buffer.
+	h2, recv2 := newTestHandler(t, nil)
+	fields := []RawField{{"MESSAGE", []byte("large")}, {"DATA", nil}}
+	fields[1].Value = bytes.Repeat([]byte("x"), initial-20-len("MESSAGE=large\nDATA=\n"))
+	if err := h2.SendFields(fields); err != nil {
+		t.Fatal(err)
+	}
+	recv2.wait(t, 1)
+	size := len(recv2.datagrams()[0])
+	if n := h2.DatagramLimit(); n > initial || runtime.GOOS == "linux" && n != size-1 {
+		t.Errorf("limit after entry of %d bytes: %d", size, n)
+	}
+	if n := h2.Stats().MaxEntryBytes; n != size {
+		t.Errorf("MaxEntryBytes: %d", n)
+	}
+
+	slog.New(h).Info("a")
+	slog.New(h).Info("bb", "x", 1)
+	recv.wait(t, 2)
+
+	var total, largest int
+	for _, b := range recv.datagrams() {
+		total += len(b)
+		largest = max(largest, len(b))
+	}
+	s := h.Stats()
+	if s.SentBytes != uint64(total) || s.MaxEntryBytes != largest {
+		t.Errorf("stats: %d bytes, max %d; expected %d, %d", s.SentBytes, s.MaxEntryBytes, total, largest)
+	}
+	if mean := s.MeanEntryBytes(); mean != float64(total)/2 {
+		t.Errorf("mean: %v", mean)
+	}
+}
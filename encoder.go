@@ -0,0 +1,96 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"strconv"
+	"unicode/utf8"
+)
+
+// encoder holds the handler settings which are consulted for every attribute.
+// It's copied into handleState once per record so that the per-attribute path
+// doesn't need to chase pointers into the handler and its root.
+type encoder struct {
+	quoteStyle    QuoteStyle
+	utf8Policy    UTF8Policy
+	escapeControl bool
+	trackSpans    bool
+	maxAttrs      int
+	offload       int    // OffloadThreshold, or 0.
+	anyFormat     string // Empty means %v.
+	fieldAttrs    bool
+
+	// scalars can be appended directly: values are not redacted, mirrored
+	// to syslog fields, tracked or written as fields.
+	scalars bool
+}
+
+func (h *Handler) newEncoder() encoder {
+	return encoder{
+		quoteStyle:    h.quoteStyle,
+		utf8Policy:    h.utf8Policy,
+		escapeControl: h.escapeControl,
+		trackSpans:    h.trackSpans,
+		maxAttrs:      h.maxAttrs,
+		offload:       h.offloadThreshold,
+		anyFormat:     h.anyFormat,
+		fieldAttrs:    h.fieldAttrs,
+		scalars:       h.redactValue == nil && h.root.syslog == nil && !h.trackSpans && !h.fieldAttrs,
+	}
+}
+
+// quoted reports whether a key or a value is quoted.
+func (e *encoder) quoted(s string) bool {
+	switch e.quoteStyle {
+	case GoQuote, ASCIIQuote:
+		return needsQuoting(s)
+	case MinimalQuote:
+		return needsMinimalQuoting(s)
+	default:
+		return false
+	}
+}
+
+// unquotedASCII reports whether the bytes are ASCII characters which are not
+// quoted on their own or as part of a longer string.  It's conservative: false
+// means that the caller must check the whole string.
+func (e *encoder) unquotedASCII(b []byte) bool {
+	if e.quoteStyle == NeverQuote {
+		return true
+	}
+	for _, c := range b {
+		if c >= utf8.RuneSelf || c == ' ' || c == '=' || c == '"' || c < 0x20 || c == 0x7f {
+			return false
+		}
+		if e.quoteStyle != MinimalQuote && c != '\\' && !safeSet[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// appendScalar appends a number or a boolean attribute without converting the
+// value to a string first.  The output is the same as with Value.String.  It
+// returns false if the value is of another kind.
+func (s *handleState) appendScalar(key string, v slog.Value) bool {
+	switch v.Kind() {
+	case slog.KindInt64:
+		s.appendKey(key)
+		*s.buf = strconv.AppendInt(*s.buf, v.Int64(), 10)
+	case slog.KindUint64:
+		s.appendKey(key)
+		*s.buf = strconv.AppendUint(*s.buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		s.appendKey(key)
+		*s.buf = strconv.AppendFloat(*s.buf, v.Float64(), 'g', -1, 64)
+	case slog.KindBool:
+		s.appendKey(key)
+		*s.buf = strconv.AppendBool(*s.buf, v.Bool())
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func goldenAttrs() []any {
+	return []any{
+		"int", -42,
+		"zero", 0,
+		"uint", uint64(math.MaxUint64),
+		"nan", math.NaN(),
+		"inf", math.Inf(1),
+		"-inf", math.Inf(-1),
+		"big", 1e21,
+		"small", 1e-7,
+		"frac", 0.1,
+		"negzero", math.Copysign(0, -1),
+		"true", true,
+		"false", false,
+		"str", "x y",
+		"empty", "",
+		"", "nokey",
+		"k=v", 1,
+		"k v", 2,
+		"ä", 3,
+		`a\b`, 4,
+		`q"`, 5,
+		"\x01", 6,
+		slog.Group("inner", "n", 7, slog.Group("ä ö", "n", 8)),
+	}
+}
+
+// TestAttrsGolden checks that the encoding of attributes doesn't change.
+func TestAttrsGolden(t *testing.T) {
+	var b strings.Builder
+
+	for _, style := range []QuoteStyle{GoQuote, ASCIIQuote, MinimalQuote, NeverQuote} {
+		h, recv := newTestHandler(t, &HandlerOptions{
+			Delimiter:  ColonDelimiter,
+			QuoteStyle: style,
+		})
+
+		logger := slog.New(h)
+		logger.Info("top", goldenAttrs()...)
+		for _, group := range []string{"req", "a b", "ä", `q"`, `b\s`} {
+			logger.WithGroup(group).Info(group, goldenAttrs()...)
+		}
+
+		for _, m := range recv.wait(t, 6) {
+			fmt.Fprintf(&b, "%d: %s\n", style, m["MESSAGE"])
+		}
+	}
+
+	golden, err := os.ReadFile("testdata/attrs.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); s != string(golden) {
+		t.Errorf("output:\n%s", s)
+	}
+}
+
+// TestAttrsScalars checks that the direct encoding of numbers and booleans
+// matches the general formatting path.
+func TestAttrsScalars(t *testing.T) {
+	for _, style := range []QuoteStyle{GoQuote, ASCIIQuote, MinimalQuote, NeverQuote} {
+		h1, recv1 := newTestHandler(t, &HandlerOptions{QuoteStyle: style})
+		h2, recv2 := newTestHandler(t, &HandlerOptions{
+			QuoteStyle:  style,
+			RedactValue: func(key, value string) string { return value },
+		})
+
+		for _, h := range []*Handler{h1, h2} {
+			slog.New(h).WithGroup("g").Info("msg", goldenAttrs()...)
+		}
+
+		m1 := recv1.wait(t, 1)[0]["MESSAGE"]
+		m2 := recv2.wait(t, 1)[0]["MESSAGE"]
+		if m1 != m2 {
+			t.Errorf("style %d:\n%s\n%s", style, m1, m2)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// EntryBuilder gives EntryHook access to the fields of an assembled entry.
+// Field names must be uppercase, and they must not start with an underscore
+// (like with SendFields).  PRIORITY and MESSAGE can be replaced but not
+// removed.  The entry is serialized by the handler after the hook returns.
+type EntryBuilder struct {
+	fields []RawField
+}
+
+// Field returns the value of the first field with the name.  The value must
+// not be modified.
+func (e *EntryBuilder) Field(name string) ([]byte, bool) {
+	for _, f := range e.fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Fields returns the fields in order.  The values must not be modified.
+func (e *EntryBuilder) Fields() []RawField {
+	return slices.Clone(e.fields)
+}
+
+// AddField sets the value of a field.  If the entry already has fields with
+// the name, the first one is replaced and the others are removed.  Otherwise
+// the field is appended.
+func (e *EntryBuilder) AddField(name, value string) error {
+	return e.AddBinaryField(name, []byte(value))
+}
+
+// AddBinaryField is like AddField, but the value may contain arbitrary bytes.
+// The value is copied.
+func (e *EntryBuilder) AddBinaryField(name string, value []byte) error {
+	if err := checkBuilderFieldName(name); err != nil {
+		return err
+	}
+	if name == "PRIORITY" && (len(value) != 1 || value[0] < '0' || value[0] >= '0'+numPriorities) {
+		return fmt.Errorf("sjournal: invalid priority: %q", value)
+	}
+
+	value = slices.Clone(value)
+	if value == nil {
+		value = []byte{}
+	}
+
+	i := slices.IndexFunc(e.fields, func(f RawField) bool { return f.Name == name })
+	if i < 0 {
+		e.fields = append(e.fields, RawField{name, value})
+		return nil
+	}
+	e.fields[i].Value = value
+	rest := slices.DeleteFunc(e.fields[i+1:], func(f RawField) bool { return f.Name == name })
+	e.fields = e.fields[:i+1+len(rest)]
+	return nil
+}
+
+// RemoveField removes the fields with the name.
+func (e *EntryBuilder) RemoveField(name string) error {
+	if err := checkBuilderFieldName(name); err != nil {
+		return err
+	}
+	if name == "PRIORITY" || name == "MESSAGE" {
+		return fmt.Errorf("sjournal: cannot remove %s field", name)
+	}
+
+	e.fields = slices.DeleteFunc(e.fields, func(f RawField) bool { return f.Name == name })
+	return nil
+}
+
+func checkBuilderFieldName(name string) error {
+	if n, ok := fieldName(name); !ok || n != name {
+		return fmt.Errorf("sjournal: invalid field name: %q", name)
+	}
+	return nil
+}
+
+// applyEntryHook parses the entry, calls EntryHook, and serializes the entry
+// into the buffer.  PRIORITY stays first, since it can't be removed.
+func (s *handleState) applyEntryHook(r slog.Record) {
+	var e EntryBuilder
+	rangeFields(slices.Clone(*s.buf), func(name string, value []byte) {
+		e.fields = append(e.fields, RawField{name, value})
+	})
+
+	s.h.entryHook(r, &e)
+
+	b := (*s.buf)[:0]
+	for _, f := range e.fields {
+		b = appendField(b, f.Name, f.Value)
+	}
+	*s.buf = b
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEntryHook(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter: ColonDelimiter,
+		Fields:    map[string]string{"STATIC": "x", "DROPME": "y"},
+		EntryHook: func(r slog.Record, e *EntryBuilder) {
+			message, _ := e.Field("MESSAGE")
+			if _, found := e.Field("CODE_FILE"); !found {
+				t.Error("no CODE_FILE")
+			}
+
+			sum := sha256.Sum256(message)
+			for _, err := range []error{
+				e.AddField("DIGEST", hex.EncodeToString(sum[:])),
+				e.AddField("STATIC", "overridden"),
+				e.AddBinaryField("BLOB", []byte("a\nb")),
+				e.AddField("PRIORITY", "2"),
+				e.RemoveField("DROPME"),
+				e.AddField("LEVEL", r.Level.String()),
+			} {
+				if err != nil {
+					t.Error(err)
+				}
+			}
+
+			for i, err := range []error{
+				e.AddField("lower", "x"),
+				e.AddField("_PID", "1"),
+				e.AddField("PRIORITY", "8"),
+				e.RemoveField("PRIORITY"),
+				e.RemoveField("MESSAGE"),
+			} {
+				if err == nil {
+					t.Errorf("invalid operation %d succeeded", i)
+				}
+			}
+		},
+	})
+
+	slog.New(h).Info("hello", "a", 1)
+
+	recv.wait(t, 1)
+	b := recv.datagrams()[0]
+
+	fields := datagramFields(b)
+	if !strings.HasPrefix(string(b), "PRIORITY=2\n") {
+		t.Errorf("entry starts with %q", fields[0])
+	}
+
+	sum := sha256.Sum256([]byte("hello: a=1"))
+	for _, expect := range []string{
+		"MESSAGE=hello: a=1",
+		"STATIC=overridden",
+		"DIGEST=" + hex.EncodeToString(sum[:]),
+		"BLOB=a\nb",
+		"LEVEL=INFO",
+	} {
+		if !slices.Contains(fields, expect) {
+			t.Errorf("missing %q in %q", expect, fields)
+		}
+	}
+	for _, f := range fields {
+		if strings.HasPrefix(f, "DROPME=") {
+			t.Errorf("field not removed: %q", f)
+		}
+	}
+	if n := strings.Count(string(b), "STATIC"); n != 1 {
+		t.Errorf("%d STATIC fields", n)
+	}
+	if !strings.Contains(string(b), "BLOB\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n") {
+		t.Error("BLOB is not length-encoded")
+	}
+}
+
+func TestEntryHookPriority(t *testing.T) {
+	h, err := NewHandler(&HandlerOptions{
+		Budgets:      []Budget{{Bytes: 1}},
+		BudgetExempt: slog.LevelError,
+		EntryHook: func(r slog.Record, e *EntryBuilder) {
+			switch r.Message {
+			case "promoted":
+				e.AddField("PRIORITY", "3")
+			case "demoted":
+				e.AddField("PRIORITY", "6")
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	var sent []string
+	h.root.sink = func(b []byte) error {
+		sent = append(sent, string(b[:priorityOffset+1]))
+		return nil
+	}
+
+	logger := slog.New(h)
+	logger.Info("plain")
+	logger.Info("promoted")
+	logger.Error("demoted")
+
+	if !slices.Equal(sent, []string{"PRIORITY=3"}) {
+		t.Errorf("sent: %q", sent)
+	}
+	if s := h.Stats(); s.Dropped[DropBudget] != 2 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+func TestEntryBuilderReplace(t *testing.T) {
+	e := EntryBuilder{fields: []RawField{
+		{"PRIORITY", []byte("6")},
+		{"A", []byte("1")},
+		{"B", []byte("2")},
+		{"A", []byte("3")},
+		{"C", []byte("4")},
+		{"A", []byte("5")},
+	}}
+
+	if err := e.AddField("A", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, f := range e.Fields() {
+		names = append(names, f.Name+"="+string(f.Value))
+	}
+	if s := strings.Join(names, " "); s != "PRIORITY=6 A=x B=2 C=4" {
+		t.Error(s)
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !plan9
+
+package sjournal
+
+import (
+	"errors"
+	"syscall"
+)
+
+// findErrno in the error chain.
+func findErrno(err error) (int, bool) {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return int(errno), true
+	}
+	return 0, false
+}
@@ -0,0 +1,9 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+func findErrno(err error) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+)
+
+// maxErrorCauses limits the number of ERROR_CAUSE fields per error.
+const maxErrorCauses = 10
+
+// Error returns an "err" attribute which the handler includes in the message,
+// and also emits as journal fields: ERROR (message), ERROR_TYPE (Go type),
+// ERRNO (if the chain contains a syscall.Errno, and the record doesn't have an
+// ERRNO field yet) and ERROR_CAUSE for each error in the unwrap chain (up to a
+// limit).  Nil error is elided.  Other handlers see a normal "err" attribute.
+func Error(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+	return slog.Any("err", errorValue{err})
+}
+
+type errorValue struct {
+	err error
+}
+
+func (v errorValue) LogValue() slog.Value {
+	return slog.StringValue(v.err.Error())
+}
+
+func (s *handleState) appendErrorFields(err error) {
+	s.appendField("ERROR", err.Error())
+	s.appendField("ERROR_TYPE", fmt.Sprintf("%T", err))
+	s.appendErrno(err)
+
+	causes := 0
+	var walk func(error)
+	walk = func(err error) {
+		var children []error
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			if e := x.Unwrap(); e != nil {
+				children = []error{e}
+			}
+		case interface{ Unwrap() []error }:
+			children = x.Unwrap()
+		}
+
+		for _, e := range children {
+			if causes == maxErrorCauses {
+				return
+			}
+			causes++
+			s.appendField("ERROR_CAUSE", e.Error())
+			walk(e)
+		}
+	}
+	walk(err)
+}
+
+// appendErrno appends the ERRNO field if the error chain contains an errno,
+// unless the record already has one.
+func (s *handleState) appendErrno(err error) {
+	if s.errno {
+		return
+	}
+	if errno, ok := findErrno(err); ok {
+		s.appendField("ERRNO", strconv.Itoa(errno))
+		s.errno = true
+	}
+}
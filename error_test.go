@@ -0,0 +1,155 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"slices"
+	"syscall"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+
+	pathErr := &fs.PathError{Op: "open", Path: "/nonexistent", Err: syscall.ENOENT}
+	wrapped := fmt.Errorf("loading config: %w", pathErr)
+	joined := errors.Join(errors.New("first"), wrapped)
+
+	logger := slog.New(h)
+	logger.Error("wrapped", Error(wrapped))
+	logger.Error("joined", Error(joined))
+	logger.Error("nil", Error(nil))
+
+	recv.wait(t, 3)
+	datagrams := recv.datagrams()
+
+	expect := []struct {
+		message string
+		fields  []testField
+	}{
+		{
+			"wrapped err=\"loading config: open /nonexistent: no such file or directory\"",
+			[]testField{
+				{"ERROR", wrapped.Error()},
+				{"ERROR_TYPE", "*fmt.wrapError"},
+				{"ERRNO", "2"},
+				{"ERROR_CAUSE", pathErr.Error()},
+				{"ERROR_CAUSE", syscall.ENOENT.Error()},
+			},
+		},
+		{
+			"joined err=\"first\\nloading config: open /nonexistent: no such file or directory\"",
+			[]testField{
+				{"ERROR", joined.Error()},
+				{"ERROR_TYPE", "*errors.joinError"},
+				{"ERRNO", "2"},
+				{"ERROR_CAUSE", "first"},
+				{"ERROR_CAUSE", wrapped.Error()},
+				{"ERROR_CAUSE", pathErr.Error()},
+				{"ERROR_CAUSE", syscall.ENOENT.Error()},
+			},
+		},
+		{
+			"nil",
+			nil,
+		},
+	}
+
+	for i, x := range expect {
+		fields, err := parseProtocolFields(datagrams[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var errorFields []testField
+		for _, f := range fields {
+			switch f.key {
+			case "MESSAGE":
+				if f.value != x.message {
+					t.Errorf("entry %d: message %q", i, f.value)
+				}
+			case "ERROR", "ERROR_TYPE", "ERRNO", "ERROR_CAUSE":
+				errorFields = append(errorFields, f)
+			}
+		}
+		if !slices.Equal(errorFields, x.fields) {
+			t.Errorf("entry %d: fields %q", i, errorFields)
+		}
+	}
+}
+
+func TestErrorCauseLimit(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+
+	err := os.ErrNotExist
+	for i := 0; i < maxErrorCauses*2; i++ {
+		err = fmt.Errorf("level %d: %w", i, err)
+	}
+	slog.New(h).Error("deep", Error(err))
+
+	recv.wait(t, 1)
+	fields, _ := parseProtocolFields(recv.datagrams()[0])
+
+	var causes int
+	for _, f := range fields {
+		if f.key == "ERROR_CAUSE" {
+			causes++
+		}
+	}
+	if causes != maxErrorCauses {
+		t.Errorf("%d causes", causes)
+	}
+}
+
+func TestErrorOtherHandler(t *testing.T) {
+	var b bytes.Buffer
+	slog.New(slog.NewTextHandler(&b, nil)).Error("x", Error(os.ErrNotExist), Error(nil))
+
+	if !bytes.HasSuffix(b.Bytes(), []byte(` level=ERROR msg=x err="file does not exist"`+"\n")) {
+		t.Error(b.String())
+	}
+}
+
+func TestErrnoField(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{ErrnoField: true})
+
+	var err error = syscall.ENOENT
+	err = &fs.PathError{Op: "open", Path: "/nonexistent", Err: err}
+	err = fmt.Errorf("loading config: %w", err)
+	err = fmt.Errorf("starting: %w", err)
+
+	logger := slog.New(h)
+	logger.Error("deep", "err", err, "again", err, "other", syscall.EPERM)
+	logger.With("cause", err).Error("with", Error(err), "errno", syscall.EPERM)
+	logger.Error("direct", "errno", syscall.EACCES)
+	logger.Error("none", "err", os.ErrClosed, "n", 2)
+
+	recv.wait(t, 4)
+
+	expect := [][]string{{"2"}, {"2"}, {"13"}, nil}
+
+	for i, datagram := range recv.datagrams() {
+		fields, err := parseProtocolFields(datagram)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var errnos []string
+		for _, f := range fields {
+			if f.key == "ERRNO" {
+				errnos = append(errnos, f.value)
+			}
+		}
+		if !slices.Equal(errnos, expect[i]) {
+			t.Errorf("entry %d: ERRNO %q", i, errnos)
+		}
+	}
+}
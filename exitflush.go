@@ -0,0 +1,128 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// exitFlushTimeout limits the time spent draining the queue by the safety net
+// installed by RegisterExitFlush.
+const exitFlushTimeout = 5 * time.Second
+
+var exitSignals = []os.Signal{syscall.SIGTERM, syscall.SIGINT}
+
+// lifetime is referenced by all handlers derived from the same NewHandler
+// call, but not by the background goroutines, so it becomes unreachable when
+// the application has dropped the handlers.
+type lifetime struct {
+	// Small pointer-free objects may share an allocation with other
+	// objects, which would delay finalization indefinitely.
+	_ *byte
+}
+
+type exitFlush struct {
+	r    *root
+	sigs chan os.Signal
+	done chan struct{}
+	once sync.Once
+}
+
+// RegisterExitFlush installs a best-effort safety net for programs which may
+// exit without calling Shutdown:
+//
+//   - When the process receives SIGTERM or SIGINT, the queued entries are
+//     flushed (for at most a few seconds), after which the signal is raised
+//     again with the default disposition.  The handler remains usable in the
+//     meantime.
+//   - If all handlers derived from the same NewHandler call become
+//     unreachable before they are shut down, the garbage collector's
+//     finalizer drains the queue and shuts down the handler.
+//
+// The returned function unregisters the signal handler and shuts down the
+// handler; it's meant to be deferred in main:
+//
+//	defer h.RegisterExitFlush()()
+//
+// Nothing can be done if the program calls os.Exit or crashes due to an
+// unrecovered panic: deferred functions, signal handlers and finalizers don't
+// run, and the queued entries are lost.  Finalizers are not guaranteed to run
+// before the program exits, or at all.  A program which handles SIGTERM or
+// SIGINT itself should call Shutdown in its own handler instead: it would also
+// see the raised signal again.
+//
+// The safety net is installed only once for handlers derived from the same
+// NewHandler call; subsequent calls return the same function.
+func (h *Handler) RegisterExitFlush() (shutdown func()) {
+	r := h.root
+
+	r.exitFlushOnce.Do(func() {
+		e := &exitFlush{
+			r:    r,
+			sigs: make(chan os.Signal, 1),
+			done: make(chan struct{}),
+		}
+
+		signal.Notify(e.sigs, exitSignals...)
+		go e.run()
+
+		runtime.SetFinalizer(h.lifetime, func(*lifetime) {
+			e.shutdown()
+		})
+
+		r.exitFlush = e.shutdown
+	})
+
+	return r.exitFlush
+}
+
+func (e *exitFlush) run() {
+	select {
+	case sig := <-e.sigs:
+		e.stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), exitFlushTimeout)
+		if q := e.r.queue; q != nil {
+			q.flush(ctx)
+		}
+		cancel()
+
+		raise(sig)
+
+	case <-e.done:
+	}
+}
+
+func (e *exitFlush) stop() {
+	e.once.Do(func() {
+		signal.Stop(e.sigs)
+		close(e.done)
+	})
+}
+
+func (e *exitFlush) shutdown() {
+	e.stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), exitFlushTimeout)
+	defer cancel()
+	e.r.shutdown(ctx, true)
+}
+
+// raise a signal which has been reset to the default disposition.  The
+// process is expected to terminate.
+func raise(sig os.Signal) {
+	if p, err := os.FindProcess(os.Getpid()); err == nil {
+		if p.Signal(sig) == nil {
+			time.Sleep(time.Second) // Wait for delivery.
+		}
+	}
+	os.Exit(1)
+}
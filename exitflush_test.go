@@ -0,0 +1,82 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// slowTransport delays entries so that they stay in the queue.
+type slowTransport struct {
+	Transport
+}
+
+func (t slowTransport) Send(payload []byte, needsFile bool) error {
+	time.Sleep(10 * time.Millisecond)
+	return t.Transport.Send(payload, needsFile)
+}
+
+func TestExitFlushFinalizer(t *testing.T) {
+	tr := new(recordingTransport)
+	r := exitFlushFinalizerHandler(t, tr)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !r.closed.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("handler was not finalized")
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	r.shutdown(nil, false) // Wait for the finalizer.
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if n := len(tr.payloads); n != 20 {
+		t.Errorf("%d entries", n)
+	}
+}
+
+func exitFlushFinalizerHandler(t *testing.T, tr *recordingTransport) *root {
+	h, err := NewHandler(&HandlerOptions{
+		QueueSize: 100,
+		Transport: slowTransport{tr},
+		Announce:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.RegisterExitFlush()
+
+	logger := slog.New(h.WithAttrs([]slog.Attr{slog.Int("n", 1)}))
+	for i := range 19 {
+		logger.Info(strconv.Itoa(i))
+	}
+	return h.root
+}
+
+func TestExitFlushTwice(t *testing.T) {
+	tr := new(recordingTransport)
+	h, err := NewHandler(&HandlerOptions{
+		QueueSize: 100,
+		Transport: tr,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shutdown := h.RegisterExitFlush()
+	h.WithName("x").RegisterExitFlush()
+	h.WithAttrs([]slog.Attr{slog.Int("n", 1)}).(*Handler).RegisterExitFlush()()
+
+	if !h.root.closed.Load() {
+		t.Error("handler was not shut down")
+	}
+	shutdown()
+}
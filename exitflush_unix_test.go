@@ -0,0 +1,89 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package sjournal
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const exitFlushFileEnv = "SJOURNAL_TEST_EXIT_FLUSH_FILE"
+
+func TestExitFlushSignal(t *testing.T) {
+	if file := os.Getenv(exitFlushFileEnv); file != "" {
+		exitFlushChild(t, file)
+		return
+	}
+
+	file := path.Join(t.TempDir(), "entries.export")
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestExitFlushSignal$")
+	cmd.Env = append(os.Environ(), exitFlushFileEnv+"="+file)
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("child: %v\n%s", err, out)
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); !ok || !status.Signaled() || status.Signal() != syscall.SIGTERM {
+		t.Fatalf("child: %v\n%s", err, out)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := NewDecoder(bytes.NewReader(data))
+	for i := 0; ; i++ {
+		e, err := dec.Next()
+		if err == io.EOF {
+			if i != 20 {
+				t.Errorf("%d entries", i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := string(e["MESSAGE"]); s != strconv.Itoa(i) {
+			t.Errorf("entry %d: %q", i, s)
+		}
+	}
+}
+
+func exitFlushChild(t *testing.T, file string) {
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHandler(&HandlerOptions{
+		QueueSize: 100,
+		Transport: slowTransport{NewWriterTransport(f)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.RegisterExitFlush()
+
+	logger := slog.New(h)
+	for i := range 20 {
+		logger.Info(strconv.Itoa(i))
+	}
+
+	syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	time.Sleep(10 * time.Second)
+	t.Fatal("not terminated")
+}
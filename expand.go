@@ -0,0 +1,204 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxStructDepth limits the nesting of expanded structs.
+const maxStructDepth = 8
+
+// maxMapDepth limits the nesting of expanded maps.
+const maxMapDepth = 8
+
+// expand a slice or a struct according to the handler options.
+func (h *Handler) expand(x any) ([]slog.Attr, bool) {
+	if h.expandMaps {
+		if attrs, ok := expandMap(x, 0); ok {
+			return attrs, true
+		}
+	}
+	if h.expandSlices {
+		if attrs, ok := expandSlice(x, h.maxSliceElements); ok {
+			return attrs, true
+		}
+	}
+	if h.expandStructs {
+		return expandStruct(reflect.ValueOf(x), 0, nil)
+	}
+	return nil, false
+}
+
+// expandSlice converts a slice or array of basic values to attributes with
+// indexed keys and a length attribute.
+func expandSlice(x any, limit int) ([]slog.Attr, bool) {
+	v := reflect.ValueOf(x)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+	default:
+		return nil, false
+	}
+
+	switch v.Type().Elem().Kind() {
+	case reflect.Uint8:
+		return nil, false // Byte slices are data rather than lists.
+
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+
+	default:
+		return nil, false
+	}
+
+	n := v.Len()
+	count := n
+	if limit > 0 {
+		count = min(count, limit)
+	}
+
+	attrs := make([]slog.Attr, 0, count+1)
+	for i := range count {
+		attrs = append(attrs, slog.Any(strconv.Itoa(i), v.Index(i).Interface()))
+	}
+	attrs = append(attrs, slog.Int("len", n))
+	return attrs, true
+}
+
+var (
+	errorType     = reflect.TypeFor[error]()
+	logValuerType = reflect.TypeFor[slog.LogValuer]()
+	stringerType  = reflect.TypeFor[fmt.Stringer]()
+)
+
+// expandable reports if a value of the type can be expanded as a struct.
+func expandable(t reflect.Type) bool {
+	if t.Implements(logValuerType) || t.Implements(stringerType) || t.Implements(errorType) {
+		return false
+	}
+	if t.Kind() == reflect.Pointer {
+		return expandable(t.Elem())
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// expandStruct converts the exported fields of a struct (or a non-nil pointer
+// to a struct) to attributes.  visited contains the pointers which have been
+// dereferenced on the path from the outermost struct.
+func expandStruct(v reflect.Value, depth int, visited []uintptr) ([]slog.Attr, bool) {
+	if !v.IsValid() || !expandable(v.Type()) {
+		return nil, false
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, false
+		}
+		visited = append(visited, v.Pointer())
+		v = v.Elem()
+	}
+
+	t := v.Type()
+	attrs := make([]slog.Attr, 0, t.NumField())
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name := f.Name
+		if tag, found := f.Tag.Lookup("slog"); found {
+			name = tag
+		} else if tag, found := f.Tag.Lookup("json"); found {
+			if tag, _, _ = strings.Cut(tag, ","); tag != "" {
+				name = tag
+			}
+		}
+		if name == "-" {
+			continue
+		}
+
+		attrs = append(attrs, structFieldAttr(name, v.Field(i), depth, visited))
+	}
+
+	return attrs, true
+}
+
+func structFieldAttr(name string, v reflect.Value, depth int, visited []uintptr) slog.Attr {
+	// Look through interface-typed fields so that their contents are subject
+	// to the depth limit and cycle detection.
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Pointer && !v.IsNil() && slices.Contains(visited, v.Pointer()) {
+		return slog.String(name, "<cycle>")
+	}
+
+	if depth+1 < maxStructDepth {
+		if attrs, ok := expandStruct(v, depth+1, visited); ok {
+			return slog.Attr{Key: name, Value: slog.GroupValue(attrs...)}
+		}
+	} else if v.IsValid() && expandable(v.Type()) {
+		// Format it so that it won't be expanded by appendAttr.
+		return slog.String(name, fmt.Sprint(v.Interface()))
+	}
+
+	return slog.Any(name, v.Interface())
+}
+
+// expandMap converts a map with string keys or an attribute slice to
+// attributes.  Map keys are sorted.
+func expandMap(x any, depth int) ([]slog.Attr, bool) {
+	switch m := x.(type) {
+	case map[string]string:
+		attrs := make([]slog.Attr, 0, len(m))
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			attrs = append(attrs, slog.String(k, m[k]))
+		}
+		return attrs, true
+
+	case map[string]any:
+		attrs := make([]slog.Attr, 0, len(m))
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			attrs = append(attrs, mapEntryAttr(k, m[k], depth))
+		}
+		return attrs, true
+
+	case []slog.Attr:
+		attrs := make([]slog.Attr, 0, len(m))
+		for _, a := range m {
+			if a.Value.Kind() == slog.KindAny {
+				a = mapEntryAttr(a.Key, a.Value.Any(), depth)
+			}
+			attrs = append(attrs, a)
+		}
+		return attrs, true
+	}
+
+	return nil, false
+}
+
+func mapEntryAttr(name string, x any, depth int) slog.Attr {
+	if depth+1 < maxMapDepth {
+		if attrs, ok := expandMap(x, depth+1); ok {
+			return slog.Attr{Key: name, Value: slog.GroupValue(attrs...)}
+		}
+	} else if _, ok := expandMap(x, depth+1); ok {
+		// Format it so that it won't be expanded by appendAttr.
+		return slog.String(name, fmt.Sprint(x))
+	}
+
+	return slog.Any(name, x)
+}
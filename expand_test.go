@@ -0,0 +1,181 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandSlices(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		ExpandSlices:     true,
+		MaxSliceElements: 3,
+	})
+
+	logger := slog.New(h)
+	logger.With("ports", []int{80, 443}).Info("preformatted")
+	logger.WithGroup("g").Info("grouped", "names", []string{"a b", "c=d", ""})
+	logger.Info("capped", "x", [5]float64{1, 2.5, 3, 4, 5})
+	logger.Info("empty", "x", []bool{})
+	logger.Info("unexpanded", "bytes", []byte{1, 2}, "nested", [][]int{{1}}, "structs", []struct{ A int }{{1}})
+
+	expect := []string{
+		"preformatted ports.0=80 ports.1=443 ports.len=2",
+		`grouped g.names.0="a b" g.names.1="c=d" g.names.2="" g.names.len=3`,
+		"capped x.0=1 x.1=2.5 x.2=3 x.len=5",
+		"empty x.len=0",
+		`unexpanded bytes="[1 2]" nested=[[1]] structs=[{1}]`,
+	}
+
+	ms := recv.wait(t, len(expect))
+	for i, s := range expect {
+		if msg := ms[i]["MESSAGE"]; msg != s {
+			t.Errorf("entry %d: %q", i, msg)
+		}
+	}
+}
+
+func TestExpandSlicesDisabled(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+	slog.New(h).Info("msg", "ports", []int{80, 443})
+
+	if s := recv.wait(t, 1)[0]["MESSAGE"]; s != `msg ports="[80 443]"` {
+		t.Errorf("message: %q", s)
+	}
+}
+
+type testInner struct {
+	Port    int    `json:"port,omitempty"`
+	Host    string `slog:"hostname" json:"host"`
+	Ignored string `json:"-"`
+	private int
+}
+
+type testOuter struct {
+	Name   string
+	Inner  testInner
+	Ptr    *testInner
+	Nil    *testInner
+	Time   time.Time
+	Values []int
+}
+
+type testNode struct {
+	Name string
+	Next *testNode
+}
+
+type testIface struct {
+	Name string
+	Next any
+}
+
+type testDeep struct {
+	Next *testDeep
+}
+
+func TestExpandStructs(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		ExpandStructs: true,
+		ExpandSlices:  true,
+		TimeFormat:    time.DateOnly,
+	})
+
+	a := &testNode{Name: "a"}
+	a.Next = &testNode{Name: "b", Next: a}
+
+	b := &testIface{Name: "a"}
+	b.Next = &testIface{Name: "b", Next: b}
+
+	var deep *testDeep
+	for range maxStructDepth + 2 {
+		deep = &testDeep{deep}
+	}
+
+	logger := slog.New(h)
+	logger.Info("nested", "x", testOuter{
+		Name:   "outer",
+		Inner:  testInner{80, "localhost", "ignored", 1},
+		Ptr:    &testInner{Port: 443},
+		Time:   time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC),
+		Values: []int{1},
+	})
+	logger.Info("pointer", "x", &testInner{Host: "a b"})
+	logger.Info("nil", "x", (*testInner)(nil))
+	logger.Info("cycle", "x", a)
+	logger.Info("interface", "x", b, "y", testIface{Next: testInner{Port: 1}})
+	logger.Info("empty", "x", struct{}{}, "y", 1)
+	logger.Info("deep", "x", deep)
+
+	expect := []string{
+		`nested x.Name=outer x.Inner.port=80 x.Inner.hostname=localhost x.Ptr.port=443 x.Ptr.hostname="" x.Nil=<nil> x.Time=2006-01-02 x.Values.0=1 x.Values.len=1`,
+		`pointer x.port=0 x.hostname="a b"`,
+		`nil x=<nil>`,
+		`cycle x.Name=a x.Next.Name=b x.Next.Next=<cycle>`,
+		`interface x.Name=a x.Next.Name=b x.Next.Next=<cycle> y.Name="" y.Next.port=1 y.Next.hostname=""`,
+		`empty y=1`,
+	}
+
+	ms := recv.wait(t, len(expect)+1)
+	for i, s := range expect {
+		if msg := ms[i]["MESSAGE"]; msg != s {
+			t.Errorf("entry %d: %q", i, msg)
+		}
+	}
+
+	msg := ms[len(expect)]["MESSAGE"]
+	if n := strings.Count(msg, "Next"); n != maxStructDepth {
+		t.Errorf("deep: %d levels: %q", n, msg)
+	}
+}
+
+func TestExpandMaps(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		ExpandMaps: true,
+	})
+
+	deep := map[string]any{"v": 0}
+	for range maxMapDepth {
+		deep = map[string]any{"d": deep}
+	}
+
+	logger := slog.New(h)
+	logger.With("labels", map[string]string{"z": "last", "a": "first one"}).Info("preformatted")
+	logger.WithGroup("g").Info("nested", "m", map[string]any{
+		"b": map[string]any{"y": 2, "x": true},
+		"a": 1.5,
+		"c": []slog.Attr{slog.Int("n", 3), slog.Any("inner", map[string]string{"k": "v"})},
+	})
+	logger.Info("mixed", "m", map[string]any{
+		"err":   errors.New("failed"),
+		"list":  []int{1, 2},
+		"time":  time.Duration(0),
+		"empty": map[string]any{},
+		"nil":   nil,
+	})
+	logger.Info("attrs", "a", []slog.Attr{slog.String("s", "x"), slog.Group("grp", "i", 1)})
+	logger.Info("deep", "m", deep)
+	logger.Info("unexpanded", "m", map[int]string{1: "x"})
+
+	expect := []string{
+		`preformatted labels.a="first one" labels.z=last`,
+		"nested g.m.a=1.5 g.m.b.x=true g.m.b.y=2 g.m.c.n=3 g.m.c.inner.k=v",
+		`mixed m.err=failed m.list="[1 2]" m.nil=<nil> m.time=0s`,
+		"attrs a.s=x a.grp.i=1",
+		"deep m" + strings.Repeat(".d", maxMapDepth) + "=map[v:0]",
+		"unexpanded m=map[1:x]",
+	}
+
+	ms := recv.wait(t, len(expect))
+	for i, s := range expect {
+		if msg := ms[i]["MESSAGE"]; msg != s {
+			t.Errorf("entry %d: %q", i, msg)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+var errExportFieldName = errors.New("sjournal: export format: empty field name")
+
+// Encoder writes entries in the Journal Export Format.
+//
+// Entries in the export format consist of fields in the native protocol
+// format, and each entry is terminated by an empty line.  The terminator is
+// the only boundary between entries in a stream, so it's always written; it's
+// not optional.  Uploader and DeadLetterPath use the same framing.  The output
+// is byte-for-byte what "journalctl -o export" produces for the same fields,
+// minus the fields which journald adds.
+type Encoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewEncoder returns an encoder which writes to w.  Each entry is written
+// with a single Write call.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes an entry consisting of fields in the native protocol format
+// (such as the datagrams sent by Handler) followed by the terminator.  A
+// missing final newline of the last field is added.
+func (e *Encoder) Encode(entry []byte) error {
+	e.buf = appendExportEntry(e.buf[:0], entry, time.Time{})
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+// appendExportEntry appends an entry with the terminator.  If the time is not
+// zero, it's written as the __REALTIME_TIMESTAMP field before the entry.
+func appendExportEntry(b, entry []byte, realtime time.Time) []byte {
+	if !realtime.IsZero() {
+		b = append(b, "__REALTIME_TIMESTAMP="...)
+		b = strconv.AppendInt(b, realtime.UnixMicro(), 10)
+		b = append(b, '\n')
+	}
+	b = append(b, entry...)
+	if len(entry) > 0 && entry[len(entry)-1] != '\n' {
+		b = append(b, '\n')
+	}
+	return append(b, '\n') // Terminator.
+}
+
+// Decoder reads entries in the Journal Export Format, which is produced by
+// "journalctl -o export" and consumed by systemd-journal-remote.  A final
+// entry without the terminator is also accepted, e.g. a single native protocol
+// datagram.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder returns a decoder which reads from r.  The input is buffered, so
+// the decoder may read more than the entries which have been returned.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{bufio.NewReader(r)}
+}
+
+// Next returns the fields of the next entry.  If a field is repeated within
+// an entry, the last value is returned.  io.EOF is returned after the last
+// entry.  io.ErrUnexpectedEOF is returned if the input ends in the middle of
+// a field.
+func (d *Decoder) Next() (map[string][]byte, error) {
+	var entry map[string][]byte
+	err := d.next(func(key string, value []byte) {
+		if entry == nil {
+			entry = make(map[string][]byte)
+		}
+		entry[key] = value
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// NextValues is like Next, but it returns all values of repeated fields in the
+// order in which they appear in the entry.
+func (d *Decoder) NextValues() (map[string][][]byte, error) {
+	var entry map[string][][]byte
+	err := d.next(func(key string, value []byte) {
+		if entry == nil {
+			entry = make(map[string][][]byte)
+		}
+		entry[key] = append(entry[key], value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// next reads an entry, calling add for each field.
+func (d *Decoder) next(add func(key string, value []byte)) error {
+	var found bool
+
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF {
+				if len(line) > 0 {
+					return io.ErrUnexpectedEOF
+				}
+				if found {
+					return nil
+				}
+			}
+			return err
+		}
+
+		line = line[:len(line)-1]
+
+		if len(line) == 0 {
+			if found {
+				return nil
+			}
+			continue // Tolerate extra empty lines.
+		}
+
+		var key string
+		var value []byte
+
+		if i := bytes.IndexByte(line, '='); i >= 0 {
+			key = string(line[:i])
+			value = line[i+1:]
+		} else {
+			key = string(line)
+			if value, err = d.readBinary(); err != nil {
+				return err
+			}
+		}
+
+		if key == "" {
+			return errExportFieldName
+		}
+
+		add(key, value)
+		found = true
+	}
+}
+
+// readBinary reads the size, the data and the terminating newline of a
+// length-encoded value.
+func (d *Decoder) readBinary() ([]byte, error) {
+	var size uint64
+	if err := binary.Read(d.r, binary.LittleEndian, &size); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if size > math.MaxInt64 {
+		return nil, errors.New("sjournal: export format: invalid field size")
+	}
+
+	// The buffer grows as data is read, so a bogus size doesn't cause a huge
+	// allocation.
+	var b bytes.Buffer
+	if _, err := io.CopyN(&b, d.r, int64(size)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if c != '\n' {
+		return nil, errors.New("sjournal: export format: newline expected after binary field")
+	}
+
+	return b.Bytes(), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
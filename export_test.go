@@ -0,0 +1,341 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// dumpExport formats decoded entries deterministically.
+func dumpExport(entries []map[string][]byte) string {
+	var b strings.Builder
+	for i, entry := range entries {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		for _, key := range slices.Sorted(maps.Keys(entry)) {
+			fmt.Fprintf(&b, "%s=%q\n", key, entry[key])
+		}
+	}
+	return b.String()
+}
+
+// encodeExport with all values length-encoded.
+func encodeExport(entries []map[string][]byte) []byte {
+	var b []byte
+	for _, entry := range entries {
+		for _, key := range slices.Sorted(maps.Keys(entry)) {
+			b = append(b, key...)
+			b = append(b, '\n')
+			b = binary.LittleEndian.AppendUint64(b, uint64(len(entry[key])))
+			b = append(b, entry[key]...)
+			b = append(b, '\n')
+		}
+		b = append(b, '\n')
+	}
+	return b
+}
+
+func decodeAll(b []byte) ([]map[string][]byte, error) {
+	d := NewDecoder(bytes.NewReader(b))
+	var entries []map[string][]byte
+	for {
+		entry, err := d.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	for _, name := range []string{
+		"export",
+		"journalctl", // Captured from "journalctl -o export".
+	} {
+		input, err := os.ReadFile("testdata/" + name + ".sample")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := decodeAll(input)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		golden, err := os.ReadFile("testdata/" + name + ".golden")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := dumpExport(entries); s != string(golden) {
+			t.Errorf("%s output:\n%s", name, s)
+		}
+	}
+}
+
+func TestDecoderJournalctlRepeated(t *testing.T) {
+	input, err := os.ReadFile("testdata/journalctl.sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDecoder(bytes.NewReader(input))
+	var last map[string][][]byte
+	for {
+		entry, err := d.NextValues()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = entry
+	}
+
+	if !slices.EqualFunc(last["TAG"], [][]byte{[]byte("first"), []byte("second")}, bytes.Equal) {
+		t.Errorf("TAG: %q", last["TAG"])
+	}
+}
+
+func TestDecoderTruncated(t *testing.T) {
+	input, err := os.ReadFile("testdata/export.sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cut inside the binary MESSAGE value of the second entry.
+	i := bytes.Index(input, []byte("multi-line"))
+	if _, err := decodeAll(input[:i+5]); err != io.ErrUnexpectedEOF {
+		t.Errorf("data: %v", err)
+	}
+
+	// Cut inside the size.
+	i = bytes.Index(input, []byte("MESSAGE\n"))
+	if _, err := decodeAll(input[:i+10]); err != io.ErrUnexpectedEOF {
+		t.Errorf("size: %v", err)
+	}
+
+	// Missing final newline.
+	if _, err := decodeAll([]byte("A=1\nB=2")); err != io.ErrUnexpectedEOF {
+		t.Errorf("line: %v", err)
+	}
+
+	// No trailing empty line.
+	if entries, err := decodeAll([]byte("A=1\n")); err != nil || len(entries) != 1 {
+		t.Errorf("last entry: %v %v", entries, err)
+	}
+
+	if _, err := decodeAll([]byte("=1\n")); !errors.Is(err, errExportFieldName) {
+		t.Errorf("empty key: %v", err)
+	}
+}
+
+func FuzzDecoder(f *testing.F) {
+	input, err := os.ReadFile("testdata/export.sample")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(input)
+	if input, err = os.ReadFile("testdata/journalctl.sample"); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(input)
+	f.Add([]byte("A=1\n\n\nB\n\x01\x00\x00\x00\x00\x00\x00\x00x\n"))
+	f.Add([]byte("A\n\xff\xff\xff\xff\xff\xff\xff\xff"))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		entries, err := decodeAll(b)
+		if err != nil {
+			return
+		}
+
+		again, err := decodeAll(encodeExport(entries))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dumpExport(again) != dumpExport(entries) {
+			t.Errorf("round trip mismatch")
+		}
+	})
+}
+
+// encodeNative encodes the fields of an entry in the native protocol format,
+// without the terminator.
+func encodeNative(entry map[string][]byte) []byte {
+	var b []byte
+	for _, key := range slices.Sorted(maps.Keys(entry)) {
+		b = appendField(b, key, entry[key])
+	}
+	return b
+}
+
+func TestDecoderNextValues(t *testing.T) {
+	d := NewDecoder(strings.NewReader("A=1\nB=x\nA\n\x01\x00\x00\x00\x00\x00\x00\x002\n\nA=3\n"))
+
+	for i, expect := range []map[string][][]byte{
+		{"A": {[]byte("1"), []byte("2")}, "B": {[]byte("x")}},
+		{"A": {[]byte("3")}},
+	} {
+		entry, err := d.NextValues()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !maps.EqualFunc(entry, expect, func(a, b [][]byte) bool {
+			return slices.EqualFunc(a, b, bytes.Equal)
+		}) {
+			t.Errorf("entry %d: %q", i, entry)
+		}
+	}
+
+	if _, err := d.NextValues(); err != io.EOF {
+		t.Errorf("error: %v", err)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	input, err := os.ReadFile("testdata/export.sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := decodeAll(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	for _, entry := range entries {
+		if err := e.Encode(encodeNative(entry)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	again, err := decodeAll(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dumpExport(again) != dumpExport(entries) {
+		t.Errorf("round trip mismatch:\n%s", b.Bytes())
+	}
+
+	// Same framing as journalctl.
+	if !bytes.HasSuffix(input, []byte("\n\n")) || !bytes.HasSuffix(b.Bytes(), []byte("\n\n")) {
+		t.Error("terminator missing")
+	}
+}
+
+// splitExport returns the fields of each entry in the native protocol format,
+// without the terminator.
+func splitExport(t *testing.T, b []byte) [][]byte {
+	t.Helper()
+
+	var entries [][]byte
+	var start int
+
+	for i := 0; i < len(b); {
+		n := bytes.IndexByte(b[i:], '\n')
+		if n < 0 {
+			t.Fatal("truncated line")
+		}
+		line := b[i : i+n]
+		i += n + 1
+
+		switch {
+		case len(line) == 0:
+			entries = append(entries, b[start:i-1])
+			start = i
+
+		case bytes.IndexByte(line, '=') < 0:
+			size := binary.LittleEndian.Uint64(b[i:])
+			i += 8 + int(size) + 1
+		}
+	}
+
+	return entries
+}
+
+// TestEncoderJournalctl checks that Encoder reproduces output captured from
+// "journalctl -o export".
+func TestEncoderJournalctl(t *testing.T) {
+	input, err := os.ReadFile("testdata/journalctl.sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := splitExport(t, input)
+	if len(entries) != 3 {
+		t.Fatalf("%d entries", len(entries))
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	for i, entry := range entries {
+		if i%2 == 1 {
+			entry = entry[:len(entry)-1] // Exercise the added final newline.
+		}
+		if err := e.Encode(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(b.Bytes(), input) {
+		t.Errorf("output differs from journalctl:\n%q", b.Bytes())
+	}
+}
+
+func TestEncoderHandler(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+
+	logger := slog.New(h)
+	logger.Info("first")
+	logger.Info("second\nline", Binary("DATA", []byte("\n\n")))
+	recv.wait(t, 2)
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	for _, datagram := range recv.datagrams() {
+		// Without the final newline.
+		if err := e.Encode(datagram[:len(datagram)-1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := decodeAll(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d entries", len(entries))
+	}
+	for i, m := range recv.entries() {
+		for key, value := range m {
+			if string(entries[i][key]) != value {
+				t.Errorf("entry %d: %s: %q", i, key, entries[i][key])
+			}
+		}
+		if len(entries[i]) != len(m) {
+			t.Errorf("entry %d: %d fields", i, len(entries[i]))
+		}
+	}
+
+	// A single datagram without the terminator is also accepted.
+	single, err := decodeAll(recv.datagrams()[1])
+	if err != nil || len(single) != 1 || dumpExport(single) != dumpExport(entries[1:]) {
+		t.Errorf("datagram: %v %v", single, err)
+	}
+}
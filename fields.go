@@ -0,0 +1,158 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Field returns an attribute which the handler emits as a separate journal
+// field instead of including it in the message.  The field name is the key
+// converted to uppercase; it may contain letters, digits and underscores, and
+// it must not start with a digit or an underscore.  The attribute is included
+// in the message (as a normal attribute) if the key is not a valid field name.
+// Groups don't affect the field name.  Other handlers see a normal string
+// attribute.
+func Field(key, value string) slog.Attr {
+	return slog.Any(key, fieldValue(value))
+}
+
+type fieldValue string
+
+// WithFields returns a handler which emits the journal fields with every
+// entry.  The field names are derived from the keys like with Field; the
+// fields with invalid names are included in the message as normal attributes
+// instead.  The fields are merged with the ones added by the parent handlers
+// and the Fields option: if the names are the same, the new value replaces
+// the old one.
+func (h *Handler) WithFields(fields map[string]string) *Handler {
+	h2 := h.clone()
+	h2.fields = maps.Clone(h.fields)
+	if h2.fields == nil {
+		h2.fields = make(map[string]string, len(fields))
+	}
+
+	var invalid []slog.Attr
+
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		name, ok := fieldName(key)
+		if ok {
+			name, ok = h.reserved.name(name)
+		}
+		if ok {
+			h2.fields[name] = h.utf8Policy.apply(fields[key])
+		} else {
+			invalid = append(invalid, slog.String(key, fields[key]))
+		}
+	}
+
+	h2.encodedFields = nil
+	for _, name := range slices.Sorted(maps.Keys(h2.fields)) {
+		h2.encodedFields = appendField(h2.encodedFields, name, h2.fields[name])
+	}
+	h2.static = new(staticFields)
+
+	// The variants handle the invalid fields themselves.
+	h2.variants = nil
+	if len(invalid) > 0 {
+		h2 = h2.WithAttrs(invalid).(*Handler)
+	}
+	h2.variants = h.variants
+	h2.deriveVariants(func(v *Handler) *Handler { return v.WithFields(fields) })
+	return h2
+}
+
+func (v fieldValue) LogValue() slog.Value {
+	return slog.StringValue(string(v))
+}
+
+// Binary returns an attribute which the handler emits as a separate journal
+// field with the data as is.  The field name is derived from the key like with
+// Field.  If the key is not a valid field name, the attribute is included in
+// the message in base64 encoding.  Other handlers see a base64-encoded string
+// attribute.
+func Binary(key string, data []byte) slog.Attr {
+	return slog.Any(key, binaryValue(data))
+}
+
+type binaryValue []byte
+
+func (v binaryValue) LogValue() slog.Value {
+	return slog.StringValue(base64.StdEncoding.EncodeToString(v))
+}
+
+// SyslogPID returns an attribute which sets the SYSLOG_PID field of a record,
+// overriding HandlerOptions.SyslogPID.  Non-positive values are ignored.  The
+// attribute may also be passed to WithAttrs; the record's own value takes
+// precedence.  Other handlers see a "syslog_pid" attribute.
+func SyslogPID(pid int) slog.Attr {
+	return slog.Any("syslog_pid", syslogPID(pid))
+}
+
+type syslogPID int
+
+func (pid syslogPID) LogValue() slog.Value {
+	return slog.IntValue(int(pid))
+}
+
+// maxFieldNameLen is journald's limit.
+const maxFieldNameLen = 64
+
+// fieldName converts an attribute key to a journal field name.
+func fieldName(key string) (string, bool) {
+	if key == "" || len(key) > maxFieldNameLen {
+		return "", false
+	}
+
+	var upper bool
+
+	for i, c := range []byte(key) {
+		switch {
+		case 'A' <= c && c <= 'Z':
+		case 'a' <= c && c <= 'z':
+			upper = true
+		case '0' <= c && c <= '9', c == '_':
+			if i == 0 {
+				return "", false
+			}
+		default:
+			return "", false
+		}
+	}
+
+	if upper {
+		key = strings.ToUpper(key)
+	}
+	return key, true
+}
+
+// appendField in the native protocol format.  The value is length-encoded if
+// it contains a newline.
+func appendField[T string | []byte](b []byte, key string, value T) []byte {
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\n' {
+			return appendBinaryField(b, key, value)
+		}
+	}
+
+	b = append(b, key...)
+	b = append(b, '=')
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// appendBinaryField in the length-encoded native protocol format.
+func appendBinaryField[T string | []byte](b []byte, key string, value T) []byte {
+	b = append(b, key...)
+	b = append(b, '\n')
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+	b = append(b, value...)
+	return append(b, '\n')
+}
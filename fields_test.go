@@ -0,0 +1,333 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestField(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+
+	logger := slog.New(h)
+	logger.Info("status", Field("http_status", "502"), Field("TENANT", "acme"), "x", 1)
+	logger.WithGroup("g").With(Field("Component", "gc")).Info("derived", Field("multi", "a\nb"), "y", 2)
+	logger.Info("invalid", Field("_PID", "1"), Field("1X", "2"), Field("A-B", "3"))
+
+	ms := recv.wait(t, 3)
+
+	if m := ms[0]; m["MESSAGE"] != "status x=1" || m["HTTP_STATUS"] != "502" || m["TENANT"] != "acme" {
+		t.Errorf("entry 0: %q", m)
+	}
+	if m := ms[1]; m["MESSAGE"] != "derived g.y=2" || m["COMPONENT"] != "gc" || m["MULTI"] != "a\nb" {
+		t.Errorf("entry 1: %q", m)
+	}
+	if m := ms[2]; m["MESSAGE"] != "invalid _PID=1 1X=2 A-B=3" || len(m) != 6 {
+		t.Errorf("entry 2: %q", m)
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	for key, expect := range map[string]string{
+		"foo":     "FOO",
+		"Foo_Bar": "FOO_BAR",
+		"X9":      "X9",
+		"_X":      "",
+		"9X":      "",
+		"é":       "",
+		"foo.bar": "",
+		"":        "",
+		"A012345678901234567890123456789012345678901234567890123456789012":  "A012345678901234567890123456789012345678901234567890123456789012",
+		"A0123456789012345678901234567890123456789012345678901234567890123": "",
+	} {
+		name, ok := fieldName(key)
+		if name != expect || ok != (expect != "") {
+			t.Errorf("%q: %q %v", key, name, ok)
+		}
+	}
+}
+
+func TestPromoteKeys(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		PromoteKeys: map[string]string{
+			"err":        "ERROR",
+			"request_id": "REQUEST_ID",
+			"duration":   "DURATION_MS",
+			"http.path":  "HTTP_PATH",
+			"http":       "HTTP",
+			"elapsed":    "ELAPSED",
+		},
+	})
+
+	logger := slog.New(h)
+	logger.Info("done",
+		"err", errors.New("multi\nline"),
+		"request_id", "abc",
+		"duration", 1500*time.Microsecond,
+		"elapsed", time.Second,
+		"other", 1,
+	)
+	logger.With("request_id", "def").WithGroup("http").Info("request", "path", "/x", "request_id", "nested")
+	logger.With(slog.Group("http", "path", "/y", "method", "GET")).Info("preformatted", "other", "z")
+
+	ms := recv.wait(t, 3)
+
+	if m := ms[0]; m["MESSAGE"] != "done other=1" || m["ERROR"] != "multi\nline" || m["REQUEST_ID"] != "abc" || m["DURATION_MS"] != "1.5" || m["ELAPSED"] != "1s" {
+		t.Errorf("entry 0: %q", m)
+	}
+	if m := ms[1]; m["MESSAGE"] != "request http.request_id=nested" || m["REQUEST_ID"] != "def" || m["HTTP_PATH"] != "/x" {
+		t.Errorf("entry 1: %q", m)
+	}
+	if m := ms[2]; m["MESSAGE"] != "preformatted http.method=GET other=z" || m["HTTP_PATH"] != "/y" || m["HTTP"] != "" {
+		t.Errorf("entry 2: %q", m)
+	}
+}
+
+func TestBinary(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+
+	data := []byte("\x00binary\ndata\x00\xff=")
+
+	logger := slog.New(h)
+	logger.Info("binary", Binary("data", data), Binary("empty", nil))
+	logger.With(Binary("data", data[1:])).Info("derived")
+	logger.Info("invalid", Binary("bad-key", data))
+
+	ms := recv.wait(t, 3)
+
+	if m := ms[0]; m["MESSAGE"] != "binary" || m["DATA"] != string(data) {
+		t.Errorf("entry 0: %q", m)
+	}
+	if s, found := ms[0]["EMPTY"]; !found || s != "" {
+		t.Errorf("empty field: %q %v", s, found)
+	}
+	if m := ms[1]; m["DATA"] != string(data[1:]) {
+		t.Errorf("entry 1: %q", m)
+	}
+	if m := ms[2]; m["MESSAGE"] != "invalid bad-key="+base64.StdEncoding.EncodeToString(data) {
+		t.Errorf("entry 2: %q", m)
+	}
+
+	if LargeMessageSupport {
+		large := bytes.Repeat(data, 20000)
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "large", 0)
+		r.AddAttrs(Binary("large", large))
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatal("large binary field:", err)
+		}
+
+		m := recv.wait(t, 4)[3]
+		if m["MESSAGE"] != "large" {
+			t.Errorf("entry 3: message %q", m["MESSAGE"])
+		}
+		if s := m["LARGE"]; s != string(large) {
+			t.Errorf("entry 3: %d bytes differ from the %d bytes sent", len(s), len(large))
+		}
+	}
+}
+
+func TestBinaryOtherHandler(t *testing.T) {
+	var b bytes.Buffer
+	slog.New(slog.NewTextHandler(&b, nil)).Info("x", Binary("data", []byte{0, 1, 2}))
+
+	if !bytes.Contains(b.Bytes(), []byte("data=AAEC")) {
+		t.Error(b.String())
+	}
+}
+
+func TestGroupField(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		GroupField: "COMPONENT",
+	})
+
+	root := slog.New(h)
+	storage := root.WithGroup("storage")
+	disk := storage.With("x", 1).WithGroup("disk")
+	net := root.WithGroup("net")
+
+	root.Info("root")
+	storage.Info("storage")
+	disk.Info("disk", "y", 2)
+	net.Info("net")
+
+	ms := recv.wait(t, 4)
+	for i, expect := range []string{"", "storage", "storage.disk", "net"} {
+		s, found := ms[i]["COMPONENT"]
+		if s != expect || found != (expect != "") {
+			t.Errorf("entry %d: component %q", i, s)
+		}
+	}
+	if s := ms[2]["MESSAGE"]; s != "disk storage.x=1 storage.disk.y=2" {
+		t.Errorf("message: %q", s)
+	}
+}
+
+func TestGroupFieldConcurrent(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		GroupField: "COMPONENT",
+	})
+
+	const (
+		goroutines = 8
+		count      = 20
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger := slog.New(h).WithGroup("c" + strconv.Itoa(i))
+			for j := 0; j < count; j++ {
+				logger.Info("x", "i", i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, m := range recv.wait(t, goroutines*count) {
+		i := strings.TrimPrefix(m["MESSAGE"], "x c")
+		i = i[:strings.IndexByte(i, '.')]
+		if s := m["COMPONENT"]; s != "c"+i {
+			t.Errorf("component %q in %q", s, m["MESSAGE"])
+		}
+	}
+}
+
+func TestAttrsField(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Prefix:     "prefix: ",
+		AttrsField: "ATTRS",
+	})
+
+	logger := slog.New(h)
+	logger.With("a", 1).WithGroup("g").Info("hello world", "b", 2, "c", "x\ny")
+	logger.Info("plain")
+
+	ms := recv.wait(t, 2)
+
+	if s := ms[0]["MESSAGE"]; s != "prefix: hello world" {
+		t.Errorf("message: %q", s)
+	}
+	m, err := parseMessageValue("msg: " + ms[0]["ATTRS"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, _ := m["g"].(map[string]any)
+	if m["a"] != "1" || g["b"] != "2" || g["c"] != `"x\ny"` {
+		t.Errorf("attrs: %q", ms[0]["ATTRS"])
+	}
+
+	if s := ms[1]["MESSAGE"]; s != "prefix: plain" {
+		t.Errorf("message: %q", s)
+	}
+	if s, found := ms[1]["ATTRS"]; found {
+		t.Errorf("attrs: %q", s)
+	}
+
+	b := appendField(nil, "ATTRS", []byte("a=\"x\ny\""))
+	fields, err := parseProtocolFields(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 1 || fields[0].value != "a=\"x\ny\"" || b[len("ATTRS")] != '\n' {
+		t.Errorf("length-encoded field: %q", b)
+	}
+}
+
+func TestSyslogPID(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+	logger := slog.New(h)
+	logger.Info("absent")
+	logger.Info("record", SyslogPID(123))
+	logger.Info("invalid", SyslogPID(-1))
+	logger.With(SyslogPID(456)).Info("preformatted")
+	logger.With(SyslogPID(456)).Info("override", SyslogPID(789))
+
+	h2, recv2 := newTestHandler(t, &HandlerOptions{SyslogPID: 42})
+	logger2 := slog.New(h2)
+	logger2.Info("handler")
+	logger2.Info("override", SyslogPID(43))
+	logger2.Info("invalid", SyslogPID(0))
+
+	for i, expect := range []string{"", "123", "", "456", "789"} {
+		m := recv.wait(t, 5)[i]
+		if s, found := m["SYSLOG_PID"]; s != expect || found != (expect != "") {
+			t.Errorf("%s: SYSLOG_PID=%q", m["MESSAGE"], s)
+		}
+		if strings.Contains(m["MESSAGE"], "syslog_pid") {
+			t.Errorf("message: %q", m["MESSAGE"])
+		}
+	}
+
+	for i, expect := range []string{"42", "43", "42"} {
+		m := recv2.wait(t, 3)[i]
+		if s := m["SYSLOG_PID"]; s != expect {
+			t.Errorf("%s: SYSLOG_PID=%q", m["MESSAGE"], s)
+		}
+	}
+
+	if _, err := NewHandler(&HandlerOptions{SyslogPID: -1}); err == nil {
+		t.Error("negative SyslogPID accepted")
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Fields: map[string]string{"SERVICE": "test", "ROLE": "primary"},
+	})
+
+	gc := h.WithFields(map[string]string{"subsystem": "gc", "ROLE": "replica"})
+	sweep := gc.WithGroup("sweep").(*Handler).WithFields(map[string]string{"SUBSYSTEM": "gc-sweep", "PHASE": "1"})
+	net := h.WithFields(map[string]string{"SUBSYSTEM": "net", "bad-key": "x"})
+
+	slog.New(gc).With("a", 1).Info("gc")
+	slog.New(sweep).Info("sweep", "b", 2)
+	slog.New(net).Info("net")
+	slog.New(h).Info("root")
+
+	recv.wait(t, 4)
+
+	for i, c := range []struct {
+		fields  []testField
+		message string
+	}{
+		{[]testField{{"SERVICE", "test"}, {"ROLE", "replica"}, {"SUBSYSTEM", "gc"}}, "gc a=1"},
+		{[]testField{{"SERVICE", "test"}, {"PHASE", "1"}, {"ROLE", "replica"}, {"SUBSYSTEM", "gc-sweep"}}, "sweep sweep.b=2"},
+		{[]testField{{"ROLE", "primary"}, {"SERVICE", "test"}, {"SUBSYSTEM", "net"}}, "net bad-key=x"},
+		{[]testField{{"ROLE", "primary"}, {"SERVICE", "test"}}, "root"},
+	} {
+		fields, err := parseProtocolFields(recv.datagrams()[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []testField
+		for _, f := range fields {
+			switch f.key {
+			case "MESSAGE":
+				if f.value != c.message {
+					t.Errorf("entry %d message: %q", i, f.value)
+				}
+			case "PRIORITY", "SYSLOG_TIMESTAMP", "CODE_FILE", "CODE_LINE", "CODE_FUNC":
+			default:
+				got = append(got, f)
+			}
+		}
+		if !slices.Equal(got, c.fields) {
+			t.Errorf("entry %d fields: %q", i, got)
+		}
+	}
+}
@@ -7,15 +7,88 @@
 package sjournal
 
 import (
+	"errors"
 	"os"
+	"sync/atomic"
+	"syscall"
 
 	"golang.org/x/sys/unix"
 )
 
+// Ways to create a nonlinked file, in order of preference.
+const (
+	fileMemfd = iota
+	fileTmpfileShm
+	fileTmpfileTmp
+	fileTemp
+)
+
+var fileStrategyNames = [...]string{
+	fileMemfd:      FileMemfd,
+	fileTmpfileShm: FileTmpfileShm,
+	fileTmpfileTmp: FileTmpfileTmp,
+	fileTemp:       FileTemp,
+}
+
+// These can be replaced by tests.
+var (
+	memfdCreate = unix.MemfdCreate
+	openTmpfile = func(dir string) (*os.File, error) {
+		return os.OpenFile(dir, os.O_RDWR|unix.O_TMPFILE|unix.O_CLOEXEC, 0600)
+	}
+)
+
+// fileStrategy is the first strategy which hasn't been found to be
+// unavailable.
+var fileStrategy atomic.Int32
+
 func createNonlinkedFile() (*os.File, error) {
-	fd, err := unix.MemfdCreate("journal-entry", unix.MFD_CLOEXEC)
-	if err != nil {
-		return nil, err
+	for {
+		s := fileStrategy.Load()
+
+		f, err := createNonlinkedFileUsing(s)
+		if err == nil {
+			activeFileStrategy.Store(fileStrategyNames[s])
+			return f, nil
+		}
+		if s == fileTemp || !fileStrategyUnavailable(s, err) {
+			return nil, err
+		}
+
+		fileStrategy.CompareAndSwap(s, s+1)
+	}
+}
+
+func createNonlinkedFileUsing(s int32) (*os.File, error) {
+	switch s {
+	case fileMemfd:
+		fd, err := memfdCreate("journal-entry", unix.MFD_CLOEXEC)
+		if err != nil {
+			return nil, err
+		}
+		return os.NewFile(uintptr(fd), "journal-entry"), nil
+
+	case fileTmpfileShm:
+		return openTmpfile("/dev/shm")
+
+	case fileTmpfileTmp:
+		return openTmpfile(os.TempDir())
+
+	default:
+		return createTempFile()
+	}
+}
+
+// fileStrategyUnavailable reports whether err means that the strategy will
+// never work.  memfd_create is typically blocked by seccomp.  O_TMPFILE may
+// be unsupported by the kernel or the filesystem, or the directory may be
+// missing or inaccessible.
+func fileStrategyUnavailable(s int32, err error) bool {
+	if errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.EPERM) {
+		return true
+	}
+	if s == fileMemfd {
+		return false
 	}
-	return os.NewFile(uintptr(fd), "journal-entry"), nil
+	return errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.EISDIR) || errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.EACCES)
 }
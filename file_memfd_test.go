@@ -0,0 +1,120 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFileStrategy(t *testing.T) {
+	origMemfdCreate := memfdCreate
+	origOpenTmpfile := openTmpfile
+	t.Cleanup(func() {
+		memfdCreate = origMemfdCreate
+		openTmpfile = origOpenTmpfile
+		fileStrategy.Store(fileMemfd)
+	})
+
+	for _, c := range []struct {
+		name      string
+		memfdErr  error
+		failDirs  []string
+		tmpfileOK bool
+		expect    string
+	}{
+		{"memfd", nil, nil, true, FileMemfd},
+		{"seccomp-eperm", syscall.EPERM, nil, true, FileTmpfileShm},
+		{"seccomp-enosys", syscall.ENOSYS, nil, true, FileTmpfileShm},
+		{"no-shm", syscall.EPERM, []string{"/dev/shm"}, true, FileTmpfileTmp},
+		{"no-tmpfile", syscall.ENOSYS, nil, false, FileTemp},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var memfdCalls, tmpfileCalls int
+
+			fileStrategy.Store(fileMemfd)
+			memfdCreate = func(name string, flags int) (int, error) {
+				memfdCalls++
+				if c.memfdErr != nil {
+					return -1, c.memfdErr
+				}
+				return origMemfdCreate(name, flags)
+			}
+			openTmpfile = func(dir string) (*os.File, error) {
+				tmpfileCalls++
+				if !c.tmpfileOK {
+					return nil, syscall.EOPNOTSUPP
+				}
+				for _, d := range c.failDirs {
+					if d == dir {
+						return nil, syscall.ENOENT
+					}
+				}
+				return os.CreateTemp(t.TempDir(), "tmpfile-*") // O_TMPFILE may not be supported here.
+			}
+
+			h, recv := newTestHandler(t, nil)
+			data := bytes.Repeat([]byte("x"), 300000)
+
+			for i := range 2 {
+				r := slog.NewRecord(time.Now(), slog.LevelInfo, "large", 0)
+				r.AddAttrs(Binary("data", data))
+				if err := h.Handle(context.Background(), r); err != nil {
+					t.Fatal(i, err)
+				}
+			}
+
+			ms := recv.wait(t, 2)
+			for i, m := range ms {
+				if m["MESSAGE"] != "large" || m["DATA"] != string(data) {
+					t.Errorf("entry %d: message %q, data length %d", i, m["MESSAGE"], len(m["DATA"]))
+				}
+			}
+
+			if s := h.Stats().FileStrategy; s != c.expect {
+				t.Errorf("strategy: %q", s)
+			}
+
+			// The blocked syscalls are not retried for the second entry.
+			if c.memfdErr != nil && memfdCalls != 1 {
+				t.Errorf("memfd_create called %d times", memfdCalls)
+			}
+			if expect := map[string]int{
+				FileMemfd:      0,
+				FileTmpfileShm: 2,
+				FileTmpfileTmp: 3,
+				FileTemp:       2,
+			}[c.expect]; tmpfileCalls != expect {
+				t.Errorf("O_TMPFILE attempted %d times", tmpfileCalls)
+			}
+		})
+	}
+}
+
+func TestFileStrategyOtherError(t *testing.T) {
+	origMemfdCreate := memfdCreate
+	t.Cleanup(func() {
+		memfdCreate = origMemfdCreate
+		fileStrategy.Store(fileMemfd)
+	})
+
+	memfdCreate = func(string, int) (int, error) {
+		return -1, syscall.EMFILE
+	}
+
+	if _, err := createNonlinkedFile(); err != syscall.EMFILE {
+		t.Error(err)
+	}
+	if s := fileStrategy.Load(); s != fileMemfd {
+		t.Errorf("strategy changed to %d", s)
+	}
+}
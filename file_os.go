@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-//go:build (darwin || unix) && !linux
+//go:build (darwin || unix) && !linux && !freebsd
 
 package sjournal
 
@@ -11,20 +11,9 @@ import (
 )
 
 func createNonlinkedFile() (*os.File, error) {
-	var ok bool
-
-	f, err := os.CreateTemp("", "journal-entry-*")
-	if err != nil {
-		return nil, err
+	f, err := createTempFile()
+	if err == nil {
+		activeFileStrategy.Store(FileTemp)
 	}
-	defer func() {
-		if !ok {
-			f.Close()
-		}
-	}()
-
-	os.Remove(f.Name())
-	ok = true
-
-	return f, nil
+	return f, err
 }
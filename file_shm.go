@@ -0,0 +1,64 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build freebsd
+
+package sjournal
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	sysShmOpen2 = 571        // Since FreeBSD 13.
+	shmAnon     = uintptr(1) // SHM_ANON
+)
+
+// shmOpenAnon can be replaced by tests.
+var shmOpenAnon = func() (int, error) {
+	const flags = unix.O_RDWR | unix.O_CREAT | unix.O_CLOEXEC
+
+	fd, _, errno := unix.Syscall6(sysShmOpen2, shmAnon, flags, 0600, 0, 0, 0)
+	if errno == syscall.ENOSYS {
+		fd, _, errno = unix.Syscall(unix.SYS_SHM_OPEN, shmAnon, flags, 0600)
+	}
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// shmUnavailable is set when shm_open has been found not to work.
+var shmUnavailable atomic.Bool
+
+func createNonlinkedFile() (*os.File, error) {
+	if !shmUnavailable.Load() {
+		fd, err := shmOpenAnon()
+		if err == nil {
+			activeFileStrategy.Store(FileShmAnon)
+			return os.NewFile(uintptr(fd), "journal-entry"), nil
+		}
+		if !shmOpenUnavailable(err) {
+			return nil, err
+		}
+		shmUnavailable.Store(true)
+	}
+
+	f, err := createTempFile()
+	if err == nil {
+		activeFileStrategy.Store(FileTemp)
+	}
+	return f, err
+}
+
+// shmOpenUnavailable reports whether err means that shm_open will never work,
+// e.g. due to Capsicum or a jail.
+func shmOpenUnavailable(err error) bool {
+	return errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.ENOTCAPABLE) || errors.Is(err, syscall.EACCES)
+}
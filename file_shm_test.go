@@ -0,0 +1,110 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build freebsd
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShmAnonFile(t *testing.T) {
+	t.Cleanup(func() { shmUnavailable.Store(false) })
+
+	f, err := createNonlinkedFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if s := fileStrategyName(); s != FileShmAnon {
+		t.Fatalf("strategy: %q", s)
+	}
+
+	data := bytes.Repeat([]byte("0123456789"), 30000)
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// The receiving end reads the object through its own descriptor.
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := os.NewFile(uintptr(fd), "reader")
+	defer r.Close()
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("read %d bytes", len(got))
+	}
+}
+
+func TestShmAnonFallback(t *testing.T) {
+	origShmOpenAnon := shmOpenAnon
+	t.Cleanup(func() {
+		shmOpenAnon = origShmOpenAnon
+		shmUnavailable.Store(false)
+	})
+
+	var calls int
+	shmOpenAnon = func() (int, error) {
+		calls++
+		return -1, syscall.ENOSYS
+	}
+
+	h, recv := newTestHandler(t, nil)
+	data := bytes.Repeat([]byte("x"), 300000)
+
+	for i := range 2 {
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "large", 0)
+		r.AddAttrs(Binary("data", data))
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatal(i, err)
+		}
+	}
+
+	for i, m := range recv.wait(t, 2) {
+		if m["MESSAGE"] != "large" || m["DATA"] != string(data) {
+			t.Errorf("entry %d: message %q, data length %d", i, m["MESSAGE"], len(m["DATA"]))
+		}
+	}
+
+	if s := h.Stats().FileStrategy; s != FileTemp {
+		t.Errorf("strategy: %q", s)
+	}
+	if calls != 1 {
+		t.Errorf("shm_open called %d times", calls)
+	}
+}
+
+func TestShmAnonOtherError(t *testing.T) {
+	origShmOpenAnon := shmOpenAnon
+	t.Cleanup(func() { shmOpenAnon = origShmOpenAnon })
+
+	shmOpenAnon = func() (int, error) {
+		return -1, syscall.EMFILE
+	}
+
+	if _, err := createNonlinkedFile(); err != syscall.EMFILE {
+		t.Error(err)
+	}
+	if shmUnavailable.Load() {
+		t.Error("shm_open marked unavailable")
+	}
+}
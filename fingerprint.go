@@ -0,0 +1,79 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"strconv"
+)
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+type fnv64a uint64
+
+func (h *fnv64a) add(s string) {
+	x := *h
+	for i := 0; i < len(s); i++ {
+		x ^= fnv64a(s[i])
+		x *= fnvPrime64
+	}
+	*h = x
+}
+
+func (h *fnv64a) addByte(b byte) {
+	*h = (*h ^ fnv64a(b)) * fnvPrime64
+}
+
+// addInt to the hash, preceded by a separator.
+func (h *fnv64a) addInt(n int) {
+	h.addByte(0)
+	for i := 0; i < 8; i++ {
+		h.addByte(byte(n >> (i * 8)))
+	}
+}
+
+// addNonDigits of s to the hash.
+func (h *fnv64a) addNonDigits(s string) {
+	x := *h
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < '0' || c > '9' {
+			x ^= fnv64a(c)
+			x *= fnvPrime64
+		}
+	}
+	*h = x
+}
+
+// appendFingerprint field to b.  The hash covers the normalized message, the
+// level and the source location.
+func (h *Handler) appendFingerprint(b *buffer, r slog.Record, level slog.Level, loc *codeLocation) {
+	x := fnv64a(fnvOffset64)
+
+	if h.fingerprintFunc != nil {
+		s := h.fingerprintFunc(r)
+		if s == "" {
+			return
+		}
+		x.add(s)
+	} else {
+		x.addNonDigits(r.Message)
+	}
+
+	x.addInt(int(level))
+	x.add(loc.file)
+	x.addInt(loc.line)
+
+	b.WriteString("FINGERPRINT=")
+	var hex [16]byte
+	s := strconv.AppendUint(hex[:0], uint64(x), 16)
+	for i := len(s); i < 16; i++ {
+		b.WriteByte('0')
+	}
+	b.Write(s)
+	b.WriteByte('\n')
+}
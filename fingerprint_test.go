@@ -0,0 +1,82 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Fingerprint: true,
+	})
+	logger := slog.New(h)
+
+	for _, n := range []int{1, 20, 300} {
+		logger.Info("disk 0 is " + strings.Repeat("9", n) + "% full")
+	}
+	logger.Warn("disk 0 is 10% full")
+	logger.Info("disk 1 is busy")
+
+	ms := recv.wait(t, 5)
+
+	fp := ms[0]["FINGERPRINT"]
+	if len(fp) != 16 {
+		t.Fatalf("fingerprint: %q", fp)
+	}
+	for i, m := range ms[1:3] {
+		if m["FINGERPRINT"] != fp {
+			t.Errorf("entry %d: %q", i+1, m["FINGERPRINT"])
+		}
+	}
+	for i, m := range ms[3:] {
+		if m["FINGERPRINT"] == fp || len(m["FINGERPRINT"]) != 16 {
+			t.Errorf("entry %d: %q", i+3, m["FINGERPRINT"])
+		}
+	}
+}
+
+func TestFingerprintFunc(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		FingerprintFunc: func(r slog.Record) string {
+			s, _, _ := strings.Cut(r.Message, ":")
+			return s
+		},
+	})
+
+	for _, msg := range []string{"a: x", "a: y", ": z"} {
+		r := slog.NewRecord(time.Time{}, slog.LevelInfo, msg, 0)
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ms := recv.wait(t, 3)
+	if fp := ms[0]["FINGERPRINT"]; fp == "" || ms[1]["FINGERPRINT"] != fp {
+		t.Errorf("fingerprints: %q %q", fp, ms[1]["FINGERPRINT"])
+	}
+	if fp, found := ms[2]["FINGERPRINT"]; found {
+		t.Errorf("fingerprint: %q", fp)
+	}
+}
+
+func TestFingerprintAllocs(t *testing.T) {
+	h := &Handler{fingerprint: true}
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "disk 0 is 99% full", 0)
+	loc := &codeLocation{file: "/src/main.go", line: 123}
+	b := newBuffer()
+	defer b.Free()
+
+	if n := testing.AllocsPerRun(100, func() {
+		*b = (*b)[:0]
+		h.appendFingerprint(b, r, slog.LevelInfo, loc)
+	}); n != 0 {
+		t.Errorf("%v allocations", n)
+	}
+}
@@ -5,21 +5,32 @@
 package sjournal
 
 import (
+	"cmp"
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"maps"
 	"net"
+	"os"
 	"runtime"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var defaultSocket = "/run/systemd/journal/socket"
 
+// ErrClosed is returned by Handle after the handler has been closed or shut
+// down.
+var ErrClosed = errors.New("sjournal: handler is closed")
+
 const (
 	DefaultDelimiter = " "
 	ColonDelimiter   = ": "
@@ -40,12 +51,171 @@ type HandlerOptions struct {
 	// Prefix is prepended to message strings.
 	Prefix string
 
+	// EscapeControlChars causes C0 and C1 control characters (other than tab)
+	// in the message and attribute values to be replaced with visible escape
+	// sequences such as \x1b.  Newlines are retained in the message (but not
+	// in attribute values).
+	EscapeControlChars bool
+
+	// ErrnoField causes an ERRNO field to be emitted when an attribute value
+	// is a syscall.Errno or an error which wraps one (such as *os.PathError).
+	// The value is also included in the message as usual.  Only the first
+	// errno of a record is emitted, including those found by Error.
+	ErrnoField bool
+
+	// SyslogPID is emitted as the SYSLOG_PID field of every entry, if
+	// positive.  It can be used to convey the process id of the origin of
+	// forwarded records.  See also the SyslogPID function.  Negative value is
+	// an error.
+	SyslogPID int
+
+	// Facility is emitted as the SYSLOG_FACILITY field of every entry, if
+	// positive (e.g. FacilityDaemon).  It's an error to specify also a
+	// SYSLOG_FACILITY field in Fields.
+	Facility int
+
+	// UTF8Policy determines how invalid UTF-8 in the message, attribute values
+	// and journal fields is handled.  The default is UTF8Pass.
+	UTF8Policy UTF8Policy
+
+	// MaxAttrs limits the number of attributes included in the message of a
+	// record, including the attributes added using WithAttrs and the
+	// attributes within groups.  Excess attributes are replaced by a
+	// sjournal.truncated_attrs attribute holding their count.  Zero means no
+	// limit.
+	MaxAttrs int
+
+	// MaxGroupDepth limits the nesting of group-valued attributes (groups
+	// opened using WithGroup are not counted), and MaxKeyPrefixLen limits the
+	// length of the group prefix of keys.  A group which would exceed either
+	// limit is emitted as a single attribute with a truncated rendering of
+	// its value instead, and the entry gets the field DEPTH_LIMITED=1.  This
+	// protects against deeply nested or recursive values from untrusted
+	// sources.  Zero means the defaults, 64 levels and 1024 bytes, and a
+	// negative value disables a limit.
+	MaxGroupDepth   int
+	MaxKeyPrefixLen int
+
+	// OffloadThreshold moves attribute values which are longer than the
+	// given number of bytes out of the message, if positive.  Such a value
+	// is written as a separate journal field named after the key (converted
+	// like with FieldAttrs), and the message contains a reference such as
+	// <see BODY, 18234 bytes> instead, quoted like other values.  This keeps
+	// the default output of journalctl readable while preserving the data.
+	// Values whose keys can't be converted to field names (or whose field
+	// names are reserved and may not be used) stay in the message.
+	OffloadThreshold int
+
+	// Identifier is emitted as the SYSLOG_IDENTIFIER field of every entry.
+	Identifier string
+
+	// IncludeHostname causes the HOSTNAME field to be emitted with every
+	// entry.  The hostname is resolved when the handler is created; if it
+	// can't be resolved, the field is omitted.
+	IncludeHostname bool
+
+	// Hostname overrides the resolved hostname.  It implies IncludeHostname.
+	Hostname string
+
+	// IncludeContainer causes the CONTAINER_ID (12 digits), CONTAINER_ID_FULL
+	// and KUBERNETES_POD_UID fields to be emitted with every entry, if the
+	// process runs in a container.  They are determined from
+	// /proc/self/cgroup and /proc/self/mountinfo when the handler is created;
+	// the fields which can't be determined are omitted.
+	IncludeContainer bool
+
+	// IncludeProcessInfo causes the EXECUTABLE, CWD and ARGV fields to be
+	// emitted with every entry.  They are resolved when the handler is
+	// created; the fields which can't be resolved are omitted.  ARGV contains
+	// the command-line arguments separated by spaces, truncated to 4096
+	// bytes.
+	IncludeProcessInfo bool
+
+	// Fields are emitted as journal fields of every entry.  The keys are
+	// converted to upper case; they may contain only ASCII letters, digits and
+	// underscores, and must not begin with a digit or an underscore.
+	Fields map[string]string
+
 	IgnoreAttrs []string
 
+	// DropKeys are patterns of full attribute keys (including group names,
+	// separated by dots) which are omitted.  A pattern may contain wildcards
+	// supported by path.Match, e.g. "http.request.*".  Omitting a group omits
+	// all attributes within it.
+	DropKeys []string
+
+	// RedactKeys are patterns of full attribute keys (like DropKeys) whose
+	// values are replaced with RedactPlaceholder.  The value of a matching
+	// group is replaced as a whole.  Attributes which would be emitted as
+	// journal fields (see Field, Binary and Error) are included in the message
+	// instead.
+	RedactKeys []string
+
+	// GlobalKeys are attribute keys which are emitted without the names of
+	// the enclosing groups, like with Absolute.  They are matched against the
+	// bare key (without group names).
+	GlobalKeys []string
+
+	// IncludePprofLabels causes the profiler labels of the context (see
+	// runtime/pprof.Do) to be included as attributes.  They are not in the
+	// groups started with WithGroup, but they are in PprofLabelGroup if it's
+	// set (e.g. "pprof").  If PprofLabelKeys is not nil, only the labels with
+	// the keys are included.
+	IncludePprofLabels bool
+	PprofLabelGroup    string
+	PprofLabelKeys     []string
+
+	// PromoteKeys maps full attribute keys (including group names, separated
+	// by dots) to journal field names.  The values of matching attributes are
+	// written as the fields instead of being included in the message, e.g.
+	// {"err": "ERROR", "request_id": "REQUEST_ID"}.  Duration values are
+	// written as milliseconds if the field name ends with _MS.  Groups are not
+	// promoted; their attributes may be.
+	PromoteKeys map[string]string
+
+	// RedactValue is called for the message, attribute values and journal
+	// field values before they are written.  The key is the full attribute
+	// key, "msg" for the message, or the field name.  It returns the value to
+	// be written instead, e.g. with secrets scrubbed.  Quoting is decided
+	// based on the returned value.
+	RedactValue func(key, value string) string
+
+	// RedactPlaceholder replaces redacted values.  It defaults to
+	// "[REDACTED]".
+	RedactPlaceholder string
+
 	// TimeFormat for attribute values.  Default is to use [time.Time.String]
 	// method.
 	TimeFormat string
 
+	// TimeValueFormat determines whether time-valued attributes are rendered
+	// using TimeFormat (the default) or as integers.  TimeFormat and
+	// TimeLocation don't affect the integer formats.
+	TimeValueFormat TimeValueFormat
+
+	// TimeLocation converts time attribute values to a time zone before
+	// formatting, if set.  It also applies to other textual timestamps
+	// produced by the handler.
+	TimeLocation *time.Location
+
+	// EntryHook is called with the record and the assembled entry before
+	// Mungers and sending.  It can add, replace and remove fields (see
+	// EntryBuilder).  If it replaces PRIORITY, budgets and CaptureTrigger
+	// treat the entry as if it had the closest level (like ParseLevel).
+	// Entries are parsed and reserialized for the hook, so it adds overhead
+	// to every entry.
+	EntryHook func(r slog.Record, e *EntryBuilder)
+
+	// SignatureKey causes a SIGNATURE field to be appended to every entry
+	// (after Mungers).  It holds an HMAC-SHA256 of the other fields, computed
+	// using the key (see VerifyEntry).
+	SignatureKey []byte
+
+	// SignatureKeyID identifies SignatureKey in the SIGNATURE_KEY_ID field,
+	// which is covered by the signature.  It allows verifiers to select the
+	// key when keys are rotated.  The field is omitted if the ID is empty.
+	SignatureKeyID string
+
 	// Mungers will be called for raw protocol messages before sending them to
 	// journald.  The buffer can be mutated in-place, or a new buffer may be
 	// allocated.  A munger must not keep references to the input or output
@@ -53,45 +223,899 @@ type HandlerOptions struct {
 	Mungers []func(context.Context, []byte) ([]byte, error)
 
 	Socket string
+
+	// SendSockets is the number of sockets used for sending entries to
+	// journald.  Concurrent Handle calls use them in turns.  It defaults to
+	// one.  Entries logged by different goroutines may be reordered.
+	SendSockets int
+
+	// Uploader replaces the journald socket as the destination of entries.
+	// The handler takes ownership of the uploader: Close and Shutdown close it.
+	Uploader *Uploader
+
+	// Syslog replaces the journald socket as the destination of entries.  It
+	// can't be used together with Uploader.  The handler takes ownership of
+	// the transport: Close and Shutdown close it.
+	Syslog *Syslog
+
+	// Transport replaces the journald socket as the destination of entries
+	// (see WriterTransport).  It can't be used together with Uploader or
+	// Syslog.  The handler doesn't close it.
+	Transport Transport
+
+	// Destinations receive the entries in addition to the socket (or Uploader,
+	// Syslog or Transport).  They may have different formatting options.
+	Destinations []Destination
+
+	// ChunkMessages causes entries which are too large to be sent as
+	// datagrams to be split into multiple entries, instead of passing them to
+	// journald via files.  It's the default behavior when LargeMessageSupport
+	// is false.  The chunks have the same CHUNK_ID field, a CHUNK field with
+	// the 1-based index and the count (e.g. "1/3"), and consecutive parts of
+	// MESSAGE split at UTF-8 boundaries.  The first chunk has all the other
+	// fields; the subsequent ones have only the PRIORITY and CODE_* fields.
+	ChunkMessages bool
+
+	// ChunkSize is the maximum size of the MESSAGE part of a chunk.  It
+	// defaults to 32 KiB.
+	ChunkSize int
+
+	// OnLargeEntry is called with the encoded size and the record when an
+	// entry which was too large to be sent as a datagram has been passed to
+	// journald via a file (see Stats.LargeEntries).  It's called
+	// synchronously after the send, so in asynchronous mode it's called by
+	// the background goroutine.  It isn't called for entries which were
+	// buffered due to WaitForSocket or captured due to CaptureLevel.
+	OnLargeEntry func(size int, r slog.Record)
+
+	// LargeEntryField causes a LARGE_ENTRY=1 field to be added to entries
+	// which are passed to journald via files.
+	LargeEntryField bool
+
+	// MirrorToStderr causes records at or above the level to be written also
+	// to the standard error stream as single lines, after sending them to
+	// journald (regardless of success).  Mirroring is disabled if standard
+	// error is connected to journald (see StderrIsJournal), unless
+	// MirrorWriter is set.
+	MirrorToStderr slog.Leveler
+
+	// MirrorWriter replaces the standard error stream of MirrorToStderr.
+	MirrorWriter io.Writer
+
+	// CaptureLevel enables capturing of records which are below Level: the
+	// ones at or above CaptureLevel are encoded into an in-memory ring buffer
+	// instead of being sent.  When a record at or above CaptureTrigger is
+	// handled, the captured entries are sent before it with the REPLAYED=1
+	// field, and the buffer is cleared.  The entries keep their original
+	// SYSLOG_TIMESTAMP (and SEQNUM).  The oldest entries are discarded when
+	// the buffer is full.  The buffer is shared by the handlers derived from
+	// the same NewHandler call.
+	CaptureLevel slog.Leveler
+
+	// CaptureTrigger defaults to LevelError.
+	CaptureTrigger slog.Leveler
+
+	// CaptureEntries and CaptureBytes limit the size of the capture buffer.
+	// They default to 1000 entries and 1 MiB.
+	CaptureEntries int
+	CaptureBytes   int
+
+	// ExpandSlices causes slice and array values with elements of basic types
+	// (booleans, numbers and strings) to be expanded into attributes with
+	// indexed keys, followed by the length.  For example "ports.0=80
+	// ports.1=443 ports.len=2".  Byte slices are not expanded.
+	ExpandSlices bool
+
+	// MaxSliceElements limits the number of expanded elements.  Zero means no
+	// limit.
+	MaxSliceElements int
+
+	// ExpandStructs causes struct values (and pointers to structs) which don't
+	// implement slog.LogValuer, fmt.Stringer or error to be expanded into
+	// groups of their exported fields.  The field names can be changed with
+	// "slog" or "json" struct tags; "-" excludes a field.  Nested structs are
+	// expanded up to a limited depth.
+	ExpandStructs bool
+
+	// ExpandMaps causes map[string]string, map[string]any and []slog.Attr
+	// values to be expanded like groups.  Map keys are sorted.  Nested maps
+	// and attribute slices are expanded up to a limited depth; other values
+	// are rendered as usual.
+	ExpandMaps bool
+
+	// AttrMode determines whether attributes are formatted into MESSAGE or
+	// written as separate journal fields.
+	AttrMode AttrMode
+
+	// SourceFormat determines how *slog.Source attribute values are rendered.
+	// Wrapper libraries can attach the source location of their caller as an
+	// attribute when the record's PC refers to the wrapper.
+	SourceFormat SourceFormat
+
+	// ValueFormatter is called for each attribute value (except groups) after
+	// it has been resolved.  If it returns true, the string is used as the
+	// value instead of the default rendering (including TimeFormat,
+	// AnyFormat, ExpandSlices, ExpandStructs and ExpandMaps).  The string is
+	// quoted like other values.
+	ValueFormatter func(v slog.Value) (string, bool)
+
+	// QuoteStyle determines how attribute keys and values are quoted.  The
+	// default is GoQuote.
+	QuoteStyle QuoteStyle
+
+	// AnyFormat is the fmt verb used to format values of other than basic
+	// kinds: "%v" (the default), "%+v" or "%#v".
+	AnyFormat string
+
+	// TextCompat renders the attributes within MESSAGE byte-for-byte like
+	// slog.TextHandler: time values use RFC 3339 with millisecond precision
+	// in their own location (TimeFormat and TimeLocation don't apply),
+	// encoding.TextMarshaler implementations are marshaled, byte slices are
+	// always quoted, other values are formatted using "%+v", and
+	// *slog.Source values are rendered as file:line.  It cannot be combined
+	// with a non-default QuoteStyle, AnyFormat or TimeValueFormat.
+	//
+	// The attributes are compatible with the part of TextHandler's output
+	// which follows the msg attribute, including the leading space, when
+	// Delimiter is DefaultDelimiter.  The message itself is written as is
+	// instead of as a quoted msg attribute, and the time, level and source
+	// are conveyed as journal fields.  Options which transform attributes
+	// (such as ValueFormatter, RedactKeys, DuplicateKeys, SortAttrs and the
+	// expansion options) still apply.
+	TextCompat bool
+
+	// DuplicateKeys determines how attributes with the same key are handled
+	// within a record.  The default is KeepAll.
+	DuplicateKeys DuplicateKeys
+
+	// SortAttrs causes the attributes to be emitted in the order of their
+	// full keys (including group prefixes), instead of the order in which
+	// they were added.  It requires per-attribute bookkeeping and sorting of
+	// every record, which is significantly slower.
+	SortAttrs bool
+
+	// AttrOrder determines whether the attributes added using WithAttrs are
+	// emitted before or after the attributes of the record.  The default is
+	// ContextFirst.  Other orders require per-attribute bookkeeping like
+	// SortAttrs.  Duplicate keys are resolved in the order in which the
+	// attributes were added, regardless of AttrOrder.
+	AttrOrder AttrOrder
+
+	// AttrCompare orders the attributes by their full keys (including group
+	// prefixes), like SortAttrs, but using a custom comparison function.  The
+	// sort is stable: attributes which compare equal are emitted according to
+	// AttrOrder.  It overrides SortAttrs.
+	AttrCompare func(a, b string) int
+
+	// MaxPriority is the most severe journald priority number (0-7) which
+	// will be emitted.  More severe priorities are replaced with it.  It
+	// applies after priority overrides.
+	MaxPriority int
+
+	// MinPriority is the least severe journald priority number (1-7) which
+	// will be emitted.  Less severe priorities are replaced with it.  Zero
+	// means no limit.  It applies after priority overrides.
+	MinPriority int
+
+	// LevelField causes a LEVEL field holding the name of the record's level
+	// (e.g. INFO or WARN+2) to be emitted.  It's the level after LevelRules,
+	// unaffected by priority overrides, MaxPriority and MinPriority.  Levels
+	// below LevelDebug are named TRACE, replacing the TRACE=1 field.
+	LevelField bool
+
+	// LevelRules change the levels of matching records before priority
+	// mapping.  The first matching rule applies.  Records whose new level is
+	// below Level are dropped.  (Note that records which are below Level to
+	// begin with are usually filtered out by slog.Logger before they reach
+	// the rules, so the rules cannot raise their levels.)
+	LevelRules []LevelRule
+
+	// ReplaceRecord is called for each record before anything else.  It
+	// returns the record to be handled instead, or false if the record should
+	// be dropped (counted in Stats with reason DropFiltered).  The record is a
+	// clone, so it may be modified.  Attributes added using WithAttrs are not
+	// part of the record, and cannot be seen or replaced.
+	ReplaceRecord func(ctx context.Context, r slog.Record) (slog.Record, bool)
+
+	// Middleware is applied around the encoding and sending of each record.
+	// The first middleware is the outermost one.
+	Middleware []Middleware
+
+	// Filter is called for each record (after LevelRules).  The record is
+	// dropped if Filter returns false.  Dropped records are counted in Stats
+	// with reason DropFiltered.  The record is a clone, so the function may
+	// modify it without affecting the handler.
+	Filter func(ctx context.Context, r slog.Record) bool
+
+	// FilterInHandle causes Handle to check the level of each record like
+	// Enabled, for frontends which call Handle without calling Enabled first.
+	// Records below the minimum level are dropped and counted in Stats with
+	// reason DropFiltered.
+	FilterInHandle bool
+
+	// GroupField is the name of a journal field which contains the names of
+	// the groups opened with WithGroup, separated by dots.  For example
+	// "COMPONENT".  The field is omitted if there are no groups.
+	GroupField string
+
+	// SourceLevel is the minimum level of records which include the
+	// CODE_FILE, CODE_LINE and CODE_FUNC fields.  The source location of
+	// records below it isn't resolved, unless it's needed for Mute or
+	// Fingerprint.  If SourceLevel is nil, all records include the fields.
+	SourceLevel slog.Leveler
+
+	// NamePrefix causes the logger name set using WithName to be prepended
+	// to the message, followed by a colon and a space (after Prefix).
+	NamePrefix bool
+
+	// RecordRealtime causes a RECORD_REALTIME_USEC field to be emitted with
+	// every record which has a time.  It holds the record time as
+	// microseconds since the Unix epoch (sub-microsecond part truncated).
+	RecordRealtime bool
+
+	// MonotonicTime causes a MONOTONIC_USEC field to be emitted with every
+	// entry.  It holds the number of microseconds since the package was
+	// initialized, measured using a monotonic clock when the record is
+	// handled.  The values are strictly increasing for handlers derived from
+	// the same NewHandler call.
+	MonotonicTime bool
+
+	// SequenceNumbers causes SEQNUM and SEQNUM_EPOCH fields to be emitted with
+	// every entry.  SEQNUM is a counter shared by the handlers derived from
+	// the same NewHandler call, starting at 1.  It is incremented when a
+	// record is encoded, so records filtered out by level are not counted,
+	// but gaps appear if entries are dropped afterwards (by a munger, the
+	// queue or a failed send).  Repeated entries merged by QueueCoalesce keep
+	// the first number.  SEQNUM_EPOCH is a random identifier of the counter.
+	SequenceNumbers bool
+
+	// CallerSkip is the number of additional stack frames to skip when
+	// determining the CODE_FILE, CODE_LINE and CODE_FUNC fields.  It's useful
+	// when slog.Logger methods are called via wrapper functions: the record's
+	// PC refers to the wrapper, so the stack is walked to find the actual
+	// caller.  The walk is done for every record, because a wrapper may be
+	// called from many places: the result isn't cached by the record's PC
+	// and CallerSkip.  (The source location of the caller's PC is cached like
+	// without CallerSkip.)  The record's PC is used if it's handled in a
+	// different goroutine than the one which created it.
+	CallerSkip int
+
+	// Fingerprint causes a FINGERPRINT field to be emitted with every entry.
+	// It holds a 64-bit hash (in hex) of the normalized message, the level and
+	// the source location, so that entries logged by the same statement with
+	// different details get the same fingerprint.  By default digits are
+	// removed from the message.
+	Fingerprint bool
+
+	// FingerprintFunc replaces the default message normalization of
+	// fingerprints.  It implies Fingerprint.  The field is omitted if the
+	// function returns an empty string.
+	FingerprintFunc func(r slog.Record) string
+
+	// AttrsField is the name of a journal field which contains the
+	// attributes, for example "ATTRS".  If set, the attributes are not
+	// appended to the message.
+	AttrsField string
+
+	// WaitForSocket is how long to wait for the socket to appear if it
+	// doesn't exist when the first entry is sent.  Meanwhile, entries are
+	// buffered in memory (up to a limit) and Handle doesn't block.  When the
+	// socket appears, the buffered entries are sent in order.  If the socket
+	// doesn't appear in time, the buffered entries are dropped (counted in
+	// Stats with reason DropNoSocket) and a summary is written to the
+	// standard error stream.  Zero means no waiting.  It's ignored if
+	// Uploader or Syslog is set.
+	WaitForSocket time.Duration
+
+	// QueueSize enables asynchronous mode if positive.  Handle encodes the
+	// record and queues it for a background goroutine which sends it to
+	// journald.  Records are dropped if the queue is full.  See Close.
+	QueueSize int
+
+	// MaxQueueBytes limits the total size of queued entries if positive.
+	// Entries larger than the limit are always dropped.
+	MaxQueueBytes int
+
+	// QueueDropPolicy determines which entry is dropped when the queue is
+	// full.
+	QueueDropPolicy DropPolicy
+
+	// QueueOverflow determines whether Handle blocks when the queue is full.
+	// The default is OverflowDrop.
+	QueueOverflow Overflow
+
+	// QueueCoalesce causes an entry to be discarded if it's identical to the
+	// most recently queued entry which hasn't been sent yet (ignoring
+	// SYSLOG_TIMESTAMP).  The queued entry is sent with the REPEATS field
+	// indicating the total number of identical entries.
+	QueueCoalesce bool
+
+	// QueueTTL causes queued entries to be discarded instead of sent if they
+	// have been queued for longer than the duration.  Zero means no expiry.
+	QueueTTL time.Duration
+
+	// QueueErrorTTL is the QueueTTL for entries with error or more severe
+	// priority.  Zero means that they don't expire.
+	QueueErrorTTL time.Duration
+
+	// Clock is used for reading the current time, e.g. for QueueTTL,
+	// SlowSendThreshold and MonotonicTime, and for the records created by
+	// Writer.  (Timers and socket deadlines use the real time.)  It defaults
+	// to SystemClock.
+	Clock Clock
+
+	// SlowSendThreshold enables detection of slow sends: if writing an entry
+	// to the journald socket (including the large entry fallbacks) takes
+	// longer than the threshold, it's counted in Stats.SlowSends and reported
+	// to OnSlowSend.  A warning entry with the SEND_DURATION_MS field is sent
+	// at most once per SlowSendInterval, which defaults to one minute.
+	SlowSendThreshold time.Duration
+	SlowSendInterval  time.Duration
+
+	// OnSlowSend is called with the duration of a slow send (see
+	// SlowSendThreshold).  It's called synchronously after the send.
+	OnSlowSend func(d time.Duration)
+
+	// OnPermissionError is called with the first error wrapping ErrPermission,
+	// i.e. when the operating system denies sending to journald.  It's called
+	// synchronously after the send, at most once per NewHandler call.
+	OnPermissionError func(err error)
+
+	// PermissionFallback receives the records (in the format of
+	// MirrorToStderr) instead of journald after sending has been denied due to
+	// a permission error, for the rest of the handler's lifetime.  The first
+	// error is also written to it.  Handle returns nil for the records
+	// written to it.
+	PermissionFallback io.Writer
+
+	// Announce causes an informational entry describing the logging
+	// configuration to be sent after the first successful send (see
+	// Handler.Announce).  It's sent at most once by a handler and the
+	// handlers derived from it.  Failure to send it doesn't affect Handle.
+	Announce bool
+
+	// DeadLetterPath is the name of a file to which entries are appended if
+	// sending them fails.  The entries are written in the Journal Export
+	// Format (see Decoder) with the __REALTIME_TIMESTAMP field and the
+	// SJOURNAL_ERROR field describing the failure.  The file is written in
+	// the background on a best-effort basis; entries which couldn't be
+	// written are counted in Stats.DeadLetterErrors.
+	DeadLetterPath string
+
+	// DeadLetterBytes limits the size of the dead-letter file if positive.
+	// The oldest entries are discarded when the limit would be exceeded.  It
+	// defaults to 16 MiB.
+	DeadLetterBytes int
+
+	// Budgets limit the encoded size of entries per level range and interval.
+	// The intervals are measured using Clock, starting from the first record.
+	// Records exceeding a matching budget are dropped with DropBudget as the
+	// reason.  Records captured for CaptureLevel aren't subject to budgets.
+	Budgets []Budget
+
+	// BudgetExempt is the minimum level of records which are never dropped
+	// due to Budgets.  Their sizes are still counted against the budgets.
+	BudgetExempt slog.Leveler
+
+	// BudgetSummaryInterval is the minimum interval between warnings about
+	// records dropped due to Budgets.  The warning is sent by the first Handle
+	// call after the interval has passed, and has the DROPPED_ENTRIES and
+	// DROPPED_BYTES fields, and DROPPED_ENTRIES_<PRIORITY> and
+	// DROPPED_BYTES_<PRIORITY> fields (such as DROPPED_BYTES_DEBUG) for each
+	// priority.  The sizes of records which were dropped before encoding (see
+	// Budget) are unknown, so they only add to the entry counts.  It defaults
+	// to the shortest interval of Budgets.
+	BudgetSummaryInterval time.Duration
+
+	// Strict enables checking of every entry (after Mungers) against
+	// journald's constraints: field names must consist of uppercase letters,
+	// digits and underscores, and must not start with an underscore or a
+	// digit; MESSAGE must be valid UTF-8; the size and the number of fields
+	// must be within limits.  The entry is sent regardless of violations.
+	// The violations are reported as an error wrapping ErrInvalidEntry.
+	Strict bool
+
+	// StrictMaxEntryBytes and StrictMaxFields are the limits checked in
+	// Strict mode.  They default to journald's limits (768 MiB and 1024).
+	StrictMaxEntryBytes int
+	StrictMaxFields     int
+
+	// ReservedFields determines how the reserved journal field names (see
+	// ReservedPolicy) are treated when they are used by Fields, WithFields,
+	// ContextWithFields, PromoteKeys, Field and Binary.  The default is
+	// AllowReserved.  With RejectReserved, NewHandler and Reload return an
+	// error for the Fields and PromoteKeys, WithFields and Field include the
+	// rejected values in the message as normal attributes, Binary values are
+	// base64-encoded in the message, and context fields are omitted; the
+	// rejections during Handle are reported like Strict mode violations.
+	ReservedFields ReservedPolicy
+
+	// ReservedPrefix is prepended to reserved names with RenameReserved.  It
+	// defaults to "X_".
+	ReservedPrefix string
+
+	// OnViolation is called with the violations found in Strict mode.  If
+	// it's nil, Handle returns them as an error (unless sending fails).
+	OnViolation func(err error)
+
+	// AllowInvalid disables the validation of Prefix, TimeFormat, Socket and
+	// Fields (see ErrInvalidOption).  Fields keys which contain lowercase
+	// letters are converted to uppercase.  Invalid options may cause garbled
+	// entries or send failures.
+	AllowInvalid bool
 }
 
 func NewHandler(opts *HandlerOptions) (*Handler, error) {
+	if opts != nil {
+		if err := validateOptions(opts); err != nil {
+			return nil, err
+		}
+	}
+
 	sock, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
 	if err != nil {
 		return nil, err
 	}
 
 	h := &Handler{
-		sock: sock,
-		addr: net.UnixAddr{
-			Net:  "unixgram",
-			Name: defaultSocket,
+		priority:        -1,
+		maxGroupDepth:   defaultMaxGroupDepth,
+		maxKeyPrefixLen: defaultMaxKeyPrefixLen,
+		static:          new(staticFields),
+		lifetime:        new(lifetime),
+		root: &root{
+			socks: []*net.UnixConn{sock},
+			clock: SystemClock,
 		},
 	}
+	h.root.transport = &journalTransport{h.root}
+
+	socket := defaultSocket
+	cfg := new(config)
 
 	if opts != nil {
-		h.level = opts.Level
+		h.reserved = newReservedFields(opts)
+		h.utf8Policy = opts.UTF8Policy
+		if cfg, err = newConfig(opts, h.reserved, h.utf8Policy); err != nil {
+			sock.Close()
+			return nil, err
+		}
+		if h.dropKeys, err = newKeyPatterns(opts.DropKeys); err != nil {
+			sock.Close()
+			return nil, err
+		}
+		if h.redactKeys, err = newKeyPatterns(opts.RedactKeys); err != nil {
+			sock.Close()
+			return nil, err
+		}
+		h.promoteKeys = newPromoteKeys(opts.PromoteKeys, h.reserved)
+		h.globalKeys = newGlobalKeys(opts.GlobalKeys)
+		h.pprofLabels = newPprofLabels(opts)
+		h.redactValue = opts.RedactValue
+		h.redactPlaceholder = cmp.Or(opts.RedactPlaceholder, defaultRedactPlaceholder)
 		if opts.Socket != "" {
-			h.addr.Name = opts.Socket
+			socket = opts.Socket
 		}
 		h.delimiter = opts.Delimiter
-		h.timeFormat = opts.TimeFormat
-		h.msgPrefix = opts.Prefix
+		h.escapeControl = opts.EscapeControlChars
+		h.errnoField = opts.ErrnoField
+		h.maxAttrs = max(opts.MaxAttrs, 0)
+		h.offloadThreshold = max(opts.OffloadThreshold, 0)
+		h.maxGroupDepth = depthLimit(opts.MaxGroupDepth, defaultMaxGroupDepth)
+		h.maxKeyPrefixLen = depthLimit(opts.MaxKeyPrefixLen, defaultMaxKeyPrefixLen)
 		h.mungers = opts.Mungers
+		h.entryHook = opts.EntryHook
+		h.signer = newSigner(opts)
 		h.addIgnore(opts.IgnoreAttrs)
+		h.duplicateKeys = opts.DuplicateKeys
+		h.attrOrder = opts.AttrOrder
+		h.attrCompare = opts.AttrCompare
+		if h.attrCompare == nil && opts.SortAttrs {
+			h.attrCompare = strings.Compare
+		}
+		h.sortAttrs = h.attrCompare != nil || h.attrOrder != ContextFirst
+		h.trackSpans = h.duplicateKeys != KeepAll || h.sortAttrs
+		h.groupFieldKey = opts.GroupField
+		h.attrsField = opts.AttrsField
+		h.namePrefix = opts.NamePrefix
+		h.timeValueFormat = opts.TimeValueFormat
+		h.strict = newStrictChecker(opts)
+		h.sourceLevel = opts.SourceLevel
+		h.expandSlices = opts.ExpandSlices
+		h.expandStructs = opts.ExpandStructs
+		h.expandMaps = opts.ExpandMaps
+		h.sourceFormat = opts.SourceFormat
+		h.fieldAttrs = opts.AttrMode == FieldAttrs
+		h.quoteStyle = opts.QuoteStyle
+		h.textCompat = opts.TextCompat
+		if opts.AnyFormat != "%v" {
+			h.anyFormat = opts.AnyFormat
+		}
+		h.maxSliceElements = max(opts.MaxSliceElements, 0)
+		h.monotonicTime = opts.MonotonicTime
+		h.recordRealtime = opts.RecordRealtime
+		h.syslogPID = opts.SyslogPID
+		h.facility = opts.Facility
+		h.callerSkip = max(opts.CallerSkip, 0)
+		h.valueFormatter = opts.ValueFormatter
+		h.fingerprint = opts.Fingerprint || opts.FingerprintFunc != nil
+		h.fingerprintFunc = opts.FingerprintFunc
+
+		if opts.SequenceNumbers {
+			h.root.seqnumEpoch = newSeqnumEpoch()
+		}
+		h.levelRules = slices.Clone(opts.LevelRules)
+		h.filter = opts.Filter
+		h.filterInHandle = opts.FilterInHandle
+		h.root.mirror = newMirror(opts)
+		h.root.capture = newCaptureRing(opts)
+		h.root.slowSend = newSlowSend(opts)
+		h.root.permission.init(opts)
+		h.root.syslog = opts.Syslog
+		switch {
+		case opts.Transport != nil:
+			h.root.transport = opts.Transport
+			h.root.transportName = "custom"
+		case opts.Uploader != nil:
+			h.root.transport = sendFunc(opts.Uploader.Send)
+			h.root.transportName = "upload"
+			h.root.closeTransport = opts.Uploader.Close
+		case opts.Syslog != nil:
+			h.root.transport = sendFunc(opts.Syslog.Send)
+			h.root.transportName = "syslog"
+			h.root.closeTransport = opts.Syslog.Close
+		default:
+			h.root.wait = newSocketWait(opts.WaitForSocket)
+		}
+		h.replaceRecord = opts.ReplaceRecord
+		h.middleware = slices.Clone(opts.Middleware)
+		h.initChain()
+		h.maxPriority = min(max(opts.MaxPriority, 0), priorityDebug)
+		h.minPriority = min(max(opts.MinPriority, 0), priorityDebug)
+		h.levelField = opts.LevelField
+
+		for len(h.root.socks) < opts.SendSockets {
+			sock, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+			if err != nil {
+				h.root.closeSockets()
+				return nil, err
+			}
+			h.root.socks = append(h.root.socks, sock)
+		}
+
+		if opts.Clock != nil {
+			h.root.clock = opts.Clock
+		}
+
+		h.root.deadLetters = newDeadLetter(opts, &h.root.stats)
+		h.root.budgets = newBudgets(opts)
+		h.root.onLargeEntry = opts.OnLargeEntry
+		h.root.largeEntryField = opts.LargeEntryField
+
+		if opts.QueueSize > 0 {
+			h.root.queue = newQueue(opts, &h.root.stats, h.root.clock.Now)
+			h.root.done = make(chan struct{})
+			go h.root.sendQueued()
+		}
+	}
+
+	h.root.chunkSize = chunkSize(opts)
+	h.root.dgramMax.Store(int64(socketSendBufferSize(sock)))
+	h.root.addr.Store(&net.UnixAddr{Net: "unixgram", Name: socket})
+	h.root.config.Store(cfg)
+
+	if opts != nil && len(opts.Destinations) > 0 {
+		if err := h.initDestinations(opts); err != nil {
+			h.Close()
+			return nil, err
+		}
+	}
+
+	if opts != nil && opts.Announce {
+		// A copy without the lifetime, so that the root doesn't prevent
+		// finalization (see RegisterExitFlush).
+		a := *h
+		a.lifetime = nil
+		a.chain = nil
+		h.root.announcer = &a
 	}
 
 	return h, nil
 }
 
+// root is shared by a handler and the handlers derived from it.
+type root struct {
+	socks     []*net.UnixConn // At least one.
+	nextSock  atomic.Uint32
+	dgramMax  atomic.Int64 // Zero if unknown.
+	addr      atomic.Pointer[net.UnixAddr]
+	config    atomic.Pointer[config]
+	queue     *queue // Nil unless in asynchronous mode.
+	done      chan struct{}
+	stats     stats
+	clock     Clock
+	closed    atomic.Bool
+	closeOnce sync.Once
+	closeErr  error
+
+	seqnum          atomic.Uint64
+	monotonicNext   atomic.Int64 // Lower bound of the next MONOTONIC_USEC.
+	seqnumEpoch     string       // Empty unless SequenceNumbers is enabled.
+	mirror          *mirror      // Nil unless MirrorToStderr is enabled.
+	capture         *captureRing // Nil unless CaptureLevel is set.
+	chunkSize       int          // Zero unless large entries are chunked.
+	slowSend        *slowSend    // Nil unless SlowSendThreshold is set.
+	deadLetters     *deadLetter  // Nil unless DeadLetterPath is set.
+	budgets         *budgets     // Nil unless Budgets is set.
+	onLargeEntry    func(int, slog.Record)
+	fanout          fanout                // Destinations with the same formatting.
+	variants        []*root               // Roots of Handler.variants.
+	override        func(*HandlerOptions) // Set in variants.
+	largeEntryField bool
+	announcer       *Handler // Nil unless Announce is set.
+	announced       atomic.Bool
+	transport       Transport                       // The journald socket by default.
+	transportName   string                          // Empty for the journald socket.
+	closeTransport  func(ctx context.Context) error // Set for Uploader and Syslog.
+	syslog          *Syslog                         // Nil unless Syslog is the transport.
+	wait            *socketWait                     // Nil unless WaitForSocket is enabled.
+	mutes           atomic.Pointer[muteSet]
+	mutesMu         sync.Mutex         // Serializes mutes updates.
+	sink            func([]byte) error // Replaces sending in tests.
+	permission      permissionState
+	exitFlushOnce   sync.Once
+	exitFlush       func() // Set by RegisterExitFlush.
+}
+
+// entryMeta is delivered alongside an encoded entry of a record.  It's set
+// only if OnLargeEntry or Syslog is used.
+type entryMeta struct {
+	record       slog.Record // Passed to OnLargeEntry if withRecord is set.
+	withRecord   bool
+	time         time.Time // Record time for Syslog.
+	syslogParams []byte    // Attributes encoded as SD-PARAMs for Syslog.
+}
+
+func (r *root) send(b []byte) error {
+	return r.sendRecord(b, nil)
+}
+
+// sendRecord is like send, but with the metadata of the record.
+func (r *root) sendRecord(b []byte, meta *entryMeta) error {
+	if err := r.sendEntry(b, meta); err != nil {
+		return err
+	}
+	r.announce()
+	return nil
+}
+
+func (r *root) sendEntry(b []byte, meta *entryMeta) error {
+	if r.sink != nil {
+		if err := r.sink(b); err != nil {
+			r.deadLetter(b, err)
+			return err
+		}
+		r.stats.sentEntry(len(b))
+		return nil
+	}
+	if r.wait != nil && r.buffer(b) {
+		return nil
+	}
+	if err := r.sendNow(b, meta); err != nil {
+		r.deadLetter(b, err)
+		return err
+	}
+	return nil
+}
+
+func (r *root) sendNow(b []byte, meta *entryMeta) error {
+	err := r.sendPrimary(b, meta)
+	if r.fanout != nil {
+		err = cmp.Or(err, r.fanout.Send(b, r.needsFile(b)))
+	}
+	return err
+}
+
+func (r *root) sendPrimary(b []byte, meta *entryMeta) error {
+	if r.sendFallback(b) {
+		return nil
+	}
+
+	var (
+		large bool
+		err   error
+	)
+	if t, ok := r.transport.(*journalTransport); ok {
+		large, err = t.send(b)
+	} else {
+		if r.syslog != nil && meta != nil {
+			err = r.syslog.send(b, meta.time, meta.syslogParams)
+		} else {
+			err = r.transport.Send(b, r.needsFile(b))
+		}
+		if err != nil {
+			err = r.permissionError(err, "transport")
+		}
+	}
+	if err != nil {
+		if err != ErrClosed && r.sendFallback(b) {
+			return nil
+		}
+		return err
+	}
+
+	r.stats.sentEntry(len(b))
+	if large {
+		var rec *slog.Record
+		if meta != nil && meta.withRecord {
+			rec = &meta.record
+		}
+		r.largeEntry(len(b), rec)
+	}
+	return nil
+}
+
+// needsFile reports whether the entry exceeds the datagram size limit (see
+// Transport).
+func (r *root) needsFile(b []byte) bool {
+	limit := r.dgramMax.Load()
+	return limit > 0 && int64(len(b)) > limit
+}
+
+func (r *root) shutdown(ctx context.Context, drain bool) error {
+	r.closeOnce.Do(func() {
+		r.closeErr = r.doShutdown(ctx, drain)
+	})
+	return r.closeErr
+}
+
+func (r *root) doShutdown(ctx context.Context, drain bool) error {
+	r.closed.Store(true)
+
+	var err error
+
+	for _, v := range r.variants {
+		if e := v.shutdown(ctx, drain); err == nil {
+			err = e
+		}
+	}
+
+	if q := r.queue; q != nil {
+		q.stop()
+		if drain {
+			if err = q.flush(ctx); err != nil {
+				// Interrupt a blocked send.
+				r.setWriteDeadline(time.Now())
+			}
+		}
+		q.close()
+		<-r.done
+	}
+
+	if w := r.wait; w != nil {
+		if e := w.finish(ctx, drain && err == nil); err == nil {
+			err = e
+		}
+	}
+
+	if drain && err == nil {
+		if deadline, ok := ctx.Deadline(); ok {
+			r.setWriteDeadline(deadline)
+		}
+		r.sendDropSummary()
+	}
+
+	if f := r.closeTransport; f != nil {
+		var tctx context.Context // Nil aborts immediately.
+		if drain && err == nil {
+			tctx = ctx
+		}
+		if e := f(tctx); err == nil {
+			err = e
+		}
+	}
+
+	if e := r.closeSockets(); err == nil {
+		err = e
+	}
+
+	if d := r.deadLetters; d != nil {
+		d.close()
+	}
+	return err
+}
+
+// socket returns the next socket in turn.
+func (r *root) socket() *net.UnixConn {
+	if len(r.socks) == 1 {
+		return r.socks[0]
+	}
+	return r.socks[(r.nextSock.Add(1)-1)%uint32(len(r.socks))]
+}
+
+func (r *root) setWriteDeadline(t time.Time) {
+	for _, sock := range r.socks {
+		sock.SetWriteDeadline(t)
+	}
+}
+
+func (r *root) closeSockets() error {
+	var err error
+	for _, sock := range r.socks {
+		if e := sock.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// sendDropSummary sends a warning entry if any entries have been dropped
+// unintentionally.  Filtered records and muted call sites are not included.
+func (r *root) sendDropSummary() {
+	s := r.stats.snapshot()
+
+	b := newBuffer()
+	defer b.Free()
+
+	b.WriteString("PRIORITY=4\nMESSAGE=sjournal: entries dropped:")
+	var found bool
+	for reason, name := range dropReasonNames {
+		switch dropReason(reason) {
+		case dropFiltered, dropMuted:
+			continue
+		}
+		if n := s.Dropped[name]; n > 0 {
+			b.WriteByte(' ')
+			b.WriteString(name)
+			b.WriteByte('=')
+			*b = strconv.AppendUint(*b, n, 10)
+			found = true
+		}
+	}
+	if !found {
+		return
+	}
+	b.WriteByte('\n')
+
+	r.send(*b)
+}
+
 type ignoreKey struct {
 	prefix string // Until last dot.
 	key    string
 }
 
+// Handler is a slog.Handler which sends entries to journald.  Handlers are
+// immutable after construction, so a Handler can be used and derived from
+// concurrently: WithAttrs, WithGroup, WithFields, WithName, WithLevel,
+// ExtendPrefix and the other derivation methods never modify the parent, and
+// the derived handlers don't share mutable state except for the counters,
+// configuration and destination shared by all handlers derived from the same
+// NewHandler call.
 type Handler struct {
-	level             slog.Leveler
 	preformattedAttrs []byte
+	// preformattedFields holds journal fields produced by WithAttrs.
+	preformattedFields []byte
+	// preformattedSyslog holds SD-PARAMs produced by WithAttrs.
+	preformattedSyslog []byte
+	level              slog.Leveler      // From WithLevel, or nil.
+	fields             map[string]string // From WithFields, by field name.
+	encodedFields      []byte            // Encoded fields in name order.
+	priority           int               // Priority override from WithAttrs, or -1.
+	syslogPID          int               // From options or WithAttrs, or 0.
+	facility           int               // From options, or 0.
+	attrs              []slog.Attr       // From WithAttrs, with qualified keys.
+	preformattedSpans  []keySpan
+	preformattedCount  int    // Number of attributes in preformattedAttrs.
+	truncatedCount     int    // Number of attributes omitted by WithAttrs.
+	preformattedErrno  bool   // ERRNO field is in preformattedFields.
+	depthLimited       bool   // DEPTH_LIMITED field is in preformattedFields.
+	groupField         []byte // Encoded GroupField.
+	groupPath          string // Groups joined with keyComponentSep.
+	name               string // From WithName, joined with dots.
+	nameField          []byte // Encoded LOGGER field.
+	static             *staticFields
+	lifetime           *lifetime // See RegisterExitFlush.
 	// groupPrefix is for the text handler only.
 	// It holds the prefix for groups that were already pre-formatted.
 	// A group will appear here when a call to WithGroup is followed by
@@ -99,24 +1123,228 @@ type Handler struct {
 	groupPrefix string
 	groups      []string // all groups started from WithGroup
 	nOpenGroups int      // the number of groups opened in preformattedAttrs
-	sock        *net.UnixConn
-	addr        net.UnixAddr
+	root        *root
 	delimiter   string
-	timeFormat  string
-	msgPrefix   string
+	msgPrefix   string // Added by ExtendPrefix or set by ResetPrefix.
+	prefixReset bool   // Ignore config's msgPrefix.
 	mungers     []func(context.Context, []byte) ([]byte, error)
+	entryHook   func(slog.Record, *EntryBuilder)
+	signer      *signer // Nil unless SignatureKey is set.
 	ignore      map[ignoreKey]struct{}
+	// duplicateKeys policy and sortAttrs require per-attribute bookkeeping.
+	duplicateKeys     DuplicateKeys
+	sortAttrs         bool // Attributes are reordered after formatting.
+	attrOrder         AttrOrder
+	attrCompare       func(a, b string) int
+	trackSpans        bool
+	groupFieldKey     string
+	maxPriority       int
+	minPriority       int
+	levelField        bool
+	levelRules        []LevelRule
+	filter            func(context.Context, slog.Record) bool
+	filterInHandle    bool
+	middleware        []Middleware
+	replaceRecord     func(context.Context, slog.Record) (slog.Record, bool)
+	chain             func(context.Context, slog.Record) error // Middleware around handle.
+	escapeControl     bool
+	errnoField        bool
+	utf8Policy        UTF8Policy
+	maxAttrs          int
+	offloadThreshold  int
+	maxGroupDepth     int
+	maxKeyPrefixLen   int
+	attrsField        string
+	namePrefix        bool
+	timeValueFormat   TimeValueFormat
+	strict            *strictChecker // Nil unless Strict is enabled.
+	sourceLevel       slog.Leveler
+	expandSlices      bool
+	expandStructs     bool
+	expandMaps        bool
+	sourceFormat      SourceFormat
+	fieldAttrs        bool
+	variants          []*Handler // Encoders for Destinations with distinct formatting.
+	anyFormat         string     // Empty means %v.
+	quoteStyle        QuoteStyle
+	textCompat        bool
+	dropKeys          *keyPatterns
+	redactKeys        *keyPatterns
+	redactPlaceholder string
+	redactValue       func(key, value string) string
+	promoteKeys       map[string]string   // Attribute keys to field names.
+	globalKeys        map[string]struct{} // Bare keys emitted without prefix.
+	reserved          reservedFields
+	pprofLabels       *pprofLabels // Nil unless IncludePprofLabels is set.
+	maxSliceElements  int
+	monotonicTime     bool
+	recordRealtime    bool
+	callerSkip        int
+	valueFormatter    func(slog.Value) (string, bool)
+	fingerprint       bool
+	fingerprintFunc   func(slog.Record) string
+}
+
+// Close stops the background goroutine (in asynchronous mode) and closes the
+// socket.  Entries which are still queued are discarded.  Subsequent Handle
+// calls return ErrClosed.  Close affects all handlers derived from the same
+// NewHandler call.
+func (h *Handler) Close() error {
+	return h.root.shutdown(nil, false)
+}
+
+// Shutdown stops accepting records, sends the queued entries (in asynchronous
+// mode) and closes the socket.  If the context is done before the queue has
+// been drained, the remaining entries are discarded and the context error is
+// returned.  A warning entry summarizing dropped entries (except filtered
+// records and muted call sites) is sent before closing the socket, unless the
+// context was done.  Subsequent Handle calls return ErrClosed.  Repeated
+// Shutdown and Close calls return the result of the first call.  Shutdown
+// affects all handlers derived from the same NewHandler call.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	return h.root.shutdown(ctx, true)
+}
+
+// SetSocket changes the destination socket path of the handler.  It affects
+// all handlers derived from the same NewHandler call, including the ones
+// derived before the SetSocket call.  Entries which are being sent concurrently
+// go to either the old or the new socket.  In asynchronous mode, the entries
+// which are still queued go to the new socket.
+//
+// The path must refer to an existing socket.
+func (h *Handler) SetSocket(path string) error {
+	if h.root.closed.Load() {
+		return ErrClosed
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("sjournal: not a socket: %s", path)
+	}
+
+	h.root.addr.Store(&net.UnixAddr{Net: "unixgram", Name: path})
+	return nil
+}
+
+// Flush blocks until the entries which have been queued in asynchronous mode
+// have been sent (or discarded), or the context is done.  It returns
+// immediately in synchronous mode.
+func (h *Handler) Flush(ctx context.Context) error {
+	if q := h.root.queue; q != nil {
+		return q.flush(ctx)
+	}
+	return nil
+}
+
+// Stats returns the counters shared by the handler and the handlers derived
+// from it.
+func (h *Handler) Stats() Stats {
+	return h.root.stats.snapshot()
 }
 
 func (h *Handler) ExtendPrefix(s string) *Handler {
 	h2 := h.clone()
 	h2.msgPrefix = h.msgPrefix + s
+	h2.deriveVariants(func(v *Handler) *Handler { return v.ExtendPrefix(s) })
 	return h2
 }
 
+// nameFieldKey is the journal field which holds the logger name.
+const nameFieldKey = "LOGGER"
+
+// WithName returns a handler which appends the name to the logger name,
+// separated by a dot.  The logger name is emitted as the LOGGER field of
+// every entry, and it's also prepended to the message if the NamePrefix
+// option is set.  The name is independent of groups and the message prefix.
+// Empty name is ignored.
+func (h *Handler) WithName(name string) *Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := h.clone()
+	if h.name == "" {
+		h2.name = name
+	} else {
+		h2.name = h.name + "." + name
+	}
+	h2.nameField = appendField(nil, nameFieldKey, h2.utf8Policy.apply(h2.name))
+	h2.static = new(staticFields)
+	h2.deriveVariants(func(v *Handler) *Handler { return v.WithName(name) })
+	return h2
+}
+
+// Name returns the logger name set using WithName.
+func (h *Handler) Name() string {
+	return h.name
+}
+
+// ResetPrefix returns a handler which uses the specified message prefix
+// instead of the current one.  The Prefix option doesn't apply to the returned
+// handler (or the handlers derived from it), even after Reload.
+func (h *Handler) ResetPrefix(s string) *Handler {
+	h2 := h.clone()
+	h2.msgPrefix = s
+	h2.prefixReset = true
+	h2.deriveVariants(func(v *Handler) *Handler { return v.ResetPrefix(s) })
+	return h2
+}
+
+// Prefix returns the current message prefix of the handler, consisting of the
+// Prefix option and the strings added with ExtendPrefix.
+func (h *Handler) Prefix() string {
+	return h.prefix(h.root.config.Load())
+}
+
+// Groups returns the names of the groups opened using WithGroup.
+func (h *Handler) Groups() []string {
+	return slices.Clone(h.groups)
+}
+
+// PreformattedAttrs returns the attributes added using WithAttrs, in order.
+// The keys are qualified by the groups which were open when the attributes were
+// added, and attributes of groups with empty keys are inlined, like in the
+// output.  The values are returned as they were added: they are not resolved,
+// and options such as DuplicateKeys, DropKeys and IgnoreAttrs are not taken
+// into account.
+func (h *Handler) PreformattedAttrs() []slog.Attr {
+	return slices.Clone(h.attrs)
+}
+
+// appendQualifiedAttrs appends attributes with keys qualified by the prefix.
+func appendQualifiedAttrs(dst []slog.Attr, prefix string, as []slog.Attr) []slog.Attr {
+	for _, a := range as {
+		switch {
+		case a.Equal(slog.Attr{}):
+		case a.Key == "" && a.Value.Kind() == slog.KindGroup:
+			dst = appendQualifiedAttrs(dst, prefix, a.Value.Group())
+		default:
+			a.Key = prefix + a.Key
+			dst = append(dst, a)
+		}
+	}
+	return dst
+}
+
+// hasMessagePrefix reports whether the records get a message prefix.
+func (h *Handler) hasMessagePrefix(cfg *config) bool {
+	return !h.prefixReset && cfg.msgPrefix != "" || h.msgPrefix != "" || h.namePrefix && h.name != ""
+}
+
+func (h *Handler) prefix(cfg *config) string {
+	if h.prefixReset {
+		return h.msgPrefix
+	}
+	return cfg.msgPrefix + h.msgPrefix
+}
+
 func (h *Handler) IgnoreAttrs(keys ...string) *Handler {
 	h2 := h.clone()
 	h2.addIgnore(keys)
+	h2.deriveVariants(func(v *Handler) *Handler { return v.IgnoreAttrs(keys...) })
 	return h2
 }
 
@@ -137,26 +1365,52 @@ func (h *Handler) addIgnore(keys []string) {
 }
 
 func (h *Handler) Enabled(ctx context.Context, l slog.Level) bool {
-	minLevel := slog.LevelDebug
+	return h.enabled(h.root.config.Load(), l) || h.root.capture.captures(l)
+}
+
+// WithLevel returns a handler which uses a different minimum level than the
+// parent handler.  The level overrides the Level option and the levels of
+// parent handlers; it wins even if the level of the root handler is changed
+// later (e.g. by Reload).  Handlers derived from the returned handler inherit
+// the level.
+func (h *Handler) WithLevel(l slog.Leveler) *Handler {
+	h2 := h.clone()
+	h2.level = l
+	h2.deriveVariants(func(v *Handler) *Handler { return v.WithLevel(l) })
+	return h2
+}
+
+func (h *Handler) enabled(cfg *config, l slog.Level) bool {
 	if h.level != nil {
-		minLevel = h.level.Level()
+		return l >= h.level.Level()
 	}
-	return l >= minLevel
+	return cfg.enabled(l)
 }
 
 func (h *Handler) clone() *Handler {
 	h2 := *h
+	// Clipping causes appends to reallocate, so that handlers derived
+	// concurrently from the same parent never write to shared arrays.
 	h2.preformattedAttrs = slices.Clip(h.preformattedAttrs)
+	h2.preformattedFields = slices.Clip(h.preformattedFields)
+	h2.preformattedSyslog = slices.Clip(h.preformattedSyslog)
+	h2.attrs = slices.Clip(h.attrs)
 	h2.groups = slices.Clip(h.groups)
 	h2.ignore = maps.Clone(h.ignore)
+	h2.initChain()
 	return &h2
 }
 
 func (h *Handler) WithAttrs(as []slog.Attr) slog.Handler {
 	h2 := h.clone()
 	// Pre-format the attributes as an optimization.
-	state := h2.newHandleState((*buffer)(&h2.preformattedAttrs), false, "")
+	if h2.duplicateKeys != KeepAll {
+		// Earlier attributes may be removed in place.
+		h2.preformattedAttrs = slices.Clone(h2.preformattedAttrs)
+	}
+	state := h2.newHandleState((*buffer)(&h2.preformattedAttrs), (*buffer)(&h2.preformattedFields), false, "")
 	defer state.free()
+	state.spans = slices.Clone(h2.preformattedSpans)
 	state.prefix.WriteString(h.groupPrefix)
 	if len(h2.preformattedAttrs) > 0 {
 		state.sep = " "
@@ -165,17 +1419,38 @@ func (h *Handler) WithAttrs(as []slog.Attr) slog.Handler {
 	for _, a := range as {
 		state.appendAttr(a)
 	}
+	if state.priority >= 0 {
+		h2.priority = state.priority
+	}
+	if state.syslogPID > 0 {
+		h2.syslogPID = state.syslogPID
+	}
+	h2.preformattedSpans = state.spans
+	h2.preformattedSyslog = state.syslogParams
+	h2.preformattedCount = state.attrCount
+	h2.truncatedCount = state.truncatedCount
+	h2.preformattedErrno = state.errno
+	h2.depthLimited = state.depthLimited
+	h2.static = new(staticFields)
 	// Remember the new prefix for later keys.
 	h2.groupPrefix = state.prefix.String()
+	h2.attrs = appendQualifiedAttrs(h2.attrs, h2.groupPrefix, as)
 	// Remember how many opened groups are in preformattedAttrs,
 	// so we don't open them again when we handle a Record.
 	h2.nOpenGroups = len(h2.groups)
+	h2.deriveVariants(func(v *Handler) *Handler { return v.WithAttrs(as).(*Handler) })
 	return h2
 }
 
 func (h *Handler) WithGroup(name string) slog.Handler {
 	h2 := h.clone()
 	h2.groups = append(h2.groups, name)
+	h2.groupPath = strings.Join(h2.groups, string(keyComponentSep))
+	if h2.groupFieldKey != "" {
+		h2.groupField = appendField(nil, h2.groupFieldKey, h2.utf8Policy.apply(h2.groupPath))
+		h2.static = new(staticFields)
+	}
+	h2.deriveVariants(func(v *Handler) *Handler { return v.WithGroup(name).(*Handler) })
 	return h2
 }
 
@@ -190,6 +1465,10 @@ const (
 	prefixDebug   = "PRIORITY=7\nMESSAGE\n\x00\x00\x00\x00\x00\x00\x00\x00"
 )
 
+// priorityOffset is the position of the priority digit in the prefixes (and
+// in the headers returned by entryHeader).
+const priorityOffset = len("PRIORITY=")
+
 var priorityPrefixes = [...]string{
 	// Lower levels are prefixDebug (if enabled).
 	prefixDebug, // LevelDebug
@@ -212,48 +1491,322 @@ var priorityPrefixes = [...]string{
 	// Higher levels are prefixAlert.
 }
 
-var suffixCache sync.Map
-
-func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
-	var prefix string
-	var suffix string
-
-	switch i := int(r.Level.Level() - slog.LevelDebug); {
+func levelPrefix(level slog.Level) string {
+	switch i := int(level - slog.LevelDebug); {
 	case i < 0:
-		prefix = prefixDebug
+		return prefixDebug
 	case i < len(priorityPrefixes):
-		prefix = priorityPrefixes[i]
+		return priorityPrefixes[i]
 	default:
-		prefix = prefixAlert
+		return prefixAlert
 	}
+}
 
-	if x, found := suffixCache.Load(r.PC); found {
-		suffix = x.(string)
-	} else {
-		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
-		suffix = fmt.Sprintf("\nCODE_FILE=%s\nCODE_LINE=%d\nCODE_FUNC=%s\n", f.File, f.Line, f.Function)
-		suffixCache.Store(r.PC, suffix)
+// codeLocation is the resolved source location of a program counter.
+type codeLocation struct {
+	file     string
+	line     int
+	function string
+	suffix   []byte // Encoded CODE_* fields.  Must not be modified.
+}
+
+// codeLocationKey identifies resolved locations which are shared by multiple
+// program counters.
+type codeLocationKey struct {
+	file     string
+	line     int
+	function string
+}
+
+// unknownCodeLocation is used when the location isn't resolved.  Its suffix
+// only terminates the message.
+var unknownCodeLocation = &codeLocation{suffix: []byte("\n")}
+
+var (
+	codeLocationCache    sync.Map // By program counter.
+	codeLocationSharedMu sync.Mutex
+	codeLocationShared   = make(map[codeLocationKey]*codeLocation)
+)
+
+// callerPC finds pc in the call stack and returns the program counter of the
+// frame which is skip frames above it.  The pc is returned as is if it's not
+// found (e.g. if the record is handled in a different goroutine).
+func callerPC(pc uintptr, skip int) uintptr {
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:])
+
+	for i, x := range pcs[:n] {
+		if x == pc {
+			if i+skip < n {
+				return pcs[i+skip]
+			}
+			break
+		}
 	}
+	return pc
+}
 
-	state := h.newHandleState(newBuffer(), true, "")
+func lookupCodeLocation(pc uintptr) *codeLocation {
+	if x, found := codeLocationCache.Load(pc); found {
+		return x.(*codeLocation)
+	}
+
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	key := codeLocationKey{f.File, f.Line, f.Function}
+
+	codeLocationSharedMu.Lock()
+	loc := codeLocationShared[key]
+	if loc == nil {
+		loc = newCodeLocation(key)
+		codeLocationShared[key] = loc
+	}
+	codeLocationSharedMu.Unlock()
+
+	codeLocationCache.Store(pc, loc)
+	return loc
+}
+
+func newCodeLocation(key codeLocationKey) *codeLocation {
+	const (
+		fileKey = "\nCODE_FILE="
+		lineKey = "\nCODE_LINE="
+		funcKey = "\nCODE_FUNC="
+	)
+
+	b := make([]byte, 0, len(fileKey)+len(key.file)+len(lineKey)+20+len(funcKey)+len(key.function)+1)
+	b = append(b, fileKey...)
+	b = append(b, key.file...)
+	b = append(b, lineKey...)
+	b = strconv.AppendInt(b, int64(key.line), 10)
+	b = append(b, funcKey...)
+	b = append(b, key.function...)
+	b = append(b, '\n')
+
+	return &codeLocation{
+		file:     key.file,
+		line:     key.line,
+		function: key.function,
+		suffix:   b,
+	}
+}
+
+// Handle sends a record to journald.  The level of the record is checked only
+// if LevelRules, CaptureLevel or FilterInHandle is set.  If the record time is
+// zero, SYSLOG_TIMESTAMP and RECORD_REALTIME_USEC fields are omitted, and
+// journald uses the time of reception.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if h.root.closed.Load() {
+		return ErrClosed
+	}
+	if h.replaceRecord != nil {
+		var ok bool
+		if r, ok = h.replaceRecord(ctx, r.Clone()); !ok {
+			h.root.stats.drop(dropFiltered, PriorityForLevel(r.Level), 1)
+			return nil
+		}
+	}
+	if h.chain != nil {
+		return h.chain(ctx, r)
+	}
+	return h.handle(ctx, r)
+}
+
+// EstimateSize returns the size of the entry which Handle would send for the
+// record with a background context, in bytes.  The record is encoded as is:
+// ReplaceRecord, Middleware and level filtering are not applied, and
+// Destinations are not considered.  Nothing is sent and the handler's state is
+// not changed, but Mungers and EntryHook are invoked.  Zero is returned if a
+// munger drops the entry.
+func (h *Handler) EstimateSize(r slog.Record) int {
+	var size int
+	h.handleEntry(context.Background(), r, &size)
+	return size
+}
+
+// handle a record with the handler and the variants for Destinations.
+func (h *Handler) handle(ctx context.Context, r slog.Record) error {
+	err := h.handleEntry(ctx, r, nil)
+	for _, v := range h.variants {
+		err = cmp.Or(err, v.handleEntry(ctx, r, nil))
+	}
+	return err
+}
+
+// handleEntry encodes and sends a record.  If size is not nil, the record is
+// not filtered or sent, handler state is not changed, and the entry size is
+// stored in it.
+func (h *Handler) handleEntry(ctx context.Context, r slog.Record, size *int) error {
+	state := h.newHandleState(newBuffer(), newBuffer(), true, "")
+	state.ctx = ctx
 	defer state.free()
 
+	level := r.Level
+	if len(h.levelRules) > 0 {
+		level = h.remapLevel(r)
+	}
+
+	capture := false
+	if size == nil && (len(h.levelRules) > 0 || h.root.capture != nil || h.filterInHandle) {
+		if !h.enabled(state.cfg, level) {
+			if !h.root.capture.captures(level) {
+				h.root.stats.drop(dropFiltered, PriorityForLevel(level), 1)
+				return nil
+			}
+			capture = true
+		}
+	}
+
+	if size == nil && h.filter != nil && !h.filter(ctx, r.Clone()) {
+		h.root.stats.drop(dropFiltered, PriorityForLevel(level), 1)
+		return nil
+	}
+
+	withSource := h.sourceLevel == nil || level >= h.sourceLevel.Level()
+	loc := unknownCodeLocation
+	if withSource || h.fingerprint || h.root.mutes.Load() != nil {
+		pc := r.PC
+		if h.callerSkip > 0 {
+			pc = callerPC(pc, h.callerSkip)
+		}
+		loc = lookupCodeLocation(pc)
+		if size == nil && h.root.muted(loc) {
+			h.root.stats.drop(dropMuted, PriorityForLevel(level), 1)
+			return nil
+		}
+	}
+
+	if size == nil && h.root.budgets != nil && h.entryHook == nil && h.root.budgetExhausted(ctx, level, h.Priority(level)) {
+		return nil
+	}
+
+	prefix := levelPrefix(level)
+	if h.facility > 0 {
+		prefix = entryHeader(PriorityForLevel(level), h.facility)
+	}
+	suffix := loc.suffix
+	if !withSource {
+		suffix = unknownCodeLocation.suffix
+	}
+
 	state.buf.WriteString(prefix)
 	messageOffset := state.buf.Len()
-	state.buf.WriteString(h.msgPrefix)
-	state.buf.WriteString(r.Message)
+	if h.hasMessagePrefix(state.cfg) && !hasNoPrefix(r) {
+		if !h.prefixReset {
+			state.buf.WriteString(state.cfg.msgPrefix)
+		}
+		state.buf.WriteString(h.msgPrefix)
+		if h.namePrefix && h.name != "" {
+			state.buf.WriteString(h.utf8Policy.apply(h.name))
+			state.buf.WriteString(": ")
+		}
+	}
+	message := h.utf8Policy.apply(r.Message)
+	if h.escapeControl {
+		message = escapeControl(message, true)
+	}
+	if h.redactValue != nil {
+		message = h.redactValue(slog.MessageKey, message)
+	}
+	state.buf.WriteString(message)
 	state.sep = h.delimiter
-	state.appendNonBuiltIns(r)
+	attrsOffset := state.buf.Len()
+	cf := fieldsFromContext(ctx)
+	state.appendNonBuiltIns(ctx, r, cf)
+	if h.sortAttrs {
+		state.sortSpans()
+	}
+	if h.attrsField != "" {
+		if attrs := (*state.buf)[attrsOffset:]; len(attrs) > 0 {
+			*state.fields = appendField(*state.fields, h.attrsField, []byte(attrs[len(h.delimiter):]))
+			*state.buf = (*state.buf)[:attrsOffset]
+		}
+	}
 	messageLen := state.buf.Len() - messageOffset
-	state.buf.WriteString(suffix)
+	if size == nil && !capture && h.root.mirror.enabled(level) {
+		text := string((*state.buf)[messageOffset : messageOffset+messageLen])
+		defer h.root.mirror.write(state.cfg, r.Time, level, text)
+	}
+	priority := h.effectivePriority(state.priority, level)
+	(*state.buf)[priorityOffset] = byte('0' + priority)
+	state.buf.Write(suffix)
+	if cf != nil && cf.reserved && h.reserved.policy != AllowReserved {
+		cf = state.reserveContextFields(cf)
+	}
+	if cf == nil {
+		state.buf.Write(h.staticFields(state.cfg))
+	} else {
+		h.appendFields(state.buf, state.cfg, cf)
+		state.buf.Write(h.groupField)
+		state.buf.Write(h.nameField)
+		state.buf.Write(h.preformattedFields)
+	}
+	state.buf.Write(*state.fields)
+	if pid := cmp.Or(state.syslogPID, h.syslogPID); pid > 0 {
+		state.buf.WriteString("SYSLOG_PID=")
+		*state.buf = strconv.AppendInt(*state.buf, int64(pid), 10)
+		state.buf.WriteByte('\n')
+	}
+	if h.levelField {
+		state.buf.WriteString("LEVEL=")
+		if level < LevelDebug {
+			state.buf.WriteString("TRACE")
+		} else {
+			state.buf.WriteString(level.String())
+		}
+		state.buf.WriteByte('\n')
+	} else if level < LevelDebug {
+		state.buf.WriteString("TRACE=1\n")
+	}
+	if h.fingerprint {
+		h.appendFingerprint(state.buf, r, level, loc)
+	}
+	keyLen := state.buf.Len()
 	if !r.Time.IsZero() {
 		state.buf.WriteString("SYSLOG_TIMESTAMP=")
 		*state.buf = strconv.AppendInt(*state.buf, r.Time.Unix(), 10)
 		state.buf.WriteByte('\n')
+		if h.recordRealtime {
+			state.buf.WriteString("RECORD_REALTIME_USEC=")
+			*state.buf = strconv.AppendInt(*state.buf, r.Time.UnixMicro(), 10)
+			state.buf.WriteByte('\n')
+		}
+	}
+	if epoch := h.root.seqnumEpoch; epoch != "" {
+		state.buf.WriteString("SEQNUM=")
+		var n uint64
+		if size == nil {
+			n = h.root.seqnum.Add(1)
+		} else {
+			n = h.root.seqnum.Load() + 1
+		}
+		*state.buf = strconv.AppendUint(*state.buf, n, 10)
+		state.buf.WriteString("\nSEQNUM_EPOCH=")
+		state.buf.WriteString(epoch)
+		state.buf.WriteByte('\n')
+	}
+	if h.monotonicTime {
+		state.buf.WriteString("MONOTONIC_USEC=")
+		var usec int64
+		if size == nil {
+			usec = h.root.monotonicUsec()
+		} else {
+			usec = h.root.peekMonotonicUsec()
+		}
+		*state.buf = strconv.AppendInt(*state.buf, usec, 10)
+		state.buf.WriteByte('\n')
+	}
+
+	binary.LittleEndian.PutUint64((*state.buf)[messageOffset-8:], uint64(messageLen))
+
+	if h.entryHook != nil {
+		state.applyEntryHook(r)
+		if p := entryPriority(*state.buf); p != priority {
+			priority = p
+			level = priorityLevels[p] // Replaced by the hook.
+		}
 	}
 
 	b := *state.buf
-	binary.LittleEndian.PutUint64(b[messageOffset-8:], uint64(messageLen))
 
 	for _, f := range h.mungers {
 		var err error
@@ -266,17 +1819,100 @@ func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
 		}
 	}
 
-	if _, _, err := h.sock.WriteMsgUnix(b, nil, &h.addr); err != nil {
-		return h.sendViaFileIfTooLarge(err, b)
+	if h.signer != nil {
+		b = h.signer.sign(b)
+		if cap(b) > cap(*state.buf) {
+			*state.buf = b
+		}
 	}
-	return nil
+
+	if size != nil {
+		*size = len(b)
+		return nil
+	}
+
+	var violation error
+	if h.strict != nil {
+		if violation = h.strict.check(b); violation != nil && h.strict.report != nil {
+			h.strict.report(violation)
+			violation = nil
+		}
+	}
+
+	violation = cmp.Or(violation, state.reserved)
+
+	if capture {
+		var meta *entryMeta
+		if h.root.syslog != nil {
+			meta = &entryMeta{time: r.Time, syslogParams: state.syslogParams}
+		}
+		h.root.capture.put(b, priority, meta)
+		return violation
+	}
+	if len(h.mungers) > 0 || h.entryHook != nil || h.signer != nil {
+		keyLen = len(b)
+	}
+	return cmp.Or(h.deliver(ctx, &r, level, priority, b, keyLen, state.syslogParams), violation)
+}
+
+// recordMeta returns the metadata of a record, or false if it's not needed.
+// It's returned by value so that it doesn't need to be allocated unless it's
+// queued.
+func (r *root) recordMeta(rec *slog.Record, syslogParams []byte) (meta entryMeta, ok bool) {
+	if r.onLargeEntry == nil && r.syslog == nil {
+		return meta, false
+	}
+	meta.time = rec.Time
+	meta.syslogParams = syslogParams
+	if r.onLargeEntry != nil {
+		meta.record = rec.Clone()
+		meta.withRecord = true
+	}
+	return meta, true
 }
 
-func (s *handleState) appendNonBuiltIns(r slog.Record) {
+// deliver an encoded entry of a record: it's subject to budgets, and it's
+// queued in asynchronous mode or sent.  The priority is not parsed from the
+// entry, as Mungers may have moved or removed the PRIORITY field.  keyLen is
+// the length of the entry prefix which is compared when coalescing.
+func (h *Handler) deliver(ctx context.Context, r *slog.Record, level slog.Level, priority int, b []byte, keyLen int, syslogParams []byte) error {
+	if h.root.budgets != nil && !h.root.checkBudgets(ctx, level, priority, b) {
+		return nil
+	}
+	var replayErr error
+	if h.root.capture.triggers(level) {
+		replayErr = h.root.replay(ctx)
+	}
+
+	meta, withMeta := h.root.recordMeta(r, syslogParams)
+
+	if q := h.root.queue; q != nil {
+		e := queueEntry{
+			data:     slices.Clone(b),
+			keyLen:   keyLen,
+			priority: priority,
+			time:     h.root.clock.Now(),
+		}
+		if withMeta {
+			queued := meta
+			e.meta = &queued
+		}
+		return cmp.Or(q.put(ctx, e), replayErr)
+	}
+
+	if withMeta {
+		return cmp.Or(h.root.sendRecord(b, &meta), replayErr)
+	}
+	return cmp.Or(h.root.send(b), replayErr)
+}
+
+func (s *handleState) appendNonBuiltIns(ctx context.Context, r slog.Record, cf *contextFields) {
 	// preformatted Attrs
 	if len(s.h.preformattedAttrs) > 0 {
 		s.buf.WriteString(s.sep)
+		offset := s.buf.Len()
 		s.buf.Write(s.h.preformattedAttrs)
+		s.appendPreformattedSpans(offset, s.sep)
 		s.sep = " "
 	}
 	// Attrs in Record -- unlike the built-in ones, they are in groups started
@@ -287,32 +1923,91 @@ func (s *handleState) appendNonBuiltIns(r slog.Record) {
 		s.appendAttr(a)
 		return true
 	})
+	if cf != nil && len(cf.invalid) > 0 {
+		s.appendContextAttrs(cf.invalid)
+	}
+	if s.h.pprofLabels != nil {
+		s.appendPprofLabels(ctx)
+	}
+	if s.truncatedCount > 0 {
+		s.appendTruncatedCount()
+	}
+}
+
+const truncatedAttrsKey = "sjournal.truncated_attrs"
+
+// appendTruncatedCount appends the MaxAttrs marker outside of any groups.
+func (s *handleState) appendTruncatedCount() {
+	prefixLen := len(*s.prefix)
+	*s.prefix = (*s.prefix)[:0]
+	value := strconv.Itoa(s.truncatedCount)
+	if s.h.trackSpans {
+		s.appendTrackedAttr(truncatedAttrsKey, value)
+	} else {
+		s.appendKey(truncatedAttrsKey)
+		s.appendString(value)
+	}
+	*s.prefix = (*s.prefix)[:prefixLen]
 }
 
 // handleState holds state for a single call to commonHandler.handle.
 // The initial value of sep determines whether to emit a separator
 // before the next key, after which it stays non-empty.
 type handleState struct {
-	h       *Handler
-	buf     *buffer
-	freeBuf bool    // should buf be freed?
-	sep     string  // separator to write before next key
-	prefix  *buffer // for text: key prefix
+	h   *Handler
+	cfg *config
+	ctx context.Context // Nil unless handling a record.
+	encoder
+	buf      *buffer
+	fields   *buffer // journal fields
+	freeBuf  bool    // should buf and fields be freed?
+	sep      string  // separator to write before next key
+	prefix   *buffer // for text: key prefix
+	priority int     // priority override, or -1
+	spans    []keySpan
+
+	syslogPID    int    // SyslogPID attribute, or 0.
+	syslogParams []byte // SD-PARAMs for Syslog (see Handler.preformattedSyslog).
+
+	attrCount      int // Attributes appended so far, for MaxAttrs.
+	truncatedCount int // Attributes omitted due to MaxAttrs.
+
+	errno bool // ERRNO field has been appended.
+
+	depth        int  // Nesting of the group attribute being appended.
+	depthLimited bool // DEPTH_LIMITED field has been appended.
+
+	quoteValue bool // The next value is quoted regardless of its content.
+
+	reserved error // First rejected field (see RejectReserved).
 }
 
-func (h *Handler) newHandleState(buf *buffer, freeBuf bool, sep string) handleState {
+func (h *Handler) newHandleState(buf, fields *buffer, freeBuf bool, sep string) handleState {
 	return handleState{
-		h:       h,
-		buf:     buf,
-		freeBuf: freeBuf,
-		sep:     sep,
-		prefix:  newBuffer(),
+		h:        h,
+		cfg:      h.root.config.Load(),
+		encoder:  h.newEncoder(),
+		buf:      buf,
+		fields:   fields,
+		freeBuf:  freeBuf,
+		sep:      sep,
+		prefix:   newBuffer(),
+		priority: -1,
+
+		syslogParams: slices.Clip(h.preformattedSyslog),
+
+		attrCount:      h.preformattedCount,
+		truncatedCount: h.truncatedCount,
+
+		errno:        h.preformattedErrno,
+		depthLimited: h.depthLimited,
 	}
 }
 
 func (s *handleState) free() {
 	if s.freeBuf {
 		s.buf.Free()
+		s.fields.Free()
 	}
 	s.prefix.Free()
 }
@@ -343,6 +2038,14 @@ func (s *handleState) closeGroup(name string) {
 // It handles replacement and checking for an empty key.
 // after replacement).
 func (s *handleState) appendAttr(a slog.Attr) {
+	if abs, ok := s.h.absoluteAttr(a); ok {
+		if s.prefix != nil && len(*s.prefix) > 0 {
+			s.appendAbsoluteAttr(abs)
+			return
+		}
+		a = abs
+	}
+
 	var prefix []byte
 	if s.prefix != nil {
 		prefix = *s.prefix
@@ -350,63 +2053,215 @@ func (s *handleState) appendAttr(a slog.Attr) {
 	if _, ignore := s.h.ignore[ignoreKey{string(prefix), a.Key}]; ignore {
 		return
 	}
+	if s.h.dropKeys != nil && a.Key != "" && s.h.dropKeys.match(s.fullKey(a.Key)) {
+		return
+	}
+	if s.h.redactKeys != nil && a.Key != "" && s.h.redactKeys.match(s.fullKey(a.Key)) {
+		// Replaced before resolution so that the original value is never
+		// seen.
+		a.Value = slog.StringValue(s.h.redactPlaceholder)
+	}
+
+	if a.Value.Kind() == slog.KindLogValuer {
+		switch v := a.Value.Any().(type) {
+		case messageTemplate:
+			s.appendMessageTemplate(v)
+			return
+
+		case priorityOverride:
+			if v >= 0 && v < numPriorities {
+				s.priority = int(v)
+			}
+			return
+
+		case noPrefix:
+			return
+
+		case syslogPID:
+			if v > 0 {
+				s.syslogPID = int(v)
+			}
+			return
+
+		case fieldValue:
+			if name, ok := fieldName(a.Key); ok {
+				if field, ok := s.h.reserved.name(name); ok {
+					s.appendField(field, string(v))
+					if s.h.root.syslog != nil {
+						s.syslogParams = appendSyslogParam(s.syslogParams, s.fullKey(a.Key), string(v))
+					}
+					return
+				}
+				s.rejectField(name)
+			}
+
+		case binaryValue:
+			if name, ok := fieldName(a.Key); ok {
+				if field, ok := s.h.reserved.name(name); ok {
+					*s.fields = appendBinaryField(*s.fields, field, []byte(v))
+					if s.h.root.syslog != nil {
+						s.syslogParams = appendSyslogParam(s.syslogParams, s.fullKey(a.Key), v.LogValue().String())
+					}
+					return
+				}
+				s.rejectField(name)
+			}
+
+		case *readerValue:
+			ctx := s.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			v.read(ctx)
+			if name, ok := fieldName(a.Key); ok {
+				if field, ok := s.h.reserved.name(name); ok {
+					s.appendReaderFields(field, v)
+					return
+				}
+				s.rejectField(name)
+			}
+			a.Value = slog.StringValue(string(v.data))
+
+		case errorValue:
+			s.appendErrorFields(v.err)
+
+		case timeValue:
+			if v.layout != "" || !s.h.textCompat {
+				a.Value = slog.StringValue(v.format(s.h, s.cfg))
+			}
+		}
+	}
 
 	a.Value = a.Value.Resolve()
 	// Elide empty Attrs.
 	if a.Equal(slog.Attr{}) {
 		return
 	}
+	if s.h.errnoField && !s.errno && a.Value.Kind() == slog.KindAny {
+		if err, ok := a.Value.Any().(error); ok {
+			s.appendErrno(err)
+		}
+	}
+	if s.h.valueFormatter != nil && a.Value.Kind() != slog.KindGroup {
+		if str, ok := s.h.valueFormatter(a.Value); ok {
+			a.Value = slog.StringValue(str)
+		}
+	}
 	// Special cases.
 	switch v := a.Value; v.Kind() {
 	case slog.KindAny:
-		if src, ok := v.Any().(*slog.Source); ok {
-			a.Value = slog.StringValue(fmt.Sprintf("%s:%d", src.File, src.Line))
+		if src, ok := v.Any().(*slog.Source); ok && src != nil {
+			if s.appendSourceFields(a.Key, src) {
+				return
+			}
+			if s.h.textCompat {
+				a.Value = slog.StringValue(SourceFormat(0).format(src))
+			} else {
+				a.Value = slog.StringValue(s.h.sourceFormat.format(src))
+			}
+		} else if s.h.expandSlices || s.h.expandStructs || s.h.expandMaps {
+			if attrs, ok := s.h.expand(v.Any()); ok {
+				a.Value = slog.GroupValue(attrs...)
+			}
 		}
 	case slog.KindTime:
-		t := a.Value.Time()
-		if s.h.timeFormat != "" {
-			a.Value = slog.StringValue(t.Format(s.h.timeFormat))
+		if s.h.textCompat {
+			a.Value = slog.StringValue(string(appendRFC3339Millis(nil, a.Value.Time())))
 		} else {
-			a.Value = slog.StringValue(t.String())
+			a.Value = slog.StringValue(s.h.timeValueFormat.formatTime(s.cfg, a.Value.Time()))
 		}
 	}
 	if a.Value.Kind() == slog.KindGroup {
 		attrs := a.Value.Group()
 		// Output only non-empty groups.
 		if len(attrs) > 0 {
+			if s.depth >= s.h.maxGroupDepth || s.prefix.Len()+len(a.Key) >= s.h.maxKeyPrefixLen {
+				s.appendDepthLimited(a)
+				return
+			}
 			// Inline a group with an empty key.
 			if a.Key != "" {
 				s.openGroup(a.Key)
 			}
+			s.depth++
 			for _, aa := range attrs {
 				s.appendAttr(aa)
 			}
+			s.depth--
 			if a.Key != "" {
 				s.closeGroup(a.Key)
 			}
 		}
 	} else {
-		s.appendKey(a.Key)
-		s.appendString(a.Value.String())
+		if len(s.h.promoteKeys) > 0 {
+			if name, ok := s.h.promoteKeys[s.fullKey(a.Key)]; ok {
+				s.appendPromoted(name, a.Value)
+				return
+			}
+		}
+		if s.maxAttrs > 0 {
+			if s.attrCount == s.maxAttrs {
+				s.truncatedCount++
+				return
+			}
+			s.attrCount++
+		}
+		if s.scalars && s.appendScalar(a.Key, a.Value) {
+			return
+		}
+		var value string
+		var quote bool
+		if s.h.textCompat && a.Value.Kind() == slog.KindAny {
+			value, quote = textCompatValue(a.Value.Any())
+		} else if s.anyFormat != "" && a.Value.Kind() == slog.KindAny {
+			value = fmt.Sprintf(s.anyFormat, a.Value.Any())
+		} else {
+			value = a.Value.String()
+		}
+		value = s.utf8Policy.apply(value)
+		if s.escapeControl {
+			value = escapeControl(value, false)
+		}
+		if s.h.redactValue != nil {
+			value = s.h.redactValue(s.fullKey(a.Key), value)
+		}
+		if s.h.root.syslog != nil {
+			s.syslogParams = appendSyslogParam(s.syslogParams, s.fullKey(a.Key), value)
+		}
+		if s.fieldAttrs && s.appendAttrField(a.Key, value) {
+			return
+		}
+		if s.offload > 0 && len(value) > s.offload {
+			if ref, ok := s.offloadValue(a.Key, value); ok {
+				value = ref
+				quote = false
+			}
+		}
+		s.quoteValue = quote
+		if s.trackSpans {
+			s.appendTrackedAttr(a.Key, value)
+		} else {
+			s.appendKey(a.Key)
+			s.appendValue(value)
+		}
 	}
 }
 
 func (s *handleState) appendKey(key string) {
 	s.buf.WriteString(s.sep)
 	if s.prefix != nil && len(*s.prefix) > 0 {
-		// TODO: optimize by avoiding allocation.
-		s.appendString(string(*s.prefix) + key)
+		if prefix := *s.prefix; key == "" && s.h.textCompat {
+			// TextHandler quotes the prefix because an empty key would be.
+			*s.buf = strconv.AppendQuote(*s.buf, string(prefix))
+		} else if s.unquotedASCII(prefix) && (key == "" || !s.quoted(key)) {
+			s.buf.Write(prefix)
+			s.buf.WriteString(key)
+		} else {
+			s.appendString(string(prefix) + key)
+		}
 	} else {
 		s.appendString(key)
 	}
 	s.buf.WriteByte('=')
 	s.sep = " "
 }
-
-func (s *handleState) appendString(str string) {
-	if needsQuoting(str) {
-		*s.buf = strconv.AppendQuote(*s.buf, str)
-	} else {
-		s.buf.WriteString(str)
-	}
-}
@@ -14,6 +14,7 @@ import (
 	"log/slog"
 	"net"
 	"path"
+	"runtime"
 	"slices"
 	"strconv"
 	"strings"
@@ -24,94 +25,550 @@ import (
 )
 
 func TestHandler(t *testing.T) {
-	const stopMagic = "MHJKRUECSJ"
+	recv := newTestReceiver(t)
 
-	sockPath := path.Join(t.TempDir(), "socket")
-
-	sock, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram", Name: sockPath})
+	h, err := NewHandler(&HandlerOptions{
+		Level:     slog.LevelInfo,
+		Delimiter: ColonDelimiter,
+		Socket:    recv.path,
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	received := make(chan error, 1)
-	t.Cleanup(func() { <-received })
+	results := func() []map[string]any {
+		time.Sleep(time.Millisecond)
+		var ms []map[string]any
+		for _, fields := range recv.entries() {
+			m, err := parseEntry(fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ms = append(ms, m)
+		}
+		return ms
+	}
 
-	var (
-		mu sync.Mutex
-		ms []map[string]any
-	)
+	if err := slogtest.TestHandler(h, results); err != nil {
+		t.Error(err)
+	}
+	if n := recv.passedFiles(); n != 0 {
+		t.Errorf("%d entries received via file", n)
+	}
+}
 
-	go func() {
-		defer close(received)
-		defer sock.Close()
+// TestHandlerPassedFile runs the slogtest suite with every entry sent via a
+// file descriptor.
+func TestHandlerPassedFile(t *testing.T) {
+	if !LargeMessageSupport {
+		t.Skip("large messages not supported")
+	}
 
-		var (
-			buf = make([]byte, 65536)
-			err error
-		)
+	recv := newTestReceiver(t)
 
-		for {
-			var n int
+	h, err := NewHandler(&HandlerOptions{
+		Level:     slog.LevelInfo,
+		Delimiter: ColonDelimiter,
+		Socket:    recv.path,
+		Fields:    map[string]string{"PADDING": strings.Repeat("x", 16<<10)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	if err := h.root.socks[0].SetWriteBuffer(4096); err != nil {
+		t.Fatal(err)
+	}
 
-			n, _, _, _, err = sock.ReadMsgUnix(buf, nil)
+	results := func() []map[string]any {
+		time.Sleep(time.Millisecond)
+		var ms []map[string]any
+		for _, fields := range recv.entries() {
+			delete(fields, "PADDING")
+			m, err := parseEntry(fields)
 			if err != nil {
-				break
+				t.Fatal(err)
 			}
+			ms = append(ms, m)
+		}
+		return ms
+	}
 
-			b := buf[:n]
+	if err := slogtest.TestHandler(h, results); err != nil {
+		t.Error(err)
+	}
 
-			if strings.Contains(string(b), stopMagic) {
-				break
+	s := h.Stats()
+	if s.Sent == 0 || s.LargeEntries != s.Sent {
+		t.Errorf("stats: %+v", s)
+	}
+	if n := recv.passedFiles(); n != int(s.Sent) {
+		t.Errorf("%d entries received via file; %d sent", n, s.Sent)
+	}
+}
+
+func TestSetSocket(t *testing.T) {
+	h, recv1 := newTestHandler(t, nil)
+	recv2 := newTestReceiver(t)
+
+	if err := h.SetSocket(path.Join(t.TempDir(), "nonexistent")); err == nil {
+		t.Error("nonexistent socket accepted")
+	}
+	if err := h.SetSocket(t.TempDir()); err == nil {
+		t.Error("directory accepted")
+	}
+
+	const (
+		numWorkers = 4
+		numRecords = 100
+	)
+
+	logger := slog.New(h).With("x", 1) // Derived before SetSocket.
+
+	var wg sync.WaitGroup
+	for w := range numWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range numRecords {
+				logger.Info(fmt.Sprintf("%d-%d", w, i))
 			}
+		}()
+	}
 
-			var m map[string]any
+	for i := range 20 {
+		recv := recv1
+		if i%2 == 0 {
+			recv = recv2
+		}
+		if err := h.SetSocket(recv.path); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
 
-			m, err = parseProtocolMessage(b)
-			if err != nil {
-				break
+	wg.Wait()
+
+	total := numWorkers * numRecords
+	seen := make(map[string]int)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n := len(recv1.entries()) + len(recv2.entries())
+		if n >= total || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, recv := range []*testReceiver{recv1, recv2} {
+		for _, m := range recv.entries() {
+			seen[m["MESSAGE"]]++
+		}
+	}
+
+	if len(seen) != total {
+		t.Errorf("%d distinct entries received; expected %d", len(seen), total)
+	}
+	for msg, n := range seen {
+		if n != 1 {
+			t.Errorf("%q received %d times", msg, n)
+		}
+	}
+}
+
+func TestMaxAttrs(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{MaxAttrs: 3})
+
+	logger := slog.New(h)
+	derived := logger.With("a", 1, "b", 2)
+	derived.Info("over", "c", 3, slog.Group("g", "d", 4, "e", 5), "f", 6)
+	derived.Info("exact", "c", 3)
+	derived.WithGroup("g").Info("grouped", "c", 3, "d", 4)
+	derived.With("c", 3, "d", 4).Info("preformatted", "e", 5)
+	logger.Info("under", "a", 1)
+
+	expect := []string{
+		"over a=1 b=2 c=3 sjournal.truncated_attrs=3",
+		"exact a=1 b=2 c=3",
+		"grouped a=1 b=2 g.c=3 sjournal.truncated_attrs=1",
+		"preformatted a=1 b=2 c=3 sjournal.truncated_attrs=2",
+		"under a=1",
+	}
+
+	ms := recv.wait(t, len(expect))
+	for i, s := range expect {
+		if msg := ms[i]["MESSAGE"]; msg != s {
+			t.Errorf("entry %d: %q", i, msg)
+		}
+	}
+}
+
+func TestTimeLocation(t *testing.T) {
+	instant := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	times := []time.Time{
+		instant,
+		instant.In(time.FixedZone("A", 3600)),
+		instant.In(time.FixedZone("B", -7*3600)),
+	}
+
+	for _, x := range []struct {
+		loc    *time.Location
+		expect []string
+	}{
+		{nil, []string{"15:04:05Z", "16:04:05+01:00", "08:04:05-07:00"}},
+		{time.UTC, []string{"15:04:05Z", "15:04:05Z", "15:04:05Z"}},
+		{time.FixedZone("C", 2*3600), []string{"17:04:05+02:00", "17:04:05+02:00", "17:04:05+02:00"}},
+	} {
+		h, recv := newTestHandler(t, &HandlerOptions{
+			TimeFormat:   "15:04:05Z07:00",
+			TimeLocation: x.loc,
+		})
+
+		logger := slog.New(h)
+		for _, ts := range times {
+			logger.Info("time", "t", ts)
+		}
+
+		ms := recv.wait(t, len(times))
+		for i, s := range x.expect {
+			if msg := ms[i]["MESSAGE"]; msg != "time t="+s {
+				t.Errorf("%v: entry %d: %q", x.loc, i, msg)
 			}
+		}
+	}
+}
 
-			ms = append(ms, m)
+func TestTimeValueFormat(t *testing.T) {
+	instant := time.Date(2023, 11, 14, 22, 13, 20, 123456789, time.FixedZone("A", 3600))
+
+	for _, x := range []struct {
+		format TimeValueFormat
+		expect string
+	}{
+		{TimeLayout, "23:13:20+02:00"}, // TimeLocation applies.
+		{TimeUnixSeconds, "1699996400"},
+		{TimeUnixMilli, "1699996400123"},
+		{TimeUnixNano, "1699996400123456789"},
+	} {
+		h, recv := newTestHandler(t, &HandlerOptions{
+			TimeFormat:      "15:04:05Z07:00",
+			TimeLocation:    time.FixedZone("B", 2*3600),
+			TimeValueFormat: x.format,
+		})
+
+		logger := slog.New(h).With("pre", instant)
+		logger.Info("time", "t", instant, slog.Group("g", "t", instant))
+
+		ms := recv.wait(t, 1)
+		if expect := fmt.Sprintf("time pre=%s t=%s g.t=%s", x.expect, x.expect, x.expect); ms[0]["MESSAGE"] != expect {
+			t.Errorf("format %d: %q", x.format, ms[0]["MESSAGE"])
 		}
+	}
+}
 
-		received <- err
-	}()
+func TestTimeOverride(t *testing.T) {
+	instant := time.Date(2023, 11, 14, 22, 13, 20, 123456789, time.FixedZone("A", 3600))
 
-	h, err := NewHandler(&HandlerOptions{
-		Level:     slog.LevelInfo,
-		Delimiter: ColonDelimiter,
-		Socket:    sockPath,
+	h, recv := newTestHandler(t, &HandlerOptions{
+		TimeFormat:   "15:04:05Z07:00",
+		TimeLocation: time.FixedZone("B", 2*3600),
+	})
+
+	logger := slog.New(h).With(Time("pre", instant, LayoutUnixSeconds))
+	logger.Info("time",
+		"t", instant,
+		Time("audit", instant, time.RFC3339),
+		Time("metric", instant, LayoutUnixMilli),
+		Time("nano", instant, LayoutUnixNano),
+		Time("default", instant, ""),
+	)
+
+	const expect = "time pre=1699996400 t=23:13:20+02:00 audit=2023-11-14T23:13:20+02:00 metric=1699996400123 nano=1699996400123456789 default=23:13:20+02:00"
+	if s := recv.wait(t, 1)[0]["MESSAGE"]; s != expect {
+		t.Errorf("%q", s)
+	}
+
+	var b bytes.Buffer
+	slog.New(slog.NewJSONHandler(&b, nil)).Info("time", Time("audit", instant, time.Kitchen))
+	if s := b.String(); !strings.Contains(s, `"audit":"2023-11-14T22:13:20.123456789+01:00"`) {
+		t.Error(s)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Filter: func(ctx context.Context, r slog.Record) bool {
+			if strings.Contains(r.Message, "noisy") {
+				return false
+			}
+			keep := true
+			r.Attrs(func(a slog.Attr) bool {
+				if a.Key == "incident" && a.Value.String() == "42" {
+					keep = false
+				}
+				return keep
+			})
+			r.AddAttrs(slog.String("filter", "corruption"))
+			return keep
+		},
 	})
+
+	logger := slog.New(h).With("x", 1)
+	logger.Info("noisy vendor message")
+	logger.Info("kept", "incident", 41)
+	logger.Info("dropped", "incident", 42)
+	logger.Info("kept", "a", 1, "b", 2, "c", 3, "d", 4, "e", 5, "f", 6)
+
+	time.Sleep(10 * time.Millisecond)
+	ms := recv.entries()
+	expect := []string{"kept x=1 incident=41", "kept x=1 a=1 b=2 c=3 d=4 e=5 f=6"}
+	if len(ms) != len(expect) {
+		t.Fatalf("%d entries", len(ms))
+	}
+	for i, s := range expect {
+		if msg := ms[i]["MESSAGE"]; msg != s {
+			t.Errorf("entry %d: %q", i, msg)
+		}
+	}
+
+	if n := h.Stats().Dropped[DropFiltered]; n != 2 {
+		t.Errorf("filtered: %d", n)
+	}
+}
+
+type testDetailedError struct{}
+
+func (testDetailedError) Error() string { return "short" }
+
+func (e testDetailedError) Format(f fmt.State, verb rune) {
+	if f.Flag('+') {
+		io.WriteString(f, "short\ndetail")
+	} else {
+		io.WriteString(f, e.Error())
+	}
+}
+
+func TestAnyFormat(t *testing.T) {
+	type inner struct{ B int }
+	type outer struct {
+		A string
+		I inner
+	}
+
+	for _, x := range []struct {
+		format string
+		expect string
+	}{
+		{"", `msg s=x n=1 d=1s struct="{x {1}}" err=short`},
+		{"%v", `msg s=x n=1 d=1s struct="{x {1}}" err=short`},
+		{"%+v", `msg s=x n=1 d=1s struct="{A:x I:{B:1}}" err="short\ndetail"`},
+		{"%#v", `msg s=x n=1 d=1s struct="sjournal.outer{A:\"x\", I:sjournal.inner{B:1}}" err=short`},
+	} {
+		h, recv := newTestHandler(t, &HandlerOptions{AnyFormat: x.format})
+		slog.New(h).Info("msg", "s", "x", "n", 1, "d", time.Second, "struct", outer{"x", inner{1}}, "err", testDetailedError{})
+
+		if s := recv.wait(t, 1)[0]["MESSAGE"]; s != x.expect {
+			t.Errorf("%q: %s", x.format, s)
+		}
+	}
+
+	if _, err := NewHandler(&HandlerOptions{AnyFormat: "%d"}); err == nil {
+		t.Error("unsupported format accepted")
+	}
+}
+
+// newTestHandler with a test receiver.  Delimiter defaults to space.
+func newTestHandler(t *testing.T, opts *HandlerOptions) (*Handler, *testReceiver) {
+	t.Helper()
+
+	recv := newTestReceiver(t)
+
+	if opts == nil {
+		opts = new(HandlerOptions)
+	}
+	if opts.Delimiter == "" {
+		opts.Delimiter = DefaultDelimiter
+	}
+	opts.Socket = recv.path
+
+	h, err := NewHandler(opts)
 	if err != nil {
 		t.Fatal(err)
 	}
+	t.Cleanup(func() { h.Close() })
 
-	results := func() []map[string]any {
-		time.Sleep(time.Millisecond)
-		mu.Lock()
-		defer mu.Unlock()
-		return slices.Clip(ms)
+	return h, recv
+}
+
+// testReceiver collects entries sent to a socket in a temporary directory.
+type testReceiver struct {
+	path string
+
+	mu   sync.Mutex
+	ms   []map[string]string
+	raw  [][]byte
+	err  error
+	hold chan struct{} // Non-nil while paused.
+
+	files int // Entries received via passed file descriptors.
+}
+
+func newTestReceiver(t *testing.T) *testReceiver {
+	t.Helper()
+	return newTestReceiverAt(t, path.Join(t.TempDir(), "socket"))
+}
+
+func newTestReceiverAt(t *testing.T, path string) *testReceiver {
+	t.Helper()
+
+	r := &testReceiver{
+		path: path,
 	}
 
-	if err := slogtest.TestHandler(h, results); err != nil {
-		t.Error(err)
+	sock, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram", Name: r.path})
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, stopMagic, 0)); err != nil {
-		t.Error(err)
+	done := make(chan struct{})
+
+	t.Cleanup(func() {
+		r.resume()
+		sock.Close()
+		<-done
+
+		if r.err != nil && !errors.Is(r.err, net.ErrClosed) {
+			t.Error("receiver error:", r.err)
+		}
+	})
+
+	go func() {
+		defer close(done)
+
+		buf := make([]byte, 65536)
+		oob := make([]byte, 64)
+
+		for {
+			n, oobn, _, _, err := sock.ReadMsgUnix(buf, oob)
+			var data []byte
+			if err == nil {
+				data = buf[:n]
+				if oobn > 0 {
+					data, err = readPassedFile(oob[:oobn])
+					r.mu.Lock()
+					r.files++
+					r.mu.Unlock()
+				}
+			}
+			if err == nil {
+				var m map[string]string
+				if m, err = parseProtocolMessage(data); err == nil {
+					r.mu.Lock()
+					hold := r.hold
+					r.mu.Unlock()
+					if hold != nil {
+						<-hold
+					}
+
+					r.mu.Lock()
+					r.ms = append(r.ms, m)
+					r.raw = append(r.raw, slices.Clone(data))
+					r.mu.Unlock()
+					continue
+				}
+			}
+
+			r.mu.Lock()
+			r.err = err
+			r.mu.Unlock()
+			return
+		}
+	}()
+
+	return r
+}
+
+// pause reading from the socket after the next datagram.
+func (r *testReceiver) pause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hold == nil {
+		r.hold = make(chan struct{})
 	}
+}
 
-	if err, ok := <-received; !ok {
-		t.Error("receiver panicked")
-	} else if err != nil {
-		t.Error("receiver error:", err)
+func (r *testReceiver) resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hold != nil {
+		close(r.hold)
+		r.hold = nil
 	}
 }
 
-func parseProtocolMessage(b []byte) (map[string]any, error) {
-	r := bytes.NewBuffer(b)
+// entries received so far.
+func (r *testReceiver) entries() []map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clip(r.ms)
+}
+
+// datagrams received so far.
+func (r *testReceiver) datagrams() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clip(r.raw)
+}
+
+// passedFiles returns the number of entries received via file descriptors.
+func (r *testReceiver) passedFiles() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.files
+}
+
+// wait until at least n entries have been received, or a timeout.
+func (r *testReceiver) wait(t *testing.T, n int) []map[string]string {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ms := r.entries()
+		if len(ms) >= n {
+			return ms
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received %d entries; expected %d", len(ms), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type testField struct {
+	key   string
+	value string
+}
+
+func parseProtocolMessage(b []byte) (map[string]string, error) {
+	fields, err := parseProtocolFields(b)
+	if err != nil {
+		return nil, err
+	}
 
 	data := make(map[string]string)
+	for _, f := range fields {
+		data[f.key] = f.value
+	}
+	return data, nil
+}
+
+// parseProtocolFields in order, including repeated fields.
+func parseProtocolFields(b []byte) ([]testField, error) {
+	r := bytes.NewBuffer(b)
+
+	var fields []testField
 
 	for {
 		line, err := r.ReadString('\n')
@@ -155,9 +612,14 @@ func parseProtocolMessage(b []byte) (map[string]any, error) {
 			}
 		}
 
-		data[key] = value
+		fields = append(fields, testField{key, value})
 	}
 
+	return fields, nil
+}
+
+// parseEntry converts journal fields to the format expected by slogtest.
+func parseEntry(data map[string]string) (map[string]any, error) {
 	value, found := data["MESSAGE"]
 	if !found {
 		return nil, errors.New("MESSAGE key not found")
@@ -238,3 +700,489 @@ func setAttr(m map[string]any, key, value string) {
 
 	setAttr(group.(map[string]any), pair[1], value)
 }
+
+func TestCallerSkip(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		CallerSkip: 2,
+	})
+	logger := slog.New(h)
+
+	logWrapped(logger, "a")
+	logWrapped(logger, "b")
+	logger.Info("direct")
+
+	ms := recv.wait(t, 3)
+	for i, m := range ms[:2] {
+		if m["CODE_FUNC"] != "import.name/sjournal.TestCallerSkip" {
+			t.Errorf("entry %d: %s", i, m["CODE_FUNC"])
+		}
+	}
+	// The records have the same PC, so the caller can't be cached by it.
+	if ms[0]["CODE_LINE"] == ms[1]["CODE_LINE"] {
+		t.Errorf("same line: %s", ms[0]["CODE_LINE"])
+	}
+
+	// Without a wrapper the frames above the test function are reported.
+	if m := ms[2]; m["CODE_FUNC"] == "import.name/sjournal.TestCallerSkip" {
+		t.Errorf("direct: %s", m["CODE_FUNC"])
+	}
+}
+
+//go:noinline
+func logWrapped(logger *slog.Logger, msg string) {
+	logWrappedInner(logger, msg)
+}
+
+func logWrappedInner(logger *slog.Logger, msg string) {
+	logger.Info(msg)
+}
+
+func TestResetPrefix(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Prefix:    "app: ",
+		Delimiter: DefaultDelimiter,
+	})
+
+	worker := h.ExtendPrefix("worker-7: ").WithGroup("g").WithAttrs([]slog.Attr{slog.Int("x", 1)}).(*Handler)
+	sibling := worker.ResetPrefix("pool: ")
+	task := sibling.ExtendPrefix("task: ")
+
+	handlers := []*Handler{h, worker, sibling, task}
+	prefixes := []string{"app: ", "app: worker-7: ", "pool: ", "pool: task: "}
+
+	for i, h := range handlers {
+		if s := h.Prefix(); s != prefixes[i] {
+			t.Errorf("handler %d prefix: %q", i, s)
+		}
+		slog.New(h).Info("msg")
+	}
+
+	ms := recv.wait(t, len(handlers))
+	for i, expect := range []string{
+		"app: msg",
+		"app: worker-7: msg" + DefaultDelimiter + "g.x=1",
+		"pool: msg" + DefaultDelimiter + "g.x=1",
+		"pool: task: msg" + DefaultDelimiter + "g.x=1",
+	} {
+		if s := ms[i]["MESSAGE"]; s != expect {
+			t.Errorf("entry %d: %q", i, s)
+		}
+	}
+}
+
+type point struct{ x, y int }
+
+func (p point) String() string { return fmt.Sprintf("point(%d, %d)", p.x, p.y) }
+
+func TestValueFormatter(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		ExpandStructs: true,
+		ValueFormatter: func(v slog.Value) (string, bool) {
+			switch v.Kind() {
+			case slog.KindAny:
+				if p, ok := v.Any().(point); ok {
+					return fmt.Sprintf("%d,%d", p.x, p.y), true
+				}
+			case slog.KindTime:
+				return "then", true
+			}
+			return "", false
+		},
+	})
+
+	logger := slog.New(h).With("origin", point{0, 0})
+	logger.Info("x", "p", point{1, 2}, "t", time.Now(), "n", 3, "s", "two words", slog.Group("g", "p", point{3, 4}))
+
+	if s := recv.wait(t, 1)[0]["MESSAGE"]; s != `x origin=0,0 p=1,2 t=then n=3 s="two words" g.p=3,4` {
+		t.Error(s)
+	}
+}
+
+func TestWithName(t *testing.T) {
+	for _, prefix := range []bool{false, true} {
+		t.Run(fmt.Sprint("NamePrefix=", prefix), func(t *testing.T) {
+			h, recv := newTestHandler(t, &HandlerOptions{
+				Prefix:     "[app] ",
+				GroupField: "GROUPS",
+				NamePrefix: prefix,
+			})
+
+			if h.WithName("") != h {
+				t.Error("empty name created a new handler")
+			}
+
+			controller := h.WithName("controller")
+			pods := controller.WithName("reconcile").WithName("").WithName("pods")
+			if s := pods.Name(); s != "controller.reconcile.pods" {
+				t.Errorf("name: %q", s)
+			}
+
+			slog.New(h).Info("root")
+			slog.New(controller).WithGroup("req").Info("start", "id", 1)
+			slog.New(pods.WithGroup("g").(*Handler).WithName("x")).Info("done", "n", 2)
+
+			ms := recv.wait(t, 3)
+
+			expect := []struct {
+				name, groups, message string
+			}{
+				{"", "", "[app] root"},
+				{"controller", "req", "[app] start req.id=1"},
+				{"controller.reconcile.pods.x", "g", "[app] done g.n=2"},
+			}
+			for i, x := range expect {
+				message := x.message
+				if prefix && x.name != "" {
+					message = "[app] " + x.name + ": " + strings.TrimPrefix(message, "[app] ")
+				}
+
+				m := ms[i]
+				if m["LOGGER"] != x.name || m["GROUPS"] != x.groups || m["MESSAGE"] != message {
+					t.Errorf("entry %d: LOGGER=%q GROUPS=%q MESSAGE=%q", i, m["LOGGER"], m["GROUPS"], m["MESSAGE"])
+				}
+			}
+		})
+	}
+}
+
+func TestDeriveConcurrent(t *testing.T) {
+	const (
+		goroutines = 256
+		rounds     = 10
+	)
+
+	for _, dup := range []DuplicateKeys{KeepAll, LastWins} {
+		t.Run(fmt.Sprint("DuplicateKeys=", dup), func(t *testing.T) {
+			h, err := NewHandler(&HandlerOptions{
+				Delimiter:     ColonDelimiter,
+				GroupField:    "GROUPS",
+				DuplicateKeys: dup,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			var (
+				mu       sync.Mutex
+				messages = make(map[string]int)
+			)
+			h.root.sink = func(b []byte) error {
+				var message, groups string
+				rangeFields(b, func(name string, value []byte) {
+					switch name {
+					case "MESSAGE":
+						message = string(value)
+					case "GROUPS":
+						groups = string(value)
+					}
+				})
+				mu.Lock()
+				defer mu.Unlock()
+				messages[groups+" "+message]++
+				return nil
+			}
+
+			parent := slog.New(h).With("base", 0).WithGroup("g").With("p", 1).Handler().(*Handler)
+			attrs := string(parent.preformattedAttrs)
+			groups := slices.Clone(parent.groups)
+
+			var wg sync.WaitGroup
+			start := make(chan struct{})
+			for i := range goroutines {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					for j := range rounds {
+						prefixed := parent.ExtendPrefix(fmt.Sprintf("[%d] ", i))
+						logger := slog.New(prefixed).With("i", i).WithGroup(fmt.Sprint("w", i)).With("j", j)
+						logger.Info("msg", "k", i+j)
+						slog.New(parent).WithGroup(fmt.Sprint("v", i)).Info("msg", "j", j)
+					}
+				}()
+			}
+			close(start)
+			wg.Wait()
+
+			if s := string(parent.preformattedAttrs); s != attrs {
+				t.Errorf("parent attributes changed: %q", s)
+			}
+			if !slices.Equal(parent.groups, groups) {
+				t.Errorf("parent groups changed: %q", parent.groups)
+			}
+
+			if len(messages) != 2*goroutines*rounds {
+				t.Errorf("%d distinct entries", len(messages))
+			}
+			for i := range goroutines {
+				for j := range rounds {
+					for _, key := range []string{
+						fmt.Sprintf("g.w%d [%d] msg: base=0 g.p=1 g.i=%d g.w%d.j=%d g.w%d.k=%d", i, i, i, i, j, i, i+j),
+						fmt.Sprintf("g.v%d msg: base=0 g.p=1 g.v%d.j=%d", i, i, j),
+					} {
+						if n := messages[key]; n != 1 {
+							t.Errorf("%q: %d", key, n)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkHandleManyAttrs(b *testing.B) {
+	h, err := NewHandler(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer h.Close()
+	h.root.sink = func([]byte) error { return nil }
+
+	ctx := context.Background()
+	h2 := h.WithGroup("request")
+
+	for _, n := range []int{8, 32, 128} {
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "message", 0)
+		for i := range n {
+			key := "key" + strconv.Itoa(i)
+			switch i % 4 {
+			case 0:
+				r.AddAttrs(slog.String(key, "value"))
+			case 1:
+				r.AddAttrs(slog.Int(key, i*1000))
+			case 2:
+				r.AddAttrs(slog.Bool(key, i%3 == 0))
+			case 3:
+				r.AddAttrs(slog.Float64(key, float64(i)/4))
+			}
+		}
+
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				h2.Handle(ctx, r)
+			}
+		})
+	}
+}
+
+func TestEstimateSize(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Prefix:          "app: ",
+		SequenceNumbers: true,
+		MonotonicTime:   true,
+		RecordRealtime:  true,
+		SignatureKey:    []byte("key"),
+	})
+
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+
+	now := time.Now()
+	large := slog.NewRecord(now, slog.LevelInfo, "large", 0)
+	large.AddAttrs(slog.String("data", strings.Repeat("x", 10000)))
+	source := slog.NewRecord(now, slog.LevelWarn, "source", pcs[0])
+	source.AddAttrs(slog.Int("n", 1), slog.Group("g", "a", true))
+	multiline := slog.NewRecord(now, slog.LevelError, "multi\nline", pcs[0])
+	multiline.AddAttrs(slog.Any("err", errors.New("failed")))
+
+	handlers := []*Handler{
+		h,
+		h.WithAttrs([]slog.Attr{slog.String("pre", "formatted")}).(*Handler),
+		h.WithGroup("group").WithAttrs([]slog.Attr{slog.Int("x", 2)}).(*Handler),
+		h.ExtendPrefix("sub: "),
+	}
+	records := []slog.Record{
+		slog.NewRecord(time.Time{}, slog.LevelDebug, "", 0),
+		slog.NewRecord(now, slog.LevelInfo, "hello", 0),
+		large,
+		source,
+		multiline,
+	}
+
+	var estimates []int
+	for _, x := range handlers {
+		for _, r := range records {
+			estimates = append(estimates, x.EstimateSize(r.Clone()))
+			if err := x.Handle(context.Background(), r.Clone()); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if s := h.Stats(); s.Sent != uint64(len(estimates)) {
+		t.Errorf("stats: %+v", s)
+	}
+
+	recv.wait(t, len(estimates))
+	for i, b := range recv.datagrams() {
+		if n := estimates[i]; n != len(b) {
+			t.Errorf("entry %d: estimate %d, actual %d", i, n, len(b))
+		}
+	}
+}
+
+func TestStaticFieldsCache(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Identifier: "app",
+		Fields:     map[string]string{"SERVICE": "api"},
+	})
+
+	level := h.WithLevel(slog.LevelInfo)
+	named := level.WithName("store")
+	fields := named.WithFields(map[string]string{"SERVICE": "db"})
+
+	for _, x := range []*Handler{h, level, named, fields} {
+		slog.New(x).Info("first")
+	}
+	if err := h.Reload(&HandlerOptions{Identifier: "app2", Fields: map[string]string{"SERVICE": "api"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []*Handler{h, level, named, fields} {
+		slog.New(x).Info("second")
+	}
+
+	for i, expect := range []string{
+		"SYSLOG_IDENTIFIER=app SERVICE=api",
+		"SYSLOG_IDENTIFIER=app SERVICE=api",
+		"SYSLOG_IDENTIFIER=app SERVICE=api LOGGER=store",
+		"SYSLOG_IDENTIFIER=app SERVICE=db LOGGER=store",
+		"SYSLOG_IDENTIFIER=app2 SERVICE=api",
+		"SYSLOG_IDENTIFIER=app2 SERVICE=api",
+		"SYSLOG_IDENTIFIER=app2 SERVICE=api LOGGER=store",
+		"SYSLOG_IDENTIFIER=app2 SERVICE=db LOGGER=store",
+	} {
+		recv.wait(t, 8)
+		var fields []string
+		for _, f := range datagramFields(recv.datagrams()[i]) {
+			if !strings.HasPrefix(f, "PRIORITY=") && !strings.HasPrefix(f, "MESSAGE=") && !strings.HasPrefix(f, "CODE_") && !strings.HasPrefix(f, "SYSLOG_TIMESTAMP=") {
+				fields = append(fields, f)
+			}
+		}
+		if s := strings.Join(fields, " "); s != expect {
+			t.Errorf("entry %d: %s", i, s)
+		}
+	}
+}
+
+func BenchmarkHandleStaticFields(b *testing.B) {
+	h, err := NewHandler(&HandlerOptions{
+		Identifier: "benchmark",
+		Hostname:   "host.example.com",
+		Fields: map[string]string{
+			"SERVICE": "api",
+			"VERSION": "1.2.3",
+			"REGION":  "eu-north-1",
+			"BUILD":   "0123456789abcdef",
+		},
+		GroupField: "GROUP",
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer h.Close()
+	h.root.sink = func([]byte) error { return nil }
+
+	h2 := h.WithFields(map[string]string{"COMPONENT": "db"}).WithName("store").WithGroup("request")
+	h2 = h2.WithAttrs([]slog.Attr{Field("request_id", "abc")})
+
+	ctx := context.Background()
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "message", 0)
+	r.AddAttrs(slog.Int("n", 1))
+
+	b.ReportAllocs()
+	for range b.N {
+		h2.Handle(ctx, r)
+	}
+}
+
+func TestDerivedState(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter: ColonDelimiter,
+		Prefix:    "app: ",
+	})
+
+	x := h.WithAttrs([]slog.Attr{slog.Int("a", 1)}).(*Handler)
+	x = x.WithGroup("g").(*Handler).ExtendPrefix("sub: ")
+	x = x.WithGroup("h").WithAttrs([]slog.Attr{
+		slog.String("b", "x"),
+		slog.Group("", slog.Bool("c", true)),
+		slog.Group("sub", slog.Int("d", 2)),
+	}).(*Handler)
+	x = x.WithGroup("i").(*Handler)
+
+	if s := x.Groups(); !slices.Equal(s, []string{"g", "h", "i"}) {
+		t.Errorf("groups: %q", s)
+	}
+	if s := x.Prefix(); s != "app: sub: " {
+		t.Errorf("prefix: %q", s)
+	}
+
+	var attrs []string
+	for _, a := range x.PreformattedAttrs() {
+		attrs = append(attrs, a.String())
+	}
+	if s := strings.Join(attrs, " "); s != "a=1 g.h.b=x g.h.c=true g.h.sub=[d=2]" {
+		t.Errorf("attrs: %s", s)
+	}
+
+	slog.New(x).Info("msg", "e", 3)
+	if s := recv.wait(t, 1)[0]["MESSAGE"]; s != "app: sub: msg: a=1 g.h.b=x g.h.c=true g.h.sub.d=2 g.h.i.e=3" {
+		t.Errorf("message: %q", s)
+	}
+
+	if len(h.Groups()) != 0 || len(h.PreformattedAttrs()) != 0 {
+		t.Error("parent state changed")
+	}
+}
+
+func TestFilterInHandle(t *testing.T) {
+	for _, filter := range []bool{false, true} {
+		h, recv := newTestHandler(t, &HandlerOptions{
+			Level:          slog.LevelInfo,
+			RecordRealtime: true,
+			FilterInHandle: filter,
+		})
+
+		now := time.Unix(1700000000, 0)
+		for _, r := range []slog.Record{
+			slog.NewRecord(time.Time{}, slog.LevelDebug, "debug zero", 0),
+			slog.NewRecord(now, slog.LevelDebug, "debug", 0),
+			slog.NewRecord(time.Time{}, slog.LevelInfo, "info zero", 0),
+			slog.NewRecord(now, slog.LevelInfo, "info", 0),
+		} {
+			if err := h.Handle(context.Background(), r); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		expect := []string{"debug zero", "debug", "info zero", "info"}
+		if filter {
+			expect = expect[2:]
+		}
+
+		ms := recv.wait(t, len(expect))
+		time.Sleep(10 * time.Millisecond)
+		ms = append(ms, recv.entries()[len(ms):]...)
+		if len(ms) != len(expect) {
+			t.Fatalf("filter %v: %d entries", filter, len(ms))
+		}
+
+		for i, m := range ms {
+			if m["MESSAGE"] != expect[i] {
+				t.Errorf("filter %v: entry %d: message %q", filter, i, m["MESSAGE"])
+			}
+			zero := strings.HasSuffix(expect[i], "zero")
+			for _, name := range []string{"SYSLOG_TIMESTAMP", "RECORD_REALTIME_USEC"} {
+				if _, found := m[name]; found == zero {
+					t.Errorf("filter %v: entry %d: %s found: %v", filter, i, name, found)
+				}
+			}
+		}
+
+		if n := h.Stats().Dropped[DropFiltered]; n != uint64(4-len(expect)) {
+			t.Errorf("filter %v: %d filtered", filter, n)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+const numFacilities = FacilityLocal7 + 1
+
+var priorityHeaders = [numPriorities]string{
+	prefixEmerg,
+	prefixAlert,
+	prefixCrit,
+	prefixErr,
+	prefixWarning,
+	prefixNotice,
+	prefixInfo,
+	prefixDebug,
+}
+
+// facilityHeaders are built on first use.
+var facilityHeaders [numFacilities][numPriorities]atomic.Pointer[string]
+
+// entryHeader returns the beginning of an entry: the PRIORITY field, the
+// SYSLOG_FACILITY field if the facility is positive, and the MESSAGE field
+// name followed by the length placeholder.  The priority digit is at
+// priorityOffset.
+func entryHeader(priority, facility int) string {
+	if facility <= 0 {
+		return priorityHeaders[priority]
+	}
+
+	p := &facilityHeaders[facility][priority]
+	if s := p.Load(); s != nil {
+		return *s
+	}
+
+	b := make([]byte, 0, len(prefixInfo)+len("SYSLOG_FACILITY=23\n"))
+	b = append(b, priorityHeaders[priority][:priorityOffset+2]...)
+	b = append(b, "SYSLOG_FACILITY="...)
+	b = strconv.AppendInt(b, int64(facility), 10)
+	b = append(b, '\n')
+	b = append(b, priorityHeaders[priority][priorityOffset+2:]...)
+	s := string(b)
+	p.Store(&s)
+	return s
+}
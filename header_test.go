@@ -0,0 +1,77 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEntryHeader(t *testing.T) {
+	for facility := range numFacilities {
+		for priority := range numPriorities {
+			expect := "PRIORITY=" + strconv.Itoa(priority) + "\n"
+			if facility > 0 {
+				expect += "SYSLOG_FACILITY=" + strconv.Itoa(facility) + "\n"
+			}
+			expect += "MESSAGE\n\x00\x00\x00\x00\x00\x00\x00\x00"
+
+			for range 2 {
+				if s := entryHeader(priority, facility); s != expect {
+					t.Errorf("priority %d, facility %d: %q", priority, facility, s)
+				}
+			}
+		}
+	}
+}
+
+func TestFacility(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Facility:    FacilityLocal3,
+		MaxPriority: 3,
+	})
+
+	logger := slog.New(h)
+	logger.Info("info")
+	logger.Error("error")
+	logger.Info("override", Priority(1))
+
+	for i, expect := range []string{
+		"PRIORITY=6 SYSLOG_FACILITY=19 MESSAGE=info",
+		"PRIORITY=3 SYSLOG_FACILITY=19 MESSAGE=error",
+		"PRIORITY=3 SYSLOG_FACILITY=19 MESSAGE=override",
+	} {
+		recv.wait(t, 3)
+		fields := datagramFields(recv.datagrams()[i])[:3]
+		if s := fields[0] + " " + fields[1] + " " + fields[2]; s != expect {
+			t.Errorf("entry %d: %s", i, s)
+		}
+	}
+}
+
+func BenchmarkEntryHeader(b *testing.B) {
+	for _, facility := range []int{0, FacilityDaemon} {
+		h, err := NewHandler(&HandlerOptions{Facility: facility})
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer h.Close()
+		h.root.sink = func([]byte) error { return nil }
+
+		ctx := context.Background()
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "message", 0)
+		r.AddAttrs(slog.String("key", "value"))
+
+		b.Run("facility="+strconv.Itoa(facility), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				h.Handle(ctx, r)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const defaultRedactPlaceholder = "[REDACTED]"
+
+// keyPatterns matches full attribute keys (including group prefixes).  A
+// pattern may contain the wildcards supported by path.Match; a star matches
+// also dots.
+type keyPatterns struct {
+	exact map[string]struct{}
+	globs []string
+}
+
+// newKeyPatterns returns nil if there are no patterns.
+func newKeyPatterns(patterns []string) (*keyPatterns, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	p := &keyPatterns{
+		exact: make(map[string]struct{}),
+	}
+
+	for _, s := range patterns {
+		if !strings.ContainsAny(s, `*?[\`) {
+			p.exact[s] = struct{}{}
+			continue
+		}
+		if _, err := path.Match(s, ""); err != nil {
+			return nil, fmt.Errorf("invalid key pattern %q: %w", s, err)
+		}
+		p.globs = append(p.globs, s)
+	}
+
+	return p, nil
+}
+
+func (p *keyPatterns) match(key string) bool {
+	if p == nil {
+		return false
+	}
+	if _, found := p.exact[key]; found {
+		return true
+	}
+	for _, pattern := range p.globs {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fullKey returns the key with the current group prefix.
+func (s *handleState) fullKey(key string) string {
+	if s.prefix != nil && len(*s.prefix) > 0 {
+		return string(*s.prefix) + key
+	}
+	return key
+}
@@ -0,0 +1,136 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestDropKeys(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		DropKeys: []string{"secret", "http.request.*", "payload", "g.x"},
+	})
+
+	logger := slog.New(h)
+	logger.Info("exact", "secret", 1, "secrets", 2)
+	logger.Info("glob", slog.Group("http", slog.Group("request", "method", "GET", "url", "/"), "status", 200))
+	logger.Info("subtree", slog.Group("payload", "a", 1, slog.Group("b", "c", 2)), "d", 3)
+	logger.With("secret", 1, "kept", 2).Info("preformatted", "secret", 3)
+	logger.WithGroup("g").With("x", 1, "y", 2).Info("grouped", "x", 3, "z", 4)
+	logger.WithGroup("http").WithGroup("request").Info("withgroup", "method", "GET")
+
+	expect := []string{
+		"exact secrets=2",
+		"glob http.status=200",
+		"subtree d=3",
+		"preformatted kept=2",
+		"grouped g.y=2 g.z=4",
+		"withgroup",
+	}
+
+	ms := recv.wait(t, len(expect))
+	for i, s := range expect {
+		if msg := ms[i]["MESSAGE"]; msg != s {
+			t.Errorf("entry %d: %q", i, msg)
+		}
+	}
+
+	if _, err := NewHandler(&HandlerOptions{DropKeys: []string{"["}}); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
+
+type testSecretValuer struct{}
+
+func (testSecretValuer) LogValue() slog.Value {
+	return slog.StringValue("s3cr3t-valuer")
+}
+
+func TestRedactKeys(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		RedactKeys: []string{"password", "*.authorization", "ssn", "err"},
+	})
+
+	logger := slog.New(h).With("ssn", "s3cr3t-ssn")
+	logger.Info("redacted",
+		"password", "s3cr3t-password",
+		slog.Group("http", slog.Group("header", "authorization", "s3cr3t-auth", "accept", "*/*")),
+		"ssn", testSecretValuer{},
+		Field("password", "s3cr3t-field"),
+		Error(errors.New("s3cr3t-error")),
+	)
+	logger.Info("group", slog.Group("password", "a", "s3cr3t-group"))
+
+	expect := []string{
+		"redacted ssn=[REDACTED] password=[REDACTED] http.header.authorization=[REDACTED] http.header.accept=*/* ssn=[REDACTED] password=[REDACTED] err=[REDACTED]",
+		"group ssn=[REDACTED] password=[REDACTED]",
+	}
+
+	ms := recv.wait(t, len(expect))
+	for i, s := range expect {
+		if msg := ms[i]["MESSAGE"]; msg != s {
+			t.Errorf("entry %d: %q", i, msg)
+		}
+	}
+
+	for _, b := range recv.datagrams() {
+		if bytes.Contains(b, []byte("s3cr3t")) {
+			t.Errorf("datagram contains secret: %q", b)
+		}
+	}
+
+	h2, recv2 := newTestHandler(t, &HandlerOptions{
+		RedactKeys:        []string{"password"},
+		RedactPlaceholder: "***",
+	})
+	slog.New(h2).Info("custom", "password", "x")
+	if s := recv2.wait(t, 1)[0]["MESSAGE"]; s != "custom password=***" {
+		t.Errorf("message: %q", s)
+	}
+}
+
+func TestRedactValue(t *testing.T) {
+	token := regexp.MustCompile(`tok_[0-9a-z]+`)
+	var keys []string
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		RedactValue: func(key, value string) string {
+			keys = append(keys, key)
+			if value == "token=tok_abc123" {
+				return "***"
+			}
+			return token.ReplaceAllString(value, "tok_***")
+		},
+	})
+
+	slog.New(h).WithGroup("g").Info("auth with tok_abc123 failed",
+		"url", "https://example.com/?t=tok_abc123",
+		"header", "token=tok_abc123",
+		Field("bearer", "Bearer tok_abc123"),
+	)
+
+	m := recv.wait(t, 1)[0]
+	if s := m["MESSAGE"]; s != `auth with tok_*** failed g.url="https://example.com/?t=tok_***" g.header=***` {
+		t.Errorf("message: %q", s)
+	}
+	if s := m["BEARER"]; s != "Bearer tok_***" {
+		t.Errorf("field: %q", s)
+	}
+
+	for _, b := range recv.datagrams() {
+		if bytes.Contains(b, []byte("abc123")) {
+			t.Errorf("datagram contains token: %q", b)
+		}
+	}
+
+	if !slices.Equal(keys, []string{"msg", "g.url", "g.header", "BEARER"}) {
+		t.Errorf("keys: %q", keys)
+	}
+}
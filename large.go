@@ -0,0 +1,22 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+)
+
+// largeEntryField is appended to entries passed to journald via files if
+// LargeEntryField is set.
+const largeEntryField = "LARGE_ENTRY=1\n"
+
+// largeEntry is called after an entry has been passed to journald via a file.
+// The record is nil if the entry didn't originate from Handle.
+func (r *root) largeEntry(size int, rec *slog.Record) {
+	r.stats.largeEntries.Add(1)
+	if r.onLargeEntry != nil && rec != nil {
+		r.onLargeEntry(size, *rec)
+	}
+}
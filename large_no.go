@@ -6,8 +6,26 @@
 
 package sjournal
 
+import (
+	"net"
+)
+
 const LargeMessageSupport = false
 
-func (h *Handler) sendViaFileIfTooLarge(err error, b []byte) error {
-	return err
+func fileStrategyName() string {
+	return ""
+}
+
+// tooLarge can't identify the cause of a send error portably, so any failure
+// to send a large entry is assumed to be caused by its size.
+func tooLarge(err error) bool {
+	return true
+}
+
+func sendViaFileIfTooLarge(err error, b []byte, mark bool, sock *net.UnixConn, addr *net.UnixAddr) (bool, error) {
+	return false, err
+}
+
+func socketSendBufferSize(sock *net.UnixConn) int {
+	return 0
 }
@@ -0,0 +1,15 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !(darwin || unix)
+
+package sjournal
+
+import (
+	"errors"
+)
+
+func readPassedFile(oob []byte) ([]byte, error) {
+	return nil, errors.New("file descriptor passing is not supported")
+}
@@ -8,28 +8,68 @@ package sjournal
 
 import (
 	"errors"
+	"net"
+	"sync/atomic"
 	"syscall"
 )
 
 const LargeMessageSupport = true
 
-func (h *Handler) sendViaFileIfTooLarge(err error, b []byte) error {
-	if !(errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)) {
-		return err
+// activeFileStrategy is the name of the most recently used way to create a
+// nonlinked file.
+var activeFileStrategy atomic.Value
+
+func fileStrategyName() string {
+	s, _ := activeFileStrategy.Load().(string)
+	return s
+}
+
+// tooLarge reports whether a send error is caused by the entry size.
+func tooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// sendViaFileIfTooLarge reports whether the entry was sent via a file.  The
+// LARGE_ENTRY field is appended to the file contents if mark is true.
+func sendViaFileIfTooLarge(err error, b []byte, mark bool, sock *net.UnixConn, addr *net.UnixAddr) (bool, error) {
+	if !tooLarge(err) {
+		return false, err
 	}
 
 	f, err := createNonlinkedFile()
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer f.Close()
 
 	if _, err := f.Write(b); err != nil {
-		return err
+		return false, err
+	}
+	if mark {
+		if _, err := f.WriteString(largeEntryField); err != nil {
+			return false, err
+		}
 	}
 
-	if _, _, err := h.sock.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), &h.addr); err != nil {
-		return err
+	if _, _, err := sock.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), addr); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// socketSendBufferSize returns the SO_SNDBUF value of a socket, or zero.
+func socketSendBufferSize(sock *net.UnixConn) int {
+	conn, err := sock.SyscallConn()
+	if err != nil {
+		return 0
+	}
+
+	var size int
+	conn.Control(func(fd uintptr) {
+		size, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_SNDBUF)
+	})
+	if err != nil {
+		return 0
 	}
-	return nil
+	return size
 }
@@ -0,0 +1,39 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || unix
+
+package sjournal
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// readPassedFile reads the contents of a file descriptor received in a
+// control message.
+func readPassedFile(oob []byte) ([]byte, error) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) != 1 {
+		return nil, errors.New("unexpected control messages")
+	}
+
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, fd := range fds[1:] {
+		syscall.Close(fd)
+	}
+
+	f := os.NewFile(uintptr(fds[0]), "passed")
+	defer f.Close()
+
+	return io.ReadAll(io.NewSectionReader(f, 0, 1<<30))
+}
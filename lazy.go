@@ -0,0 +1,33 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Lazy returns an attribute whose value is computed by calling fn when a
+// handler resolves it.  The handler doesn't resolve it for records which are
+// dropped due to the level, Filter, muting or a used-up budget.  The function
+// is called at most once, even if the record is handled by multiple handlers
+// or Destinations.
+func Lazy(key string, fn func() slog.Value) slog.Attr {
+	return slog.Any(key, &lazyValue{fn: fn})
+}
+
+type lazyValue struct {
+	once sync.Once
+	fn   func() slog.Value
+	v    slog.Value
+}
+
+func (l *lazyValue) LogValue() slog.Value {
+	l.once.Do(func() {
+		l.v = l.fn()
+		l.fn = nil
+	})
+	return l.v
+}
@@ -0,0 +1,91 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLazy(t *testing.T) {
+	var calls atomic.Int32
+	lazy := func() slog.Attr {
+		return Lazy("dump", func() slog.Value {
+			calls.Add(1)
+			return slog.StringValue("expensive")
+		})
+	}
+
+	clock := &budgetClock{time.Unix(1700000000, 0)}
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter:      DefaultDelimiter,
+		Level:          slog.LevelInfo,
+		FilterInHandle: true,
+		Filter:         func(_ context.Context, r slog.Record) bool { return r.Message != "filtered" },
+		Clock:          clock,
+		Budgets:        []Budget{{Bytes: 1}},
+		BudgetExempt:   slog.LevelWarn,
+	})
+	logger := slog.New(h)
+
+	check := func(what string, expect int32) {
+		t.Helper()
+		if n := calls.Swap(0); n != expect {
+			t.Errorf("%s: %d calls", what, n)
+		}
+	}
+
+	logger.Debug("debug", lazy())
+	check("below level", 0)
+
+	r := slog.NewRecord(clock.t, slog.LevelDebug, "debug", 0)
+	r.AddAttrs(lazy())
+	h.Handle(context.Background(), r)
+	check("below level in Handle", 0)
+
+	logger.Warn("filtered", lazy())
+	check("filtered", 0)
+
+	logger.Info("first", lazy()) // Exceeds the budget.
+	check("first", 1)
+
+	logger.Warn("delivered", lazy()) // Uses up the budget.
+	check("delivered", 1)
+
+	logger.Info("sampled", lazy())
+	check("budget used up", 0)
+
+	m := recv.wait(t, 1)[0]
+	if s := m["MESSAGE"]; s != "delivered dump=expensive" {
+		t.Errorf("message: %q", s)
+	}
+	if n := h.Stats().Dropped[DropBudget]; n != 2 {
+		t.Errorf("%d dropped due to budget", n)
+	}
+}
+
+func TestLazyOnce(t *testing.T) {
+	var calls int
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter: DefaultDelimiter,
+	})
+	a := Lazy("n", func() slog.Value {
+		calls++
+		return slog.IntValue(calls)
+	})
+
+	slog.New(h).Info("first", a)
+	slog.New(h).Info("second", a)
+
+	ms := recv.wait(t, 2)
+	if s := ms[1]["MESSAGE"]; s != "second n=1" || calls != 1 {
+		t.Errorf("message %q, %d calls", s, calls)
+	}
+}
@@ -5,10 +5,13 @@
 package sjournal
 
 import (
+	"fmt"
 	"log/slog"
+	"strings"
 )
 
 const (
+	LevelTrace  = slog.LevelDebug - 4
 	LevelDebug  = slog.LevelDebug
 	LevelInfo   = slog.LevelInfo
 	LevelNotice = slog.LevelInfo + 2
@@ -17,3 +20,105 @@ const (
 	LevelCrit   = slog.LevelError + 4
 	LevelAlert  = slog.LevelError + 8
 )
+
+// Priority returns an attribute which overrides the journald priority (0-7)
+// of a record, regardless of its level.  Invalid values are ignored.  The
+// attribute may also be passed to WithAttrs; the record's own override takes
+// precedence.  Other handlers see a "priority" attribute.
+func Priority(p int) slog.Attr {
+	return slog.Any("priority", priorityOverride(p))
+}
+
+type priorityOverride int
+
+func (p priorityOverride) LogValue() slog.Value {
+	return slog.IntValue(int(p))
+}
+
+// PriorityForLevel returns the journald priority (0-7) which corresponds to a
+// level by default.  Levels below LevelDebug map to the debug priority, and
+// levels above LevelCrit to the alert priority.
+func PriorityForLevel(l slog.Level) int {
+	return int(levelPrefix(l)[priorityOffset] - '0')
+}
+
+// Priority returns the journald priority which the handler uses for records
+// of the level.  It takes into account Priority attributes passed to
+// WithAttrs, MaxPriority and MinPriority, but not LevelRules or the Priority
+// attributes of records.
+func (h *Handler) Priority(l slog.Level) int {
+	return h.effectivePriority(-1, l)
+}
+
+// effectivePriority applies the overrides to the priority of a record.  The
+// record's own override is p, or -1.
+func (h *Handler) effectivePriority(p int, l slog.Level) int {
+	if p < 0 {
+		p = h.priority
+	}
+	if p < 0 {
+		p = PriorityForLevel(l)
+	}
+	p = max(p, h.maxPriority)
+	if h.minPriority > 0 {
+		p = min(p, h.minPriority)
+	}
+	return p
+}
+
+var priorityNames = [numPriorities][]string{
+	{"emerg", "panic"},
+	{"alert"},
+	{"crit"},
+	{"err", "error"},
+	{"warning", "warn"},
+	{"notice"},
+	{"info"},
+	{"debug"},
+}
+
+// priorityLevels maps journald priorities to the closest levels.
+var priorityLevels = [numPriorities]slog.Level{
+	LevelAlert, // No level maps to emergency priority.
+	LevelAlert,
+	LevelCrit,
+	LevelError,
+	LevelWarn,
+	LevelNotice,
+	LevelInfo,
+	LevelDebug,
+}
+
+// ParsePriority parses a journald priority name (such as "err" or "warning")
+// or number (0-7).  Names are case-insensitive.
+func ParsePriority(s string) (int, error) {
+	if len(s) == 1 && s[0] >= '0' && s[0] < '0'+numPriorities {
+		return int(s[0] - '0'), nil
+	}
+	for p, names := range priorityNames {
+		for _, name := range names {
+			if strings.EqualFold(s, name) {
+				return p, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("sjournal: unknown priority: %q", s)
+}
+
+// ParseLevel parses a journald priority name or number (see ParsePriority),
+// "trace", or a slog level name (such as "WARN" or "INFO+2").  Emergency
+// priority is parsed as LevelAlert.
+func ParseLevel(s string) (slog.Level, error) {
+	if p, err := ParsePriority(s); err == nil {
+		return priorityLevels[p], nil
+	}
+	if strings.EqualFold(s, "trace") {
+		return LevelTrace, nil
+	}
+
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("sjournal: unknown level: %q", s)
+	}
+	return l, nil
+}
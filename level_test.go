@@ -0,0 +1,402 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPriority(t *testing.T) {
+	recv := newTestReceiver(t)
+
+	h, err := NewHandler(&HandlerOptions{
+		Delimiter: DefaultDelimiter,
+		Socket:    recv.path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	logger := slog.New(h)
+	logger.Info("absent")
+	logger.Info("present", Priority(5))
+	logger.Info("invalid", Priority(8))
+	logger.Error("invalid", Priority(-1))
+	logger.Error("emergency", Priority(0))
+
+	derived := logger.With(Priority(2), "x", 1)
+	derived.Info("preformatted")
+	derived.Info("overridden", Priority(7))
+	derived.With(Priority(100)).Info("invalid")
+
+	expect := []struct {
+		message  string
+		priority string
+	}{
+		{"absent", "6"},
+		{"present", "5"},
+		{"invalid", "6"},
+		{"invalid", "3"},
+		{"emergency", "0"},
+		{"preformatted x=1", "2"},
+		{"overridden x=1", "7"},
+		{"invalid x=1", "2"},
+	}
+
+	ms := recv.wait(t, len(expect))
+	for i, x := range expect {
+		if s := ms[i]["MESSAGE"]; s != x.message {
+			t.Errorf("entry %d: message %q", i, s)
+		}
+		if s := ms[i]["PRIORITY"]; s != x.priority {
+			t.Errorf("entry %d: priority %q", i, s)
+		}
+	}
+}
+
+func TestPriorityClamp(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		MaxPriority: 4,
+		MinPriority: 6,
+	})
+
+	logger := slog.New(h)
+	logger.Log(context.Background(), LevelCrit, "crit")
+	logger.Error("error")
+	logger.Warn("warn")
+	logger.Log(context.Background(), LevelNotice, "notice")
+	logger.Info("info")
+	logger.Debug("debug")
+	logger.Info("override", Priority(1))
+	logger.Error("override", Priority(7))
+
+	ms := recv.wait(t, 8)
+	for i, expect := range []string{"4", "4", "4", "5", "6", "6", "4", "6"} {
+		if s := ms[i]["PRIORITY"]; s != expect {
+			t.Errorf("%s: priority %s", ms[i]["MESSAGE"], s)
+		}
+	}
+}
+
+func TestLevelField(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level:       LevelDebug,
+		MaxPriority: 4,
+		MinPriority: 6,
+		LevelField:  true,
+	})
+
+	logger := slog.New(h)
+	logger.Log(context.Background(), LevelCrit, "crit")
+	logger.Warn("warn")
+	logger.Debug("debug")
+	logger.Info("override", Priority(1))
+
+	ms := recv.wait(t, 4)
+	for i, x := range []struct {
+		priority string
+		level    string
+	}{
+		{"4", "ERROR+4"},
+		{"4", "WARN"},
+		{"6", "DEBUG"},
+		{"4", "INFO"},
+	} {
+		if s := ms[i]["PRIORITY"]; s != x.priority {
+			t.Errorf("%s: priority %s", ms[i]["MESSAGE"], s)
+		}
+		if s := ms[i]["LEVEL"]; s != x.level {
+			t.Errorf("%s: level %q", ms[i]["MESSAGE"], s)
+		}
+	}
+}
+
+func TestPriorityForLevel(t *testing.T) {
+	for _, x := range []struct {
+		level    slog.Level
+		priority int
+	}{
+		{slog.LevelDebug - 100, 7},
+		{LevelTrace, 7},
+		{slog.LevelDebug, 7},
+		{slog.LevelDebug + 1, 6},
+		{slog.LevelInfo, 6},
+		{slog.LevelInfo + 1, 5},
+		{LevelNotice, 5},
+		{slog.LevelWarn, 4},
+		{slog.LevelWarn + 1, 3},
+		{slog.LevelError, 3},
+		{slog.LevelError + 1, 2},
+		{LevelCrit, 2},
+		{LevelCrit + 1, 1},
+		{LevelAlert, 1},
+		{LevelAlert + 100, 1},
+	} {
+		if p := PriorityForLevel(x.level); p != x.priority {
+			t.Errorf("%v: priority %d", x.level, p)
+		}
+	}
+}
+
+func TestPriorityForLevelWire(t *testing.T) {
+	for _, opts := range []HandlerOptions{
+		{},
+		{MaxPriority: 3, MinPriority: 5},
+	} {
+		h, recv := newTestHandler(t, &opts)
+		for _, h := range []*Handler{h, h.WithAttrs([]slog.Attr{Priority(2)}).(*Handler)} {
+			base := len(recv.entries())
+			var expect []int
+			for l := slog.LevelDebug - 8; l <= LevelAlert+8; l++ {
+				r := slog.NewRecord(time.Now(), l, l.String(), 0)
+				if err := h.Handle(context.Background(), r); err != nil {
+					t.Fatal(err)
+				}
+				if h.priority < 0 && opts.MaxPriority == 0 {
+					expect = append(expect, PriorityForLevel(l))
+				} else {
+					expect = append(expect, h.Priority(l))
+				}
+			}
+
+			ms := recv.wait(t, base+len(expect))[base:]
+			for i, p := range expect {
+				if s := ms[i]["PRIORITY"]; s != strconv.Itoa(p) {
+					t.Errorf("%+v: %s: priority %s; expected %d", opts, ms[i]["MESSAGE"], s, p)
+				}
+			}
+		}
+	}
+}
+
+func TestHandlerPriority(t *testing.T) {
+	h, _ := newTestHandler(t, &HandlerOptions{MaxPriority: 3, MinPriority: 5})
+	fixed := h.WithAttrs([]slog.Attr{Priority(1)}).(*Handler)
+
+	for _, x := range []struct {
+		h        *Handler
+		level    slog.Level
+		priority int
+	}{
+		{h, LevelAlert, 3},
+		{h, slog.LevelError, 3},
+		{h, slog.LevelWarn, 4},
+		{h, slog.LevelInfo, 5},
+		{h, slog.LevelDebug, 5},
+		{fixed, slog.LevelDebug, 3},
+	} {
+		if p := x.h.Priority(x.level); p != x.priority {
+			t.Errorf("%v: priority %d", x.level, p)
+		}
+	}
+}
+
+func TestLevelRules(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level: slog.LevelInfo,
+		LevelRules: []LevelRule{
+			{Group: "redis", Level: slog.LevelDebug},
+			{MessagePrefix: "retry", Level: slog.LevelInfo},
+			{AttrKey: "status", AttrValue: "503", Level: slog.LevelError},
+		},
+	})
+
+	logger := slog.New(h)
+	logger.WithGroup("redis").Warn("connection lost")
+	logger.WithGroup("redis").WithGroup("pool").Warn("connection lost")
+	logger.WithGroup("redistribute").Warn("untouched")
+	logger.Warn("retrying")
+	logger.Warn("request", "status", 503)
+	logger.Warn("request", "status", 200)
+
+	recv.wait(t, 4)
+	time.Sleep(10 * time.Millisecond)
+	ms := recv.entries()
+
+	expect := []struct {
+		message  string
+		priority string
+	}{
+		{"untouched", "4"},
+		{"retrying", "6"},
+		{"request status=503", "3"},
+		{"request status=200", "4"},
+	}
+
+	if len(ms) != len(expect) {
+		t.Fatalf("%d entries", len(ms))
+	}
+	for i, x := range expect {
+		if s := ms[i]["MESSAGE"]; s != x.message {
+			t.Errorf("entry %d: message %q", i, s)
+		}
+		if s := ms[i]["PRIORITY"]; s != x.priority {
+			t.Errorf("entry %d: priority %q", i, s)
+		}
+	}
+
+	if n := h.Stats().Dropped[DropFiltered]; n != 2 {
+		t.Errorf("filtered: %d", n)
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for s, expect := range map[string]int{
+		"0": 0, "emerg": 0, "panic": 0, "alert": 1, "CRIT": 2, "err": 3, "error": 3,
+		"warning": 4, "warn": 4, "notice": 5, "info": 6, "Debug": 7, "7": 7,
+	} {
+		if p, err := ParsePriority(s); err != nil || p != expect {
+			t.Errorf("%q: %d %v", s, p, err)
+		}
+	}
+
+	for _, s := range []string{"", "8", "-1", "verbose"} {
+		if _, err := ParsePriority(s); err == nil {
+			t.Errorf("%q accepted", s)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, expect := range map[string]slog.Level{
+		"emerg":   LevelAlert,
+		"1":       LevelAlert,
+		"crit":    LevelCrit,
+		"err":     LevelError,
+		"warning": LevelWarn,
+		"notice":  LevelNotice,
+		"6":       LevelInfo,
+		"debug":   LevelDebug,
+		"WARN":    LevelWarn,
+		"INFO+2":  LevelNotice,
+		"ERROR-1": LevelError - 1,
+		"Trace":   LevelTrace,
+	} {
+		if l, err := ParseLevel(s); err != nil || l != expect {
+			t.Errorf("%q: %v %v", s, l, err)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("invalid level accepted")
+	}
+}
+
+func TestWithLevel(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level: slog.LevelInfo,
+	})
+
+	var replLevel slog.LevelVar
+	replLevel.Set(slog.LevelDebug)
+
+	repl := slog.New(h.WithLevel(&replLevel).ExtendPrefix("repl: "))
+	other := slog.New(h.ExtendPrefix("other: "))
+
+	var wg sync.WaitGroup
+	for _, logger := range []*slog.Logger{repl, other} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 10 {
+				logger.Debug("debug", "i", i)
+				logger.Info("info", "i", i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	counts := make(map[string]int)
+	for _, m := range recv.wait(t, 30) {
+		msg, _, _ := strings.Cut(m["MESSAGE"], " i=")
+		counts[msg]++
+	}
+	if len(counts) != 3 || counts["repl: debug"] != 10 || counts["repl: info"] != 10 || counts["other: info"] != 10 {
+		t.Errorf("counts: %v", counts)
+	}
+
+	// Override wins over a root level change.
+	if err := h.Reload(&HandlerOptions{Level: slog.LevelError}); err != nil {
+		t.Fatal(err)
+	}
+	if !repl.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("override lost")
+	}
+	if other.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("root level not applied")
+	}
+
+	replLevel.Set(slog.LevelWarn)
+	if repl.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("leveler not consulted")
+	}
+}
+
+func TestTraceLevel(t *testing.T) {
+	var level slog.LevelVar
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level: &level,
+	})
+	logger := slog.New(h)
+
+	logger.Log(context.Background(), LevelTrace, "hidden")
+
+	level.Set(LevelTrace)
+	logger.Log(context.Background(), LevelTrace, "trace")
+	logger.Log(context.Background(), LevelDebug-1, "almost debug")
+	logger.Debug("debug")
+
+	ms := recv.wait(t, 3)
+	if len(ms) != 3 {
+		t.Fatalf("%d entries", len(ms))
+	}
+	for i, c := range []struct {
+		message string
+		trace   bool
+	}{
+		{"trace", true},
+		{"almost debug", true},
+		{"debug", false},
+	} {
+		m := ms[i]
+		if m["MESSAGE"] != c.message || m["PRIORITY"] != "7" {
+			t.Errorf("entry %d: %q", i, m)
+		}
+		if s, found := m["TRACE"]; found != c.trace || (found && s != "1") {
+			t.Errorf("entry %d: TRACE=%q", i, s)
+		}
+	}
+}
+
+func TestTraceLevelField(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level:      LevelTrace,
+		LevelField: true,
+	})
+	logger := slog.New(h)
+
+	logger.Log(context.Background(), LevelTrace, "trace")
+	logger.Log(context.Background(), LevelDebug-1, "almost debug")
+	logger.Debug("debug")
+
+	ms := recv.wait(t, 3)
+	for i, level := range []string{"TRACE", "TRACE", "DEBUG"} {
+		m := ms[i]
+		if s := m["LEVEL"]; s != level {
+			t.Errorf("%s: LEVEL=%q", m["MESSAGE"], s)
+		}
+		if s, found := m["TRACE"]; found {
+			t.Errorf("%s: TRACE=%q", m["MESSAGE"], s)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// levelFilePollInterval is used when the file cannot be watched for changes.
+var levelFilePollInterval = time.Second
+
+// WatchLevelFile sets the level variable according to the contents of a file,
+// and updates it when the file changes, until the context is done.  The file
+// contains a level name or journald priority accepted by ParseLevel
+// (surrounding whitespace is ignored).  A level change is logged using the
+// default logger.  If the file doesn't exist, the current level is left in
+// place.  Malformed contents are logged as warnings and ignored.
+//
+// On Linux the file is watched using inotify; elsewhere it's polled
+// periodically.  The context error is returned when the context is done.
+func WatchLevelFile(ctx context.Context, path string, v *slog.LevelVar) error {
+	w := &levelFileWatcher{path: path, v: v}
+	w.update()
+	return w.watch(ctx)
+}
+
+type levelFileWatcher struct {
+	path    string
+	v       *slog.LevelVar
+	content []byte // Most recently read content.
+}
+
+// update the level if the file content has changed.
+func (w *levelFileWatcher) update() {
+	content, err := os.ReadFile(w.path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("sjournal: cannot read level file", "path", w.path, "err", err)
+		}
+		w.content = nil
+		return
+	}
+
+	if w.content != nil && bytes.Equal(content, w.content) {
+		return
+	}
+	w.content = content
+
+	level, err := ParseLevel(string(bytes.TrimSpace(content)))
+	if err != nil {
+		slog.Warn("sjournal: invalid level file", "path", w.path, "err", err)
+		return
+	}
+
+	if old := w.v.Level(); level != old {
+		w.v.Set(level)
+		slog.Info("sjournal: log level changed", "path", w.path, "old", old, "new", level)
+	}
+}
+
+// poll the file until the context is done.  The file is small, so it's read
+// on every tick instead of relying on the modification time, which may have
+// coarse granularity.
+func (w *levelFileWatcher) poll(ctx context.Context) error {
+	ticker := time.NewTicker(levelFilePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.update()
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// watch the directory of the file, so that replacement by rename is noticed.
+// Falls back to polling if inotify is unavailable.
+func (w *levelFileWatcher) watch(ctx context.Context) error {
+	fd, err := unix.InotifyInit1(unix.IN_NONBLOCK | unix.IN_CLOEXEC)
+	if err != nil {
+		return w.poll(ctx)
+	}
+	f := os.NewFile(uintptr(fd), "inotify")
+	defer f.Close()
+
+	dir, name := filepath.Split(w.path)
+	if dir == "" {
+		dir = "."
+	}
+
+	const mask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM
+	if _, err := unix.InotifyAddWatch(fd, dir, mask); err != nil {
+		return w.poll(ctx)
+	}
+
+	stop := context.AfterFunc(ctx, func() { f.Close() })
+	defer stop()
+
+	buf := make([]byte, 4096)
+
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		changed := false
+		for b := buf[:n]; len(b) >= unix.SizeofInotifyEvent; {
+			nameLen := int(binary.NativeEndian.Uint32(b[12:16]))
+			end := unix.SizeofInotifyEvent + nameLen
+			if end > len(b) {
+				break
+			}
+			if eventName := cString(b[unix.SizeofInotifyEvent:end]); eventName == name {
+				changed = true
+			}
+			b = b[end:]
+		}
+
+		if changed {
+			w.update()
+		}
+	}
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
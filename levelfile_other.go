@@ -0,0 +1,15 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package sjournal
+
+import (
+	"context"
+)
+
+func (w *levelFileWatcher) watch(ctx context.Context) error {
+	return w.poll(ctx)
+}
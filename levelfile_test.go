@@ -0,0 +1,117 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testWatchLevelFile(t *testing.T, watch func(context.Context, string, *slog.LevelVar) error) {
+	h, recv := newTestHandler(t, nil)
+	orig := slog.Default()
+	slog.SetDefault(slog.New(h))
+	defer slog.SetDefault(orig)
+
+	path := filepath.Join(t.TempDir(), "loglevel")
+	write := func(s string) {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var v slog.LevelVar
+	v.Set(LevelWarn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- watch(ctx, path, &v) }()
+
+	waitLevel := func(expect slog.Level) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for v.Level() != expect {
+			if time.Now().After(deadline) {
+				t.Fatalf("level is %v; expected %v", v.Level(), expect)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	waitLevel(LevelWarn) // Missing file leaves the level alone.
+
+	write("debug\n")
+	waitLevel(LevelDebug)
+
+	if err := os.WriteFile(path, []byte(" err "), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitLevel(LevelError)
+
+	write("loud")
+	var found bool
+	for deadline := time.Now().Add(5 * time.Second); !found && time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		for _, m := range recv.entries() {
+			if strings.HasPrefix(m["MESSAGE"], "sjournal: invalid level file") {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Error("malformed content not reported")
+	}
+	if l := v.Level(); l != LevelError {
+		t.Errorf("level changed to %v", l)
+	}
+
+	os.Remove(path)
+	write("notice")
+	waitLevel(LevelNotice)
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Error(err)
+	}
+
+	countChanges := func() (n int) {
+		for _, m := range recv.entries() {
+			if strings.HasPrefix(m["MESSAGE"], "sjournal: log level changed") {
+				n++
+			}
+		}
+		return
+	}
+	for deadline := time.Now().Add(5 * time.Second); countChanges() < 3 && time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+	}
+	if n := countChanges(); n != 3 {
+		t.Errorf("%d level changes logged", n)
+	}
+}
+
+func TestWatchLevelFile(t *testing.T) {
+	testWatchLevelFile(t, WatchLevelFile)
+}
+
+func TestWatchLevelFilePoll(t *testing.T) {
+	orig := levelFilePollInterval
+	defer func() { levelFilePollInterval = orig }()
+	levelFilePollInterval = 5 * time.Millisecond
+
+	testWatchLevelFile(t, func(ctx context.Context, path string, v *slog.LevelVar) error {
+		w := &levelFileWatcher{path: path, v: v}
+		w.update()
+		return w.poll(ctx)
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Middleware wraps the function which encodes and sends a record.  A
+// middleware may call next with a modified record, or not call it at all.
+// Modifications should be made to a clone of the record (see
+// slog.Record.Clone).  Attributes added using WithAttrs are not visible to
+// middleware.
+type Middleware func(next func(context.Context, slog.Record) error) func(context.Context, slog.Record) error
+
+// initChain builds the middleware chain around the handle method of this
+// handler instance.
+func (h *Handler) initChain() {
+	if len(h.middleware) == 0 {
+		return
+	}
+
+	next := h.handle
+	for i := len(h.middleware) - 1; i >= 0; i-- {
+		next = h.middleware[i](next)
+	}
+	h.chain = next
+}
+
+// RedactedValue replaces attribute values redacted by RedactAttrs.
+const RedactedValue = "REDACTED"
+
+// RedactAttrs returns a middleware which replaces the values of record
+// attributes with the given keys with RedactedValue.  Attributes within groups
+// are matched by their keys within the group.
+func RedactAttrs(keys ...string) Middleware {
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+
+	var redact func(slog.Attr) (slog.Attr, bool)
+	redact = func(a slog.Attr) (slog.Attr, bool) {
+		if _, found := set[a.Key]; found {
+			return slog.String(a.Key, RedactedValue), true
+		}
+
+		if a.Value.Kind() == slog.KindGroup {
+			attrs := a.Value.Group()
+			var changed []slog.Attr
+			for i, aa := range attrs {
+				if aa, ok := redact(aa); ok {
+					if changed == nil {
+						changed = append([]slog.Attr(nil), attrs...)
+					}
+					changed[i] = aa
+				}
+			}
+			if changed != nil {
+				return slog.Attr{Key: a.Key, Value: slog.GroupValue(changed...)}, true
+			}
+		}
+
+		return a, false
+	}
+
+	return func(next func(context.Context, slog.Record) error) func(context.Context, slog.Record) error {
+		return func(ctx context.Context, r slog.Record) error {
+			var attrs []slog.Attr
+			changed := false
+			r.Attrs(func(a slog.Attr) bool {
+				a, ok := redact(a)
+				changed = changed || ok
+				attrs = append(attrs, a)
+				return true
+			})
+			if !changed {
+				return next(ctx, r)
+			}
+
+			r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+			r2.AddAttrs(attrs...)
+			return next(ctx, r2)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	var trace []string
+
+	tag := func(name string) Middleware {
+		return func(next func(context.Context, slog.Record) error) func(context.Context, slog.Record) error {
+			return func(ctx context.Context, r slog.Record) error {
+				trace = append(trace, name)
+				r2 := slog.NewRecord(r.Time, r.Level, r.Message+" "+name, r.PC)
+				r.Attrs(func(a slog.Attr) bool {
+					r2.AddAttrs(a)
+					return true
+				})
+				return next(ctx, r2)
+			}
+		}
+	}
+
+	errSkipped := errors.New("skipped")
+
+	gate := func(next func(context.Context, slog.Record) error) func(context.Context, slog.Record) error {
+		return func(ctx context.Context, r slog.Record) error {
+			trace = append(trace, "gate")
+			switch r.Message {
+			case "drop outer":
+				return nil
+			case "fail outer":
+				return errSkipped
+			}
+			r = r.Clone()
+			r.Level = slog.LevelError
+			r.AddAttrs(slog.Bool("gated", true))
+			return next(ctx, r)
+		}
+	}
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Middleware: []Middleware{tag("outer"), gate, tag("inner")},
+	})
+
+	logger := slog.New(h).With("x", 1)
+	logger.Info("hello", "y", 2)
+	logger.Info("drop")
+	if err := h.WithGroup("g").Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "fail", 0)); err != errSkipped {
+		t.Errorf("error: %v", err)
+	}
+
+	expectTrace := []string{"outer", "gate", "inner", "outer", "gate", "outer", "gate"}
+	if len(trace) != len(expectTrace) {
+		t.Fatalf("trace: %q", trace)
+	}
+	for i, s := range expectTrace {
+		if trace[i] != s {
+			t.Errorf("trace: %q", trace)
+			break
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	ms := recv.entries()
+	if len(ms) != 1 {
+		t.Fatalf("%d entries", len(ms))
+	}
+	if s := ms[0]["MESSAGE"]; s != "hello outer inner x=1 y=2 gated=true" {
+		t.Errorf("message: %q", s)
+	}
+	if s := ms[0]["PRIORITY"]; s != "3" {
+		t.Errorf("priority: %s", s)
+	}
+}
+
+func TestRedactAttrs(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Middleware: []Middleware{RedactAttrs("password", "token")},
+	})
+
+	logger := slog.New(h)
+	logger.Info("login", "user", "alice", "password", "hunter2", slog.Group("auth", "token", "abc", "scheme", "bearer"))
+	logger.Info("plain", "user", "bob")
+
+	ms := recv.wait(t, 2)
+	if s := ms[0]["MESSAGE"]; s != "login user=alice password=REDACTED auth.token=REDACTED auth.scheme=bearer" {
+		t.Errorf("message: %q", s)
+	}
+	if s := ms[1]["MESSAGE"]; s != "plain user=bob" {
+		t.Errorf("message: %q", s)
+	}
+}
+
+func TestReplaceRecord(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		ReplaceRecord: func(ctx context.Context, r slog.Record) (slog.Record, bool) {
+			if r.Message == "drop" {
+				return r, false
+			}
+
+			r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+			r.Attrs(func(a slog.Attr) bool {
+				if a.Key == "user" {
+					r2.Message += " by " + a.Value.String()
+				} else {
+					r2.AddAttrs(a)
+				}
+				return true
+			})
+			if r.Level >= slog.LevelWarn {
+				r2.AddAttrs(slog.Bool("alert", true))
+			}
+			return r2, true
+		},
+	})
+
+	logger := slog.New(h).With("user", "preformatted")
+	logger.Info("login", "user", "alice", "x", 1)
+	logger.Info("drop", "x", 2)
+	logger.Warn("failure", "x", 3)
+
+	time.Sleep(10 * time.Millisecond)
+	ms := recv.entries()
+	expect := []string{
+		"login by alice user=preformatted x=1",
+		"failure user=preformatted x=3 alert=true",
+	}
+	if len(ms) != len(expect) {
+		t.Fatalf("%d entries", len(ms))
+	}
+	for i, s := range expect {
+		if msg := ms[i]["MESSAGE"]; msg != s {
+			t.Errorf("entry %d: %q", i, msg)
+		}
+	}
+
+	if n := h.Stats().Dropped[DropFiltered]; n != 1 {
+		t.Errorf("filtered: %d", n)
+	}
+}
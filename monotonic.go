@@ -0,0 +1,33 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"time"
+)
+
+// monotonicStart is the reference point of SystemClock.Monotonic.  time.Since
+// uses the monotonic clock reading.
+var monotonicStart = time.Now()
+
+// monotonicUsec returns the monotonic time of the clock in microseconds.  The
+// returned values are strictly increasing for handlers derived from the same
+// NewHandler call.
+func (r *root) monotonicUsec() int64 {
+	now := r.clock.Monotonic().Microseconds()
+	for {
+		next := r.monotonicNext.Load()
+		v := max(now, next)
+		if r.monotonicNext.CompareAndSwap(next, v+1) {
+			return v
+		}
+	}
+}
+
+// peekMonotonicUsec returns the value which monotonicUsec would return,
+// without advancing it.
+func (r *root) peekMonotonicUsec() int64 {
+	return max(r.clock.Monotonic().Microseconds(), r.monotonicNext.Load())
+}
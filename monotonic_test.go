@@ -0,0 +1,102 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMonotonicTime(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{MonotonicTime: true})
+
+	handlers := []slog.Handler{h, h.WithAttrs([]slog.Attr{slog.Int("x", 1)}), h.WithGroup("g")}
+	wall := time.Now()
+
+	const n = 30
+	for i := range n {
+		// Wall times go backwards.
+		r := slog.NewRecord(wall.Add(-time.Duration(i)*time.Minute), slog.LevelInfo, "msg", 0)
+		if err := handlers[i%len(handlers)].Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var last int64 = -1
+	for i, m := range recv.wait(t, n) {
+		v, err := strconv.ParseInt(m["MONOTONIC_USEC"], 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v <= last {
+			t.Errorf("entry %d: %d after %d", i, v, last)
+		}
+		last = v
+	}
+}
+
+func TestMonotonicTimeClock(t *testing.T) {
+	ahead, recvAhead := newTestHandler(t, &HandlerOptions{
+		MonotonicTime: true,
+		Clock:         &budgetClock{time.Unix(0, 0).Add(100 * time.Hour)},
+	})
+	clock := &budgetClock{time.Unix(0, 0).Add(time.Second)}
+	h, recv := newTestHandler(t, &HandlerOptions{
+		MonotonicTime: true,
+		Clock:         clock,
+	})
+
+	slog.New(ahead).Info("ahead")
+	recvAhead.wait(t, 1)
+
+	logger := slog.New(h)
+	logger.Info("first")
+	logger.Info("same time")
+	clock.t = clock.t.Add(time.Millisecond)
+	logger.Info("later")
+
+	// Other handlers don't affect the values.
+	ms := recv.wait(t, 3)
+	for i, expect := range []string{"1000000", "1000001", "1001000"} {
+		if s := ms[i]["MONOTONIC_USEC"]; s != expect {
+			t.Errorf("entry %d: MONOTONIC_USEC=%s", i, s)
+		}
+	}
+}
+
+func TestMonotonicTimeAbsent(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+	slog.New(h).Info("msg")
+
+	if s, found := recv.wait(t, 1)[0]["MONOTONIC_USEC"]; found {
+		t.Errorf("MONOTONIC_USEC=%s", s)
+	}
+}
+
+func TestRecordRealtime(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{RecordRealtime: true})
+
+	for _, ts := range []time.Time{
+		time.Unix(1700000000, 123456789),
+		time.Unix(1700000000, 999),
+		time.Unix(1, 1000),
+		{},
+	} {
+		r := slog.NewRecord(ts, slog.LevelInfo, "msg", 0)
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ms := recv.wait(t, 4)
+	for i, expect := range []string{"1700000000123456", "1700000000000000", "1000001", ""} {
+		if s, found := ms[i]["RECORD_REALTIME_USEC"]; s != expect || found != (expect != "") {
+			t.Errorf("entry %d: RECORD_REALTIME_USEC=%q", i, s)
+		}
+	}
+}
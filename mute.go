@@ -0,0 +1,89 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"maps"
+)
+
+type muteSite struct {
+	file string
+	line int
+}
+
+// muteSet is immutable; it's replaced when changed.
+type muteSet struct {
+	sites map[muteSite]struct{}
+	funcs map[string]struct{}
+}
+
+// Mute drops the records logged at a source code line.  The file is matched
+// against the CODE_FILE path either exactly or as a suffix following a slash
+// (e.g. "server/api.go").  Muted records are counted in Stats with
+// reason DropMuted.  Muting affects all handlers derived from the same
+// NewHandler call.
+func (h *Handler) Mute(file string, line int) {
+	h.root.updateMutes(func(m *muteSet) {
+		m.sites[muteSite{file, line}] = struct{}{}
+	})
+}
+
+// MuteFunc drops the records logged by a function, identified by its fully
+// qualified name (like CODE_FUNC).  Unlike a line, the name is not affected by
+// inlining or unrelated code changes.  See Mute.
+func (h *Handler) MuteFunc(function string) {
+	h.root.updateMutes(func(m *muteSet) {
+		m.funcs[function] = struct{}{}
+	})
+}
+
+// UnmuteAll removes the mutes set using Mute and MuteFunc.
+func (h *Handler) UnmuteAll() {
+	h.root.mutesMu.Lock()
+	defer h.root.mutesMu.Unlock()
+	h.root.mutes.Store(nil)
+}
+
+func (r *root) updateMutes(f func(*muteSet)) {
+	r.mutesMu.Lock()
+	defer r.mutesMu.Unlock()
+
+	m := &muteSet{
+		sites: make(map[muteSite]struct{}),
+		funcs: make(map[string]struct{}),
+	}
+	if old := r.mutes.Load(); old != nil {
+		m.sites = maps.Clone(old.sites)
+		m.funcs = maps.Clone(old.funcs)
+	}
+	f(m)
+	r.mutes.Store(m)
+}
+
+func (r *root) muted(loc *codeLocation) bool {
+	m := r.mutes.Load()
+	if m == nil {
+		return false
+	}
+
+	if _, found := m.funcs[loc.function]; found {
+		return true
+	}
+
+	if len(m.sites) == 0 {
+		return false
+	}
+	if _, found := m.sites[muteSite{loc.file, loc.line}]; found {
+		return true
+	}
+	for i := 0; i < len(loc.file); i++ {
+		if loc.file[i] == '/' {
+			if _, found := m.sites[muteSite{loc.file[i+1:], loc.line}]; found {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"runtime"
+	"testing"
+)
+
+func TestMute(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+	logger := slog.New(h)
+
+	_, file, line, _ := runtime.Caller(0)
+	logNoisy := func() { logger.Info("noisy") } // Logs at line+1.
+	logQuiet := func() { logger.Info("quiet") }
+
+	h.Mute(file, line+1)
+	logNoisy()
+	logQuiet()
+
+	h.UnmuteAll()
+	logNoisy()
+
+	h.Mute("mute_test.go", line+1)
+	logNoisy()
+	h.UnmuteAll()
+
+	h.MuteFunc("import.name/sjournal.logMuteTest")
+	logMuteTest(logger)
+	logQuiet()
+
+	ms := recv.wait(t, 3)
+	var messages []string
+	for _, m := range ms {
+		messages = append(messages, m["MESSAGE"])
+	}
+	if len(messages) != 3 || messages[0] != "quiet" || messages[1] != "noisy" || messages[2] != "quiet" {
+		t.Errorf("messages: %q", messages)
+	}
+
+	if n := h.Stats().Dropped[DropMuted]; n != 3 {
+		t.Errorf("muted: %d", n)
+	}
+}
+
+//go:noinline
+func logMuteTest(logger *slog.Logger) {
+	logger.Info("func")
+}
@@ -0,0 +1,35 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+)
+
+// NoPrefix returns an attribute which causes the handler to omit the message
+// prefix (set using the Prefix option, ExtendPrefix or ResetPrefix, and the
+// logger name if NamePrefix is set) from a record.  It's effective only among
+// the record's own attributes outside of groups.  The attribute itself is not
+// emitted.  Other handlers see an empty group, which they ignore.
+func NoPrefix() slog.Attr {
+	return slog.Any("", noPrefix{})
+}
+
+type noPrefix struct{}
+
+func (noPrefix) LogValue() slog.Value {
+	return slog.GroupValue()
+}
+
+// hasNoPrefix reports whether the record has a NoPrefix attribute.
+func hasNoPrefix(r slog.Record) (found bool) {
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Value.Kind() == slog.KindLogValuer {
+			_, found = a.Value.Any().(noPrefix)
+		}
+		return !found
+	})
+	return
+}
@@ -0,0 +1,57 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestNoPrefix(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter:  DefaultDelimiter,
+		Prefix:     "app: ",
+		NamePrefix: true,
+	})
+
+	logger := slog.New(h.ExtendPrefix("sub: ").WithName("worker"))
+	logger.Info("one", "x", 1)
+	logger.Info("banner", NoPrefix(), "x", 2)
+	logger.Info("two", slog.Group("g", NoPrefix()))
+	logger.With(NoPrefix()).Info("three")
+	logger.Info("raw", "x", 3, NoPrefix())
+	logger.Info("four")
+
+	ms := recv.wait(t, 6)
+	for i, expect := range []string{
+		"app: sub: worker: one x=1",
+		"banner x=2",
+		"app: sub: worker: two",
+		"app: sub: worker: three",
+		"raw x=3",
+		"app: sub: worker: four",
+	} {
+		if s := ms[i]["MESSAGE"]; s != expect {
+			t.Errorf("entry %d: message %q", i, s)
+		}
+	}
+}
+
+func TestNoPrefixOtherHandler(t *testing.T) {
+	var b bytes.Buffer
+	slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})).Info("hello", NoPrefix(), "x", 1)
+
+	if s := b.String(); s != "level=INFO msg=hello x=1\n" {
+		t.Errorf("%q", s)
+	}
+}
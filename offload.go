@@ -0,0 +1,28 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"strconv"
+	"strings"
+)
+
+// offloadValue writes a large attribute value as a separate journal field (see
+// OffloadThreshold) and returns the reference which replaces it in the
+// message.  It returns false if the key can't be converted to a field name
+// which may be used.
+func (s *handleState) offloadValue(key, value string) (string, bool) {
+	name, ok := fieldName(strings.ReplaceAll(s.fullKey(key), string(keyComponentSep), "_"))
+	if !ok {
+		return "", false
+	}
+	field, ok := s.h.reserved.name(name)
+	if !ok {
+		return "", false
+	}
+
+	*s.fields = appendBinaryField(*s.fields, field, value)
+	return "<see " + field + ", " + strconv.Itoa(len(value)) + " bytes>", true
+}
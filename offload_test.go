@@ -0,0 +1,69 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestOffload(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter:        ColonDelimiter,
+		OffloadThreshold: 16,
+		ReservedFields:   RenameReserved,
+	})
+
+	body := strings.Repeat("data\n", 1000)
+	stack := strings.Repeat("x", 17)
+
+	slog.New(h).WithGroup("req").Info("hello",
+		"body", body,
+		"short", "0123456789abcdef",
+		"bad-key", stack,
+		slog.Group("", "message", stack),
+	)
+
+	m := recv.wait(t, 1)[0]
+	if s := m["MESSAGE"]; s != `hello: req.body="<see REQ_BODY, 5000 bytes>" req.short=0123456789abcdef req.bad-key=xxxxxxxxxxxxxxxxx req.message="<see REQ_MESSAGE, 17 bytes>"` {
+		t.Errorf("message: %q", s)
+	}
+	if s := m["REQ_BODY"]; s != body {
+		t.Errorf("body: %q", s)
+	}
+	if s := m["REQ_MESSAGE"]; s != stack {
+		t.Errorf("stack: %q", s)
+	}
+}
+
+func TestOffloadLargeEntry(t *testing.T) {
+	if !LargeMessageSupport {
+		t.Skip("large messages not supported")
+	}
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter:        DefaultDelimiter,
+		QuoteStyle:       NeverQuote,
+		OffloadThreshold: 1024,
+	})
+	if err := h.root.socks[0].SetWriteBuffer(4096); err != nil {
+		t.Fatal(err)
+	}
+
+	body := strings.Repeat("y", 64<<10)
+	slog.New(h.WithAttrs([]slog.Attr{slog.String("body", body)})).Info("large")
+
+	m := recv.wait(t, 1)[0]
+	if s := m["MESSAGE"]; s != "large body=<see BODY, 65536 bytes>" {
+		t.Errorf("message: %q", s)
+	}
+	if s := m["BODY"]; s != body {
+		t.Errorf("body: %d bytes", len(s))
+	}
+	if n := h.Stats().LargeEntries; n != 1 {
+		t.Errorf("%d large entries", n)
+	}
+}
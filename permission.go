@@ -0,0 +1,72 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sync/atomic"
+)
+
+// ErrPermission is wrapped by the errors which Handle returns when the
+// operating system denies sending to journald (EACCES or EPERM), e.g. due to
+// socket permissions or a security policy.  See OnPermissionError and
+// PermissionFallback.
+var ErrPermission = errors.New("sjournal: permission denied")
+
+// permissionState remembers if sending has been denied.
+type permissionState struct {
+	denied   atomic.Bool
+	onError  func(error)
+	fallback *mirror // Nil unless PermissionFallback is set.
+}
+
+func (p *permissionState) init(opts *HandlerOptions) {
+	p.onError = opts.OnPermissionError
+	if opts.PermissionFallback != nil {
+		p.fallback = &mirror{level: slog.LevelDebug, w: opts.PermissionFallback}
+	}
+}
+
+// permissionError wraps err in ErrPermission if it's a permission error.  The
+// first one is reported to OnPermissionError and PermissionFallback.
+func (r *root) permissionError(err error, dest string) error {
+	if !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+
+	err = fmt.Errorf("%w: sending to %s (the process needs write access to the journald socket): %w", ErrPermission, dest, err)
+
+	p := &r.permission
+	if p.denied.CompareAndSwap(false, true) {
+		if p.onError != nil {
+			p.onError(err)
+		}
+		if p.fallback != nil {
+			p.fallback.write(r.config.Load(), r.clock.Now(), LevelError, err.Error())
+		}
+	}
+	return err
+}
+
+// sendFallback writes the entry to PermissionFallback if sending has been
+// denied.
+func (r *root) sendFallback(b []byte) bool {
+	p := &r.permission
+	if p.fallback == nil || !p.denied.Load() {
+		return false
+	}
+
+	var text string
+	rangeFields(b, func(name string, value []byte) {
+		if name == "MESSAGE" {
+			text = string(value)
+		}
+	})
+	p.fallback.write(r.config.Load(), r.clock.Now(), priorityLevels[entryPriority(b)], text)
+	return true
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// deniedTransport fails with a permission error.
+type deniedTransport struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (t *deniedTransport) Send([]byte, bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls++
+	return &fs.PathError{Op: "write", Path: "test", Err: fs.ErrPermission}
+}
+
+func TestPermissionError(t *testing.T) {
+	var reports []error
+
+	tr := new(deniedTransport)
+	h, err := NewHandler(&HandlerOptions{
+		Transport:         tr,
+		OnPermissionError: func(err error) { reports = append(reports, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	logger := slog.New(h)
+	for range 3 {
+		logger.Info("hello")
+	}
+
+	err = h.Handle(context.Background(), slog.NewRecord(h.root.clock.Now(), slog.LevelInfo, "hello", 0))
+	if !errors.Is(err, ErrPermission) || !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("error: %v", err)
+	}
+	if tr.calls != 4 {
+		t.Errorf("%d sends", tr.calls)
+	}
+	if len(reports) != 1 || !errors.Is(reports[0], ErrPermission) {
+		t.Errorf("reports: %q", reports)
+	}
+}
+
+func TestPermissionFallback(t *testing.T) {
+	var (
+		fallback bytes.Buffer
+		reports  int
+	)
+
+	tr := new(deniedTransport)
+	h, err := NewHandler(&HandlerOptions{
+		Delimiter:          DefaultDelimiter,
+		Transport:          tr,
+		OnPermissionError:  func(error) { reports++ },
+		PermissionFallback: &fallback,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	logger := slog.New(h)
+	logger.Info("first", "x", 1)
+	logger.Warn("second")
+	if err := h.Handle(context.Background(), slog.NewRecord(h.root.clock.Now(), slog.LevelError, "third", 0)); err != nil {
+		t.Errorf("error: %v", err)
+	}
+
+	if tr.calls != 1 {
+		t.Errorf("%d sends", tr.calls)
+	}
+	if reports != 1 {
+		t.Errorf("%d reports", reports)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(fallback.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("fallback: %q", lines)
+	}
+	for i, expect := range []string{
+		"ERROR sjournal: permission denied: sending to transport",
+		"INFO first x=1",
+		"WARN second",
+		"ERROR third",
+	} {
+		if _, s, _ := strings.Cut(lines[i], " "); !strings.HasPrefix(s, expect) {
+			t.Errorf("line %d: %q", i, lines[i])
+		}
+	}
+}
+
+func TestPermissionSocket(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+
+	recv := newTestReceiver(t)
+	if err := os.Chmod(recv.path, 0o400); err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []error
+
+	h, err := NewHandler(&HandlerOptions{
+		Socket:            recv.path,
+		OnPermissionError: func(err error) { reports = append(reports, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	for range 2 {
+		err := h.Handle(context.Background(), slog.NewRecord(h.root.clock.Now(), slog.LevelInfo, "hello", 0))
+		if !errors.Is(err, ErrPermission) || !strings.Contains(err.Error(), recv.path) {
+			t.Errorf("error: %v", err)
+		}
+	}
+	if len(reports) != 1 {
+		t.Errorf("reports: %q", reports)
+	}
+}
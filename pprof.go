@@ -0,0 +1,65 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"runtime/pprof"
+	"slices"
+)
+
+// pprofLabels configures the inclusion of profiler labels.
+type pprofLabels struct {
+	group string
+	keys  map[string]struct{} // Nil means all.
+}
+
+// newPprofLabels returns nil unless IncludePprofLabels is set.
+func newPprofLabels(opts *HandlerOptions) *pprofLabels {
+	if !opts.IncludePprofLabels {
+		return nil
+	}
+
+	p := &pprofLabels{
+		group: opts.PprofLabelGroup,
+	}
+	if opts.PprofLabelKeys != nil {
+		p.keys = make(map[string]struct{}, len(opts.PprofLabelKeys))
+		for _, key := range opts.PprofLabelKeys {
+			p.keys[key] = struct{}{}
+		}
+	}
+	return p
+}
+
+// appendPprofLabels appends the profiler labels of the context outside of any
+// groups (except PprofLabelGroup).  The labels are sorted by key.
+func (s *handleState) appendPprofLabels(ctx context.Context) {
+	p := s.h.pprofLabels
+
+	var attrs []slog.Attr
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		if p.keys != nil {
+			if _, found := p.keys[key]; !found {
+				return true
+			}
+		}
+		attrs = append(attrs, slog.String(key, value))
+		return true
+	})
+	if len(attrs) == 0 {
+		return
+	}
+
+	slices.SortFunc(attrs, func(a, b slog.Attr) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	if p.group != "" {
+		attrs = []slog.Attr{{Key: p.group, Value: slog.GroupValue(attrs...)}}
+	}
+	s.appendContextAttrs(attrs)
+}
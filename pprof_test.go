@@ -0,0 +1,74 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"runtime/pprof"
+	"testing"
+	"time"
+)
+
+func TestPprofLabels(t *testing.T) {
+	for _, x := range []struct {
+		opts   HandlerOptions
+		expect []string
+	}{
+		{
+			HandlerOptions{IncludePprofLabels: true},
+			[]string{"unlabeled g.x=1", "labeled g.x=1 endpoint=/api request_id=abc"},
+		},
+		{
+			HandlerOptions{IncludePprofLabels: true, PprofLabelGroup: "pprof", PprofLabelKeys: []string{"request_id", "other"}},
+			[]string{"unlabeled g.x=1", "labeled g.x=1 pprof.request_id=abc"},
+		},
+		{
+			HandlerOptions{IncludePprofLabels: true, PprofLabelKeys: []string{}},
+			[]string{"unlabeled g.x=1", "labeled g.x=1"},
+		},
+		{
+			HandlerOptions{},
+			[]string{"unlabeled g.x=1", "labeled g.x=1"},
+		},
+	} {
+		h, recv := newTestHandler(t, &x.opts)
+		logger := slog.New(h).WithGroup("g")
+
+		logger.InfoContext(context.Background(), "unlabeled", "x", 1)
+		pprof.Do(context.Background(), pprof.Labels("request_id", "abc", "endpoint", "/api"), func(ctx context.Context) {
+			logger.InfoContext(ctx, "labeled", "x", 1)
+		})
+
+		ms := recv.wait(t, len(x.expect))
+		for i, s := range x.expect {
+			if msg := ms[i]["MESSAGE"]; msg != s {
+				t.Errorf("%+v: entry %d: %q", x.opts, i, msg)
+			}
+		}
+	}
+}
+
+func TestPprofLabelsAllocs(t *testing.T) {
+	allocs := func(opts *HandlerOptions) float64 {
+		h, err := NewHandler(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer h.Close()
+		h.root.sink = func([]byte) error { return nil }
+
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "unlabeled", 0)
+		return testing.AllocsPerRun(100, func() {
+			h.Handle(context.Background(), r)
+		})
+	}
+
+	without := allocs(&HandlerOptions{})
+	with := allocs(&HandlerOptions{IncludePprofLabels: true, PprofLabelGroup: "pprof"})
+	if with != without {
+		t.Errorf("%v allocations with IncludePprofLabels, %v without", with, without)
+	}
+}
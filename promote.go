@@ -0,0 +1,45 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// newPromoteKeys returns the PromoteKeys mapping with normalized field names,
+// or nil.  Invalid field names are skipped (see AllowInvalid).
+func newPromoteKeys(m map[string]string, reserved reservedFields) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+
+	keys := make(map[string]string, len(m))
+	for key, value := range m {
+		if name, ok := fieldName(value); ok {
+			if name, ok = reserved.name(name); ok {
+				keys[key] = name
+			}
+		}
+	}
+	return keys
+}
+
+// appendPromoted writes an attribute value as a journal field (see
+// PromoteKeys).
+func (s *handleState) appendPromoted(name string, v slog.Value) {
+	var value string
+	switch {
+	case v.Kind() == slog.KindDuration && strings.HasSuffix(name, "_MS"):
+		value = strconv.FormatFloat(float64(v.Duration())/1e6, 'f', -1, 64)
+	case s.h.anyFormat != "" && v.Kind() == slog.KindAny:
+		value = fmt.Sprintf(s.h.anyFormat, v.Any())
+	default:
+		value = v.String()
+	}
+	s.appendField(name, value)
+}
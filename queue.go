@@ -0,0 +1,424 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Journald priority numbers.
+const (
+	priorityErr     = 3
+	priorityWarning = 4
+	priorityInfo    = 6
+	priorityDebug   = 7
+	numPriorities   = 8
+)
+
+// DropPolicy determines which entry is dropped when the asynchronous mode
+// queue is full.
+type DropPolicy int
+
+const (
+	// DropNewest drops the incoming entry.
+	DropNewest DropPolicy = iota
+
+	// DropLowestPriority evicts the oldest queued entry with the lowest
+	// priority (highest PRIORITY number) if it's less important than the
+	// incoming entry.  Otherwise the incoming entry is dropped.
+	DropLowestPriority
+)
+
+// Overflow determines what Handle does when the asynchronous mode queue is
+// full (and the drop policy doesn't make room).
+type Overflow struct {
+	block   bool
+	timeout time.Duration
+}
+
+var (
+	// OverflowDrop drops the entry without blocking.
+	OverflowDrop = Overflow{}
+
+	// OverflowBlock waits until there is room in the queue, or the Handle
+	// context is done.
+	OverflowBlock = Overflow{block: true}
+)
+
+// OverflowBlockWithTimeout waits until there is room in the queue, or the
+// Handle context is done.  If there is no room after the timeout, the entry is
+// dropped.
+func OverflowBlockWithTimeout(d time.Duration) Overflow {
+	return Overflow{block: true, timeout: d}
+}
+
+var errOverflowTimeout = errors.New("queue overflow timeout")
+
+type queueEntry struct {
+	data     []byte
+	keyLen   int // Length of the data prefix which is compared when coalescing.
+	priority int
+	time     time.Time // When the entry was queued.
+	seq      uint64
+	repeats  int        // Number of identical entries coalesced into this one.
+	meta     *entryMeta // Nil unless the entry is of a record.
+}
+
+// payload of the entry, with the REPEATS field if entries were coalesced.
+func (e queueEntry) payload() []byte {
+	if e.repeats == 0 {
+		return e.data
+	}
+	b := append(e.data, "REPEATS="...)
+	b = strconv.AppendInt(b, int64(e.repeats+1), 10)
+	return append(b, '\n')
+}
+
+// queue of encoded entries waiting to be sent in asynchronous mode.  Entries
+// are kept in per-priority sub-queues so that eviction victims can be found
+// cheaply; sequence numbers preserve the overall order.
+type queue struct {
+	mu         sync.Mutex
+	cond       sync.Cond // Signaled when an entry is added or the queue is closed.
+	levels     [numPriorities][]queueEntry
+	levelBytes [numPriorities]int
+	count      int
+	bytes      int
+	seq        uint64
+	inflight   uint64        // Sequence number of the entry being sent.
+	progress   chan struct{} // Closed when an entry has been sent.
+	space      chan struct{} // Closed when an entry has been removed.
+	size       int
+	maxBytes   int
+	policy     DropPolicy
+	overflow   Overflow
+	coalesce   bool
+	ttl        time.Duration
+	errorTTL   time.Duration
+	stopped    bool // No more entries are accepted.
+	closed     bool // The remaining entries have been discarded.
+	stats      *stats
+	now        func() time.Time
+}
+
+func newQueue(opts *HandlerOptions, stats *stats, now func() time.Time) *queue {
+	q := &queue{
+		size:     opts.QueueSize,
+		maxBytes: opts.MaxQueueBytes,
+		policy:   opts.QueueDropPolicy,
+		overflow: opts.QueueOverflow,
+		coalesce: opts.QueueCoalesce,
+		ttl:      opts.QueueTTL,
+		errorTTL: opts.QueueErrorTTL,
+		stats:    stats,
+		now:      now,
+	}
+	q.cond.L = &q.mu
+	return q
+}
+
+// put an entry to the queue, or drop it if the queue is full.  ErrClosed is
+// returned if the queue has been stopped or closed.  Context error is returned
+// if blocking was interrupted.
+func (q *queue) put(ctx context.Context, e queueEntry) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.stopped {
+		return ErrClosed
+	}
+
+	if q.coalesce && q.coalesceWithTail(e) {
+		return nil
+	}
+
+	if q.maxBytes > 0 && len(e.data) > q.maxBytes {
+		q.stats.drop(dropTooLarge, e.priority, 1)
+		return nil
+	}
+
+	if !q.makeRoom(e) {
+		if !q.overflow.block {
+			q.stats.drop(dropQueueFull, e.priority, 1)
+			return nil
+		}
+		if err := q.waitForRoom(ctx, e); err != nil {
+			if err == errOverflowTimeout {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	q.seq++
+	e.seq = q.seq
+	q.levels[e.priority] = append(q.levels[e.priority], e)
+	q.levelBytes[e.priority] += len(e.data)
+	q.count++
+	q.bytes += len(e.data)
+	q.cond.Signal()
+	return nil
+}
+
+// coalesceWithTail increments the repeat count of the most recently queued
+// entry if it's identical to the given entry.
+func (q *queue) coalesceWithTail(e queueEntry) bool {
+	l := q.levels[e.priority]
+	if len(l) == 0 {
+		return false
+	}
+
+	tail := &l[len(l)-1]
+	if tail.seq != q.seq || !bytes.Equal(tail.data[:tail.keyLen], e.data[:e.keyLen]) {
+		return false
+	}
+
+	tail.repeats++
+	return true
+}
+
+// makeRoom for an entry by evicting less important entries, if the policy
+// allows it.  Nothing is evicted if enough room cannot be made.
+func (q *queue) makeRoom(e queueEntry) bool {
+	if q.fits(0, 0, len(e.data)) {
+		return true
+	}
+	if q.policy != DropLowestPriority {
+		return false
+	}
+
+	var count, bytes int
+	for victim := e.priority + 1; victim < numPriorities; victim++ {
+		count += len(q.levels[victim])
+		bytes += q.levelBytes[victim]
+	}
+	if !q.fits(count, bytes, len(e.data)) {
+		return false
+	}
+
+	for victim := priorityDebug; !q.fits(0, 0, len(e.data)); {
+		if len(q.levels[victim]) == 0 {
+			victim--
+			continue
+		}
+		q.removeFirst(victim)
+		q.stats.drop(dropQueueFull, victim, 1)
+	}
+	return true
+}
+
+// waitForRoom blocks until the entry fits in the queue.  If the overflow
+// timeout expires or the context is done, the entry is dropped and
+// errOverflowTimeout or the context error is returned.
+func (q *queue) waitForRoom(ctx context.Context, e queueEntry) error {
+	var timeout <-chan time.Time
+	if q.overflow.timeout > 0 {
+		timer := time.NewTimer(q.overflow.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	start := q.now()
+	defer func() {
+		q.stats.block(q.now().Sub(start))
+	}()
+
+	for !q.makeRoom(e) {
+		if q.space == nil {
+			q.space = make(chan struct{})
+		}
+		space := q.space
+
+		var err error
+
+		q.mu.Unlock()
+		select {
+		case <-space:
+		case <-timeout:
+			err = errOverflowTimeout
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		q.mu.Lock()
+
+		if q.stopped {
+			return ErrClosed
+		}
+		if err != nil && !q.makeRoom(e) {
+			q.stats.drop(dropQueueFull, e.priority, 1)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fits checks if an entry of size n could be added after removing entries.
+func (q *queue) fits(removeCount, removeBytes, n int) bool {
+	if q.count-removeCount >= q.size {
+		return false
+	}
+	return q.maxBytes <= 0 || q.bytes-removeBytes+n <= q.maxBytes
+}
+
+func (q *queue) removeFirst(priority int) queueEntry {
+	l := q.levels[priority]
+	e := l[0]
+	l[0] = queueEntry{}
+	q.levels[priority] = l[1:]
+	q.levelBytes[priority] -= len(e.data)
+	q.count--
+	q.bytes -= len(e.data)
+	return e
+}
+
+// get blocks until an unexpired entry is available.  Expired entries are
+// discarded.  False is returned when the queue is closed.
+func (q *queue) get() (queueEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		for q.count == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.closed {
+			return queueEntry{}, false
+		}
+
+		oldest := -1
+		for priority, l := range q.levels {
+			if len(l) > 0 && (oldest < 0 || l[0].seq < q.levels[oldest][0].seq) {
+				oldest = priority
+			}
+		}
+
+		e := q.removeFirst(oldest)
+		notify(&q.space)
+
+		if !q.expired(e, q.now()) {
+			q.inflight = e.seq
+			return e, true
+		}
+		q.stats.drop(dropExpired, e.priority, 1)
+	}
+}
+
+// done is called after the entry returned by get has been sent.
+func (q *queue) done() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.inflight = 0
+	notify(&q.progress)
+}
+
+// notify waiters by closing the channel, if any.
+func notify(c *chan struct{}) {
+	if *c != nil {
+		close(*c)
+		*c = nil
+	}
+}
+
+// flush blocks until the entries which are currently queued have been sent
+// or discarded.
+func (q *queue) flush(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	target := q.seq
+
+	for !q.flushed(target) {
+		if q.progress == nil {
+			q.progress = make(chan struct{})
+		}
+		progress := q.progress
+
+		q.mu.Unlock()
+		select {
+		case <-progress:
+		case <-ctx.Done():
+			q.mu.Lock()
+			return ctx.Err()
+		}
+		q.mu.Lock()
+	}
+
+	return nil
+}
+
+// flushed checks if all entries up to and including the sequence number have
+// been processed.
+func (q *queue) flushed(seq uint64) bool {
+	if q.inflight != 0 && q.inflight <= seq {
+		return false
+	}
+	for _, l := range q.levels {
+		if len(l) > 0 && l[0].seq <= seq {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *queue) expired(e queueEntry, now time.Time) bool {
+	ttl := q.ttl
+	if e.priority <= priorityErr {
+		ttl = q.errorTTL
+	}
+	return ttl > 0 && now.Sub(e.time) > ttl
+}
+
+// stop accepting entries.
+func (q *queue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.stopped = true
+	notify(&q.space)
+}
+
+// close the queue and discard the remaining entries.
+func (q *queue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.closed {
+		q.stopped = true
+		q.closed = true
+		for priority, l := range q.levels {
+			q.stats.drop(dropClosed, priority, len(l))
+			q.levels[priority] = nil
+			q.levelBytes[priority] = 0
+		}
+		q.count = 0
+		q.bytes = 0
+		q.cond.Broadcast()
+		notify(&q.progress)
+		notify(&q.space)
+	}
+}
+
+// sendQueued until the queue is closed.
+func (r *root) sendQueued() {
+	defer close(r.done)
+
+	for {
+		e, ok := r.queue.get()
+		if !ok {
+			return
+		}
+		if err := r.sendRecord(e.payload(), e.meta); err != nil {
+			r.stats.drop(dropError, e.priority, 1)
+		}
+		r.queue.done()
+	}
+}
@@ -0,0 +1,630 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{time.Unix(1700000000, 0)}
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func testEntry(priority int, t time.Time) queueEntry {
+	return queueEntry{data: []byte{byte(priority)}, keyLen: 1, priority: priority, time: t}
+}
+
+func TestQueueTTL(t *testing.T) {
+	const ttl = time.Minute
+
+	clock := newFakeClock()
+	ctx := context.Background()
+	st := new(stats)
+	q := newQueue(&HandlerOptions{QueueSize: 10, QueueTTL: ttl}, st, clock.now)
+
+	q.put(ctx, testEntry(7, clock.now()))
+	clock.advance(time.Second)
+	q.put(ctx, testEntry(7, clock.now()))
+	q.put(ctx, testEntry(3, clock.now()))
+	q.put(ctx, testEntry(6, clock.now().Add(time.Second)))
+
+	// First entry is just over the TTL, the others are exactly at it.
+	clock.advance(ttl)
+
+	for _, expect := range []int{7, 3, 6} {
+		e, ok := q.get()
+		if !ok {
+			t.Fatal("queue closed")
+		}
+		if e.priority != expect {
+			t.Errorf("priority %d; expected %d", e.priority, expect)
+		}
+	}
+
+	if n := st.snapshot().Dropped[DropExpired]; n != 1 {
+		t.Errorf("%d expired entries", n)
+	}
+}
+
+func TestQueueErrorTTL(t *testing.T) {
+	clock := newFakeClock()
+	ctx := context.Background()
+
+	for _, errorTTL := range []time.Duration{0, time.Hour} {
+		st := new(stats)
+		q := newQueue(&HandlerOptions{QueueSize: 10, QueueTTL: time.Minute, QueueErrorTTL: errorTTL}, st, clock.now)
+
+		q.put(ctx, testEntry(7, clock.now()))
+		q.put(ctx, testEntry(2, clock.now()))
+		q.put(ctx, testEntry(3, clock.now()))
+		q.put(ctx, testEntry(4, clock.now()))
+
+		clock.advance(30 * time.Minute)
+		q.put(ctx, testEntry(7, clock.now()))
+
+		for _, expect := range []int{2, 3, 7} {
+			e, _ := q.get()
+			if e.priority != expect {
+				t.Errorf("error TTL %v: priority %d; expected %d", errorTTL, e.priority, expect)
+			}
+		}
+
+		clock.advance(2 * time.Hour)
+		q.put(ctx, testEntry(3, clock.now().Add(-2*time.Hour)))
+		q.put(ctx, testEntry(6, clock.now()))
+
+		e, _ := q.get()
+		if expect := map[time.Duration]int{0: 3, time.Hour: 6}[errorTTL]; e.priority != expect {
+			t.Errorf("error TTL %v: priority %d; expected %d", errorTTL, e.priority, expect)
+		}
+	}
+}
+
+func TestQueueDropLowestPriority(t *testing.T) {
+	clock := newFakeClock()
+	ctx := context.Background()
+	st := new(stats)
+	q := newQueue(&HandlerOptions{QueueSize: 3, QueueDropPolicy: DropLowestPriority}, st, clock.now)
+
+	q.put(ctx, testEntry(7, clock.now()))
+	q.put(ctx, testEntry(6, clock.now()))
+	q.put(ctx, testEntry(7, clock.now()))
+	q.put(ctx, testEntry(3, clock.now())) // Evicts first debug entry.
+	q.put(ctx, testEntry(7, clock.now())) // Dropped.
+	q.put(ctx, testEntry(4, clock.now())) // Evicts second debug entry.
+	q.put(ctx, testEntry(4, clock.now())) // Evicts info entry.
+	q.put(ctx, testEntry(4, clock.now())) // Dropped.
+
+	for _, expect := range []int{3, 4, 4} {
+		e, _ := q.get()
+		if e.priority != expect {
+			t.Errorf("priority %d; expected %d", e.priority, expect)
+		}
+	}
+
+	s := st.snapshot()
+	if n := s.Dropped[DropQueueFull]; n != 5 {
+		t.Errorf("%d dropped entries", n)
+	}
+	if s.DroppedByPriority != [8]uint64{4: 1, 6: 1, 7: 3} {
+		t.Errorf("dropped by priority: %v", s.DroppedByPriority)
+	}
+}
+
+func TestQueueDropNewest(t *testing.T) {
+	clock := newFakeClock()
+	ctx := context.Background()
+	st := new(stats)
+	q := newQueue(&HandlerOptions{QueueSize: 2}, st, clock.now)
+
+	q.put(ctx, testEntry(7, clock.now()))
+	q.put(ctx, testEntry(7, clock.now()))
+	q.put(ctx, testEntry(3, clock.now()))
+
+	if s := st.snapshot(); s.DroppedByPriority != [8]uint64{3: 1} {
+		t.Errorf("dropped by priority: %v", s.DroppedByPriority)
+	}
+}
+
+func TestQueueMaxBytes(t *testing.T) {
+	const (
+		small = 1000
+		large = 300 << 10
+		limit = 1 << 20
+	)
+
+	for _, policy := range []DropPolicy{DropNewest, DropLowestPriority} {
+		clock := newFakeClock()
+		ctx := context.Background()
+		st := new(stats)
+		q := newQueue(&HandlerOptions{QueueSize: 1000, MaxQueueBytes: limit, QueueDropPolicy: policy}, st, clock.now)
+
+		put := func(priority, size int) {
+			q.put(ctx, queueEntry{data: make([]byte, size), priority: priority, time: clock.now()})
+			if q.bytes > limit {
+				t.Fatalf("policy %d: %d bytes queued", policy, q.bytes)
+			}
+		}
+
+		for i := 0; i < 10; i++ {
+			put(7, small)
+			put(6, large)
+		}
+		put(3, limit+1)
+
+		s := st.snapshot()
+		if n := s.Dropped[DropTooLarge]; n != 1 {
+			t.Errorf("policy %d: %d too large entries", policy, n)
+		}
+		if n := s.Dropped[DropQueueFull]; n != 7 {
+			t.Errorf("policy %d: %d dropped entries", policy, n)
+		}
+
+		put(3, large)
+
+		s = st.snapshot()
+		switch policy {
+		case DropNewest:
+			if s.DroppedByPriority[3] != 2 {
+				t.Errorf("policy %d: dropped error entries: %v", policy, s.DroppedByPriority)
+			}
+		case DropLowestPriority:
+			if s.DroppedByPriority[3] != 1 || s.DroppedByPriority[7] == 0 {
+				t.Errorf("policy %d: dropped entries: %v", policy, s.DroppedByPriority)
+			}
+		}
+
+		for q.count > 0 {
+			q.get()
+		}
+		if q.bytes != 0 {
+			t.Errorf("policy %d: %d bytes accounted after draining", policy, q.bytes)
+		}
+	}
+}
+
+func TestQueueOverflow(t *testing.T) {
+	for _, c := range []struct {
+		overflow Overflow
+		cancel   bool
+		room     bool
+	}{
+		{OverflowDrop, false, false},
+		{OverflowBlock, false, true},
+		{OverflowBlock, true, false},
+		{OverflowBlockWithTimeout(20 * time.Millisecond), false, false},
+		{OverflowBlockWithTimeout(time.Hour), false, true},
+	} {
+		st := new(stats)
+		q := newQueue(&HandlerOptions{QueueSize: 1, QueueOverflow: c.overflow}, st, time.Now)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		q.put(ctx, testEntry(6, time.Now()))
+
+		done := make(chan error)
+		go func() {
+			done <- q.put(ctx, testEntry(3, time.Now()))
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+
+		switch {
+		case c.room:
+			select {
+			case err := <-done:
+				t.Fatalf("%#v: did not block: %v", c, err)
+			default:
+			}
+			q.get()
+
+		case c.cancel:
+			cancel()
+		}
+
+		err := <-done
+		switch {
+		case c.cancel:
+			if err != context.Canceled {
+				t.Errorf("%#v: %v", c, err)
+			}
+		case err != nil:
+			t.Errorf("%#v: %v", c, err)
+		}
+
+		s := st.snapshot()
+
+		if c.room {
+			if e, _ := q.get(); e.priority != 3 {
+				t.Errorf("%#v: priority %d", c, e.priority)
+			}
+			if len(s.Dropped) != 0 {
+				t.Errorf("%#v: dropped: %v", c, s.Dropped)
+			}
+		} else if s.DroppedByPriority[3] != 1 {
+			t.Errorf("%#v: dropped: %v", c, s.DroppedByPriority)
+		}
+
+		if c.overflow.block {
+			if s.Blocked != 1 || s.BlockedTime < 20*time.Millisecond {
+				t.Errorf("%#v: blocked %d times for %v", c, s.Blocked, s.BlockedTime)
+			}
+		} else if s.Blocked != 0 {
+			t.Errorf("%#v: blocked %d times", c, s.Blocked)
+		}
+	}
+}
+
+func TestQueueCoalesce(t *testing.T) {
+	clock := newFakeClock()
+	ctx := context.Background()
+	q := newQueue(&HandlerOptions{QueueSize: 10, QueueCoalesce: true}, new(stats), clock.now)
+
+	put := func(priority int, key, timestamp string) {
+		data := []byte(key + timestamp)
+		q.put(ctx, queueEntry{data: data, keyLen: len(key), priority: priority, time: clock.now()})
+	}
+
+	put(6, "a", "1")
+	put(6, "a", "2")
+	put(6, "a", "3")
+	put(6, "b", "4")
+	put(3, "b", "5")
+	put(6, "b", "6")
+	put(6, "a", "7")
+	put(6, "a", "8")
+
+	var output []string
+	for q.count > 0 {
+		e, _ := q.get()
+		output = append(output, string(e.payload()))
+	}
+
+	expect := []string{
+		"a1REPEATS=3\n",
+		"b4",
+		"b5",
+		"b6",
+		"a7REPEATS=2\n",
+	}
+	if !slices.Equal(output, expect) {
+		t.Errorf("output: %q", output)
+	}
+}
+
+func TestAsyncCoalesce(t *testing.T) {
+	recv := newTestReceiver(t)
+	recv.pause()
+
+	h, err := NewHandler(&HandlerOptions{
+		Socket:        recv.path,
+		QueueSize:     100,
+		QueueCoalesce: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	ctx := context.Background()
+	payload := strings.Repeat("x", 20000)
+
+	// Fill the kernel buffer so that the following entries stay queued.
+	for i := 0; i < 20; i++ {
+		h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, payload, 0))
+	}
+
+	for i := 0; i < 500; i++ {
+		h.Handle(ctx, slog.NewRecord(time.Unix(int64(i), 0), slog.LevelError, "retrying", 0))
+	}
+	h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "recovered", 0))
+
+	recv.resume()
+	if err := h.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ms := recv.wait(t, int(h.Stats().Sent))
+
+	var retries int
+	for _, m := range ms {
+		if m["MESSAGE"] == "retrying" {
+			n := 1
+			if s, found := m["REPEATS"]; found {
+				n, _ = strconv.Atoi(s)
+			}
+			retries += n
+		}
+	}
+	if retries != 500 {
+		t.Errorf("%d retries", retries)
+	}
+	if len(ms) > 40 {
+		t.Errorf("%d entries", len(ms))
+	}
+	if m := ms[len(ms)-1]; m["MESSAGE"] != "recovered" {
+		t.Errorf("last message: %q", m["MESSAGE"])
+	}
+}
+
+func TestFlush(t *testing.T) {
+	const count = 50
+
+	recv := newTestReceiver(t)
+	recv.pause()
+
+	h, err := NewHandler(&HandlerOptions{
+		Socket:    recv.path,
+		QueueSize: count,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	logger := slog.New(h)
+	payload := strings.Repeat("x", 20000)
+	for i := 0; i < count; i++ {
+		logger.Info(payload)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := h.Flush(ctx); err != context.DeadlineExceeded {
+		t.Fatal("flush with paused receiver:", err)
+	}
+
+	done := make(chan error, 2)
+	for i := 0; i < cap(done); i++ {
+		go func() {
+			done <- h.Flush(context.Background())
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	recv.resume()
+
+	for i := 0; i < cap(done); i++ {
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+
+	if s := h.Stats(); s.Sent != count {
+		t.Errorf("sent %d", s.Sent)
+	}
+	recv.wait(t, count)
+
+	if err := h.Flush(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAsync(t *testing.T) {
+	recv := newTestReceiver(t)
+
+	h, err := NewHandler(&HandlerOptions{
+		Delimiter: DefaultDelimiter,
+		Socket:    recv.path,
+		QueueSize: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	logger := slog.New(h)
+	for i := 0; i < 10; i++ {
+		logger.Info("hello", "i", i)
+	}
+
+	ms := recv.wait(t, 10)
+	for i, m := range ms {
+		if s := m["MESSAGE"]; s != "hello i="+strconv.Itoa(i) {
+			t.Errorf("message %d: %q", i, s)
+		}
+	}
+
+	if err := h.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "closed", 0)); err != ErrClosed {
+		t.Error("handle after close:", err)
+	}
+
+	if s := h.Stats(); s.Sent != 10 {
+		t.Errorf("sent %d", s.Sent)
+	}
+}
+
+// prependMunger moves the PRIORITY field away from the start of the entry.
+func prependMunger(_ context.Context, b []byte) ([]byte, error) {
+	return append([]byte("SYSLOG_IDENTIFIER=x\n"), b...), nil
+}
+
+func TestAsyncMunger(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		QueueSize: 10,
+		Mungers:   []func(context.Context, []byte) ([]byte, error){prependMunger},
+	})
+
+	slog.New(h).Warn("munged")
+
+	m := recv.wait(t, 1)[0]
+	if m["MESSAGE"] != "munged" || m["PRIORITY"] != "4" || m["SYSLOG_IDENTIFIER"] != "x" {
+		t.Errorf("entry: %q", m)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		recv := newTestReceiver(t)
+
+		opts := &HandlerOptions{
+			Socket: recv.path,
+		}
+		if async {
+			opts.QueueSize = 3
+			recv.pause()
+		}
+
+		h, err := NewHandler(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		logger := slog.New(h)
+		payload := strings.Repeat("x", 20000)
+		for i := 0; i < 20; i++ {
+			logger.Info(payload)
+		}
+
+		time.AfterFunc(10*time.Millisecond, recv.resume)
+
+		if err := h.Shutdown(context.Background()); err != nil {
+			t.Errorf("async %v: %v", async, err)
+		}
+
+		if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "closed", 0)); err != ErrClosed {
+			t.Errorf("async %v: handle after shutdown: %v", async, err)
+		}
+		if err := h.Shutdown(context.Background()); err != nil {
+			t.Errorf("async %v: repeated shutdown: %v", async, err)
+		}
+		if err := h.Close(); err != nil {
+			t.Errorf("async %v: close after shutdown: %v", async, err)
+		}
+
+		if !async {
+			recv.wait(t, 20)
+			continue
+		}
+
+		s := h.Stats()
+		n := s.Dropped[DropQueueFull]
+		if n == 0 || s.Sent != 20-n+1 {
+			t.Fatalf("async %v: stats: %v", async, s)
+		}
+
+		ms := recv.wait(t, int(s.Sent))
+		if s := ms[len(ms)-1]["MESSAGE"]; s != "sjournal: entries dropped: queue-full="+strconv.FormatUint(n, 10) {
+			t.Errorf("summary message: %q", s)
+		}
+		if s := ms[len(ms)-1]["PRIORITY"]; s != "4" {
+			t.Errorf("summary priority: %q", s)
+		}
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	const count = 50
+
+	recv := newTestReceiver(t)
+	recv.pause()
+
+	h, err := NewHandler(&HandlerOptions{
+		Socket:    recv.path,
+		QueueSize: count,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(h)
+	payload := strings.Repeat("x", 20000)
+	for i := 0; i < count; i++ {
+		logger.Info(payload)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		handled atomic.Int32
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelDebug, "racing", 0)) != ErrClosed {
+				handled.Add(1)
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Error("shutdown with paused receiver:", err)
+	}
+	wg.Wait()
+
+	recv.resume()
+
+	s := h.Stats()
+	if total := s.Sent + s.DroppedByPriority[6] + s.DroppedByPriority[7]; total != count+uint64(handled.Load()) {
+		t.Errorf("%d entries accounted for; expected %d", total, count+handled.Load())
+	}
+	if s.Dropped[DropClosed] == 0 {
+		t.Errorf("dropped: %v", s.Dropped)
+	}
+}
+
+func TestShutdownSummaryIntentional(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level:          slog.LevelInfo,
+		FilterInHandle: true,
+		Filter: func(_ context.Context, r slog.Record) bool {
+			return r.Message != "filtered"
+		},
+	})
+
+	logger := slog.New(h)
+	logger.Info("filtered")
+	logger.Info("sent")
+	h.MuteFunc("import.name/sjournal.TestShutdownSummaryIntentional")
+	logger.Info("muted")
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelDebug, "below level", 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	s := h.Stats()
+	if n := s.Dropped[DropFiltered]; n != 2 {
+		t.Errorf("%d filtered", n)
+	}
+	if n := s.Dropped[DropMuted]; n != 1 {
+		t.Errorf("%d muted", n)
+	}
+	if s.Sent != 1 {
+		t.Errorf("%d sent", s.Sent)
+	}
+	if m := recv.wait(t, 1)[0]; m["MESSAGE"] != "sent" {
+		t.Errorf("entry: %q", m)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// QuoteStyle determines how attribute keys and values are quoted.
+type QuoteStyle int
+
+const (
+	// GoQuote quotes strings which contain spaces, '=', quotes, or
+	// non-printable characters, using Go syntax (strconv.Quote).
+	GoQuote QuoteStyle = iota
+
+	// ASCIIQuote is like GoQuote, but non-ASCII characters within quoted
+	// strings are escaped (strconv.QuoteToASCII).
+	ASCIIQuote
+
+	// MinimalQuote quotes strings which contain spaces, '=', quotes, or
+	// control characters.  Only quotes, backslashes and control characters
+	// are escaped.
+	MinimalQuote
+
+	// NeverQuote writes strings as is.
+	NeverQuote
+)
+
+func (s *handleState) appendString(str string) {
+	switch s.quoteStyle {
+	case GoQuote:
+		if needsQuoting(str) {
+			*s.buf = strconv.AppendQuote(*s.buf, str)
+			return
+		}
+
+	case ASCIIQuote:
+		if needsQuoting(str) {
+			*s.buf = strconv.AppendQuoteToASCII(*s.buf, str)
+			return
+		}
+
+	case MinimalQuote:
+		if needsMinimalQuoting(str) {
+			*s.buf = appendMinimalQuote(*s.buf, str)
+			return
+		}
+	}
+
+	s.buf.WriteString(str)
+}
+
+// appendValue appends an attribute value.  It's quoted using Go syntax if
+// quoteValue is set.
+func (s *handleState) appendValue(str string) {
+	if s.quoteValue {
+		s.quoteValue = false
+		*s.buf = strconv.AppendQuote(*s.buf, str)
+		return
+	}
+	s.appendString(str)
+}
+
+func needsMinimalQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; {
+		case b == ' ' || b == '=' || b == '"':
+			return true
+		case b < 0x20 || b == 0x7f:
+			return true
+		case b == 0xc2 && i+1 < len(s) && s[i+1] <= 0x9f:
+			return true // C1 control.
+		}
+	}
+	return false
+}
+
+func appendMinimalQuote(b []byte, s string) []byte {
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"' || r == '\\':
+			b = append(b, '\\', byte(r))
+		case r == '\n':
+			b = append(b, '\\', 'n')
+		case r == '\t':
+			b = append(b, '\\', 't')
+		case isControl(r, false):
+			b = appendEscapedControl(b, s[i:i+size], false)
+		default:
+			b = append(b, s[i:i+size]...) // Invalid UTF-8 is retained.
+		}
+		i += size
+	}
+	return append(b, '"')
+}
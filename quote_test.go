@@ -0,0 +1,42 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestQuoteStyle(t *testing.T) {
+	for _, x := range []struct {
+		style  QuoteStyle
+		expect string
+	}{
+		{GoQuote, `msg "my key"="a 😀" e=😀 q="say \"hi\"" n="x\ny" c="\x1b" "my key"="a 😀" e=😀 q="say \"hi\"" n="x\ny" c="\x1b"`},
+		{ASCIIQuote, `msg "my key"="a \U0001f600" e=😀 q="say \"hi\"" n="x\ny" c="\x1b" "my key"="a \U0001f600" e=😀 q="say \"hi\"" n="x\ny" c="\x1b"`},
+		{MinimalQuote, `msg "my key"="a 😀" e=😀 q="say \"hi\"" n="x\ny" c="\x1b" "my key"="a 😀" e=😀 q="say \"hi\"" n="x\ny" c="\x1b"`},
+		{NeverQuote, "msg my key=a 😀 e=😀 q=say \"hi\" n=x\ny c=\x1b my key=a 😀 e=😀 q=say \"hi\" n=x\ny c=\x1b"},
+	} {
+		h, recv := newTestHandler(t, &HandlerOptions{QuoteStyle: x.style})
+
+		attrs := []any{"my key", "a 😀", "e", "😀", "q", `say "hi"`, "n", "x\ny", "c", "\x1b"}
+		slog.New(h).With(attrs...).Info("msg", attrs...)
+
+		if s := recv.wait(t, 1)[0]["MESSAGE"]; s != x.expect {
+			t.Errorf("style %d: %s", x.style, s)
+		}
+	}
+
+	for s, expect := range map[string]bool{
+		"ä":        false,
+		"\xff":     false,
+		"a\u0085b": true,
+		"":         true,
+	} {
+		if needsMinimalQuoting(s) != expect {
+			t.Errorf("minimal quoting of %q", s)
+		}
+	}
+}
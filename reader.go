@@ -0,0 +1,160 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	readerPlaceholder = "<io.Reader>"
+	readerChunkSize   = 32 << 10
+	readerMaxEmpty    = 100 // Consecutive empty reads before io.ErrNoProgress.
+)
+
+// readerTimeout limits reading if the context has no deadline.
+var readerTimeout = 10 * time.Second
+
+// Reader returns an attribute which the handler emits as a separate journal
+// field with up to limit bytes read from r.  The field name is derived from
+// the key like with Field.  The number of bytes read is emitted in the
+// NAME_BYTES_READ field, NAME_TRUNCATED=1 is emitted if r has more data, and
+// NAME_READ_ERROR is emitted if reading fails; the data read before the error
+// is kept.  The marker fields are omitted if their names would be too long.
+// If the key is not a valid field name, the data is included in the message as
+// a string.
+//
+// The reader is consumed when the record is handled for the first time (or by
+// EstimateSize); other destinations and handlers sharing the attribute see the
+// same data.  Reading gives up when the context passed to Handle is done, or
+// after 10 seconds if the context has no deadline.  The deadline is applied to
+// readers which have a SetReadDeadline method; a Read call of other readers
+// is abandoned in the background.  Other handlers see a placeholder string.
+//
+// The data is buffered in memory (up to limit bytes) and copied into the
+// encoded entry, so limit should be chosen accordingly.  Large entries are
+// passed to journald via a file as usual (see LargeMessageSupport), but the
+// data is not streamed directly into the file.
+func Reader(key string, r io.Reader, limit int64) slog.Attr {
+	return slog.Any(key, &readerValue{r: r, limit: max(limit, 0)})
+}
+
+type readerValue struct {
+	r     io.Reader
+	limit int64
+
+	once      sync.Once
+	data      []byte
+	truncated bool
+	err       error
+}
+
+func (v *readerValue) LogValue() slog.Value {
+	return slog.StringValue(readerPlaceholder)
+}
+
+// read consumes the reader unless it has already been done.
+func (v *readerValue) read(ctx context.Context) {
+	v.once.Do(func() {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, readerTimeout)
+			defer cancel()
+		}
+		v.data, v.truncated, v.err = readLimited(ctx, v.r, v.limit)
+		v.r = nil
+	})
+}
+
+// readLimited reads at most limit bytes, and reports whether there was more.
+func readLimited(ctx context.Context, r io.Reader, limit int64) (data []byte, truncated bool, err error) {
+	if d, ok := r.(interface{ SetReadDeadline(time.Time) error }); ok {
+		if deadline, ok := ctx.Deadline(); ok && d.SetReadDeadline(deadline) == nil {
+			defer d.SetReadDeadline(time.Time{})
+		}
+	}
+
+	for empty := 0; int64(len(data)) <= limit; {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		// Read one byte past the limit to find out if there is more.
+		var chunk []byte
+		chunk, err = readContext(ctx, r, int(min(limit+1-int64(len(data)), readerChunkSize)))
+		data = append(data, chunk...)
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			break
+		}
+
+		if len(chunk) > 0 {
+			empty = 0
+		} else if empty++; empty == readerMaxEmpty {
+			err = io.ErrNoProgress
+			break
+		}
+	}
+
+	if int64(len(data)) > limit {
+		data = data[:limit]
+		truncated = true
+	}
+	return
+}
+
+// readContext reads once.  If the context can be canceled, the read is done
+// in a separate goroutine which is abandoned when the context is done.
+func readContext(ctx context.Context, r io.Reader, n int) ([]byte, error) {
+	buf := make([]byte, n)
+
+	if ctx.Done() == nil {
+		n, err := r.Read(buf)
+		return buf[:n], err
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+
+	c := make(chan result, 1)
+	go func() {
+		n, err := r.Read(buf)
+		c <- result{n, err}
+	}()
+
+	select {
+	case res := <-c:
+		return buf[:res.n], res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// appendReaderFields appends the data and the marker fields.
+func (s *handleState) appendReaderFields(name string, v *readerValue) {
+	*s.fields = appendBinaryField(*s.fields, name, v.data)
+	s.appendReaderMarker(name+"_BYTES_READ", strconv.Itoa(len(v.data)))
+	if v.truncated {
+		s.appendReaderMarker(name+"_TRUNCATED", "1")
+	}
+	if v.err != nil {
+		s.appendReaderMarker(name+"_READ_ERROR", v.err.Error())
+	}
+}
+
+func (s *handleState) appendReaderMarker(name, value string) {
+	if len(name) <= maxFieldNameLen {
+		s.appendField(name, value)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestReader(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter: DefaultDelimiter,
+	})
+	logger := slog.New(h)
+
+	errTest := errors.New("test error")
+
+	logger.Info("short", Reader("body", iotest.OneByteReader(strings.NewReader("hello\nworld")), 100))
+	logger.Info("long", Reader("body", strings.NewReader(strings.Repeat("x", 100)), 10))
+	logger.Info("exact", Reader("body", strings.NewReader("0123456789"), 10))
+	logger.Info("error", Reader("body", io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errTest)), 100))
+	logger.Info("invalid", Reader("bad-key", strings.NewReader("text"), 100))
+
+	ms := recv.wait(t, 5)
+
+	for i, expect := range []struct {
+		message   string
+		body      string
+		bytesRead string
+		truncated string
+		err       string
+	}{
+		{"short", "hello\nworld", "11", "", ""},
+		{"long", "xxxxxxxxxx", "10", "1", ""},
+		{"exact", "0123456789", "10", "", ""},
+		{"error", "partial", "7", "", errTest.Error()},
+		{"invalid bad-key=text", "", "", "", ""},
+	} {
+		m := ms[i]
+		if s := m["MESSAGE"]; s != expect.message {
+			t.Errorf("entry %d: message %q", i, s)
+		}
+		if s := m["BODY"]; s != expect.body {
+			t.Errorf("entry %d: body %q", i, s)
+		}
+		if s := m["BODY_BYTES_READ"]; s != expect.bytesRead {
+			t.Errorf("entry %d: bytes read %q", i, s)
+		}
+		if s := m["BODY_TRUNCATED"]; s != expect.truncated {
+			t.Errorf("entry %d: truncated %q", i, s)
+		}
+		if s := m["BODY_READ_ERROR"]; s != expect.err {
+			t.Errorf("entry %d: error %q", i, s)
+		}
+	}
+}
+
+func TestReaderShared(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter: DefaultDelimiter,
+	})
+
+	var other bytes.Buffer
+	r := Reader("body", strings.NewReader("data"), 100)
+
+	slog.New(h).Info("first", r)
+	slog.New(h).Info("second", r)
+	slog.New(slog.NewTextHandler(&other, nil)).Info("other", r)
+
+	ms := recv.wait(t, 2)
+	for i, m := range ms {
+		if s := m["BODY"]; s != "data" {
+			t.Errorf("entry %d: body %q", i, s)
+		}
+	}
+	if s := other.String(); !strings.Contains(s, "body="+readerPlaceholder) {
+		t.Errorf("other handler: %q", s)
+	}
+}
+
+func TestReaderContext(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter: DefaultDelimiter,
+	})
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	go pw.Write([]byte("begin"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	slog.New(h).InfoContext(ctx, "blocked", Reader("body", pr, 100))
+
+	m := recv.wait(t, 1)[0]
+	if s := m["BODY"]; s != "begin" {
+		t.Errorf("body %q", s)
+	}
+	if s := m["BODY_READ_ERROR"]; s != context.DeadlineExceeded.Error() {
+		t.Errorf("error %q", s)
+	}
+}
+
+func TestReaderTimeout(t *testing.T) {
+	defer func(d time.Duration) { readerTimeout = d }(readerTimeout)
+	readerTimeout = 50 * time.Millisecond
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter: DefaultDelimiter,
+	})
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	go pw.Write([]byte("begin"))
+
+	slog.New(h).Info("blocked", Reader("body", pr, 100))
+
+	m := recv.wait(t, 1)[0]
+	if s := m["BODY"]; s != "begin" {
+		t.Errorf("body %q", s)
+	}
+	if s := m["BODY_READ_ERROR"]; s != context.DeadlineExceeded.Error() {
+		t.Errorf("error %q", s)
+	}
+}
+
+func TestReaderNoProgress(t *testing.T) {
+	data, truncated, err := readLimited(context.Background(), emptyReader{}, 10)
+	if len(data) != 0 || truncated || err != io.ErrNoProgress {
+		t.Errorf("%q %v %v", data, truncated, err)
+	}
+}
+
+type emptyReader struct{}
+
+func (emptyReader) Read([]byte) (int, error) { return 0, nil }
+
+func TestReaderLargeEntry(t *testing.T) {
+	if !LargeMessageSupport {
+		t.Skip("large messages not supported")
+	}
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter: DefaultDelimiter,
+	})
+	if err := h.root.socks[0].SetWriteBuffer(4096); err != nil {
+		t.Fatal(err)
+	}
+
+	body := strings.Repeat("0123456789abcdef", 16<<10)
+	slog.New(h).Info("large", Reader("body", iotest.HalfReader(strings.NewReader(body)), 1<<20))
+
+	m := recv.wait(t, 1)[0]
+	if s := m["BODY"]; s != body {
+		t.Errorf("body: %d bytes", len(s))
+	}
+	if s := m["BODY_BYTES_READ"]; s != "262144" {
+		t.Errorf("bytes read %q", s)
+	}
+	if n := h.Stats().LargeEntries; n != 1 {
+		t.Errorf("%d large entries", n)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ReservedPolicy determines how user-supplied journal field names which
+// collide with the fields written by the handler or journald are treated.
+// The reserved names are MESSAGE, MESSAGE_ID, PRIORITY, the names starting
+// with SYSLOG_ or CODE_, and the names starting with an underscore.
+type ReservedPolicy int
+
+const (
+	AllowReserved  ReservedPolicy = iota // Reserved names are used as is.
+	RenameReserved                       // ReservedPrefix is prepended to reserved names.
+	RejectReserved                       // Fields with reserved names are rejected.
+)
+
+const defaultReservedPrefix = "X_"
+
+// ErrReservedField is wrapped by the errors reported for fields which have
+// reserved names when the policy is RejectReserved (see
+// HandlerOptions.ReservedFields).
+var ErrReservedField = errors.New("sjournal: reserved field name")
+
+// reservedFieldName reports whether a journal field name is reserved.
+func reservedFieldName(name string) bool {
+	switch name {
+	case "MESSAGE", "MESSAGE_ID", "PRIORITY":
+		return true
+	}
+	return strings.HasPrefix(name, "_") || strings.HasPrefix(name, "SYSLOG_") || strings.HasPrefix(name, "CODE_")
+}
+
+// reservedFields applies a ReservedPolicy.
+type reservedFields struct {
+	policy ReservedPolicy
+	prefix string
+	report func(error) // Nil means that Handle returns the error.
+}
+
+func newReservedFields(opts *HandlerOptions) reservedFields {
+	return reservedFields{
+		policy: opts.ReservedFields,
+		prefix: cmp.Or(opts.ReservedPrefix, defaultReservedPrefix),
+		report: opts.OnViolation,
+	}
+}
+
+// name returns the field name to use, or false if the field is rejected.
+func (r reservedFields) name(name string) (string, bool) {
+	if r.policy == AllowReserved || !reservedFieldName(name) {
+		return name, true
+	}
+	if r.policy == RenameReserved {
+		return r.prefix + name, true
+	}
+	return "", false
+}
+
+func reservedFieldError(name string) error {
+	return fmt.Errorf("%w: %s", ErrReservedField, name)
+}
+
+// rejectField reports a field with a reserved name.  Only the first error is
+// returned by Handle.
+func (s *handleState) rejectField(name string) {
+	err := reservedFieldError(name)
+	if report := s.h.reserved.report; report != nil {
+		report(err)
+	} else if s.reserved == nil {
+		s.reserved = err
+	}
+}
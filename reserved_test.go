@@ -0,0 +1,174 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReservedFieldName(t *testing.T) {
+	for name, reserved := range map[string]bool{
+		"MESSAGE":           true,
+		"MESSAGE_ID":        true,
+		"PRIORITY":          true,
+		"SYSLOG_IDENTIFIER": true,
+		"SYSLOG_FACILITY":   true,
+		"CODE_FILE":         true,
+		"CODE_FUNC":         true,
+		"_SYSTEMD_UNIT":     true,
+		"MESSAGES":          false,
+		"PRIORITY_X":        false,
+		"SYSLOG":            false,
+		"CODE":              false,
+		"X_MESSAGE":         false,
+	} {
+		if reservedFieldName(name) != reserved {
+			t.Errorf("%s: %v", name, !reserved)
+		}
+	}
+}
+
+func TestReservedFields(t *testing.T) {
+	for _, c := range []struct {
+		policy  ReservedPolicy
+		prefix  string
+		message string
+		fields  []string
+		err     bool
+	}{
+		{
+			AllowReserved, "",
+			"msg",
+			[]string{"MESSAGE=x", "CODE_LINE=1", "CODE_FUNC=f", "MESSAGE_ID=m"},
+			false,
+		},
+		{
+			RenameReserved, "",
+			"msg",
+			[]string{"X_MESSAGE=x", "X_CODE_LINE=1", "X_CODE_FUNC=f", "X_MESSAGE_ID=m"},
+			false,
+		},
+		{
+			RenameReserved, "APP_",
+			"msg",
+			[]string{"APP_MESSAGE=x", "APP_CODE_LINE=1", "APP_CODE_FUNC=f", "APP_MESSAGE_ID=m"},
+			false,
+		},
+		{
+			RejectReserved, "",
+			`msg: code_func=f message=x code_line="MQ=="`,
+			nil,
+			true,
+		},
+	} {
+		h, recv := newTestHandler(t, &HandlerOptions{
+			Delimiter:      ColonDelimiter,
+			ReservedFields: c.policy,
+			ReservedPrefix: c.prefix,
+		})
+
+		ctx := ContextWithFields(context.Background(), map[string]string{"message_id": "m"})
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0)
+		r.AddAttrs(Field("message", "x"), Binary("code_line", []byte("1")))
+
+		err := h.WithFields(map[string]string{"code_func": "f"}).Handle(ctx, r)
+		if c.err {
+			if !errors.Is(err, ErrReservedField) || err.Error() != "sjournal: reserved field name: MESSAGE" {
+				t.Errorf("policy %d: error: %v", c.policy, err)
+			}
+		} else if err != nil {
+			t.Errorf("policy %d: error: %v", c.policy, err)
+		}
+
+		recv.wait(t, 1)
+		fields := datagramFields(recv.datagrams()[0])
+
+		messages := 0
+		for _, f := range fields {
+			if f == "MESSAGE="+c.message {
+				messages++
+			}
+		}
+		if messages != 1 {
+			t.Errorf("policy %d: message not found: %q", c.policy, fields)
+		}
+
+		for _, f := range c.fields {
+			if !slices.Contains(fields, f) {
+				t.Errorf("policy %d: %s not found: %q", c.policy, f, fields)
+			}
+		}
+	}
+}
+
+func TestReservedFieldsReport(t *testing.T) {
+	var reported []string
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		ReservedFields: RejectReserved,
+		OnViolation: func(err error) {
+			if !errors.Is(err, ErrReservedField) {
+				t.Errorf("error: %v", err)
+			}
+			reported = append(reported, err.Error())
+		},
+	})
+
+	ctx := ContextWithFields(context.Background(), map[string]string{"syslog_facility": "3", "unit": "u"})
+	if err := h.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0)); err != nil {
+		t.Errorf("error: %v", err)
+	}
+
+	m := recv.wait(t, 1)[0]
+	if _, found := m["SYSLOG_FACILITY"]; found {
+		t.Error("rejected context field found")
+	}
+	if s := m["UNIT"]; s != "u" {
+		t.Errorf("unit: %q", s)
+	}
+	if !slices.Equal(reported, []string{"sjournal: reserved field name: SYSLOG_FACILITY"}) {
+		t.Errorf("reported: %q", reported)
+	}
+}
+
+func TestReservedFieldsOptions(t *testing.T) {
+	for _, c := range []struct {
+		policy ReservedPolicy
+		expect []string
+	}{
+		{AllowReserved, []string{"SYSLOG_FACILITY=3", "PRIORITY=5"}},
+		{RenameReserved, []string{"APP_SYSLOG_FACILITY=3", "APP_PRIORITY=5"}},
+	} {
+		h, recv := newTestHandler(t, &HandlerOptions{
+			Fields:         map[string]string{"SYSLOG_FACILITY": "3"},
+			PromoteKeys:    map[string]string{"p": "PRIORITY"},
+			ReservedFields: c.policy,
+			ReservedPrefix: "APP_",
+		})
+
+		slog.New(h).Info("msg", "p", 5)
+
+		recv.wait(t, 1)
+		fields := datagramFields(recv.datagrams()[0])
+		for _, f := range c.expect {
+			if !slices.Contains(fields, f) {
+				t.Errorf("policy %d: %s not found: %q", c.policy, f, fields)
+			}
+		}
+	}
+}
+
+// datagramFields returns the fields of an entry as NAME=value strings.
+func datagramFields(b []byte) (fields []string) {
+	rangeFields(b, func(name string, value []byte) {
+		fields = append(fields, name+"="+string(value))
+	})
+	return
+}
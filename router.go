@@ -0,0 +1,91 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+)
+
+// Level range bounds which include all levels below or above some level.
+const (
+	LevelLowest  = slog.Level(math.MinInt)
+	LevelHighest = slog.Level(math.MaxInt)
+)
+
+// Route of a LevelRouter.  Records with levels between Min and Max (inclusive)
+// are passed to the handler.
+type Route struct {
+	Min     slog.Level
+	Max     slog.Level
+	Handler slog.Handler
+}
+
+func (r *Route) contains(l slog.Level) bool {
+	return l >= r.Min && l <= r.Max
+}
+
+// LevelRouter is a handler which dispatches records to other handlers based
+// on their levels.  A record is passed to every route which contains its
+// level, if the route's handler is enabled for the level.  Records which
+// don't match any routes are discarded.
+type LevelRouter struct {
+	routes []Route
+}
+
+func NewLevelRouter(routes ...Route) *LevelRouter {
+	return &LevelRouter{routes: routes}
+}
+
+// Enabled reports whether any route which contains the level is enabled for
+// it.
+func (x *LevelRouter) Enabled(ctx context.Context, l slog.Level) bool {
+	for i := range x.routes {
+		r := &x.routes[i]
+		if r.contains(l) && r.Handler.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle the record with all matching routes.  Errors are joined.
+func (x *LevelRouter) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+
+	for i := range x.routes {
+		r := &x.routes[i]
+		if r.contains(record.Level) && r.Handler.Enabled(ctx, record.Level) {
+			if err := r.Handler.Handle(ctx, record.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (x *LevelRouter) WithAttrs(as []slog.Attr) slog.Handler {
+	return x.derive(func(h slog.Handler) slog.Handler {
+		return h.WithAttrs(as)
+	})
+}
+
+func (x *LevelRouter) WithGroup(name string) slog.Handler {
+	return x.derive(func(h slog.Handler) slog.Handler {
+		return h.WithGroup(name)
+	})
+}
+
+func (x *LevelRouter) derive(f func(slog.Handler) slog.Handler) *LevelRouter {
+	routes := make([]Route, len(x.routes))
+	for i, r := range x.routes {
+		r.Handler = f(r.Handler)
+		routes[i] = r
+	}
+	return &LevelRouter{routes: routes}
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLevelRouter(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level:     LevelInfo,
+		Delimiter: DefaultDelimiter,
+	})
+
+	var crash bytes.Buffer
+	crashHandler := slog.NewTextHandler(&crash, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+
+	router := NewLevelRouter(
+		Route{Min: LevelLowest, Max: LevelWarn, Handler: h},
+		Route{Min: LevelWarn, Max: LevelHighest, Handler: crashHandler},
+	)
+	logger := slog.New(router.WithGroup("g").WithAttrs([]slog.Attr{slog.Int("x", 1)}))
+
+	if router.Enabled(context.Background(), LevelDebug) {
+		t.Error("debug enabled")
+	}
+	if !router.Enabled(context.Background(), LevelError) {
+		t.Error("error disabled")
+	}
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+	slog.New(router).Error("underived")
+
+	var messages []string
+	for _, m := range recv.wait(t, 2) {
+		messages = append(messages, m["MESSAGE"])
+	}
+	if strings.Join(messages, "|") != "info g.x=1|warn g.x=1" {
+		t.Errorf("journal: %q", messages)
+	}
+
+	if s := crash.String(); s != "level=WARN msg=warn g.x=1\nlevel=ERROR msg=error g.x=1\nlevel=ERROR msg=underived\n" {
+		t.Errorf("crash: %q", s)
+	}
+}
+
+func TestLevelRouterNoMatch(t *testing.T) {
+	var b bytes.Buffer
+	router := NewLevelRouter(Route{Min: LevelError, Max: LevelError, Handler: slog.NewTextHandler(&b, nil)})
+
+	if router.Enabled(context.Background(), LevelWarn) || router.Enabled(context.Background(), LevelCrit) {
+		t.Error("enabled outside range")
+	}
+
+	logger := slog.New(router)
+	logger.Warn("warn")
+	logger.Log(context.Background(), LevelCrit, "crit")
+
+	if b.Len() != 0 {
+		t.Errorf("output: %q", b.String())
+	}
+}
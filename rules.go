@@ -0,0 +1,70 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// LevelRule changes the level of matching records.  All non-empty criteria
+// must match.  A rule without criteria matches all records.
+type LevelRule struct {
+	// Group path of the handler, i.e. the names passed to WithGroup joined
+	// with dots.  It matches the path and its subgroups.
+	Group string
+
+	// MessagePrefix matches the beginning of the record message.
+	MessagePrefix string
+
+	// AttrKey matches an attribute of the record (not including attributes
+	// added using WithAttrs) whose value is AttrValue when formatted as
+	// string.  Attributes within groups are not considered.
+	AttrKey   string
+	AttrValue string
+
+	// Level is the new level of matching records.
+	Level slog.Level
+}
+
+func (rule *LevelRule) match(h *Handler, r slog.Record) bool {
+	if rule.Group != "" {
+		if !strings.HasPrefix(h.groupPath, rule.Group) {
+			return false
+		}
+		if len(h.groupPath) > len(rule.Group) && h.groupPath[len(rule.Group)] != keyComponentSep {
+			return false
+		}
+	}
+
+	if !strings.HasPrefix(r.Message, rule.MessagePrefix) {
+		return false
+	}
+
+	if rule.AttrKey != "" {
+		found := false
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == rule.AttrKey && a.Value.Resolve().String() == rule.AttrValue {
+				found = true
+			}
+			return !found
+		})
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// remapLevel of a record according to the first matching rule.
+func (h *Handler) remapLevel(r slog.Record) slog.Level {
+	for i := range h.levelRules {
+		if rule := &h.levelRules[i]; rule.match(h, r) {
+			return rule.Level
+		}
+	}
+	return r.Level
+}
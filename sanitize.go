@@ -0,0 +1,67 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"unicode/utf8"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// isControl reports if r is a C0 or C1 control character (or DEL) other than
+// tab, or newline if it is kept.
+func isControl(r rune, keepNewline bool) bool {
+	switch {
+	case r == '\t':
+		return false
+	case r == '\n':
+		return !keepNewline
+	default:
+		return r < 0x20 || (r >= 0x7f && r <= 0x9f)
+	}
+}
+
+// hasControl reports if s contains control characters which would be escaped.
+func hasControl(s string, keepNewline bool) bool {
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if isControl(rune(b), keepNewline) {
+				return true
+			}
+		} else if b == 0xc2 && i+1 < len(s) && s[i+1] <= 0x9f {
+			return true // C1 control encoded as UTF-8.
+		}
+	}
+	return false
+}
+
+// appendEscapedControl appends s with control characters replaced by \xHH
+// (C0 and DEL) or \u00HH (C1) escapes.  Invalid UTF-8 is retained as is.
+func appendEscapedControl(b []byte, s string, keepNewline bool) []byte {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !isControl(r, keepNewline) {
+			// RuneError is not a control character, so invalid bytes are
+			// copied verbatim.
+			b = append(b, s[i:i+size]...)
+		} else if r < 0x80 {
+			b = append(b, '\\', 'x', hexDigits[r>>4], hexDigits[r&0xf])
+		} else {
+			b = append(b, '\\', 'u', '0', '0', hexDigits[r>>4], hexDigits[r&0xf])
+		}
+		i += size
+	}
+	return b
+}
+
+// escapeControl returns s with control characters escaped, or s itself if
+// there are none.
+func escapeControl(s string, keepNewline bool) string {
+	if !hasControl(s, keepNewline) {
+		return s
+	}
+	return string(appendEscapedControl(make([]byte, 0, len(s)+8), s, keepNewline))
+}
@@ -0,0 +1,47 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestEscapeControl(t *testing.T) {
+	for _, x := range []struct {
+		input   string
+		message string
+		value   string
+	}{
+		{"plain", "plain", "plain"},
+		{"tab\there", "tab\there", "tab\there"},
+		{"multi\nline", "multi\nline", `multi\x0aline`},
+		{"\x1b[31mred\x1b[0m", `\x1b[31mred\x1b[0m`, `\x1b[31mred\x1b[0m`},
+		{"bare\rcr", `bare\x0dcr`, `bare\x0dcr`},
+		{"nul\x00byte", `nul\x00byte`, `nul\x00byte`},
+		{"del\x7f", `del\x7f`, `del\x7f`},
+		{"c1\u009bcsi", `c1\u009bcsi`, `c1\u009bcsi`},
+		{"ä\xff", "ä\xff", "ä\xff"},
+	} {
+		if s := escapeControl(x.input, true); s != x.message {
+			t.Errorf("%q: message %q", x.input, s)
+		}
+		if s := escapeControl(x.input, false); s != x.value {
+			t.Errorf("%q: value %q", x.input, s)
+		}
+	}
+}
+
+func TestEscapeControlChars(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{EscapeControlChars: true})
+
+	logger := slog.New(h)
+	logger.With("pre", "\x1b[2J").Info("evil\r\x1b[1Amsg\nline", "x", "a\x00b", slog.Group("g", "y", "\rz"))
+
+	expect := `evil\x0d\x1b[1Amsg` + "\n" + `line pre=\x1b[2J x=a\x00b g.y=\x0dz`
+	if s := recv.wait(t, 1)[0]["MESSAGE"]; s != expect {
+		t.Errorf("message: %q", s)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ErrNoMessage is returned by Send and SendFields if there is no MESSAGE
+// field.
+var ErrNoMessage = errors.New("sjournal: MESSAGE field is missing")
+
+// RawField is a journal field with an arbitrary value.
+type RawField struct {
+	Name  string
+	Value []byte
+}
+
+// Send an entry consisting of arbitrary fields, bypassing slog.  See
+// SendFields.
+func (h *Handler) Send(fields map[string]string) error {
+	raw := make([]RawField, 0, len(fields))
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		raw = append(raw, RawField{name, []byte(fields[name])})
+	}
+	return h.SendFields(raw)
+}
+
+// SendFields sends an entry consisting of arbitrary fields, bypassing slog.
+// The fields are sent in order, except that PRIORITY and MESSAGE are placed
+// first.  Field names must be uppercase, and they must not start with an
+// underscore.  MESSAGE is required, and PRIORITY (0-7) defaults to 6 (info).
+// Values are length-encoded if needed.  The entry is sent (or queued in
+// asynchronous mode) like the entries of handled records, but the handler's
+// options which modify entries (such as Fields and Mungers) are not applied.
+func (h *Handler) SendFields(fields []RawField) error {
+	if h.root.closed.Load() {
+		return ErrClosed
+	}
+
+	priority := -1
+	message := -1
+
+	for i, f := range fields {
+		if name, ok := fieldName(f.Name); !ok || name != f.Name {
+			return fmt.Errorf("sjournal: invalid field name: %q", f.Name)
+		}
+
+		switch f.Name {
+		case "PRIORITY":
+			if priority >= 0 {
+				return errors.New("sjournal: duplicate PRIORITY field")
+			}
+			if len(f.Value) != 1 || f.Value[0] < '0' || f.Value[0] >= '0'+numPriorities {
+				return fmt.Errorf("sjournal: invalid priority: %q", f.Value)
+			}
+			priority = i
+		case "MESSAGE":
+			if message >= 0 {
+				return errors.New("sjournal: duplicate MESSAGE field")
+			}
+			message = i
+		}
+	}
+
+	if message < 0 {
+		return ErrNoMessage
+	}
+
+	b := newBuffer()
+	defer b.Free()
+
+	p := 6
+	if priority >= 0 {
+		p = int(fields[priority].Value[0] - '0')
+	}
+	b.WriteString("PRIORITY=")
+	b.WriteByte(byte('0' + p))
+	b.WriteByte('\n')
+	*b = appendField(*b, "MESSAGE", fields[message].Value)
+
+	for i, f := range fields {
+		if i != priority && i != message {
+			*b = appendField(*b, f.Name, f.Value)
+		}
+	}
+
+	if q := h.root.queue; q != nil {
+		e := queueEntry{
+			data:     slices.Clone(*b),
+			keyLen:   b.Len(),
+			priority: p,
+			time:     h.root.clock.Now(),
+		}
+		return q.put(context.Background(), e)
+	}
+
+	return h.root.send(*b)
+}
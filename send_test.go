@@ -0,0 +1,169 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSend(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+
+	if err := h.Send(map[string]string{"MESSAGE": "hello", "FOO": "multi\nline", "BAR": "x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte{0, 1, 2, '\n', 255}
+	if err := h.SendFields([]RawField{{"DATA", data}, {"MESSAGE", []byte("binary")}, {"PRIORITY", []byte("3")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	recv.wait(t, 2)
+
+	for i, expect := range [][]testField{
+		{{"PRIORITY", "6"}, {"MESSAGE", "hello"}, {"BAR", "x"}, {"FOO", "multi\nline"}},
+		{{"PRIORITY", "3"}, {"MESSAGE", "binary"}, {"DATA", string(data)}},
+	} {
+		fields, err := parseProtocolFields(recv.datagrams()[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(fields, expect) {
+			t.Errorf("entry %d: %q", i, fields)
+		}
+	}
+
+	if n := h.Stats().Sent; n != 2 {
+		t.Errorf("sent: %d", n)
+	}
+}
+
+func TestSendInvalid(t *testing.T) {
+	h, _ := newTestHandler(t, nil)
+
+	if err := h.Send(map[string]string{"FOO": "bar"}); !errors.Is(err, ErrNoMessage) {
+		t.Errorf("missing MESSAGE: %v", err)
+	}
+
+	for _, fields := range []map[string]string{
+		{"MESSAGE": "x", "foo": "bar"},
+		{"MESSAGE": "x", "_TRUSTED": "bar"},
+		{"MESSAGE": "x", "": "bar"},
+		{"MESSAGE": "x", "PRIORITY": "8"},
+		{"MESSAGE": "x", "PRIORITY": "info"},
+	} {
+		if err := h.Send(fields); err == nil {
+			t.Errorf("%q: no error", fields)
+		}
+	}
+
+	if err := h.SendFields([]RawField{{"MESSAGE", nil}, {"MESSAGE", nil}}); err == nil {
+		t.Error("duplicate MESSAGE accepted")
+	}
+}
+
+func TestSendLarge(t *testing.T) {
+	if !LargeMessageSupport {
+		t.Skip("large messages not supported")
+	}
+
+	h, recv := newTestHandler(t, nil)
+
+	data := bytes.Repeat([]byte("0123456789"), 50000)
+	if err := h.SendFields([]RawField{{"MESSAGE", []byte("large")}, {"DATA", data}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := recv.wait(t, 1)[0]; m["MESSAGE"] != "large" || m["DATA"] != string(data) {
+		t.Errorf("message %q, data length %d", m["MESSAGE"], len(m["DATA"]))
+	}
+}
+
+func TestOnLargeEntry(t *testing.T) {
+	if !LargeMessageSupport {
+		t.Skip("large messages not supported")
+	}
+
+	for _, queueSize := range []int{0, 10} {
+		var (
+			mu      sync.Mutex
+			sizes   []int
+			records []string
+		)
+
+		h, recv := newTestHandler(t, &HandlerOptions{
+			QueueSize: queueSize,
+			OnLargeEntry: func(size int, r slog.Record) {
+				mu.Lock()
+				defer mu.Unlock()
+				sizes = append(sizes, size)
+				records = append(records, r.Message)
+			},
+			LargeEntryField: true,
+		})
+		if err := h.root.socks[0].SetWriteBuffer(4096); err != nil {
+			t.Fatal(err)
+		}
+
+		large := strings.Repeat("x", 64<<10)
+		logger := slog.New(h)
+		logger.Info("small")
+		logger.Info("large", "data", large)
+
+		ms := recv.wait(t, 2)
+		if _, found := ms[0]["LARGE_ENTRY"]; found {
+			t.Errorf("queue size %d: small entry has LARGE_ENTRY", queueSize)
+		}
+		if s := ms[1]["LARGE_ENTRY"]; s != "1" {
+			t.Errorf("queue size %d: LARGE_ENTRY: %q", queueSize, s)
+		}
+		if s := ms[1]["MESSAGE"]; s != "large data="+large {
+			t.Errorf("queue size %d: message length %d", queueSize, len(s))
+		}
+
+		h.Close()
+
+		if s := h.Stats(); s.LargeEntries != 1 || s.Sent != 2 {
+			t.Errorf("queue size %d: stats: %+v", queueSize, s)
+		}
+
+		mu.Lock()
+		if !slices.Equal(records, []string{"large"}) || sizes[0] <= len(large) || sizes[0] != h.Stats().MaxEntryBytes {
+			t.Errorf("queue size %d: callbacks: %q %v", queueSize, records, sizes)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestOnLargeEntryAllocs(t *testing.T) {
+	allocs := func(opts *HandlerOptions) float64 {
+		h, _ := newTestHandler(t, opts)
+		h.root.sink = func([]byte) error { return nil }
+		logger := slog.New(h)
+		return testing.AllocsPerRun(100, func() {
+			logger.Info("hello", "n", 1)
+		})
+	}
+
+	base := allocs(nil)
+	if n := allocs(&HandlerOptions{OnLargeEntry: func(int, slog.Record) {}}); n != base {
+		t.Errorf("%v allocations with OnLargeEntry, %v without", n, base)
+	}
+}
+
+func TestSendClosed(t *testing.T) {
+	h, _ := newTestHandler(t, nil)
+	h.Close()
+
+	if err := h.Send(map[string]string{"MESSAGE": "x"}); err != ErrClosed {
+		t.Error(err)
+	}
+}
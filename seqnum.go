@@ -0,0 +1,18 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// newSeqnumEpoch returns a random identifier in the same format as journald's
+// boot and machine ids.
+func newSeqnumEpoch() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSequenceNumbers(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Level:           slog.LevelInfo,
+		SequenceNumbers: true,
+	})
+
+	const (
+		numWorkers = 4
+		numRecords = 50
+	)
+
+	var wg sync.WaitGroup
+	for w := range numWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger := slog.New(h).With("worker", w)
+			for i := range numRecords {
+				logger.Info("msg", "i", i)
+				logger.Debug("filtered")
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := numWorkers * numRecords
+	ms := recv.wait(t, total)
+
+	epoch := ms[0]["SEQNUM_EPOCH"]
+	if len(epoch) != 32 {
+		t.Errorf("epoch: %q", epoch)
+	}
+
+	seen := make([]bool, total+1)
+	for _, m := range ms {
+		if m["SEQNUM_EPOCH"] != epoch {
+			t.Errorf("epoch: %q", m["SEQNUM_EPOCH"])
+		}
+		n, err := strconv.Atoi(m["SEQNUM"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n < 1 || n > total {
+			t.Fatalf("seqnum out of range: %d", n)
+		}
+		if seen[n] {
+			t.Errorf("duplicate seqnum: %d", n)
+		}
+		seen[n] = true
+	}
+
+	for n := 1; n <= total; n++ {
+		if !seen[n] {
+			t.Errorf("missing seqnum: %d", n)
+		}
+	}
+
+	h2, recv2 := newTestHandler(t, &HandlerOptions{SequenceNumbers: true})
+	slog.New(h2).Info("msg")
+	if m := recv2.wait(t, 1)[0]; m["SEQNUM"] != "1" || m["SEQNUM_EPOCH"] == epoch {
+		t.Errorf("second handler: %q %q", m["SEQNUM"], m["SEQNUM_EPOCH"])
+	}
+}
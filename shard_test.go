@@ -0,0 +1,128 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSendSockets(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{SendSockets: 4})
+	if n := len(h.root.socks); n != 4 {
+		t.Fatalf("%d sockets", n)
+	}
+	if s := h.String(); !strings.Contains(s, " sockets=4 ") {
+		t.Error(s)
+	}
+
+	logger := slog.New(h)
+
+	const (
+		goroutines = 8
+		perRoutine = 25
+	)
+
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perRoutine {
+				logger.Info("entry", "g", g, "i", i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	ms := recv.wait(t, goroutines*perRoutine)
+
+	seen := make(map[string]bool)
+	for _, m := range ms {
+		if !strings.HasPrefix(m["MESSAGE"], "entry") || m["PRIORITY"] != "6" || m["CODE_FILE"] == "" {
+			t.Errorf("entry: %q", m)
+		}
+		if seen[m["MESSAGE"]] {
+			t.Errorf("duplicate: %q", m["MESSAGE"])
+		}
+		seen[m["MESSAGE"]] = true
+	}
+	for g := range goroutines {
+		for i := range perRoutine {
+			if !seen[fmt.Sprintf("entry g=%d i=%d", g, i)] {
+				t.Errorf("missing g=%d i=%d", g, i)
+			}
+		}
+	}
+
+	if s := h.Stats(); s.Sent != goroutines*perRoutine {
+		t.Errorf("sent %d", s.Sent)
+	}
+
+	socks := h.root.socks
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i, sock := range socks {
+		if _, err := sock.Write(nil); err == nil {
+			t.Errorf("socket %d not closed", i)
+		}
+	}
+}
+
+func TestSendSocketsInvalid(t *testing.T) {
+	if _, err := NewHandler(&HandlerOptions{SendSockets: -1}); err == nil {
+		t.Error("no error")
+	}
+}
+
+// BenchmarkSendSockets compares socket counts with many concurrent loggers.
+// Contention only shows up on a multi-core host.
+func BenchmarkSendSockets(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram", Name: path})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadBuffer(8 << 20)
+
+	// Multiple readers so that the receiving end isn't the bottleneck.
+	for range 4 {
+		go func() {
+			buf := make([]byte, 65536)
+			for {
+				if _, err := conn.Read(buf); err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	for _, n := range []int{1, 4, 16} {
+		b.Run(fmt.Sprint("sockets=", n), func(b *testing.B) {
+			h, err := NewHandler(&HandlerOptions{Socket: path, SendSockets: n})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer h.Close()
+			logger := slog.New(h)
+
+			b.SetParallelism(16)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					logger.Info("benchmark", "key", "value")
+				}
+			})
+		})
+	}
+}
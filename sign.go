@@ -0,0 +1,116 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const (
+	signatureField      = "SIGNATURE"
+	signatureKeyIDField = "SIGNATURE_KEY_ID"
+)
+
+// ErrInvalidSignature is returned by VerifyEntry if the SIGNATURE field is
+// missing or doesn't match the other fields.
+var ErrInvalidSignature = errors.New("sjournal: invalid signature")
+
+// VerifyEntry checks the SIGNATURE field of an entry signed using the
+// SignatureKey option.  The fields can be obtained from Decoder.NextValues or
+// from the JSON output of journalctl (with the values converted to bytes).
+// The key corresponding to the SIGNATURE_KEY_ID field must be used.
+//
+// The signature is the hex-encoded HMAC-SHA256 of the canonical
+// serialization of the entry: the fields sorted by name, each encoded as the
+// name, a newline, the value length as a little-endian 64-bit integer, the
+// value and a newline.  The values of a repeated field are encoded in the
+// order in which they appear in the entry.  SIGNATURE, fields added by
+// journald (names starting with an underscore) and fields added when sending
+// (REPEATS and LARGE_ENTRY) are excluded.  Chunked entries (see
+// ChunkMessages) can't be verified.
+func VerifyEntry(fields map[string][][]byte, key []byte) error {
+	values := fields[signatureField]
+	if len(values) != 1 {
+		return ErrInvalidSignature
+	}
+	signature, err := hex.DecodeString(string(values[0]))
+	if err != nil || len(signature) != sha256.Size {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, key)
+	writeCanonical(mac, fields)
+	if !hmac.Equal(mac.Sum(nil), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signed reports whether a field is included in the canonical serialization.
+func signed(name string) bool {
+	switch name {
+	case signatureField, "REPEATS", "LARGE_ENTRY":
+		return false
+	}
+	return !strings.HasPrefix(name, "_")
+}
+
+func writeCanonical(h hash.Hash, fields map[string][][]byte) {
+	var size [8]byte
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if !signed(name) {
+			continue
+		}
+		for _, value := range fields[name] {
+			binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+			h.Write([]byte(name))
+			h.Write([]byte{'\n'})
+			h.Write(size[:])
+			h.Write(value)
+			h.Write([]byte{'\n'})
+		}
+	}
+}
+
+// signer appends signature fields to entries (see SignatureKey).
+type signer struct {
+	key   []byte
+	keyID string
+}
+
+// newSigner returns nil unless SignatureKey is set.
+func newSigner(opts *HandlerOptions) *signer {
+	if len(opts.SignatureKey) == 0 {
+		return nil
+	}
+	return &signer{slices.Clone(opts.SignatureKey), opts.SignatureKeyID}
+}
+
+// sign appends the SIGNATURE_KEY_ID (if any) and SIGNATURE fields.
+func (s *signer) sign(b []byte) []byte {
+	if s.keyID != "" {
+		b = appendField(b, signatureKeyIDField, s.keyID)
+	}
+
+	fields := make(map[string][][]byte)
+	rangeFields(b, func(name string, value []byte) {
+		fields[name] = append(fields[name], value)
+	})
+
+	mac := hmac.New(sha256.New, s.key)
+	writeCanonical(mac, fields)
+
+	b = append(b, signatureField+"="...)
+	b = hex.AppendEncode(b, mac.Sum(nil))
+	return append(b, '\n')
+}
@@ -0,0 +1,159 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func signatureOf(b []byte) (signature string) {
+	rangeFields(b, func(name string, value []byte) {
+		if name == signatureField {
+			signature = string(value)
+		}
+	})
+	return
+}
+
+func entryValues(b []byte) map[string][][]byte {
+	fields := make(map[string][][]byte)
+	rangeFields(b, func(name string, value []byte) {
+		fields[name] = append(fields[name], value)
+	})
+	return fields
+}
+
+func TestSignCanonical(t *testing.T) {
+	s := &signer{key: []byte("secret"), keyID: "k1"}
+
+	a := s.sign([]byte("PRIORITY=6\nMESSAGE=hello\nA=1\nB=x\n"))
+	b := s.sign([]byte("PRIORITY=6\nB=x\nA=1\nMESSAGE\n\x05\x00\x00\x00\x00\x00\x00\x00hello\n"))
+	if signatureOf(a) == "" || signatureOf(a) != signatureOf(b) {
+		t.Errorf("signatures differ: %q %q", signatureOf(a), signatureOf(b))
+	}
+
+	c := s.sign([]byte("PRIORITY=6\nMESSAGE=hello\nA=1\nB=y\n"))
+	if signatureOf(a) == signatureOf(c) {
+		t.Error("signature doesn't depend on values")
+	}
+
+	d := (&signer{key: []byte("secret"), keyID: "k2"}).sign([]byte("PRIORITY=6\nMESSAGE=hello\nA=1\nB=x\n"))
+	if signatureOf(a) == signatureOf(d) {
+		t.Error("signature doesn't depend on key ID")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	key := []byte("secret")
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		SignatureKey:   key,
+		SignatureKeyID: "2026-10",
+	})
+
+	slog.New(h).Info("hello", "data", "multi\nline")
+	recv.wait(t, 1)
+
+	fields := entryValues(recv.datagrams()[0])
+	if s := string(fields[signatureKeyIDField][0]); s != "2026-10" {
+		t.Errorf("key ID: %q", s)
+	}
+
+	// Fields added by journald and the queue.
+	fields["_PID"] = [][]byte{[]byte("1234")}
+	fields["__CURSOR"] = [][]byte{[]byte("s=abc")}
+	fields["REPEATS"] = [][]byte{[]byte("2")}
+
+	if err := VerifyEntry(fields, key); err != nil {
+		t.Error(err)
+	}
+	if err := VerifyEntry(fields, []byte("other")); err != ErrInvalidSignature {
+		t.Errorf("wrong key: %v", err)
+	}
+
+	modified := maps.Clone(fields)
+	modified["MESSAGE"] = [][]byte{append(bytes.Clone(fields["MESSAGE"][0]), '!')}
+	if err := VerifyEntry(modified, key); err != ErrInvalidSignature {
+		t.Errorf("modified message: %v", err)
+	}
+
+	modified["MESSAGE"] = fields["MESSAGE"]
+	modified[signatureKeyIDField] = [][]byte{[]byte("other")}
+	if err := VerifyEntry(modified, key); err != ErrInvalidSignature {
+		t.Errorf("modified key ID: %v", err)
+	}
+
+	delete(modified, signatureField)
+	if err := VerifyEntry(modified, key); err != ErrInvalidSignature {
+		t.Errorf("missing signature: %v", err)
+	}
+}
+
+func TestSignVerifyExport(t *testing.T) {
+	key := []byte("secret")
+
+	h, recv := newTestHandler(t, &HandlerOptions{SignatureKey: key})
+	slog.New(h).Warn("exported", "n", 1)
+	recv.wait(t, 1)
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(recv.datagrams()[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := NewDecoder(&buf).NextValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := fields[signatureKeyIDField]; found {
+		t.Error("key ID field without key ID")
+	}
+	if err := VerifyEntry(fields, key); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSignRepeated(t *testing.T) {
+	key := []byte("secret")
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		SignatureKey: key,
+	})
+
+	slog.New(h).Info("hello", Field("tag", "first"), Field("tag", "second"))
+	recv.wait(t, 1)
+
+	fields := entryValues(recv.datagrams()[0])
+	if n := len(fields["TAG"]); n != 2 {
+		t.Fatalf("%d TAG values", n)
+	}
+	if err := VerifyEntry(fields, key); err != nil {
+		t.Error(err)
+	}
+
+	for i := range fields["TAG"] {
+		modified := maps.Clone(fields)
+		modified["TAG"] = slices.Clone(fields["TAG"])
+		modified["TAG"][i] = []byte("changed")
+		if err := VerifyEntry(modified, key); err != ErrInvalidSignature {
+			t.Errorf("modified value %d: %v", i, err)
+		}
+	}
+
+	modified := maps.Clone(fields)
+	modified["TAG"] = [][]byte{fields["TAG"][1], fields["TAG"][0]}
+	if err := VerifyEntry(modified, key); err != ErrInvalidSignature {
+		t.Errorf("reordered values: %v", err)
+	}
+
+	modified["TAG"] = fields["TAG"][1:]
+	if err := VerifyEntry(modified, key); err != ErrInvalidSignature {
+		t.Errorf("removed value: %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+module import.name/sjournal/sjournallogr
+
+go 1.23
+
+require (
+	github.com/go-logr/logr v1.4.2
+	import.name/sjournal v0.0.0-20261014071621-eea7f08d6e64
+)
+
+require golang.org/x/sys v0.26.0 // indirect
@@ -0,0 +1,154 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sjournallogr implements a logr sink which sends entries to journald
+// using an sjournal handler.  It's a separate module so that programs which
+// don't use logr don't depend on it.
+package sjournallogr
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"github.com/go-logr/logr"
+	"import.name/sjournal"
+)
+
+// Placeholders used for malformed key-value lists, like logr's funcr.
+const (
+	noValue         = "<no-value>"
+	nonStringKeyFmt = "<non-string-key: %v>"
+)
+
+// Option of NewSink.
+type Option func(*sink)
+
+// VLevel sets the function which maps V-levels to slog levels.  By default
+// V(0) is LevelInfo, V(1) is LevelDebug, and higher levels are below
+// LevelDebug (the journald priority is debug for all of them).
+func VLevel(f func(v int) slog.Level) Option {
+	return func(s *sink) { s.vlevel = f }
+}
+
+// NameField sets the journal field which holds the logger name (the names
+// passed to WithName joined with slashes).  The default is "COMPONENT".
+func NameField(name string) Option {
+	return func(s *sink) { s.nameField = name }
+}
+
+func defaultVLevel(v int) slog.Level {
+	if v <= 0 {
+		return sjournal.LevelInfo
+	}
+	return sjournal.LevelDebug - slog.Level(v-1)
+}
+
+type sink struct {
+	h         *sjournal.Handler
+	vlevel    func(int) slog.Level
+	nameField string
+	name      string
+	callDepth int
+}
+
+var (
+	_ logr.LogSink          = (*sink)(nil)
+	_ logr.CallDepthLogSink = (*sink)(nil)
+)
+
+// NewSink returns a logr.LogSink which logs via the handler.  Error messages
+// are logged at LevelError, with the error as sjournal.Error attribute.
+// WithValues uses WithAttrs.
+func NewSink(h *sjournal.Handler, opts ...Option) logr.LogSink {
+	s := &sink{
+		h:         h,
+		vlevel:    defaultVLevel,
+		nameField: "COMPONENT",
+	}
+	for _, f := range opts {
+		f(s)
+	}
+	return s
+}
+
+func (s *sink) Init(info logr.RuntimeInfo) {
+	s.callDepth = info.CallDepth
+}
+
+func (s *sink) Enabled(v int) bool {
+	return s.h.Enabled(context.Background(), s.vlevel(v))
+}
+
+func (s *sink) Info(v int, msg string, keysAndValues ...any) {
+	s.log(s.vlevel(v), nil, msg, keysAndValues)
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...any) {
+	s.log(sjournal.LevelError, err, msg, keysAndValues)
+}
+
+func (s *sink) log(level slog.Level, err error, msg string, keysAndValues []any) {
+	var pcs [1]uintptr
+	// Skip runtime.Callers, this function, Info/Error and logr.Logger method.
+	runtime.Callers(3+s.callDepth, pcs[:])
+
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	if err != nil {
+		r.AddAttrs(sjournal.Error(err))
+	}
+	r.AddAttrs(attrs(keysAndValues)...)
+
+	s.h.Handle(context.Background(), r)
+}
+
+func (s *sink) WithValues(keysAndValues ...any) logr.LogSink {
+	s2 := *s
+	s2.h = s.h.WithAttrs(attrs(keysAndValues)).(*sjournal.Handler)
+	return &s2
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	s2 := *s
+	if s2.name != "" {
+		s2.name += "/"
+	}
+	s2.name += name
+	s2.h = s.h.WithFields(map[string]string{s.nameField: s2.name})
+	return &s2
+}
+
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	s2 := *s
+	s2.callDepth += depth
+	return &s2
+}
+
+// attrs converts a logr key-value list.  A missing final value is replaced
+// with a placeholder, and so are non-string keys.  Values implementing
+// logr.Marshaler are replaced with the result of MarshalLog.
+func attrs(keysAndValues []any) []slog.Attr {
+	as := make([]slog.Attr, 0, (len(keysAndValues)+1)/2)
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprintf(nonStringKeyFmt, keysAndValues[i])
+		}
+
+		var value any = noValue
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		if m, ok := value.(logr.Marshaler); ok {
+			value = m.MarshalLog()
+		}
+
+		as = append(as, slog.Any(key, value))
+	}
+
+	return as
+}
@@ -0,0 +1,102 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournallogr
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"import.name/sjournal"
+	"import.name/sjournal/sjournaltest"
+)
+
+func newTestLogger(t *testing.T, opts *sjournal.HandlerOptions, sinkOpts ...Option) (logr.Logger, func(n int) []sjournaltest.Entry) {
+	t.Helper()
+
+	sock := sjournaltest.NewSocket(t)
+
+	opts.Socket = sock.Path
+	opts.Delimiter = sjournal.DefaultDelimiter
+	h, err := sjournal.NewHandler(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+
+	return logr.New(NewSink(h, sinkOpts...)), sock.Receive
+}
+
+type secret string
+
+func (secret) MarshalLog() any { return "***" }
+
+func TestSink(t *testing.T) {
+	logger, receive := newTestLogger(t, &sjournal.HandlerOptions{Level: sjournal.LevelDebug})
+
+	ctrl := logger.WithName("controller").WithValues("kind", "Pod")
+	ctrl.Info("reconciling", "name", "web", "token", secret("x"))
+	ctrl.WithName("cache").V(1).Info("miss")
+	ctrl.V(2).Info("hidden")
+	ctrl.Error(errors.New("boom"), "failed", "retry", 3)
+	logger.Info("malformed", 1, 2, "odd")
+
+	entries := receive(4)
+
+	for i, c := range []struct {
+		priority  string
+		message   string
+		component string
+	}{
+		{"6", "reconciling kind=Pod name=web token=***", "controller"},
+		{"7", "miss kind=Pod", "controller/cache"},
+		{"3", "failed kind=Pod err=boom retry=3", "controller"},
+		{"6", `malformed "<non-string-key: 1>"=2 odd=<no-value>`, ""},
+	} {
+		e := entries[i]
+		if e.Get("PRIORITY") != c.priority || e.Get("MESSAGE") != c.message || e.Get("COMPONENT") != c.component {
+			t.Errorf("entry %d: PRIORITY=%s MESSAGE=%q COMPONENT=%q", i, e.Get("PRIORITY"), e.Get("MESSAGE"), e.Get("COMPONENT"))
+		}
+		if e.Get("CODE_FUNC") != "import.name/sjournal/sjournallogr.TestSink" {
+			t.Errorf("entry %d: CODE_FUNC=%s", i, e.Get("CODE_FUNC"))
+		}
+	}
+
+	if e := entries[2]; e.Get("ERROR") != "boom" || e.Get("ERROR_TYPE") != "*errors.errorString" {
+		t.Errorf("error fields: %q", e)
+	}
+}
+
+func TestSinkOptions(t *testing.T) {
+	logger, receive := newTestLogger(t, &sjournal.HandlerOptions{Level: sjournal.LevelInfo},
+		VLevel(func(v int) slog.Level { return sjournal.LevelWarn - slog.Level(v) }),
+		NameField("SUBSYSTEM"),
+	)
+
+	if !logger.V(4).Enabled() || logger.V(5).Enabled() {
+		t.Error("V-level mapping")
+	}
+
+	logger.WithName("x").V(4).Info("msg")
+
+	if e := receive(1)[0]; e.Get("PRIORITY") != "6" || e.Get("SUBSYSTEM") != "x" {
+		t.Errorf("entry: %q", e)
+	}
+}
+
+func helper(logger logr.Logger) {
+	logger.WithCallDepth(1).Info("helped")
+}
+
+func TestSinkCallDepth(t *testing.T) {
+	logger, receive := newTestLogger(t, &sjournal.HandlerOptions{})
+
+	helper(logger)
+
+	if e := receive(1)[0]; e.Get("CODE_FUNC") != "import.name/sjournal/sjournallogr.TestSinkCallDepth" {
+		t.Errorf("CODE_FUNC=%s", e.Get("CODE_FUNC"))
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournaltest
+
+import (
+	"sync"
+	"time"
+
+	"import.name/sjournal"
+)
+
+// Clock is a fake sjournal.Clock which advances only when told to.
+type Clock struct {
+	mu   sync.Mutex
+	now  time.Time
+	mono time.Duration
+}
+
+var _ sjournal.Clock = (*Clock)(nil)
+
+// NewClock returns a clock which is stopped at the given time.  Its monotonic
+// reading starts at zero.
+func NewClock(t time.Time) *Clock {
+	return &Clock{now: t}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) Monotonic() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mono
+}
+
+// Advance both the wall clock and the monotonic reading.  Negative durations
+// are ignored.
+func (c *Clock) Advance(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.mono += d
+}
+
+// Set the wall clock time without affecting the monotonic reading, like a
+// system clock adjustment.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
@@ -0,0 +1,91 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournaltest
+
+import (
+	"bytes"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"import.name/sjournal"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Unix(1800000000, 0)
+	clock := NewClock(start)
+
+	clock.Advance(time.Hour)
+	clock.Advance(-time.Minute)
+	clock.Set(start.Add(-time.Hour))
+
+	if tm := clock.Now(); !tm.Equal(start.Add(-time.Hour)) {
+		t.Errorf("Now: %v", tm)
+	}
+	if d := clock.Monotonic(); d != time.Hour {
+		t.Errorf("Monotonic: %v", d)
+	}
+}
+
+func TestClockHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	sock, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram", Name: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sock.Close()
+
+	clock := NewClock(time.Unix(1800000000, 0))
+	clock.Advance(100 * time.Hour)
+
+	h, err := sjournal.NewHandler(&sjournal.HandlerOptions{
+		Socket:        path,
+		Clock:         clock,
+		MonotonicTime: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	w := sjournal.NewWriter(h, nil)
+	for range 2 {
+		if _, err := w.Write([]byte("line\n")); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(90 * time.Second)
+	}
+
+	for i, expect := range []struct {
+		timestamp int64
+		monotonic int64
+	}{
+		{1800000000 + 100*3600, 100 * 3600e6},
+		{1800000000 + 100*3600 + 90, 100*3600e6 + 90e6},
+	} {
+		buf := make([]byte, 4096)
+		sock.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := sock.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fields := make(map[string]string)
+		for _, line := range bytes.Split(bytes.TrimSuffix(buf[:n], []byte("\n")), []byte("\n")) {
+			if key, value, found := bytes.Cut(line, []byte("=")); found {
+				fields[string(key)] = string(value)
+			}
+		}
+
+		if s := fields["SYSLOG_TIMESTAMP"]; s != strconv.FormatInt(expect.timestamp, 10) {
+			t.Errorf("entry %d: SYSLOG_TIMESTAMP=%s", i, s)
+		}
+		if s := fields["MONOTONIC_USEC"]; s != strconv.FormatInt(expect.monotonic, 10) {
+			t.Errorf("entry %d: MONOTONIC_USEC=%s", i, s)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournaltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"import.name/sjournal"
+)
+
+const (
+	corpusSize       = 200
+	corpusMaxDepth   = 3
+	oversizedLen     = 300 << 10
+	conformanceLimit = 10 * time.Second
+)
+
+// Case is a record of the conformance corpus.
+type Case struct {
+	Level   slog.Level
+	Message string
+	Attrs   []slog.Attr
+
+	expect []expectedAttr
+}
+
+// Record returns a new record for the case.
+func (c *Case) Record(t time.Time) slog.Record {
+	r := slog.NewRecord(t, c.Level, c.Message, 0)
+	r.AddAttrs(c.Attrs...)
+	return r
+}
+
+// expectedAttr describes how an attribute can be recovered from an entry:
+// either from the message (key=value), or from a field whose name ends with
+// the key's last component in upper case.
+type expectedAttr struct {
+	key    string   // Qualified by group names joined with dots.
+	name   string   // Upper-case last component of key.
+	values []string // Acceptable renderings.
+}
+
+// Corpus generates records for conformance testing.  The same seed produces
+// the same corpus.  It contains all value kinds, nested and inlined groups,
+// LogValuers, Unicode and control characters, sjournal.Binary attributes and
+// oversized values.
+func Corpus(seed uint64) []Case {
+	g := &corpusGen{rand: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))}
+
+	var cases []Case
+
+	// Oversized message and attribute.
+	cases = append(cases, g.newCase(strings.Repeat("large message ", oversizedLen/14), nil))
+	big := strings.Repeat("0123456789abcdef", oversizedLen/16)
+	cases = append(cases, g.newCase("large attribute", func(c *Case) {
+		c.Attrs = append(c.Attrs, g.attr(c, "", "big", slog.StringValue(big)))
+	}))
+
+	for range corpusSize {
+		cases = append(cases, g.newCase(g.message(), func(c *Case) {
+			for range g.rand.IntN(6) {
+				c.Attrs = append(c.Attrs, g.randomAttr(c, "", 0))
+			}
+		}))
+	}
+	return cases
+}
+
+type corpusGen struct {
+	rand *rand.Rand
+	n    int // Next case number.
+	keys int // Next key number.
+}
+
+func (g *corpusGen) newCase(message string, init func(*Case)) Case {
+	levels := []slog.Level{
+		slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError,
+		sjournal.LevelNotice, sjournal.LevelCrit, sjournal.LevelAlert,
+		sjournal.LevelAlert + 100,
+	}
+
+	c := Case{
+		Level:   levels[g.rand.IntN(len(levels))],
+		Message: fmt.Sprintf("case %d: %s", g.n, message),
+	}
+	g.n++
+	if init != nil {
+		init(&c)
+	}
+	return c
+}
+
+var corpusStrings = []string{
+	"",
+	"plain",
+	"with space",
+	"quote \" and backslash \\",
+	"key=value",
+	"line\nbreak",
+	"tab\there",
+	"nul\x00byte",
+	"escape\x1b[1m",
+	"äöå € 日本語",
+	"emoji 😀",
+	" separator",
+}
+
+func (g *corpusGen) message() string {
+	s := corpusStrings[g.rand.IntN(len(corpusStrings))]
+	if g.rand.IntN(4) == 0 {
+		s += "\nsecond line"
+	}
+	return s
+}
+
+func (g *corpusGen) key() string {
+	k := "k" + strconv.Itoa(g.keys)
+	g.keys++
+	return k
+}
+
+// randomAttr generates an attribute and records its expectations.
+func (g *corpusGen) randomAttr(c *Case, prefix string, depth int) slog.Attr {
+	key := g.key()
+
+	switch n := g.rand.IntN(16); {
+	case n == 0 && depth < corpusMaxDepth:
+		var as []slog.Attr
+		for range 1 + g.rand.IntN(3) {
+			as = append(as, g.randomAttr(c, prefix+key+".", depth+1))
+		}
+		return slog.Attr{Key: key, Value: slog.GroupValue(as...)}
+
+	case n == 1 && depth < corpusMaxDepth:
+		// Inlined group.
+		var as []slog.Attr
+		for range 1 + g.rand.IntN(3) {
+			as = append(as, g.randomAttr(c, prefix, depth+1))
+		}
+		return slog.Attr{Key: "", Value: slog.GroupValue(as...)}
+
+	case n == 2:
+		data := make([]byte, g.rand.IntN(64))
+		for i := range data {
+			data[i] = byte(g.rand.Uint32())
+		}
+		name := strings.ToUpper(key)
+		c.expect = append(c.expect, expectedAttr{
+			key:    prefix + name,
+			name:   name,
+			values: []string{string(data), base64.StdEncoding.EncodeToString(data)},
+		})
+		return sjournal.Binary(name, data)
+
+	default:
+		return g.attr(c, prefix, key, g.scalar())
+	}
+}
+
+// scalar generates a value which is not a group.
+func (g *corpusGen) scalar() slog.Value {
+	switch g.rand.IntN(11) {
+	case 0:
+		return slog.StringValue(corpusStrings[g.rand.IntN(len(corpusStrings))])
+	case 1:
+		return slog.Int64Value(int64(g.rand.Uint64()))
+	case 2:
+		return slog.Uint64Value(g.rand.Uint64())
+	case 3:
+		floats := []float64{0, -1.5, 0.1, 1e21, math.MaxFloat64, math.Inf(1), math.NaN()}
+		return slog.Float64Value(floats[g.rand.IntN(len(floats))])
+	case 4:
+		return slog.BoolValue(g.rand.IntN(2) == 0)
+	case 5:
+		return slog.DurationValue(time.Duration(g.rand.Int64N(int64(100 * time.Hour))))
+	case 6:
+		return slog.TimeValue(time.Unix(g.rand.Int64N(4e9), g.rand.Int64N(1e9)).UTC())
+	case 7:
+		return slog.AnyValue([]int{1, 2, 3})
+	case 8:
+		return slog.AnyValue(errors.New("error value"))
+	case 9:
+		return slog.AnyValue(corpusValuer(corpusStrings[g.rand.IntN(len(corpusStrings))]))
+	default:
+		return slog.IntValue(g.rand.IntN(1000))
+	}
+}
+
+type corpusValuer string
+
+func (v corpusValuer) LogValue() slog.Value {
+	return slog.StringValue("valuer " + string(v))
+}
+
+func (g *corpusGen) attr(c *Case, prefix, key string, v slog.Value) slog.Attr {
+	s := v.Resolve().String()
+	values := []string{s, strconv.Quote(s), strconv.QuoteToASCII(s)}
+	if v.Kind() == slog.KindTime {
+		t := v.Time()
+		values = append(values, t.Format(time.RFC3339), t.Format(time.RFC3339Nano))
+	}
+	if v.Kind() == slog.KindDuration {
+		values = append(values, strconv.FormatInt(int64(v.Duration()), 10))
+	}
+
+	c.expect = append(c.expect, expectedAttr{
+		key:    prefix + key,
+		name:   strings.ToUpper(key),
+		values: values,
+	})
+	return slog.Attr{Key: key, Value: v}
+}
+
+// TestHandlerOutput logs the Corpus using the handler, and checks the entries
+// returned by results.  It's called repeatedly until it returns an entry for
+// each record, or a timeout.  The entries must be in the same order as the
+// records, and they must satisfy these invariants:
+//
+//   - MESSAGE and PRIORITY fields are present, and appear only once.
+//   - PRIORITY is a number between 0 and 7.
+//   - MESSAGE begins with the record's message, intact.
+//   - Each attribute can be recovered either from MESSAGE (as key=value, with
+//     keys qualified by group names joined with dots, and values possibly
+//     quoted), or from a field whose name ends with the upper-case key.
+func TestHandlerOutput(t *testing.T, h slog.Handler, results func() []Entry) {
+	t.Helper()
+
+	cases := Corpus(1)
+
+	now := time.Now()
+	for i := range cases {
+		if err := h.Handle(context.Background(), cases[i].Record(now)); err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+	}
+
+	var entries []Entry
+	for deadline := time.Now().Add(conformanceLimit); ; time.Sleep(pollInterval / 10) {
+		if entries = results(); len(entries) >= len(cases) || time.Now().After(deadline) {
+			break
+		}
+	}
+	if len(entries) != len(cases) {
+		t.Fatalf("%d entries for %d records", len(entries), len(cases))
+	}
+
+	for i, e := range entries {
+		if err := cases[i].check(e); err != nil {
+			t.Errorf("case %d: %v", i, err)
+		}
+	}
+}
+
+func (c *Case) check(e Entry) error {
+	for _, name := range []string{"MESSAGE", "PRIORITY"} {
+		if n := len(e[name]); n != 1 {
+			return fmt.Errorf("%d %s fields", n, name)
+		}
+	}
+
+	if p, err := strconv.Atoi(e.Get("PRIORITY")); err != nil || p < 0 || p > 7 {
+		return fmt.Errorf("invalid PRIORITY: %q", e.Get("PRIORITY"))
+	}
+
+	msg := e.Get("MESSAGE")
+	if !strings.HasPrefix(msg, c.Message) {
+		return fmt.Errorf("MESSAGE doesn't begin with the record message: %q", truncate(msg))
+	}
+
+	for _, x := range c.expect {
+		if !x.recoverable(e, msg[len(c.Message):]) {
+			return fmt.Errorf("attribute %s not found: %q", x.key, truncate(msg))
+		}
+	}
+	return nil
+}
+
+func (x *expectedAttr) recoverable(e Entry, attrs string) bool {
+	for _, v := range x.values {
+		if containsAttr(attrs, x.key+"="+v) {
+			return true
+		}
+	}
+
+	for name, values := range e {
+		if !strings.HasSuffix(name, x.name) {
+			continue
+		}
+		for _, value := range values {
+			for _, v := range x.values {
+				if value == v {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// containsAttr reports whether s contains the text followed by a space or the
+// end of the string.
+func containsAttr(s, text string) bool {
+	for {
+		i := strings.Index(s, text)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(text):]
+		if s == "" || s[0] == ' ' {
+			return true
+		}
+	}
+}
+
+func truncate(s string) string {
+	if len(s) > 200 {
+		return s[:200] + "..."
+	}
+	return s
+}
+
+// TestTransport checks a transport using TestHandlerOutput.  The factory
+// creates the transport, and a function which returns the entries which the
+// transport has delivered so far.  The transport is used by an
+// sjournal.Handler.
+func TestTransport(t *testing.T, factory func(t *testing.T) (sjournal.Transport, func() []Entry)) {
+	t.Helper()
+
+	tr, results := factory(t)
+
+	h, err := sjournal.NewHandler(&sjournal.HandlerOptions{
+		Delimiter: sjournal.DefaultDelimiter,
+		Transport: tr,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	TestHandlerOutput(t, h, results)
+}
+
+// ParsePayload decodes an entry in the journald native protocol format, which
+// is passed to sjournal.Transport implementations.
+func ParsePayload(b []byte) (Entry, error) {
+	e := make(Entry)
+
+	for len(b) > 0 {
+		i := bytes.IndexAny(b, "=\n")
+		if i <= 0 {
+			return nil, errors.New("sjournaltest: invalid field name")
+		}
+		name := string(b[:i])
+
+		var value []byte
+		if b[i] == '=' {
+			b = b[i+1:]
+			j := bytes.IndexByte(b, '\n')
+			if j < 0 {
+				return nil, fmt.Errorf("sjournaltest: unterminated field %s", name)
+			}
+			value = b[:j]
+			b = b[j+1:]
+		} else {
+			b = b[i+1:]
+			if len(b) < 8 {
+				return nil, fmt.Errorf("sjournaltest: truncated field %s", name)
+			}
+			n := binary.LittleEndian.Uint64(b)
+			b = b[8:]
+			if n >= uint64(len(b)) || b[n] != '\n' {
+				return nil, fmt.Errorf("sjournaltest: invalid length of field %s", name)
+			}
+			value = b[:n]
+			b = b[n+1:]
+		}
+
+		e[name] = append(e[name], string(value))
+	}
+
+	return e, nil
+}
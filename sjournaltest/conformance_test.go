@@ -0,0 +1,344 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournaltest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"import.name/sjournal"
+)
+
+func TestCorpus(t *testing.T) {
+	a := Corpus(1)
+	b := Corpus(1)
+	if len(a) != len(b) {
+		t.Fatalf("%d and %d cases", len(a), len(b))
+	}
+
+	kinds := make(map[slog.Kind]bool)
+	var walk func([]slog.Attr)
+	walk = func(as []slog.Attr) {
+		for _, a := range as {
+			kinds[a.Value.Kind()] = true
+			if a.Value.Kind() == slog.KindGroup {
+				walk(a.Value.Group())
+			}
+		}
+	}
+
+	for i := range a {
+		if a[i].Message != b[i].Message || a[i].Level != b[i].Level || len(a[i].Attrs) != len(b[i].Attrs) {
+			t.Errorf("case %d differs", i)
+		}
+		walk(a[i].Attrs)
+	}
+
+	for k := slog.KindAny; k <= slog.KindLogValuer; k++ {
+		if !kinds[k] {
+			t.Errorf("no %v values", k)
+		}
+	}
+}
+
+func TestParsePayload(t *testing.T) {
+	e, err := ParsePayload([]byte("MESSAGE=hello\nDATA\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\nMESSAGE=again\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values := e["MESSAGE"]; !slices.Equal(values, []string{"hello", "again"}) {
+		t.Errorf("MESSAGE: %q", values)
+	}
+	if s := e.Get("DATA"); s != "a\nb" {
+		t.Errorf("DATA: %q", s)
+	}
+
+	for _, bad := range []string{"=x\n", "X=y", "X\n\x01\x00", "X\n\x05\x00\x00\x00\x00\x00\x00\x00ab\n"} {
+		if _, err := ParsePayload([]byte(bad)); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
+
+func TestCaseCheck(t *testing.T) {
+	c := Case{Message: "hello"}
+	g := &corpusGen{}
+	c.Attrs = append(c.Attrs, g.attr(&c, "grp.", "key", slog.StringValue("a b")))
+
+	for _, x := range []struct {
+		e  Entry
+		ok bool
+	}{
+		{Entry{"MESSAGE": {`hello grp.key="a b"`}, "PRIORITY": {"6"}}, true},
+		{Entry{"MESSAGE": {"hello"}, "PRIORITY": {"6"}, "GRP_KEY": {"a b"}}, true},
+		{Entry{"MESSAGE": {"hello grp.key=a"}, "PRIORITY": {"6"}}, false},
+		{Entry{"MESSAGE": {`hello grp.key="a b"`}, "PRIORITY": {"8"}}, false},
+		{Entry{"MESSAGE": {`hello grp.key="a b"`}}, false},
+		{Entry{"MESSAGE": {`hel`, `lo grp.key="a b"`}, "PRIORITY": {"6"}}, false},
+		{Entry{"MESSAGE": {`help grp.key="a b"`}, "PRIORITY": {"6"}}, false},
+	} {
+		if err := c.check(x.e); (err == nil) != x.ok {
+			t.Errorf("%q: %v", x.e, err)
+		}
+	}
+}
+
+// recordingTransport parses the payloads.
+type recordingTransport struct {
+	mu      sync.Mutex
+	entries []Entry
+	err     error
+}
+
+func (tr *recordingTransport) Send(payload []byte, needsFile bool) error {
+	e, err := ParsePayload(payload)
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if err != nil {
+		tr.err = err
+		return err
+	}
+	tr.entries = append(tr.entries, e)
+	return nil
+}
+
+func (tr *recordingTransport) results() []Entry {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return slices.Clip(tr.entries)
+}
+
+func TestTransportConformance(t *testing.T) {
+	TestTransport(t, func(t *testing.T) (sjournal.Transport, func() []Entry) {
+		tr := new(recordingTransport)
+		return tr, tr.results
+	})
+}
+
+// syncBuffer is a bytes.Buffer which can be read while it's being written.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+func TestWriterTransportConformance(t *testing.T) {
+	TestTransport(t, func(t *testing.T) (sjournal.Transport, func() []Entry) {
+		buf := new(syncBuffer)
+		return sjournal.NewWriterTransport(buf), func() []Entry {
+			return decodeExport(t, buf.Bytes())
+		}
+	})
+}
+
+func TestUploaderConformance(t *testing.T) {
+	buf := new(syncBuffer)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		buf.Write(data)
+	}))
+	defer server.Close()
+
+	u, err := sjournal.NewUploader(&sjournal.UploaderOptions{
+		URL:         server.URL,
+		BufferBytes: 64 << 20,
+		Linger:      time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := sjournal.NewHandler(&sjournal.HandlerOptions{
+		Delimiter: sjournal.DefaultDelimiter,
+		Uploader:  u,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	TestHandlerOutput(t, h, func() []Entry {
+		return decodeExport(t, buf.Bytes())
+	})
+}
+
+// decodeExport decodes entries in the Journal Export Format.  A truncated
+// entry at the end is ignored.
+func decodeExport(t *testing.T, data []byte) (entries []Entry) {
+	t.Helper()
+
+	dec := sjournal.NewDecoder(bytes.NewReader(data))
+	for {
+		fields, err := dec.Next()
+		if err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				t.Fatal(err)
+			}
+			return
+		}
+
+		e := make(Entry, len(fields))
+		for name, value := range fields {
+			e[name] = []string{string(value)}
+		}
+		entries = append(entries, e)
+	}
+}
+
+func TestSyslogConformance(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var (
+		mu      sync.Mutex
+		entries []Entry
+	)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+
+		r := bufio.NewReader(c)
+		for {
+			size, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSuffix(size, " "))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			frame := make([]byte, n)
+			if _, err := io.ReadFull(r, frame); err != nil {
+				return
+			}
+			e, err := parseSyslog(string(frame))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mu.Lock()
+			entries = append(entries, e)
+			mu.Unlock()
+		}
+	}()
+
+	s, err := sjournal.NewSyslog(&sjournal.SyslogOptions{
+		Network:     "tcp",
+		Address:     l.Addr().String(),
+		BufferBytes: 64 << 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := sjournal.NewHandler(&sjournal.HandlerOptions{
+		Delimiter: sjournal.DefaultDelimiter,
+		Syslog:    s,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	TestHandlerOutput(t, h, func() []Entry {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clip(entries)
+	})
+}
+
+// parseSyslog converts an RFC 5424 message to an entry: the severity is
+// PRIORITY, MSG is MESSAGE, and the SD-PARAMs are fields with upper-case
+// names.
+func parseSyslog(s string) (Entry, error) {
+	pri, s, ok := strings.Cut(strings.TrimPrefix(s, "<"), ">")
+	if !ok {
+		return nil, fmt.Errorf("no PRI: %q", s)
+	}
+	n, err := strconv.Atoi(pri)
+	if err != nil {
+		return nil, err
+	}
+	e := Entry{"PRIORITY": {strconv.Itoa(n % 8)}}
+
+	for range 6 { // VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID
+		if _, s, ok = strings.Cut(s, " "); !ok {
+			return nil, fmt.Errorf("truncated header: %q", s)
+		}
+	}
+
+	if strings.HasPrefix(s, "[") {
+		if _, s, ok = strings.Cut(s, " "); !ok { // SD-ID
+			return nil, fmt.Errorf("truncated structured data: %q", s)
+		}
+		for !strings.HasPrefix(s, "]") {
+			var name string
+			if name, s, ok = strings.Cut(s, `="`); !ok {
+				return nil, fmt.Errorf("invalid SD-PARAM: %q", s)
+			}
+			var value strings.Builder
+			for {
+				if s == "" {
+					return nil, fmt.Errorf("unterminated SD-PARAM: %s", name)
+				}
+				c := s[0]
+				s = s[1:]
+				if c == '"' {
+					break
+				}
+				if c == '\\' && s != "" {
+					c = s[0]
+					s = s[1:]
+				}
+				value.WriteByte(c)
+			}
+			name = strings.ToUpper(name)
+			e[name] = append(e[name], value.String())
+			s = strings.TrimPrefix(s, " ")
+		}
+		s = s[1:]
+	} else {
+		s = strings.TrimPrefix(s, "-")
+	}
+
+	e["MESSAGE"] = []string{strings.TrimPrefix(s, " ")}
+	return e, nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package sjournaltest
+
+import (
+	"testing"
+
+	"import.name/sjournal"
+)
+
+func TestHandlerConformance(t *testing.T) {
+	sock := NewSocket(t)
+
+	h, err := sjournal.NewHandler(&sjournal.HandlerOptions{
+		Delimiter: sjournal.DefaultDelimiter,
+		Socket:    sock.Path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	TestHandlerOutput(t, h, sock.Entries)
+
+	if sjournal.LargeMessageSupport {
+		if h.Stats().LargeEntries == 0 || sock.PassedFiles() == 0 {
+			t.Error("no entries were passed via files")
+		}
+	}
+}
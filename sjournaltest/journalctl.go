@@ -0,0 +1,302 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sjournaltest contains test helpers: a fake clock for controlling
+// the time-dependent features of handlers, helpers for tests which run on
+// hosts with systemd, verifying that entries actually end up in the journal,
+// and a conformance suite for transports and handlers which produce journal
+// entries.
+package sjournaltest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"import.name/sjournal"
+)
+
+// ProbeField is the journal field which identifies an entry logged by Probe.
+const ProbeField = "SJOURNALTEST_PROBE"
+
+const (
+	defaultTimeout = 10 * time.Second
+	pollInterval   = 100 * time.Millisecond
+)
+
+// journalctl command; tests may substitute a fake.
+var journalctl = "journalctl"
+
+// Entry is a journal entry decoded from journalctl's JSON output (or by
+// ParsePayload).  A field which appears multiple times in the entry has
+// multiple values.  Binary values are decoded as raw bytes.  If journalctl
+// omitted the value (because it was too large), the field has no values.  A
+// null value among multiple values is decoded as empty string.
+type Entry map[string][]string
+
+// Get the first value of a field, or empty string.
+func (e Entry) Get(name string) string {
+	if values := e[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Matcher selects journal entries.
+type Matcher struct {
+	// Fields which must have exactly these values.  They are passed to
+	// journalctl as matches.
+	Fields map[string]string
+
+	// Func is an additional predicate, if set.
+	Func func(Entry) bool
+
+	// Since limits the search to entries recorded after the time, if set.
+	Since time.Time
+
+	// Timeout defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+func (m *Matcher) match(e Entry) bool {
+	for name, value := range m.Fields {
+		if !slices.Contains(e[name], value) {
+			return false
+		}
+	}
+	return m.Func == nil || m.Func(e)
+}
+
+// AssertLogged waits until journalctl returns an entry selected by the matcher,
+// and returns the entry.  The test fails if no entry appears within the
+// timeout.  The test is skipped if journalctl isn't available.
+func AssertLogged(t testing.TB, m Matcher) Entry {
+	t.Helper()
+
+	requireJournalctl(t)
+
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for first := true; ; first = false {
+		entries, err := query(ctx, &m)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if first {
+				t.Skipf("sjournaltest: %v", err)
+			}
+			t.Fatalf("sjournaltest: %v", err)
+		}
+
+		for _, e := range entries {
+			if m.match(e) {
+				return e
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	t.Fatalf("sjournaltest: no matching journal entry within %v", timeout)
+	return nil
+}
+
+// Probe logs an entry with a unique ProbeField value using the handler, and
+// asserts that it appears in the journal.  The test is skipped if the entry
+// can't be sent.
+func Probe(t testing.TB, h *sjournal.Handler) Entry {
+	t.Helper()
+	requireJournalctl(t)
+
+	var id [16]byte
+	rand.Read(id[:])
+	marker := hex.EncodeToString(id[:])
+
+	since := time.Now()
+
+	ctx := context.Background()
+	probe := h.WithFields(map[string]string{ProbeField: marker})
+	r := slog.NewRecord(since, slog.LevelInfo, "sjournaltest probe", 0)
+	if err := probe.Handle(ctx, r); err != nil {
+		t.Skipf("sjournaltest: journald is not available: %v", err)
+	}
+	if err := probe.Flush(ctx); err != nil {
+		t.Fatalf("sjournaltest: %v", err)
+	}
+
+	return AssertLogged(t, Matcher{
+		Fields: map[string]string{ProbeField: marker},
+		Since:  since,
+	})
+}
+
+func requireJournalctl(t testing.TB) {
+	t.Helper()
+
+	if _, err := exec.LookPath(journalctl); err != nil {
+		t.Skipf("sjournaltest: %v", err)
+	}
+}
+
+func query(ctx context.Context, m *Matcher) ([]Entry, error) {
+	args := []string{"--output=json", "--all", "--no-pager", "--quiet"}
+	if !m.Since.IsZero() {
+		// Whole seconds; the matcher is applied to the results anyway.
+		args = append(args, "--since=@"+strconv.FormatInt(m.Since.Unix()-1, 10))
+	}
+	for name, value := range m.Fields {
+		args = append(args, name+"="+value)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, journalctl, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", journalctl, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", journalctl, err)
+	}
+
+	return decodeEntries(out)
+}
+
+// decodeEntries parses journalctl's JSON output (one object per line).
+func decodeEntries(data []byte) ([]Entry, error) {
+	var entries []Entry
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(nil, 64<<20)
+	for s.Scan() {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		e, err := decodeEntry(line)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func decodeEntry(line []byte) (Entry, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return nil, fmt.Errorf("invalid journal entry: %w", err)
+	}
+
+	e := make(Entry, len(raw))
+	for name, data := range raw {
+		values, err := decodeValues(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid journal field %s: %w", name, err)
+		}
+		e[name] = values
+	}
+	return e, nil
+}
+
+// decodeValues of a field.  A value is a string, an array of byte values
+// (binary data), or null (omitted).  A field with multiple values is an
+// array of them.
+func decodeValues(data json.RawMessage) ([]string, error) {
+	if string(data) == "null" {
+		return nil, nil
+	}
+
+	if data[0] != '[' || isBinary(data) {
+		v, err := decodeValue(data)
+		if err != nil {
+			return nil, err
+		}
+		return []string{v}, nil
+	}
+
+	var elems []json.RawMessage
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return nil, err
+	}
+
+	values := make([]string, len(elems))
+	for i, elem := range elems {
+		v, err := decodeValue(elem)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// isBinary checks if an array contains numbers (or nothing).
+func isBinary(array json.RawMessage) bool {
+	s := bytes.TrimLeft(array[1:], " \t\r\n")
+	return len(s) > 0 && (s[0] == ']' || (s[0] >= '0' && s[0] <= '9'))
+}
+
+func decodeValue(data json.RawMessage) (string, error) {
+	switch data[0] {
+	case '"':
+		var s string
+		err := json.Unmarshal(data, &s)
+		return s, err
+
+	case '[':
+		var octets []int
+		if err := json.Unmarshal(data, &octets); err != nil {
+			return "", err
+		}
+		b := make([]byte, len(octets))
+		for i, x := range octets {
+			if x < 0 || x > 255 {
+				return "", fmt.Errorf("byte value out of range: %d", x)
+			}
+			b[i] = byte(x)
+		}
+		return string(b), nil
+
+	case 'n':
+		if string(data) == "null" {
+			return "", nil
+		}
+	}
+
+	return "", errors.New("unsupported value")
+}
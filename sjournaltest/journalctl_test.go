@@ -0,0 +1,119 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournaltest
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"import.name/sjournal"
+)
+
+const sampleOutput = `{"__CURSOR":"s=1;i=1","MESSAGE":"hello","PRIORITY":"6","SJOURNALTEST_PROBE":"abc"}
+
+{"MESSAGE":[104,105,10,0],"TAG":["x",[1,2],"y"],"BIG":null,"EMPTY":[]}
+`
+
+func TestDecodeEntries(t *testing.T) {
+	entries, err := decodeEntries([]byte(sampleOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatal(entries)
+	}
+
+	if s := entries[0].Get("MESSAGE"); s != "hello" {
+		t.Error(s)
+	}
+
+	e := entries[1]
+	if s := e.Get("MESSAGE"); s != "hi\n\x00" {
+		t.Errorf("%q", s)
+	}
+	if values := e["TAG"]; !slices.Equal(values, []string{"x", "\x01\x02", "y"}) {
+		t.Errorf("%q", values)
+	}
+	if values, found := e["BIG"]; !found || values != nil {
+		t.Errorf("%v %q", found, values)
+	}
+	if values := e["EMPTY"]; !slices.Equal(values, []string{""}) {
+		t.Errorf("%q", values)
+	}
+	if s := e.Get("MISSING"); s != "" {
+		t.Error(s)
+	}
+
+	for _, bad := range []string{`{"X":1}`, `{"X":[300]}`, `{"X":["a",true]}`, `[]`} {
+		if _, err := decodeEntries([]byte(bad)); err == nil {
+			t.Errorf("%s: no error", bad)
+		}
+	}
+}
+
+func fakeJournalctl(t *testing.T, script string) {
+	path := filepath.Join(t.TempDir(), "journalctl")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	orig := journalctl
+	t.Cleanup(func() { journalctl = orig })
+	journalctl = path
+}
+
+func TestAssertLogged(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip(err)
+	}
+
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	outFile := filepath.Join(dir, "out")
+	fakeJournalctl(t, `echo "$@" > `+argsFile+`; cat `+outFile+` 2>/dev/null; true`)
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		os.WriteFile(outFile+".tmp", []byte(sampleOutput), 0o644)
+		os.Rename(outFile+".tmp", outFile)
+	}()
+
+	e := AssertLogged(t, Matcher{
+		Fields: map[string]string{"SJOURNALTEST_PROBE": "abc"},
+		Func:   func(e Entry) bool { return e.Get("PRIORITY") == "6" },
+		Since:  time.Unix(1000, 0),
+	})
+	if s := e.Get("MESSAGE"); s != "hello" {
+		t.Error(s)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, arg := range []string{"--output=json", "--all", "--since=@999", "SJOURNALTEST_PROBE=abc"} {
+		if !strings.Contains(string(args), arg) {
+			t.Errorf("%s not in %s", arg, args)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	requireJournalctl(t)
+
+	h, err := sjournal.NewHandler(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := Probe(t, h)
+	if s := e.Get("MESSAGE"); !strings.Contains(s, "sjournaltest probe") {
+		t.Error(s)
+	}
+}
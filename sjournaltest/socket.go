@@ -0,0 +1,153 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournaltest
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	maxDatagramSize = 1 << 20
+	maxOOBSize      = 64
+)
+
+// Socket receives entries in place of journald, for use with the
+// sjournal.HandlerOptions.Socket option.  Entries which are passed via files
+// are supported on Unix-like systems.
+type Socket struct {
+	// Path of the socket.
+	Path string
+
+	t    testing.TB
+	conn *net.UnixConn
+	done chan struct{}
+
+	mu       sync.Mutex
+	entries  []Entry
+	files    int
+	consumed int           // Entries returned by Receive.
+	notify   chan struct{} // Signaled when entries are received.
+}
+
+// NewSocket listens on a datagram socket in a temporary directory.  The
+// socket is closed when the test finishes.  The test fails if a datagram
+// can't be decoded.
+func NewSocket(t testing.TB) *Socket {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram", Name: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Socket{
+		Path:   path,
+		t:      t,
+		conn:   conn,
+		done:   make(chan struct{}),
+		notify: make(chan struct{}, 1),
+	}
+	go s.receive()
+
+	t.Cleanup(func() {
+		conn.Close()
+		<-s.done
+	})
+
+	return s
+}
+
+func (s *Socket) receive() {
+	defer close(s.done)
+
+	buf := make([]byte, maxDatagramSize)
+	oob := make([]byte, maxOOBSize)
+
+	for {
+		n, oobn, _, _, err := s.conn.ReadMsgUnix(buf, oob)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.t.Errorf("sjournaltest: %v", err)
+			}
+			return
+		}
+
+		data := buf[:n]
+		if oobn > 0 {
+			if data, err = readPassedFile(oob[:oobn]); err != nil {
+				s.t.Errorf("sjournaltest: passed file: %v", err)
+				continue
+			}
+		}
+
+		e, err := ParsePayload(data)
+		if err != nil {
+			s.t.Errorf("sjournaltest: %v: %q", err, data)
+			continue
+		}
+
+		s.mu.Lock()
+		s.entries = append(s.entries, e)
+		if oobn > 0 {
+			s.files++
+		}
+		s.mu.Unlock()
+
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Receive waits for the next n entries, and returns them.  The test fails if
+// they don't arrive within 10 seconds.
+func (s *Socket) Receive(n int) []Entry {
+	s.t.Helper()
+
+	timer := time.NewTimer(defaultTimeout)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		if len(s.entries)-s.consumed >= n {
+			entries := slices.Clone(s.entries[s.consumed : s.consumed+n])
+			s.consumed += n
+			s.mu.Unlock()
+			return entries
+		}
+		received := len(s.entries) - s.consumed
+		s.mu.Unlock()
+
+		select {
+		case <-s.notify:
+		case <-timer.C:
+			s.t.Fatalf("sjournaltest: received %d entries instead of %d within %v", received, n, defaultTimeout)
+		}
+	}
+}
+
+// Entries returns all entries received so far, including those returned by
+// Receive.
+func (s *Socket) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.entries)
+}
+
+// PassedFiles returns the number of entries which have been received via
+// files.
+func (s *Socket) PassedFiles() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.files
+}
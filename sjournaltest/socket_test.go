@@ -0,0 +1,43 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournaltest
+
+import (
+	"log/slog"
+	"testing"
+
+	"import.name/sjournal"
+)
+
+func TestSocket(t *testing.T) {
+	sock := NewSocket(t)
+
+	h, err := sjournal.NewHandler(&sjournal.HandlerOptions{
+		Delimiter: sjournal.DefaultDelimiter,
+		Socket:    sock.Path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	logger := slog.New(h)
+	logger.Info("first", "x", 1)
+	logger.Warn("second")
+	logger.Error("third")
+
+	if e := sock.Receive(1)[0]; e.Get("MESSAGE") != "first x=1" || e.Get("PRIORITY") != "6" {
+		t.Errorf("entry 0: %q", e)
+	}
+	if es := sock.Receive(2); es[0].Get("MESSAGE") != "second" || es[1].Get("MESSAGE") != "third" {
+		t.Errorf("entries 1-2: %q", es)
+	}
+	if n := len(sock.Entries()); n != 3 {
+		t.Errorf("%d entries", n)
+	}
+	if n := sock.PassedFiles(); n != 0 {
+		t.Errorf("%d passed files", n)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournaltest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+
+	"import.name/sjournal"
+)
+
+// priorityNames are the syslog priority names used by journalctl.
+var priorityNames = [...]string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// NewTestHandler returns a handler which encodes records like a normal
+// handler, but instead of sending the entries to journald, it decodes them and
+// writes them to the test log as readable lines.  The test fails if an entry
+// is malformed.  The Socket, Transport, Uploader, Syslog and WaitForSocket
+// options are ignored.  The handler is shut down when the test finishes.
+func NewTestHandler(t testing.TB, opts *sjournal.HandlerOptions) slog.Handler {
+	t.Helper()
+
+	o := sjournal.HandlerOptions{Delimiter: sjournal.DefaultDelimiter}
+	if opts != nil {
+		o = *opts
+	}
+	o.Socket = ""
+	o.Transport = testTransport{t}
+	o.Uploader = nil
+	o.Syslog = nil
+	o.WaitForSocket = 0
+
+	h, err := sjournal.NewHandler(&o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := h.Shutdown(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return h
+}
+
+// testTransport writes entries to the test log.
+type testTransport struct {
+	t testing.TB
+}
+
+func (tr testTransport) Send(payload []byte, needsFile bool) error {
+	line, err := describeEntry(payload)
+	if err != nil {
+		tr.t.Errorf("sjournal: invalid entry: %v: %q", err, payload)
+		return err
+	}
+	tr.t.Log(line)
+	return nil
+}
+
+// describeEntry validates an encoded entry and formats it as a line.
+func describeEntry(b []byte) (string, error) {
+	entry, err := ParsePayload(b)
+	if err != nil {
+		return "", err
+	}
+
+	for name := range entry {
+		if !validFieldName(name) {
+			return "", fmt.Errorf("invalid field name %q", name)
+		}
+	}
+
+	if n := len(entry["MESSAGE"]); n != 1 {
+		return "", fmt.Errorf("%d MESSAGE fields", n)
+	}
+
+	var s strings.Builder
+
+	priority := -1
+	if p := entry["PRIORITY"]; len(p) == 1 && len(p[0]) == 1 && p[0][0] >= '0' && int(p[0][0]-'0') < len(priorityNames) {
+		priority = int(p[0][0] - '0')
+	}
+	if priority < 0 {
+		return "", fmt.Errorf("invalid PRIORITY field %q", entry["PRIORITY"])
+	}
+	s.WriteString(priorityNames[priority])
+	s.WriteString(": ")
+	s.WriteString(entry.Get("MESSAGE"))
+
+	for _, name := range slices.Sorted(maps.Keys(entry)) {
+		switch name {
+		case "PRIORITY", "MESSAGE", "SYSLOG_TIMESTAMP", "CODE_FILE", "CODE_LINE", "CODE_FUNC":
+		default:
+			for _, value := range entry[name] {
+				fmt.Fprintf(&s, " %s=%q", name, value)
+			}
+		}
+	}
+
+	if file := entry.Get("CODE_FILE"); file != "" {
+		fmt.Fprintf(&s, " (%s:%s)", path.Base(file), entry.Get("CODE_LINE"))
+	}
+
+	return s.String(), nil
+}
+
+// validFieldName reports whether journald accepts the field name from a
+// client: upper-case letters, digits and underscores, not beginning with a
+// digit or an underscore, and at most 64 bytes long.
+func validFieldName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for i, c := range []byte(name) {
+		switch {
+		case 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9', c == '_':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
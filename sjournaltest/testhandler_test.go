@@ -0,0 +1,95 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournaltest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"import.name/sjournal"
+)
+
+// recordingTB captures the output and results of a NewTestHandler.
+type recordingTB struct {
+	testing.TB
+	logs     []string
+	errors   []string
+	cleanups []func()
+}
+
+func (tb *recordingTB) Helper()           {}
+func (tb *recordingTB) Log(args ...any)   { tb.logs = append(tb.logs, fmt.Sprint(args...)) }
+func (tb *recordingTB) Error(args ...any) { tb.errors = append(tb.errors, fmt.Sprint(args...)) }
+func (tb *recordingTB) Cleanup(f func())  { tb.cleanups = append(tb.cleanups, f) }
+func (tb *recordingTB) Fatal(args ...any) { panic(fmt.Sprint(args...)) }
+func (tb *recordingTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *recordingTB) cleanup() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+}
+
+func TestNewTestHandler(t *testing.T) {
+	tb := &recordingTB{TB: t}
+
+	h := NewTestHandler(tb, &sjournal.HandlerOptions{
+		Delimiter: sjournal.DefaultDelimiter,
+		Fields:    map[string]string{"SERVICE": "test"},
+	})
+	logger := slog.New(h)
+
+	logger.Info("hello", "x", 1)
+	logger.Warn("multi\nline", sjournal.Field("extra", "value"))
+
+	tb.cleanup()
+
+	if len(tb.errors) != 0 {
+		t.Errorf("errors: %q", tb.errors)
+	}
+	if len(tb.logs) != 2 {
+		t.Fatalf("logs: %q", tb.logs)
+	}
+	if s := tb.logs[0]; !strings.HasPrefix(s, `info: hello x=1 SERVICE="test" (testhandler_test.go:`) {
+		t.Errorf("log 0: %s", s)
+	}
+	if s := tb.logs[1]; !strings.HasPrefix(s, `warning: multi`+"\n"+`line EXTRA="value" SERVICE="test" (testhandler_test.go:`) {
+		t.Errorf("log 1: %s", s)
+	}
+}
+
+func TestNewTestHandlerInvalid(t *testing.T) {
+	tb := &recordingTB{TB: t}
+
+	h := NewTestHandler(tb, &sjournal.HandlerOptions{
+		Mungers: []func(context.Context, []byte) ([]byte, error){
+			func(_ context.Context, b []byte) ([]byte, error) {
+				return append(b, "bad-field=x\n"...), nil
+			},
+		},
+	})
+
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "x", 0)); err == nil {
+		t.Error("no error")
+	}
+	tb.cleanup()
+
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], `invalid field name "bad-field"`) {
+		t.Errorf("errors: %q", tb.errors)
+	}
+	if len(tb.logs) != 0 {
+		t.Errorf("logs: %q", tb.logs)
+	}
+}
+
+func TestNewTestHandlerReal(t *testing.T) {
+	slog.New(NewTestHandler(t, nil)).Info("visible with go test -v", "n", 42)
+}
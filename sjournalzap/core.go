@@ -0,0 +1,109 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sjournalzap implements a zap logger core which sends entries to
+// journald using an sjournal handler.
+package sjournalzap
+
+import (
+	"context"
+	"log/slog"
+
+	"go.uber.org/zap/zapcore"
+	"import.name/sjournal"
+)
+
+// Level maps a zap level to a slog level.  DPanic and Panic are mapped to
+// LevelCrit, and Fatal to LevelAlert.
+func Level(l zapcore.Level) slog.Level {
+	switch l {
+	case zapcore.DebugLevel:
+		return sjournal.LevelDebug
+	case zapcore.InfoLevel:
+		return sjournal.LevelInfo
+	case zapcore.WarnLevel:
+		return sjournal.LevelWarn
+	case zapcore.ErrorLevel:
+		return sjournal.LevelError
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return sjournal.LevelCrit
+	case zapcore.FatalLevel:
+		return sjournal.LevelAlert
+	}
+	if l < zapcore.DebugLevel {
+		return sjournal.LevelDebug - slog.Level(zapcore.DebugLevel-l)
+	}
+	return sjournal.LevelAlert
+}
+
+// Core implements zapcore.Core.  The level of the handler determines which
+// entries are enabled.  Logger names are included as the "logger" attribute,
+// and stack traces as the "stacktrace" attribute; they are not affected by
+// namespaces (see sjournal.Absolute).  Namespaces are mapped to groups.
+type Core struct {
+	h *sjournal.Handler
+	s slog.Handler // Derived from h.
+}
+
+func NewCore(h *sjournal.Handler) *Core {
+	return &Core{h: h, s: h}
+}
+
+func (c *Core) Enabled(l zapcore.Level) bool {
+	return c.s.Enabled(context.Background(), Level(l))
+}
+
+// With returns a core which adds the fields to every entry.  The fields are
+// preformatted by the handler.
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	s := c.s
+
+	var enc attrEncoder
+	for i := range fields {
+		if fields[i].Type == zapcore.NamespaceType {
+			if attrs := enc.finish(); len(attrs) > 0 {
+				s = s.WithAttrs(attrs)
+			}
+			enc = attrEncoder{}
+			s = s.WithGroup(fields[i].Key)
+			continue
+		}
+		enc.addField(fields[i])
+	}
+	if attrs := enc.finish(); len(attrs) > 0 {
+		s = s.WithAttrs(attrs)
+	}
+
+	return &Core{h: c.h, s: s}
+}
+
+func (c *Core) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(e.Level) {
+		return ce.AddCore(e, c)
+	}
+	return ce
+}
+
+func (c *Core) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	r := slog.NewRecord(e.Time, Level(e.Level), e.Message, e.Caller.PC)
+
+	var enc attrEncoder
+	if e.LoggerName != "" {
+		enc.add(sjournal.Absolute(slog.String("logger", e.LoggerName)))
+	}
+	for i := range fields {
+		enc.addField(fields[i])
+	}
+	if e.Stack != "" {
+		enc.add(sjournal.Absolute(slog.String("stacktrace", e.Stack)))
+	}
+	r.AddAttrs(enc.finish()...)
+
+	return c.s.Handle(context.Background(), r)
+}
+
+// Sync flushes the handler's queue.
+func (c *Core) Sync() error {
+	return c.h.Flush(context.Background())
+}
@@ -0,0 +1,122 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournalzap
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"import.name/sjournal"
+	"import.name/sjournal/sjournaltest"
+)
+
+func newTestCore(t *testing.T, opts *sjournal.HandlerOptions) (*Core, func(n int) []sjournaltest.Entry) {
+	t.Helper()
+
+	sock := sjournaltest.NewSocket(t)
+
+	opts.Socket = sock.Path
+	h, err := sjournal.NewHandler(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+
+	return NewCore(h), sock.Receive
+}
+
+func TestCore(t *testing.T) {
+	core, receive := newTestCore(t, &sjournal.HandlerOptions{
+		Level:     sjournal.LevelInfo,
+		Delimiter: sjournal.DefaultDelimiter,
+	})
+
+	logger := zap.New(core, zap.AddCaller()).Named("svc").With(zap.Int("a", 1), zap.Namespace("req"), zap.String("id", "x"))
+
+	logger.Debug("hidden")
+	logger.Info("hello", zap.Bool("ok", true), zap.Namespace("db"), zap.Duration("took", time.Second))
+	logger.Warn("warning", zap.Error(errors.New("oops")), zap.Strings("tags", []string{"p", "q"}))
+	logger.Error("error", zap.Object("obj", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddInt("n", 2)
+		return nil
+	})))
+
+	entries := receive(3)
+
+	for i, c := range []struct {
+		priority string
+		message  string
+	}{
+		{"6", "hello a=1 req.id=x logger=svc req.ok=true req.db.took=1s"},
+		{"4", "warning a=1 req.id=x logger=svc req.error=oops req.tags=\"[p q]\""},
+		{"3", "error a=1 req.id=x logger=svc req.obj.n=2"},
+	} {
+		e := entries[i]
+		if e.Get("PRIORITY") != c.priority || e.Get("MESSAGE") != c.message {
+			t.Errorf("entry %d: PRIORITY=%s MESSAGE=%q", i, e.Get("PRIORITY"), e.Get("MESSAGE"))
+		}
+		if e.Get("CODE_FUNC") != "import.name/sjournal/sjournalzap.TestCore" {
+			t.Errorf("entry %d: CODE_FUNC=%s", i, e.Get("CODE_FUNC"))
+		}
+	}
+
+	if err := logger.Sync(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCoreSync(t *testing.T) {
+	core, receive := newTestCore(t, &sjournal.HandlerOptions{
+		QueueSize: 10,
+	})
+
+	logger := zap.New(core)
+	logger.Info("queued")
+	if err := logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if e := receive(1)[0]; e.Get("MESSAGE") != "queued" {
+		t.Errorf("entry: %q", e)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	for l, expect := range map[zapcore.Level]int{
+		zapcore.DebugLevel:  7,
+		zapcore.InfoLevel:   6,
+		zapcore.WarnLevel:   4,
+		zapcore.ErrorLevel:  3,
+		zapcore.DPanicLevel: 2,
+		zapcore.PanicLevel:  2,
+		zapcore.FatalLevel:  1,
+	} {
+		core, receive := newTestCore(t, &sjournal.HandlerOptions{})
+		if err := core.Write(zapcore.Entry{Level: l, Message: "x"}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if s := receive(1)[0].Get("PRIORITY"); s != string(rune('0'+expect)) {
+			t.Errorf("%v: PRIORITY=%s", l, s)
+		}
+	}
+}
+
+func TestCoreStack(t *testing.T) {
+	core, receive := newTestCore(t, &sjournal.HandlerOptions{
+		Delimiter: sjournal.DefaultDelimiter,
+	})
+
+	c := core.With([]zapcore.Field{zap.Namespace("req"), zap.String("id", "x")})
+	if err := c.Write(zapcore.Entry{Message: "failed", LoggerName: "svc", Stack: "main.main"}, []zapcore.Field{zap.Int("n", 1)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := receive(1)[0].Get("MESSAGE"); s != `failed req.id=x logger=svc req.n=1 stacktrace=main.main` {
+		t.Errorf("MESSAGE=%q", s)
+	}
+}
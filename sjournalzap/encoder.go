@@ -0,0 +1,135 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournalzap
+
+import (
+	"log/slog"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// attrEncoder converts zap fields to slog attributes.
+type attrEncoder struct {
+	attrs      []slog.Attr
+	namespaces []namespace // Open namespaces, innermost last.
+}
+
+type namespace struct {
+	key   string
+	attrs []slog.Attr
+}
+
+var _ zapcore.ObjectEncoder = (*attrEncoder)(nil)
+
+func (enc *attrEncoder) add(a slog.Attr) {
+	if n := len(enc.namespaces); n > 0 {
+		enc.namespaces[n-1].attrs = append(enc.namespaces[n-1].attrs, a)
+	} else {
+		enc.attrs = append(enc.attrs, a)
+	}
+}
+
+func (enc *attrEncoder) addField(f zapcore.Field) {
+	switch f.Type {
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			enc.add(slog.Any(f.Key, err))
+			return
+		}
+	case zapcore.StringerType:
+		enc.add(slog.Any(f.Key, f.Interface))
+		return
+	}
+	f.AddTo(enc)
+}
+
+// finish closes the namespaces and returns the attributes.
+func (enc *attrEncoder) finish() []slog.Attr {
+	for n := len(enc.namespaces); n > 0; n-- {
+		ns := enc.namespaces[n-1]
+		enc.namespaces = enc.namespaces[:n-1]
+		if len(ns.attrs) > 0 {
+			enc.add(slog.Attr{Key: ns.key, Value: slog.GroupValue(ns.attrs...)})
+		}
+	}
+	return enc.attrs
+}
+
+func (enc *attrEncoder) AddArray(key string, v zapcore.ArrayMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	err := m.AddArray(key, v)
+	enc.add(slog.Any(key, m.Fields[key]))
+	return err
+}
+
+func (enc *attrEncoder) AddObject(key string, v zapcore.ObjectMarshaler) error {
+	var sub attrEncoder
+	err := v.MarshalLogObject(&sub)
+	enc.add(slog.Attr{Key: key, Value: slog.GroupValue(sub.finish()...)})
+	return err
+}
+
+func (enc *attrEncoder) AddBinary(key string, v []byte) {
+	enc.add(slog.Any(key, v))
+}
+
+func (enc *attrEncoder) AddByteString(key string, v []byte) {
+	enc.add(slog.String(key, string(v)))
+}
+
+func (enc *attrEncoder) AddBool(key string, v bool) {
+	enc.add(slog.Bool(key, v))
+}
+
+func (enc *attrEncoder) AddComplex128(key string, v complex128) {
+	enc.add(slog.Any(key, v))
+}
+
+func (enc *attrEncoder) AddComplex64(key string, v complex64) {
+	enc.add(slog.Any(key, v))
+}
+
+func (enc *attrEncoder) AddDuration(key string, v time.Duration) {
+	enc.add(slog.Duration(key, v))
+}
+
+func (enc *attrEncoder) AddFloat64(key string, v float64) {
+	enc.add(slog.Float64(key, v))
+}
+
+func (enc *attrEncoder) AddFloat32(key string, v float32) {
+	enc.add(slog.Float64(key, float64(v)))
+}
+
+func (enc *attrEncoder) AddInt(key string, v int)     { enc.add(slog.Int(key, v)) }
+func (enc *attrEncoder) AddInt64(key string, v int64) { enc.add(slog.Int64(key, v)) }
+func (enc *attrEncoder) AddInt32(key string, v int32) { enc.add(slog.Int64(key, int64(v))) }
+func (enc *attrEncoder) AddInt16(key string, v int16) { enc.add(slog.Int64(key, int64(v))) }
+func (enc *attrEncoder) AddInt8(key string, v int8)   { enc.add(slog.Int64(key, int64(v))) }
+
+func (enc *attrEncoder) AddString(key, v string) {
+	enc.add(slog.String(key, v))
+}
+
+func (enc *attrEncoder) AddTime(key string, v time.Time) {
+	enc.add(slog.Time(key, v))
+}
+
+func (enc *attrEncoder) AddUint(key string, v uint)       { enc.add(slog.Uint64(key, uint64(v))) }
+func (enc *attrEncoder) AddUint64(key string, v uint64)   { enc.add(slog.Uint64(key, v)) }
+func (enc *attrEncoder) AddUint32(key string, v uint32)   { enc.add(slog.Uint64(key, uint64(v))) }
+func (enc *attrEncoder) AddUint16(key string, v uint16)   { enc.add(slog.Uint64(key, uint64(v))) }
+func (enc *attrEncoder) AddUint8(key string, v uint8)     { enc.add(slog.Uint64(key, uint64(v))) }
+func (enc *attrEncoder) AddUintptr(key string, v uintptr) { enc.add(slog.Uint64(key, uint64(v))) }
+
+func (enc *attrEncoder) AddReflected(key string, v any) error {
+	enc.add(slog.Any(key, v))
+	return nil
+}
+
+func (enc *attrEncoder) OpenNamespace(key string) {
+	enc.namespaces = append(enc.namespaces, namespace{key: key})
+}
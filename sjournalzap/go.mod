@@ -0,0 +1,13 @@
+module import.name/sjournal/sjournalzap
+
+go 1.23
+
+require (
+	go.uber.org/zap v1.27.0
+	import.name/sjournal v0.0.0-20261014071621-eea7f08d6e64
+)
+
+require (
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/sys v0.26.0 // indirect
+)
@@ -0,0 +1,92 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const defaultSlowSendInterval = time.Minute
+
+// slowSend detects socket sends which take longer than a threshold.
+type slowSend struct {
+	threshold time.Duration
+	interval  time.Duration
+	report    func(time.Duration)
+	lastWarn  atomic.Int64 // Monotonic time of the latest warning, or zero.
+}
+
+// newSlowSend returns nil unless SlowSendThreshold is set.
+func newSlowSend(opts *HandlerOptions) *slowSend {
+	if opts.SlowSendThreshold <= 0 {
+		return nil
+	}
+
+	s := &slowSend{
+		threshold: opts.SlowSendThreshold,
+		interval:  opts.SlowSendInterval,
+		report:    opts.OnSlowSend,
+	}
+	if s.interval <= 0 {
+		s.interval = defaultSlowSendInterval
+	}
+	return s
+}
+
+// slowSendStart returns the monotonic time, or zero if slow sends are not
+// detected.
+func (r *root) slowSendStart() time.Duration {
+	if r.slowSend == nil {
+		return 0
+	}
+	return r.clock.Monotonic()
+}
+
+// finish a send which was started at the given time.  A slow send is counted
+// and reported, and a warning entry is sent if the previous one was sent more
+// than the interval ago.
+func (r *root) finishSend(start time.Duration, sock *net.UnixConn, addr *net.UnixAddr) {
+	s := r.slowSend
+	if s == nil {
+		return
+	}
+
+	now := r.clock.Monotonic()
+	d := now - start
+	if d <= s.threshold {
+		return
+	}
+
+	r.stats.slowSends.Add(1)
+	if s.report != nil {
+		s.report(d)
+	}
+
+	last := s.lastWarn.Load()
+	if last != 0 && now-time.Duration(last) < s.interval {
+		return
+	}
+	if !s.lastWarn.CompareAndSwap(last, int64(now)) {
+		return // Another goroutine is warning.
+	}
+
+	ms := strconv.FormatFloat(float64(d)/1e6, 'f', 3, 64)
+
+	b := newBuffer()
+	defer b.Free()
+
+	b.WriteString("PRIORITY=4\nMESSAGE=sjournal: sending an entry took ")
+	b.WriteString(ms)
+	b.WriteString(" ms\nSEND_DURATION_MS=")
+	b.WriteString(ms)
+	b.WriteByte('\n')
+
+	if _, _, err := sock.WriteMsgUnix(*b, nil, addr); err == nil {
+		r.stats.sentEntry(len(*b))
+	}
+}
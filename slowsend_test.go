@@ -0,0 +1,140 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSlowSend(t *testing.T) {
+	const delay = 200 * time.Millisecond
+
+	var (
+		mu        sync.Mutex
+		durations []time.Duration
+	)
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		SlowSendThreshold: 50 * time.Millisecond,
+		OnSlowSend: func(d time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			durations = append(durations, d)
+		},
+	})
+
+	// Fill the socket buffer while the receiver is paused, and resume reading
+	// after a delay.
+	recv.pause()
+	resumed := time.AfterFunc(delay, recv.resume)
+	defer resumed.Stop()
+
+	data := strings.Repeat("x", 16<<10)
+	begin := time.Now()
+	var sent int
+	for time.Since(begin) < delay/2 && sent < 1000 {
+		if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, data, 0)); err != nil {
+			t.Fatal(err)
+		}
+		sent++
+	}
+
+	mu.Lock()
+	detected := slices.Clone(durations)
+	mu.Unlock()
+
+	if len(detected) == 0 {
+		t.Fatal("slow send not detected")
+	}
+	if d := detected[0]; d < delay/2 || d > 10*delay {
+		t.Errorf("duration: %v", d)
+	}
+	if n := h.Stats().SlowSends; n != uint64(len(detected)) {
+		t.Errorf("Stats.SlowSends: %d", n)
+	}
+
+	var warning map[string]string
+	for _, m := range recv.wait(t, sent+1) {
+		if m["SEND_DURATION_MS"] != "" {
+			warning = m
+		}
+	}
+	if warning == nil {
+		t.Fatal("no warning entry")
+	}
+	ms, err := strconv.ParseFloat(warning["SEND_DURATION_MS"], 64)
+	if err != nil || time.Duration(ms*1e6).Round(time.Microsecond) != detected[0].Round(time.Microsecond) {
+		t.Errorf("SEND_DURATION_MS: %q (detected %v)", warning["SEND_DURATION_MS"], detected[0])
+	}
+	if warning["PRIORITY"] != "4" || !strings.HasPrefix(warning["MESSAGE"], "sjournal: sending an entry took ") {
+		t.Errorf("warning: %q", warning)
+	}
+}
+
+func TestSlowSendAllocs(t *testing.T) {
+	h, _ := newTestHandler(t, &HandlerOptions{SlowSendThreshold: time.Hour})
+	r := h.root
+
+	if n := testing.AllocsPerRun(100, func() {
+		r.finishSend(r.slowSendStart(), nil, nil)
+	}); n != 0 {
+		t.Errorf("%v allocations", n)
+	}
+}
+
+// steppingClock advances by a step whenever its monotonic time is read.
+type steppingClock struct {
+	step time.Duration
+	mono atomic.Int64
+}
+
+func (c *steppingClock) Now() time.Time { return time.Unix(0, 0) }
+
+func (c *steppingClock) Monotonic() time.Duration {
+	return time.Duration(c.mono.Add(int64(c.step)))
+}
+
+func TestSlowSendClock(t *testing.T) {
+	var durations []time.Duration
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Clock:             &steppingClock{step: 300 * time.Millisecond},
+		SlowSendThreshold: 250 * time.Millisecond,
+		SlowSendInterval:  time.Second,
+		OnSlowSend:        func(d time.Duration) { durations = append(durations, d) },
+	})
+
+	logger := slog.New(h)
+	for range 6 {
+		logger.Info("x")
+	}
+
+	// Each send takes one step, and the clock is read twice per send, so the
+	// sends finish at 0.6 s intervals: there are warnings at 0.6, 1.8 and 3 s.
+	if !slices.Equal(durations, []time.Duration{300 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}) {
+		t.Errorf("durations: %v", durations)
+	}
+
+	var warnings int
+	for _, m := range recv.wait(t, 6+3) {
+		if m["SEND_DURATION_MS"] != "" {
+			if m["SEND_DURATION_MS"] != "300.000" {
+				t.Errorf("SEND_DURATION_MS=%s", m["SEND_DURATION_MS"])
+			}
+			warnings++
+		}
+	}
+	if warnings != 3 {
+		t.Errorf("%d warnings", warnings)
+	}
+}
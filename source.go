@@ -0,0 +1,85 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"path/filepath"
+	"strconv"
+)
+
+// SourceFormat determines how *slog.Source attribute values are rendered.
+// The zero value renders them as "file:line" with the full path.  The flags
+// can be combined.
+type SourceFormat int
+
+const (
+	// SourceTrimPath renders only the base name of the file.
+	SourceTrimPath SourceFormat = 1 << iota
+
+	// SourceFunction appends the function name in parentheses, like
+	// "file:line (pkg.Func)".
+	SourceFunction
+
+	// SourceFields causes the value to be written as KEY_FILE, KEY_LINE and
+	// KEY_FUNC journal fields instead of an attribute, where KEY is the
+	// attribute key converted to a field name (like in PromoteKeys).  For
+	// example an attribute with the key "origin" becomes ORIGIN_FILE,
+	// ORIGIN_LINE and ORIGIN_FUNC.  KEY_FUNC is omitted if the function name
+	// is unknown.  The attribute is rendered as text if the key (without
+	// group prefix) isn't a valid field name.
+	SourceFields
+
+	sourceFormatMask = SourceTrimPath | SourceFunction | SourceFields
+)
+
+func (f SourceFormat) file(src *slog.Source) string {
+	if f&SourceTrimPath != 0 {
+		return filepath.Base(src.File)
+	}
+	return src.File
+}
+
+// format a source location as text.
+func (f SourceFormat) format(src *slog.Source) string {
+	b := make([]byte, 0, len(src.File)+len(src.Function)+24)
+	b = append(b, f.file(src)...)
+	b = append(b, ':')
+	b = strconv.AppendInt(b, int64(src.Line), 10)
+	if f&SourceFunction != 0 && src.Function != "" {
+		b = append(b, " ("...)
+		b = append(b, src.Function...)
+		b = append(b, ')')
+	}
+	return string(b)
+}
+
+// appendSourceFields writes a source location as journal fields if
+// SourceFields is set and the key can be converted to field names.
+func (s *handleState) appendSourceFields(key string, src *slog.Source) bool {
+	if s.h.sourceFormat&SourceFields == 0 {
+		return false
+	}
+
+	name, ok := fieldName(key)
+	if !ok || len(name)+len("_FILE") > maxFieldNameLen {
+		return false
+	}
+
+	s.appendSourceField(name+"_FILE", s.h.sourceFormat.file(src))
+	s.appendSourceField(name+"_LINE", strconv.Itoa(src.Line))
+	if src.Function != "" {
+		s.appendSourceField(name+"_FUNC", src.Function)
+	}
+	return true
+}
+
+func (s *handleState) appendSourceField(name, value string) {
+	if field, ok := s.h.reserved.name(name); ok {
+		s.appendField(field, value)
+	} else {
+		s.rejectField(name)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"testing"
+)
+
+var testSource = &slog.Source{
+	Function: "example.com/pkg.Func",
+	File:     "/src/example.com/pkg/file.go",
+	Line:     42,
+}
+
+func TestSourceFormat(t *testing.T) {
+	for _, c := range []struct {
+		format SourceFormat
+		expect string
+	}{
+		{0, "msg origin=/src/example.com/pkg/file.go:42"},
+		{SourceTrimPath, "msg origin=file.go:42"},
+		{SourceFunction, `msg origin="/src/example.com/pkg/file.go:42 (example.com/pkg.Func)"`},
+		{SourceTrimPath | SourceFunction, `msg origin="file.go:42 (example.com/pkg.Func)"`},
+	} {
+		h, recv := newTestHandler(t, &HandlerOptions{SourceFormat: c.format})
+		slog.New(h).Info("msg", "origin", testSource)
+
+		e := recv.wait(t, 1)[0]
+		if s := e["MESSAGE"]; s != c.expect {
+			t.Errorf("format %d: %q", c.format, s)
+		}
+		if _, found := e["ORIGIN_FILE"]; found {
+			t.Errorf("format %d: ORIGIN_FILE", c.format)
+		}
+	}
+}
+
+func TestSourceFields(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		SourceFormat: SourceFields | SourceTrimPath,
+	})
+
+	logger := slog.New(h)
+	logger.Info("msg", "origin", testSource, "n", 1)
+	logger.Info("nofunc", "origin", &slog.Source{File: "/a/b.go", Line: 7})
+	logger.Info("invalid", "or-igin", testSource)
+
+	entries := recv.wait(t, 3)
+
+	e := entries[0]
+	for name, expect := range map[string]string{
+		"MESSAGE":     "msg n=1",
+		"ORIGIN_FILE": "file.go",
+		"ORIGIN_LINE": "42",
+		"ORIGIN_FUNC": "example.com/pkg.Func",
+	} {
+		if s := e[name]; s != expect {
+			t.Errorf("%s: %q", name, s)
+		}
+	}
+
+	e = entries[1]
+	if s := e["ORIGIN_FILE"]; s != "b.go" {
+		t.Errorf("ORIGIN_FILE: %q", s)
+	}
+	if _, found := e["ORIGIN_FUNC"]; found {
+		t.Error("ORIGIN_FUNC")
+	}
+
+	if s := entries[2]["MESSAGE"]; s != "invalid or-igin=file.go:42" {
+		t.Errorf("fallback: %q", s)
+	}
+}
+
+func TestSourceFieldsReserved(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		SourceFormat:   SourceFields,
+		ReservedFields: RenameReserved,
+	})
+
+	slog.New(h).Info("msg", "code", testSource)
+
+	e := recv.wait(t, 1)[0]
+	if s := e["X_CODE_LINE"]; s != "42" {
+		t.Errorf("X_CODE_LINE: %q", s)
+	}
+	if s := e["CODE_LINE"]; s == "42" {
+		t.Error("CODE_LINE was overridden")
+	}
+}
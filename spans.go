@@ -0,0 +1,168 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"slices"
+	"strconv"
+)
+
+// DuplicateKeys determines how attributes with the same full key (including
+// group prefix) are handled within a record.  Policies other than KeepAll
+// require bookkeeping for every attribute of every record, and finding a
+// duplicate costs time linear in the number of attributes.
+type DuplicateKeys int
+
+const (
+	KeepAll   DuplicateKeys = iota // All occurrences are included.
+	LastWins                       // Only the last occurrence is included.
+	FirstWins                      // Only the first occurrence is included.
+)
+
+// AttrOrder determines the order of the attributes added using WithAttrs
+// relative to the attributes of a record.
+type AttrOrder int
+
+const (
+	ContextFirst AttrOrder = iota // WithAttrs attributes are emitted first.
+	RecordFirst                   // Record attributes are emitted first.
+)
+
+// keySpan locates an attribute in a buffer.
+type keySpan struct {
+	key          string
+	sep          string // Separator written before the attribute.
+	sepStart     int
+	start        int
+	end          int
+	preformatted bool // Added using WithAttrs.
+}
+
+// appendTrackedAttr appends a key and a value according to the duplicate key
+// policy, and records its location.
+func (s *handleState) appendTrackedAttr(key, value string) {
+	quoteKey := false
+	if s.prefix != nil && len(*s.prefix) > 0 {
+		quoteKey = key == "" && s.h.textCompat // Like appendKey.
+		key = string(*s.prefix) + key
+	}
+
+	if s.h.duplicateKeys != KeepAll {
+		if i := slices.IndexFunc(s.spans, func(x keySpan) bool { return x.key == key }); i >= 0 {
+			if s.h.duplicateKeys == FirstWins {
+				return
+			}
+			s.removeSpan(i)
+		}
+	}
+
+	span := keySpan{
+		key:      key,
+		sep:      s.sep,
+		sepStart: len(*s.buf),
+	}
+	s.buf.WriteString(s.sep)
+	span.start = len(*s.buf)
+	if quoteKey {
+		*s.buf = strconv.AppendQuote(*s.buf, key)
+	} else {
+		s.appendString(key)
+	}
+	s.buf.WriteByte('=')
+	s.appendValue(value)
+	span.end = len(*s.buf)
+	s.sep = " "
+
+	s.spans = append(s.spans, span)
+}
+
+// removeSpan removes an attribute from the buffer.
+func (s *handleState) removeSpan(i int) {
+	span := s.spans[i]
+	s.spans = slices.Delete(s.spans, i, i+1)
+
+	if i == len(s.spans) {
+		// Remove the separator before the attribute, and use it for the
+		// next attribute.
+		*s.buf = slices.Delete(*s.buf, span.sepStart, span.end)
+		s.sep = span.sep
+		return
+	}
+
+	// Keep the separator before the attribute for the following attribute,
+	// and remove the one after it.
+	n := s.spans[i].start - span.start
+	*s.buf = slices.Delete(*s.buf, span.start, s.spans[i].start)
+
+	s.spans[i].sep = span.sep
+	s.spans[i].sepStart = span.sepStart
+	s.spans[i].start -= n
+	s.spans[i].end -= n
+
+	for j := i + 1; j < len(s.spans); j++ {
+		s.spans[j].sepStart -= n
+		s.spans[j].start -= n
+		s.spans[j].end -= n
+	}
+}
+
+// appendPreformattedSpans after the preformatted attributes have been written
+// to the buffer at the given offset, after the separator.
+func (s *handleState) appendPreformattedSpans(offset int, sep string) {
+	for i, span := range s.h.preformattedSpans {
+		if i == 0 {
+			span.sep = sep
+			span.sepStart = offset - len(sep)
+		} else {
+			span.sepStart += offset
+		}
+		span.start += offset
+		span.end += offset
+		span.preformatted = true
+		s.spans = append(s.spans, span)
+	}
+}
+
+// sortSpans reorders the attributes in the buffer according to AttrCompare
+// (or SortAttrs) and AttrOrder.  The order of equal attributes is preserved.
+func (s *handleState) sortSpans() {
+	if len(s.spans) < 2 {
+		return
+	}
+
+	sorted := slices.Clone(s.spans)
+	slices.SortStableFunc(sorted, s.h.compareSpans)
+
+	start := s.spans[0].start
+	end := s.spans[len(s.spans)-1].end
+
+	tmp := newBuffer()
+	defer tmp.Free()
+
+	for i, span := range sorted {
+		if i > 0 {
+			tmp.WriteByte(' ')
+		}
+		tmp.Write((*s.buf)[span.start:span.end])
+	}
+
+	copy((*s.buf)[start:end], *tmp)
+	s.spans = nil
+}
+
+func (h *Handler) compareSpans(a, b keySpan) int {
+	if h.attrCompare != nil {
+		if c := h.attrCompare(a.key, b.key); c != 0 {
+			return c
+		}
+	}
+	if h.attrOrder == RecordFirst && a.preformatted != b.preformatted {
+		if a.preformatted {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,200 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDuplicateKeys(t *testing.T) {
+	for policy, expect := range map[DuplicateKeys][]string{
+		KeepAll: {
+			"msg: user.id=1 a=1 a=2 b=3 a=4",
+			"msg: a=1 b=2 a=3",
+			"msg: user.id=1 x=y user.id=2",
+			"msg: user.id=1 user.id=3 x=y user.id=2 g.k=1 k=2 g.k=3",
+		},
+		LastWins: {
+			"msg: user.id=1 b=3 a=4",
+			"msg: b=2 a=3",
+			"msg: x=y user.id=2",
+			"msg: x=y user.id=2 k=2 g.k=3",
+		},
+		FirstWins: {
+			"msg: user.id=1 a=1 b=3",
+			"msg: a=1 b=2",
+			"msg: user.id=1 x=y",
+			"msg: user.id=1 x=y g.k=1 k=2",
+		},
+	} {
+		h, recv := newTestHandler(t, &HandlerOptions{
+			Delimiter:     ColonDelimiter,
+			DuplicateKeys: policy,
+		})
+
+		logger := slog.New(h)
+		user1 := logger.With(slog.Group("user", "id", 1))
+
+		logger.Info("msg", slog.Group("user", "id", 1), "a", 1, "a", 2, "b", 3, "a", 4)
+		logger.Info("msg", "a", 1, "b", 2, "a", 3)
+		user1.Info("msg", "x", "y", slog.Group("user", "id", 2))
+		user1.With(slog.Group("user", "id", 3)).With("x", "y").Info("msg", slog.Group("user", "id", 2), slog.Group("g", "k", 1), "k", 2, slog.Group("g", "k", 3))
+
+		ms := recv.wait(t, len(expect))
+		for i, s := range expect {
+			if m := ms[i]["MESSAGE"]; m != s {
+				t.Errorf("policy %d: entry %d: %q", policy, i, m)
+			}
+		}
+
+		// Parent must not be affected by in-place removal.
+		user1.Info("msg")
+		if m := recv.wait(t, len(expect)+1)[len(expect)]["MESSAGE"]; m != "msg: user.id=1" {
+			t.Errorf("policy %d: parent: %q", policy, m)
+		}
+	}
+}
+
+func TestSortAttrs(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter: ColonDelimiter,
+		SortAttrs: true,
+	})
+
+	attrs := []any{
+		"zeta", 1,
+		slog.Group("g", "b", 2, "a", 3),
+		"alpha", "x y",
+		slog.Group("g-h", "c", 4),
+		"ga", 5,
+		"dup", 1,
+		"dup", 2,
+		Field("FIELD", "f"),
+	}
+
+	const expect = `msg: alpha="x y" dup=1 dup=2 g-h.c=4 g.a=3 g.b=2 ga=5 pre=0 zeta=1`
+
+	logger := slog.New(h).With("pre", 0)
+	random := rand.New(rand.NewSource(1))
+
+	const count = 20
+
+	for i := 0; i < count; i++ {
+		pairs := make([][]any, 0, len(attrs))
+		for j := 0; j < len(attrs); {
+			if _, ok := attrs[j].(slog.Attr); ok {
+				pairs = append(pairs, attrs[j:j+1])
+				j++
+			} else {
+				pairs = append(pairs, attrs[j:j+2])
+				j += 2
+			}
+		}
+		random.Shuffle(len(pairs), func(a, b int) {
+			pairs[a], pairs[b] = pairs[b], pairs[a]
+		})
+		// Keep the order of duplicates.
+		if d1, d2 := indexOfPair(pairs, 1), indexOfPair(pairs, 2); d1 > d2 {
+			pairs[d1], pairs[d2] = pairs[d2], pairs[d1]
+		}
+
+		var args []any
+		for _, p := range pairs {
+			args = append(args, p...)
+		}
+		logger.Info("msg", args...)
+	}
+
+	for i, m := range recv.wait(t, count) {
+		if s := m["MESSAGE"]; s != expect {
+			t.Errorf("entry %d: %q", i, s)
+		}
+		if s := m["FIELD"]; s != "f" {
+			t.Errorf("entry %d: field %q", i, s)
+		}
+	}
+}
+
+func TestAttrOrder(t *testing.T) {
+	reverse := func(a, b string) int { return strings.Compare(b, a) }
+
+	for _, c := range []struct {
+		opts   HandlerOptions
+		expect string
+	}{
+		{HandlerOptions{}, "msg: a=1 g.b=2 g.c=3 g.d=4"},
+		{HandlerOptions{AttrOrder: RecordFirst}, "msg: g.c=3 g.d=4 a=1 g.b=2"},
+		{HandlerOptions{AttrOrder: RecordFirst, SortAttrs: true}, "msg: a=1 g.b=2 g.c=3 g.d=4"},
+		{HandlerOptions{AttrCompare: reverse}, "msg: g.d=4 g.c=3 g.b=2 a=1"},
+		{HandlerOptions{AttrCompare: reverse, SortAttrs: true}, "msg: g.d=4 g.c=3 g.b=2 a=1"},
+	} {
+		c.opts.Delimiter = ColonDelimiter
+		h, recv := newTestHandler(t, &c.opts)
+
+		slog.New(h).With("a", 1).WithGroup("g").With("b", 2).Info("msg", "c", 3, "d", 4)
+
+		if s := recv.wait(t, 1)[0]["MESSAGE"]; s != c.expect {
+			t.Errorf("%+v: %q", c.opts, s)
+		}
+	}
+}
+
+func TestAttrOrderDuplicateKeys(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter:     ColonDelimiter,
+		AttrOrder:     RecordFirst,
+		DuplicateKeys: LastWins,
+	})
+
+	slog.New(h).With("a", 1, "b", 2).Info("msg", "c", 3, "a", 4)
+
+	if s := recv.wait(t, 1)[0]["MESSAGE"]; s != "msg: c=3 a=4 b=2" {
+		t.Errorf("%q", s)
+	}
+}
+
+func TestAttrOrderDefaultAllocs(t *testing.T) {
+	h, err := NewHandler(&HandlerOptions{AttrOrder: ContextFirst})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	h.root.sink = func([]byte) error { return nil }
+
+	h2 := h.WithAttrs([]slog.Attr{slog.Int("a", 1), slog.Int("b", 2)}).(*Handler)
+	if h2.preformattedSpans != nil {
+		t.Error("preformatted spans are tracked")
+	}
+
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0)
+	r.AddAttrs(slog.String("c", "3"))
+
+	allocs := func(h *Handler) float64 {
+		return testing.AllocsPerRun(100, func() {
+			h.Handle(context.Background(), r)
+		})
+	}
+
+	without := allocs(h)
+	with := allocs(h2)
+	if with > without {
+		t.Errorf("%v allocations with preformatted attributes, %v without", with, without)
+	}
+}
+
+// indexOfPair finds the "dup" attribute with the given value.
+func indexOfPair(pairs [][]any, value int) int {
+	for i, p := range pairs {
+		if len(p) == 2 && p[0] == "dup" && p[1] == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,41 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"sync/atomic"
+)
+
+// staticFields caches the fields which are the same in every entry of a
+// handler: Identifier, Hostname, container and Fields options, WithFields,
+// GroupField, the logger name and the fields produced by WithAttrs.  It's
+// shared by the handlers derived without changing them.  The block depends on
+// the configuration, so it's rebuilt after Reload.
+type staticFields struct {
+	block atomic.Pointer[staticBlock]
+}
+
+type staticBlock struct {
+	cfg  *config
+	data []byte // Immutable.
+}
+
+// staticFields returns the encoded fields for the configuration.
+func (h *Handler) staticFields(cfg *config) []byte {
+	if b := h.static.block.Load(); b != nil && b.cfg == cfg {
+		return b.data
+	}
+
+	n := len(cfg.fields) + len(h.encodedFields) + len(h.groupField) + len(h.nameField) + len(h.preformattedFields)
+	data := make(buffer, 0, n)
+	cfg.appendFields(&data, h.fields)
+	data.Write(h.encodedFields)
+	data.Write(h.groupField)
+	data.Write(h.nameField)
+	data.Write(h.preformattedFields)
+
+	h.static.block.Store(&staticBlock{cfg, data})
+	return data
+}
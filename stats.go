@@ -0,0 +1,146 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Reasons for dropping entries, used as keys of Stats.Dropped.
+const (
+	DropQueueFull = "queue-full" // Asynchronous mode queue was full.
+	DropExpired   = "expired"    // Entry was queued for longer than its TTL.
+	DropTooLarge  = "too-large"  // Entry was larger than MaxQueueBytes.
+	DropClosed    = "closed"     // Handler was closed.
+	DropFiltered  = "filtered"   // Record was filtered out by the handler.
+	DropError     = "error"      // Sending failed in asynchronous mode.
+	DropNoSocket  = "no-socket"  // Socket didn't appear within WaitForSocket.
+	DropMuted     = "muted"      // Call site was muted.
+	DropBudget    = "budget"     // Level range exceeded its budget.
+)
+
+// Ways to pass large entries to journald, used as values of
+// Stats.FileStrategy.
+const (
+	FileMemfd      = "memfd"       // Anonymous file created with memfd_create.
+	FileTmpfileShm = "tmpfile-shm" // O_TMPFILE in /dev/shm.
+	FileTmpfileTmp = "tmpfile-tmp" // O_TMPFILE in the temporary directory.
+	FileShmAnon    = "shm-anon"    // Anonymous shared memory object (FreeBSD).
+	FileTemp       = "temp"        // Temporary file which is removed after creation.
+)
+
+type dropReason int
+
+const (
+	dropQueueFull dropReason = iota
+	dropExpired
+	dropTooLarge
+	dropClosed
+	dropFiltered
+	dropError
+	dropNoSocket
+	dropMuted
+	dropBudget
+	numDropReasons
+)
+
+var dropReasonNames = [numDropReasons]string{
+	dropQueueFull: DropQueueFull,
+	dropExpired:   DropExpired,
+	dropTooLarge:  DropTooLarge,
+	dropClosed:    DropClosed,
+	dropFiltered:  DropFiltered,
+	dropError:     DropError,
+	dropNoSocket:  DropNoSocket,
+	dropMuted:     DropMuted,
+	dropBudget:    DropBudget,
+}
+
+// Stats is a snapshot of handler counters.
+type Stats struct {
+	Sent              uint64            // Entries handed to the kernel.
+	Dropped           map[string]uint64 // Discarded entries by reason.  Zero counts are omitted.
+	DroppedByPriority [8]uint64         // Discarded entries by journald priority.
+	Blocked           uint64            // Handle calls which waited for room in the queue.
+	BlockedTime       time.Duration     // Total time spent waiting for room in the queue.
+	FileStrategy      string            // How the latest large entry was passed to journald (process-wide).  Empty if none.
+	SlowSends         uint64            // Sends which exceeded SlowSendThreshold.
+	SentBytes         uint64            // Total encoded size of sent entries.
+	MaxEntryBytes     int               // Encoded size of the largest sent entry.
+	DeadLettered      uint64            // Failed entries written to DeadLetterPath.
+	DeadLetterErrors  uint64            // Failed entries which couldn't be written to DeadLetterPath.
+	LargeEntries      uint64            // Entries passed to journald via files.
+}
+
+// MeanEntryBytes is the mean encoded size of the sent entries, or zero.
+func (s Stats) MeanEntryBytes() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return float64(s.SentBytes) / float64(s.Sent)
+}
+
+type stats struct {
+	sent              atomic.Uint64
+	dropped           [numDropReasons]atomic.Uint64
+	droppedByPriority [numPriorities]atomic.Uint64
+	blocked           atomic.Uint64
+	blockedTime       atomic.Int64
+	slowSends         atomic.Uint64
+	sentBytes         atomic.Uint64
+	maxEntryBytes     atomic.Int64
+	deadLettered      atomic.Uint64
+	deadLetterErrors  atomic.Uint64
+	largeEntries      atomic.Uint64
+}
+
+func (s *stats) sentEntry(size int) {
+	s.sent.Add(1)
+	s.sentBytes.Add(uint64(size))
+	for {
+		old := s.maxEntryBytes.Load()
+		if int64(size) <= old || s.maxEntryBytes.CompareAndSwap(old, int64(size)) {
+			return
+		}
+	}
+}
+
+func (s *stats) drop(reason dropReason, priority, n int) {
+	if n > 0 {
+		s.dropped[reason].Add(uint64(n))
+		s.droppedByPriority[priority].Add(uint64(n))
+	}
+}
+
+func (s *stats) block(d time.Duration) {
+	s.blocked.Add(1)
+	s.blockedTime.Add(int64(d))
+}
+
+func (s *stats) snapshot() Stats {
+	x := Stats{
+		Sent:             s.sent.Load(),
+		Dropped:          make(map[string]uint64),
+		Blocked:          s.blocked.Load(),
+		BlockedTime:      time.Duration(s.blockedTime.Load()),
+		FileStrategy:     fileStrategyName(),
+		SlowSends:        s.slowSends.Load(),
+		SentBytes:        s.sentBytes.Load(),
+		MaxEntryBytes:    int(s.maxEntryBytes.Load()),
+		DeadLettered:     s.deadLettered.Load(),
+		DeadLetterErrors: s.deadLetterErrors.Load(),
+		LargeEntries:     s.largeEntries.Load(),
+	}
+	for reason := range s.dropped {
+		if n := s.dropped[reason].Load(); n != 0 {
+			x.Dropped[dropReasonNames[reason]] = n
+		}
+	}
+	for priority := range s.droppedByPriority {
+		x.DroppedByPriority[priority] = s.droppedByPriority[priority].Load()
+	}
+	return x
+}
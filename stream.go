@@ -0,0 +1,78 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StderrIsJournal reports if the standard error stream of the process is
+// connected to journald, according to the JOURNAL_STREAM environment
+// variable.
+func StderrIsJournal() bool {
+	var dev, ino uint64
+	if _, err := fmt.Sscanf(os.Getenv("JOURNAL_STREAM"), "%d:%d", &dev, &ino); err != nil {
+		return false
+	}
+	return fileIs(os.Stderr, dev, ino)
+}
+
+var stderr io.Writer = os.Stderr
+
+// mirror writes records to a stream in addition to journald.
+type mirror struct {
+	level slog.Leveler
+	mu    sync.Mutex
+	w     io.Writer
+}
+
+// newMirror returns nil if mirroring is disabled.
+func newMirror(opts *HandlerOptions) *mirror {
+	if opts.MirrorToStderr == nil {
+		return nil
+	}
+
+	w := opts.MirrorWriter
+	if w == nil {
+		if StderrIsJournal() {
+			return nil
+		}
+		w = stderr
+	}
+
+	return &mirror{level: opts.MirrorToStderr, w: w}
+}
+
+func (m *mirror) enabled(level slog.Level) bool {
+	return m != nil && level >= m.level.Level()
+}
+
+// write a line consisting of time, level and message text.
+func (m *mirror) write(cfg *config, t time.Time, level slog.Level, text string) {
+	b := newBuffer()
+	defer b.Free()
+
+	if !t.IsZero() {
+		if cfg.timeLocation != nil {
+			t = t.In(cfg.timeLocation)
+		}
+		*b = t.AppendFormat(*b, time.RFC3339Nano)
+		b.WriteByte(' ')
+	}
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(strings.ReplaceAll(text, "\n", `\n`))
+	b.WriteByte('\n')
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.w.Write(*b)
+}
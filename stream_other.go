@@ -0,0 +1,15 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package sjournal
+
+import (
+	"os"
+)
+
+func fileIs(f *os.File, dev, ino uint64) bool {
+	return false
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package sjournal
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestMirrorToStderr(t *testing.T) {
+	var out bytes.Buffer
+
+	h, recv := newTestHandler(t, &HandlerOptions{
+		MirrorToStderr: slog.LevelWarn,
+		MirrorWriter:   &out,
+		Prefix:         "prefix: ",
+	})
+
+	logger := slog.New(h)
+	logger.Info("info", "x", 1)
+	logger.Warn("warn", "x", 2)
+	logger.Error("multi\nline", "x", 3)
+	recv.wait(t, 3)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	expect := []string{
+		"WARN prefix: warn x=2",
+		`ERROR prefix: multi\nline x=3`,
+	}
+	if len(lines) != len(expect) {
+		t.Fatalf("output: %q", out.String())
+	}
+	for i, s := range expect {
+		// Strip timestamp.
+		if _, line, _ := strings.Cut(lines[i], " "); line != s {
+			t.Errorf("line %d: %q", i, lines[i])
+		}
+	}
+}
+
+func TestMirrorToStderrSuppressed(t *testing.T) {
+	info, err := os.Stderr.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := info.Sys().(*syscall.Stat_t)
+	t.Setenv("JOURNAL_STREAM", fmt.Sprintf("%d:%d", st.Dev, st.Ino))
+
+	if !StderrIsJournal() {
+		t.Fatal("stderr is not journal")
+	}
+
+	var out bytes.Buffer
+	orig := stderr
+	defer func() { stderr = orig }()
+	stderr = &out
+
+	h, recv := newTestHandler(t, &HandlerOptions{MirrorToStderr: slog.LevelInfo})
+	slog.New(h).Error("error")
+	recv.wait(t, 1)
+
+	if out.Len() != 0 {
+		t.Errorf("output: %q", out.String())
+	}
+
+	t.Setenv("JOURNAL_STREAM", "1:2")
+	if StderrIsJournal() {
+		t.Error("stderr is journal")
+	}
+
+	h, recv = newTestHandler(t, &HandlerOptions{MirrorToStderr: slog.LevelInfo})
+	slog.New(h).Error("error")
+	recv.wait(t, 1)
+
+	if out.Len() == 0 {
+		t.Error("no output")
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package sjournal
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIs checks if the file has the given device and inode numbers.
+func fileIs(f *os.File, dev, ino uint64) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && uint64(st.Dev) == dev && uint64(st.Ino) == ino
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Journald's limits.
+const (
+	defaultStrictMaxEntryBytes = 768 << 20 // DATA_SIZE_MAX
+	defaultStrictMaxFields     = 1024      // ENTRY_FIELD_COUNT_MAX
+)
+
+// ErrInvalidEntry is wrapped by the errors reported for entries which violate
+// journald's constraints in strict mode (see HandlerOptions.Strict).
+var ErrInvalidEntry = errors.New("sjournal: invalid entry")
+
+// strictChecker checks encoded entries.
+type strictChecker struct {
+	maxBytes  int
+	maxFields int
+	report    func(error) // Nil means that Handle returns the error.
+}
+
+func newStrictChecker(opts *HandlerOptions) *strictChecker {
+	if !opts.Strict {
+		return nil
+	}
+
+	c := &strictChecker{
+		maxBytes:  opts.StrictMaxEntryBytes,
+		maxFields: opts.StrictMaxFields,
+		report:    opts.OnViolation,
+	}
+	if c.maxBytes <= 0 {
+		c.maxBytes = defaultStrictMaxEntryBytes
+	}
+	if c.maxFields <= 0 {
+		c.maxFields = defaultStrictMaxFields
+	}
+	return c
+}
+
+// check an entry in the native protocol format.  The violations are joined
+// into one error.
+func (c *strictChecker) check(b []byte) error {
+	var errs []error
+
+	if len(b) > c.maxBytes {
+		errs = append(errs, invalidEntry("size %d exceeds %d bytes", len(b), c.maxBytes))
+	}
+
+	fields := 0
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			errs = append(errs, invalidEntry("unterminated field"))
+			break
+		}
+		line := b[:i]
+		b = b[i+1:]
+
+		name, value, found := bytes.Cut(line, []byte("="))
+		if !found {
+			if len(b) < 8 {
+				errs = append(errs, invalidEntry("field %q: truncated size", name))
+				break
+			}
+			size := binary.LittleEndian.Uint64(b)
+			b = b[8:]
+			if size >= uint64(len(b)) || b[size] != '\n' {
+				errs = append(errs, invalidEntry("field %q: invalid size %d", name, size))
+				break
+			}
+			value = b[:size]
+			b = b[size+1:]
+		}
+
+		fields++
+		if err := checkFieldName(name); err != nil {
+			errs = append(errs, err)
+		}
+		if string(name) == "MESSAGE" && !utf8.Valid(value) {
+			errs = append(errs, invalidEntry("MESSAGE is not valid UTF-8"))
+		}
+	}
+
+	if fields > c.maxFields {
+		errs = append(errs, invalidEntry("%d fields exceed the limit of %d", fields, c.maxFields))
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkFieldName according to journald's rules for fields which clients may
+// send.
+func checkFieldName(name []byte) error {
+	switch {
+	case len(name) == 0:
+		return invalidEntry("empty field name")
+	case len(name) > maxFieldNameLen:
+		return invalidEntry("field name %q is longer than %d bytes", name, maxFieldNameLen)
+	case name[0] == '_':
+		return invalidEntry("field name %q starts with an underscore", name)
+	case '0' <= name[0] && name[0] <= '9':
+		return invalidEntry("field name %q starts with a digit", name)
+	}
+
+	for _, c := range name {
+		if !('A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_') {
+			return invalidEntry("field name %q contains invalid characters", name)
+		}
+	}
+	return nil
+}
+
+func invalidEntry(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalidEntry}, args...)...)
+}
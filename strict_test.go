@@ -0,0 +1,159 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStrict(t *testing.T) {
+	for _, x := range []struct {
+		name    string
+		opts    HandlerOptions
+		message string
+		append  string
+		expect  []string
+	}{
+		{
+			name:    "valid",
+			message: "hello",
+		},
+		{
+			name:   "underscore",
+			append: "_PID=1\n",
+			expect: []string{`field name "_PID" starts with an underscore`},
+		},
+		{
+			name:   "digit",
+			append: "1X=1\n",
+			expect: []string{`field name "1X" starts with a digit`},
+		},
+		{
+			name:   "characters",
+			append: "bad-key=1\nALSO.BAD=2\n",
+			expect: []string{
+				`field name "bad-key" contains invalid characters`,
+				`field name "ALSO.BAD" contains invalid characters`,
+			},
+		},
+		{
+			name:   "long",
+			append: strings.Repeat("X", 65) + "=1\n",
+			expect: []string{`field name "` + strings.Repeat("X", 65) + `" is longer than 64 bytes`},
+		},
+		{
+			name:   "empty",
+			append: "=1\n",
+			expect: []string{`empty field name`},
+		},
+		{
+			name:    "utf8",
+			message: "bad \xff",
+			expect:  []string{`MESSAGE is not valid UTF-8`},
+		},
+		{
+			name:    "size",
+			opts:    HandlerOptions{StrictMaxEntryBytes: 200},
+			message: strings.Repeat("x", 200),
+			expect:  []string{`exceeds 200 bytes`},
+		},
+		{
+			name:   "fields",
+			opts:   HandlerOptions{StrictMaxFields: 6},
+			append: "A=1\nB=2\nC=3\n",
+			expect: []string{`fields exceed the limit of 6`},
+		},
+	} {
+		t.Run(x.name, func(t *testing.T) {
+			for _, report := range []bool{false, true} {
+				var reported []error
+
+				opts := x.opts
+				opts.Strict = true
+				if report {
+					opts.OnViolation = func(err error) { reported = append(reported, err) }
+				}
+				if x.append != "" {
+					opts.Mungers = []func(context.Context, []byte) ([]byte, error){
+						func(_ context.Context, b []byte) ([]byte, error) {
+							return append(b, x.append...), nil
+						},
+					}
+				}
+
+				h, recv := newTestHandler(t, &opts)
+
+				message := x.message
+				if message == "" {
+					message = "test"
+				}
+				err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, message, 0))
+
+				if report {
+					if err != nil {
+						t.Errorf("Handle error: %v", err)
+					}
+					if len(x.expect) == 0 && len(reported) != 0 || len(x.expect) > 0 && len(reported) != 1 {
+						t.Fatalf("reported: %v", reported)
+					}
+					if len(reported) > 0 {
+						err = reported[0]
+					}
+				}
+
+				if len(x.expect) == 0 {
+					if err != nil {
+						t.Error(err)
+					}
+				} else {
+					if !errors.Is(err, ErrInvalidEntry) {
+						t.Fatalf("error: %v", err)
+					}
+					lines := strings.Split(err.Error(), "\n")
+					if len(lines) != len(x.expect) {
+						t.Errorf("violations: %q", lines)
+					}
+					for i, s := range x.expect {
+						if i < len(lines) && !(strings.HasPrefix(lines[i], "sjournal: invalid entry: ") && strings.Contains(lines[i], s)) {
+							t.Errorf("violation %d: %q", i, lines[i])
+						}
+					}
+				}
+
+				// Sent regardless.
+				if len(recv.wait(t, 1)) != 1 {
+					t.Error("not sent")
+				}
+			}
+		})
+	}
+}
+
+func TestStrictMalformed(t *testing.T) {
+	c := newStrictChecker(&HandlerOptions{Strict: true})
+
+	for entry, expect := range map[string]string{
+		"MESSAGE=x\nBINARY\n\x05\x00\x00":                           `field "BINARY": truncated size`,
+		"MESSAGE=x\nBINARY\n\x05\x00\x00\x00\x00\x00\x00\x00abc":    `field "BINARY"`,
+		"MESSAGE=x\nBINARY\n\x01\x00\x00\x00\x00\x00\x00\x00ab":     `field "BINARY"`,
+		"MESSAGE=x\nGOOD\n\x01\x00\x00\x00\x00\x00\x00\x00a\nX=1\n": "",
+	} {
+		err := c.check([]byte(entry))
+		if expect == "" {
+			if err != nil {
+				t.Errorf("%q: %v", entry, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidEntry) || !strings.Contains(err.Error(), expect) {
+			t.Errorf("%q: %v", entry, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// String returns a single-line summary of the effective configuration of the
+// handler, for debugging.  It includes the socket, the level, the prefix
+// (including ExtendPrefix), the groups, the number of attributes added using
+// WithAttrs, the time format, the static fields and the enabled optional
+// features.  Values of static fields whose names match RedactKeys are
+// replaced with the placeholder.  The format is stable, but new items may be
+// added in the future.
+func (h *Handler) String() string {
+	cfg := h.root.config.Load()
+
+	b := newBuffer()
+	defer b.Free()
+
+	item := func(key, value string) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(value)
+	}
+	flag := func(key string, enabled bool) {
+		if enabled {
+			if b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(key)
+		}
+	}
+	count := func(key string, n int) {
+		if n > 0 {
+			item(key, strconv.Itoa(n))
+		}
+	}
+	quoted := func(key, value string) {
+		if value != "" {
+			item(key, strconv.Quote(value))
+		}
+	}
+
+	if name := h.root.transportName; name != "" {
+		item("transport", name)
+	} else {
+		item("socket", h.root.addr.Load().Name)
+		if n := len(h.root.socks); n > 1 {
+			count("sockets", n)
+		}
+	}
+	if q := h.root.queue; q != nil {
+		item("mode", "async")
+		count("queue", q.size)
+	} else {
+		item("mode", "sync")
+	}
+	if h.root.closed.Load() {
+		flag("closed", true)
+	}
+
+	if h.level != nil {
+		item("level", h.level.Level().String())
+	} else if cfg.level != nil {
+		item("level", cfg.level.Level().String())
+	} else {
+		item("level", LevelDebug.String())
+	}
+
+	quoted("prefix", h.prefix(cfg))
+	quoted("groups", strings.Join(h.groups, "."))
+	quoted("name", h.name)
+	count("attrs", len(h.attrs))
+	quoted("timeformat", cfg.timeFormat)
+	if h.sourceLevel != nil {
+		item("sourcelevel", h.sourceLevel.Level().String())
+	}
+	if cfg.timeLocation != nil {
+		item("timelocation", cfg.timeLocation.String())
+	}
+
+	for _, f := range cfg.fieldList {
+		if _, found := h.fields[f.name]; !found {
+			h.appendFieldSummary(b, f.name, f.value)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(h.fields)) {
+		h.appendFieldSummary(b, name, h.fields[name])
+	}
+
+	count("facility", h.facility)
+	quoted("groupfield", h.groupFieldKey)
+	quoted("attrsfield", h.attrsField)
+	count("maxattrs", h.maxAttrs)
+	count("offload", h.offloadThreshold)
+	count("levelrules", len(h.levelRules))
+	flag("levelfield", h.levelField)
+	count("middleware", len(h.middleware))
+	count("mungers", len(h.mungers))
+	flag("nameprefix", h.namePrefix)
+	flag("strict", h.strict != nil)
+	flag("filter", h.filter != nil)
+	flag("filterinhandle", h.filterInHandle)
+	flag("replacerecord", h.replaceRecord != nil)
+	flag("mirror", h.root.mirror != nil)
+	flag("permissionfallback", h.root.permission.fallback != nil)
+	flag("capture", h.root.capture != nil)
+	flag("chunk", h.root.chunkSize > 0)
+	flag("slowsend", h.root.slowSend != nil)
+	flag("deadletter", h.root.deadLetters != nil)
+	flag("announce", h.root.announcer != nil)
+	flag("budgets", h.root.budgets != nil)
+	flag("signature", h.signer != nil)
+	count("destinations", len(h.root.fanout)+len(h.root.variants))
+	flag("fieldattrs", h.fieldAttrs)
+	flag("waitforsocket", h.root.wait != nil)
+	flag("seqnum", h.root.seqnumEpoch != "")
+	flag("monotonic", h.monotonicTime)
+	flag("realtime", h.recordRealtime)
+	flag("expandslices", h.expandSlices)
+	flag("expandstructs", h.expandStructs)
+	flag("expandmaps", h.expandMaps)
+	flag("pproflabels", h.pprofLabels != nil)
+	flag("sortattrs", h.attrCompare != nil)
+	flag("recordfirst", h.attrOrder == RecordFirst)
+	flag("escapecontrol", h.escapeControl)
+	flag("textcompat", h.textCompat)
+	flag("errnofield", h.errnoField)
+	flag("dropkeys", h.dropKeys != nil)
+	flag("redactkeys", h.redactKeys != nil)
+	flag("redactvalue", h.redactValue != nil)
+	flag("muted", h.root.mutes.Load() != nil)
+
+	return string(*b)
+}
+
+func (h *Handler) appendFieldSummary(b *buffer, name, value string) {
+	if h.redactKeys.match(name) || h.redactKeys.match(strings.ToLower(name)) {
+		value = h.redactPlaceholder
+	}
+
+	b.WriteByte(' ')
+	b.WriteString("field.")
+	b.WriteString(name)
+	b.WriteByte('=')
+	b.WriteString(strconv.Quote(value))
+}
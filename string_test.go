@@ -0,0 +1,43 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestString(t *testing.T) {
+	h, err := NewHandler(&HandlerOptions{
+		Level:           slog.LevelInfo,
+		Prefix:          "app: ",
+		Socket:          "/run/test/socket",
+		TimeFormat:      time.RFC3339,
+		Identifier:      "app",
+		Fields:          map[string]string{"API_TOKEN": "hunter2", "ROLE": "primary"},
+		RedactKeys:      []string{"api_token"},
+		GroupField:      "COMPONENT",
+		QueueSize:       10,
+		SequenceNumbers: true,
+		ExpandSlices:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	const base = `socket=/run/test/socket mode=async queue=10`
+
+	if s, expect := h.String(), base+` level=INFO prefix="app: " timeformat="2006-01-02T15:04:05Z07:00" field.SYSLOG_IDENTIFIER="app" field.API_TOKEN="[REDACTED]" field.ROLE="primary" groupfield="COMPONENT" seqnum expandslices redactkeys`; s != expect {
+		t.Errorf("root:\n%s\n%s", s, expect)
+	}
+
+	h2 := h.ExtendPrefix("db: ").WithGroup("db").WithAttrs([]slog.Attr{slog.Int("a", 1), slog.Int("b", 2)}).(*Handler).WithLevel(slog.LevelDebug).WithFields(map[string]string{"ROLE": "replica"})
+
+	if s, expect := h2.String(), base+` level=DEBUG prefix="app: db: " groups="db" attrs=2 timeformat="2006-01-02T15:04:05Z07:00" field.SYSLOG_IDENTIFIER="app" field.API_TOKEN="[REDACTED]" field.ROLE="replica" groupfield="COMPONENT" seqnum expandslices redactkeys`; s != expect {
+		t.Errorf("derived:\n%s\n%s", s, expect)
+	}
+}
@@ -0,0 +1,587 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Syslog facilities.  See RFC 5424 section 6.2.1.
+const (
+	FacilityUser   = 1
+	FacilityMail   = 2
+	FacilityDaemon = 3
+	FacilityAuth   = 4
+	FacilityLocal0 = 16
+	FacilityLocal1 = 17
+	FacilityLocal2 = 18
+	FacilityLocal3 = 19
+	FacilityLocal4 = 20
+	FacilityLocal5 = 21
+	FacilityLocal6 = 22
+	FacilityLocal7 = 23
+)
+
+// DefaultSyslogSDID is the default SD-ID of the structured data element which
+// holds the attributes.  32473 is the private enterprise number reserved for
+// documentation (RFC 5612).
+const DefaultSyslogSDID = "attrs@32473"
+
+// ErrSyslogBufferFull is returned by Syslog.Send when the buffer limit would
+// be exceeded.
+var ErrSyslogBufferFull = errors.New("sjournal: syslog buffer is full")
+
+const (
+	defaultSyslogBufferBytes = 1 << 20
+	defaultSyslogMinBackoff  = 100 * time.Millisecond
+	defaultSyslogMaxBackoff  = 30 * time.Second
+	defaultSyslogDialTimeout = 10 * time.Second
+)
+
+type SyslogOptions struct {
+	// Network is "udp" or "tcp".
+	Network string
+
+	// Address of the collector, e.g. "logs.example.net:514".
+	Address string
+
+	// Facility is combined with the priority of each entry.  Zero means
+	// FacilityUser.
+	Facility int
+
+	// SDID identifies the structured data element which holds the
+	// attributes.  It defaults to DefaultSyslogSDID.
+	SDID string
+
+	// Hostname is used if an entry doesn't have a HOSTNAME field.  It
+	// defaults to the system hostname.
+	Hostname string
+
+	// AppName is used if an entry doesn't have a SYSLOG_IDENTIFIER field.  It
+	// defaults to the program name.
+	AppName string
+
+	// BufferBytes limits the total size of frames which have been accepted
+	// but not written to a TCP connection.  It defaults to 1 MiB.
+	BufferBytes int
+
+	// MinBackoff and MaxBackoff bound the delay between failed TCP connection
+	// attempts.  The delay is doubled after each consecutive failure.  They
+	// default to 100 milliseconds and 30 seconds.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Clock is used for the TIMESTAMPs of entries which don't have a time.
+	// It defaults to SystemClock.
+	Clock Clock
+}
+
+// Syslog sends entries to a remote syslog collector as RFC 5424 messages.
+// The PRI is composed of the facility and the journald priority of the entry.
+// The TIMESTAMP is the record time; for entries passed to Send, it's taken
+// from the RECORD_REALTIME_USEC or SYSLOG_TIMESTAMP field if the entry has
+// one.  HOSTNAME, APP-NAME, PROCID and MSGID are taken from the HOSTNAME,
+// SYSLOG_IDENTIFIER, SYSLOG_PID and MESSAGE_ID fields if the entry has them.
+// The message is sent as MSG, and when a Handler uses the transport, the
+// attributes of records (including Field and Binary attributes, the latter in
+// base64) are also sent as SD-PARAMs of a structured data element.  Other
+// journal fields are not sent.
+//
+// Over UDP, each message is sent as a datagram.  Over TCP, messages use
+// octet-counting framing (RFC 6587); they are buffered and sent in the
+// background, and the connection is reestablished with backoff if it fails.
+// A message which was being written when the connection failed is sent
+// again, so it may be received more than once.
+//
+// A Syslog transport can be used by a Handler via HandlerOptions.Syslog.
+type Syslog struct {
+	network    string
+	address    string
+	facility   int
+	sdid       string
+	hostname   string
+	appName    string
+	procID     string
+	maxBytes   int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	clock      Clock
+
+	udp net.Conn // Nil if TCP is used.
+
+	mu      sync.Mutex
+	pending [][]byte // TCP frames.
+	bytes   int
+	closed  bool
+
+	notify    chan struct{}
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewSyslog(opts *SyslogOptions) (*Syslog, error) {
+	if opts == nil || opts.Address == "" {
+		return nil, errors.New("sjournal: syslog address not specified")
+	}
+
+	s := &Syslog{
+		network:    opts.Network,
+		address:    opts.Address,
+		facility:   opts.Facility,
+		sdid:       opts.SDID,
+		hostname:   opts.Hostname,
+		appName:    opts.AppName,
+		procID:     strconv.Itoa(os.Getpid()),
+		maxBytes:   opts.BufferBytes,
+		minBackoff: opts.MinBackoff,
+		maxBackoff: opts.MaxBackoff,
+		clock:      cmp.Or(opts.Clock, SystemClock),
+		notify:     make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	if s.facility == 0 {
+		s.facility = FacilityUser
+	}
+	if s.facility < 0 || s.facility > FacilityLocal7 {
+		return nil, fmt.Errorf("sjournal: invalid syslog facility: %d", s.facility)
+	}
+	if s.sdid == "" {
+		s.sdid = DefaultSyslogSDID
+	}
+	if sdName(s.sdid) != s.sdid {
+		return nil, fmt.Errorf("sjournal: invalid syslog SD-ID: %q", s.sdid)
+	}
+	if s.hostname == "" {
+		s.hostname, _ = os.Hostname()
+	}
+	if s.appName == "" && len(os.Args) > 0 {
+		s.appName = filepath.Base(os.Args[0])
+	}
+	if s.maxBytes <= 0 {
+		s.maxBytes = defaultSyslogBufferBytes
+	}
+	if s.minBackoff <= 0 {
+		s.minBackoff = defaultSyslogMinBackoff
+	}
+	if s.maxBackoff <= 0 {
+		s.maxBackoff = defaultSyslogMaxBackoff
+	}
+	s.maxBackoff = max(s.maxBackoff, s.minBackoff)
+
+	switch s.network {
+	case "udp", "udp4", "udp6":
+		conn, err := net.Dial(s.network, s.address)
+		if err != nil {
+			return nil, err
+		}
+		s.udp = conn
+		close(s.done)
+
+	case "tcp", "tcp4", "tcp6":
+		go s.run()
+
+	default:
+		return nil, fmt.Errorf("sjournal: unsupported syslog network: %q", s.network)
+	}
+
+	return s, nil
+}
+
+// Send converts an entry to a syslog message and sends it.  The entry
+// consists of fields in the native protocol format.  Over TCP, the message is
+// queued.
+func (s *Syslog) Send(entry []byte) error {
+	return s.send(entry, time.Time{}, nil)
+}
+
+// send an entry with the record time (or zero) and encoded SD-PARAMs (see
+// appendSyslogParam).
+func (s *Syslog) send(entry []byte, t time.Time, params []byte) error {
+	frame := s.format(entry, t, params)
+
+	if s.udp != nil {
+		s.mu.Lock()
+		closed := s.closed
+		s.mu.Unlock()
+		if closed {
+			return ErrClosed
+		}
+
+		_, err := s.udp.Write(frame)
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if s.bytes+len(frame) > s.maxBytes {
+		return ErrSyslogBufferFull
+	}
+
+	s.pending = append(s.pending, frame)
+	s.bytes += len(frame)
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Close stops accepting entries and sends the buffered messages.  If the
+// context is done before that, sending is aborted and the context error is
+// returned.  Nil context means that sending is aborted immediately.
+func (s *Syslog) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	})
+
+	if s.udp != nil {
+		return s.udp.Close()
+	}
+
+	if ctx == nil {
+		s.abort()
+		<-s.done
+		return nil
+	}
+
+	select {
+	case <-s.done:
+		return nil
+
+	case <-ctx.Done():
+		s.abort()
+		<-s.done
+		return ctx.Err()
+	}
+}
+
+func (s *Syslog) abort() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// run writes pending TCP frames until closed.
+func (s *Syslog) run() {
+	defer close(s.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-s.done:
+		}
+	}()
+
+	var (
+		conn    net.Conn
+		backoff time.Duration
+	)
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for s.waitPending() {
+		if conn == nil {
+			var err error
+			conn, err = s.dial(ctx)
+			if err != nil {
+				if !s.sleep(&backoff) {
+					return
+				}
+				continue
+			}
+		}
+
+		if err := s.writePending(conn); err != nil {
+			conn.Close()
+			conn = nil
+			if !s.sleep(&backoff) {
+				return
+			}
+			continue
+		}
+
+		backoff = 0
+	}
+}
+
+func (s *Syslog) dial(ctx context.Context) (net.Conn, error) {
+	d := net.Dialer{Timeout: defaultSyslogDialTimeout}
+	return d.DialContext(ctx, s.network, s.address)
+}
+
+// sleep for the next backoff duration.  It returns false if sending was
+// aborted.
+func (s *Syslog) sleep(backoff *time.Duration) bool {
+	if *backoff == 0 {
+		*backoff = s.minBackoff
+	} else {
+		*backoff = min(*backoff*2, s.maxBackoff)
+	}
+
+	timer := time.NewTimer(*backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.stop:
+		return false
+	}
+}
+
+// waitPending returns false if there will be nothing more to send.
+func (s *Syslog) waitPending() bool {
+	for {
+		s.mu.Lock()
+		n := len(s.pending)
+		closed := s.closed
+		s.mu.Unlock()
+
+		if n > 0 {
+			return true
+		}
+		if closed {
+			return false
+		}
+
+		select {
+		case <-s.notify:
+		case <-s.stop:
+			return false
+		}
+	}
+}
+
+// writePending frames to the connection.  A frame is removed from the buffer
+// only after it has been written.
+func (s *Syslog) writePending(conn net.Conn) error {
+	for {
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		frame := s.pending[0]
+		s.mu.Unlock()
+
+		var prefix [24]byte
+		b := strconv.AppendInt(prefix[:0], int64(len(frame)), 10)
+		b = append(b, ' ')
+
+		if _, err := (&net.Buffers{b, frame}).WriteTo(conn); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		s.pending[0] = nil
+		s.pending = s.pending[1:]
+		s.bytes -= len(frame)
+		s.mu.Unlock()
+	}
+}
+
+// format an entry as an RFC 5424 message.  If the time is zero, it's taken
+// from the entry or the clock.
+func (s *Syslog) format(entry []byte, t time.Time, params []byte) []byte {
+	var (
+		priority = priorityInfo
+		message  []byte
+		hostname = s.hostname
+		appName  = s.appName
+		procID   = s.procID
+		msgID    string
+		usec     int64
+		sec      int64
+	)
+
+	rangeFields(entry, func(name string, value []byte) {
+		switch name {
+		case "PRIORITY":
+			if len(value) == 1 && value[0] >= '0' && value[0] < '0'+numPriorities {
+				priority = int(value[0] - '0')
+			}
+		case "MESSAGE":
+			message = value
+		case "HOSTNAME":
+			hostname = string(value)
+		case "SYSLOG_IDENTIFIER":
+			appName = string(value)
+		case "SYSLOG_PID":
+			procID = string(value)
+		case "MESSAGE_ID":
+			msgID = string(value)
+		case "RECORD_REALTIME_USEC":
+			usec, _ = strconv.ParseInt(string(value), 10, 64)
+		case "SYSLOG_TIMESTAMP":
+			sec, _ = strconv.ParseInt(string(value), 10, 64)
+		}
+	})
+
+	if t.IsZero() {
+		switch {
+		case usec > 0:
+			t = time.UnixMicro(usec)
+		case sec > 0:
+			t = time.Unix(sec, 0)
+		default:
+			t = s.clock.Now()
+		}
+	}
+
+	b := make([]byte, 0, 128+len(message)+len(entry)/2)
+
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(s.facility*8+priority), 10)
+	b = append(b, ">1 "...)
+	b = t.UTC().AppendFormat(b, "2006-01-02T15:04:05.000000Z")
+	b = append(b, ' ')
+	b = appendHeaderField(b, hostname, 255)
+	b = append(b, ' ')
+	b = appendHeaderField(b, appName, 48)
+	b = append(b, ' ')
+	b = appendHeaderField(b, procID, 128)
+	b = append(b, ' ')
+	b = appendHeaderField(b, msgID, 32)
+	b = append(b, ' ')
+
+	if len(params) > 0 {
+		b = append(b, '[')
+		b = append(b, s.sdid...)
+		b = append(b, params...)
+		b = append(b, ']')
+	} else {
+		b = append(b, '-')
+	}
+
+	if len(message) > 0 {
+		b = append(b, ' ')
+		b = append(b, message...)
+	}
+
+	return b
+}
+
+// appendHeaderField appends a value consisting of printable US-ASCII
+// characters, or the nil value.  Other characters are replaced with
+// underscores.
+func appendHeaderField(b []byte, value string, maxLen int) []byte {
+	if value == "" {
+		return append(b, '-')
+	}
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 33 || c > 126 {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// sdName converts a string to a valid SD-NAME: at most 32 printable US-ASCII
+// characters other than '=', space, ']' and '"'.  Other characters are
+// replaced with underscores.
+func sdName(s string) string {
+	if s == "" {
+		return "_"
+	}
+	if len(s) > 32 {
+		s = s[:32]
+	}
+
+	b := []byte(s)
+	for i, c := range b {
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// appendSyslogParam appends an attribute as an SD-PARAM, preceded by a space.
+func appendSyslogParam(b []byte, key, value string) []byte {
+	b = append(b, ' ')
+	b = append(b, sdName(key)...)
+	b = append(b, `="`...)
+	b = appendParamValue(b, value)
+	return append(b, '"')
+}
+
+// appendParamValue escapes '"', '\' and ']'.
+func appendParamValue(b []byte, value string) []byte {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch c {
+		case '"', '\\', ']':
+			b = append(b, '\\')
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// rangeFields calls f for each field of an entry in the native protocol
+// format.  Iteration stops at malformed data.
+func rangeFields(b []byte, f func(name string, value []byte)) {
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			return
+		}
+		line := b[:i]
+		b = b[i+1:]
+
+		if name, value, found := bytes.Cut(line, []byte("=")); found {
+			f(string(name), value)
+			continue
+		}
+
+		if len(b) < 8 {
+			return
+		}
+		size := binary.LittleEndian.Uint64(b)
+		b = b[8:]
+		if size >= uint64(len(b)) || b[size] != '\n' {
+			return
+		}
+		f(string(line), b[:size])
+		b = b[size+1:]
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testSyslogMessage struct {
+	pri      int
+	header   []string // VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID
+	sdid     string
+	params   map[string]string
+	paramSeq []string
+	msg      string
+}
+
+// parseSyslogMessage parses an RFC 5424 message with at most one structured
+// data element.
+func parseSyslogMessage(s string) (m testSyslogMessage, err error) {
+	if !strings.HasPrefix(s, "<") {
+		return m, fmt.Errorf("no PRI: %q", s)
+	}
+	end := strings.IndexByte(s, '>')
+	if m.pri, err = strconv.Atoi(s[1:end]); err != nil {
+		return m, err
+	}
+	s = s[end+1:]
+
+	for range 6 {
+		field, rest, found := strings.Cut(s, " ")
+		if !found {
+			return m, fmt.Errorf("truncated header: %q", s)
+		}
+		m.header = append(m.header, field)
+		s = rest
+	}
+
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+	} else {
+		if !strings.HasPrefix(s, "[") {
+			return m, fmt.Errorf("no structured data: %q", s)
+		}
+		s = s[1:]
+		var found bool
+		if m.sdid, s, found = strings.Cut(s, " "); !found {
+			m.sdid, s, _ = strings.Cut(s, "]")
+			s = "]" + s
+		}
+		m.params = make(map[string]string)
+
+		for !strings.HasPrefix(s, "]") {
+			name, rest, found := strings.Cut(s, `="`)
+			if !found {
+				return m, fmt.Errorf("invalid param: %q", s)
+			}
+			s = rest
+
+			var value strings.Builder
+			for {
+				if s == "" {
+					return m, fmt.Errorf("unterminated param value")
+				}
+				c := s[0]
+				s = s[1:]
+				if c == '\\' && s != "" {
+					value.WriteByte(s[0])
+					s = s[1:]
+					continue
+				}
+				if c == '"' {
+					break
+				}
+				value.WriteByte(c)
+			}
+
+			m.params[name] = value.String()
+			m.paramSeq = append(m.paramSeq, name)
+			s = strings.TrimPrefix(s, " ")
+		}
+		s = s[1:]
+	}
+
+	m.msg = strings.TrimPrefix(s, " ")
+	return m, nil
+}
+
+func TestSyslogFormat(t *testing.T) {
+	s := &Syslog{
+		facility: FacilityLocal3,
+		sdid:     DefaultSyslogSDID,
+		hostname: "host name",
+		appName:  "app",
+		procID:   "1234",
+	}
+
+	var entry []byte
+	entry = appendField(entry, "PRIORITY", "4")
+	entry = appendField(entry, "MESSAGE", "hello\nworld")
+	entry = appendField(entry, "SYSLOG_IDENTIFIER", "ident")
+	entry = appendField(entry, "CODE_LINE", "10")
+	entry = appendField(entry, "SYSLOG_TIMESTAMP", "1")
+	params := appendSyslogParam(nil, "a", "1")
+	params = appendSyslogParam(params, "b c", `"d]\`+"\n")
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 678901234, time.UTC)
+	frame := string(s.format(entry, now, params))
+
+	expect := `<156>1 2026-01-02T03:04:05.678901Z host_name ident 1234 - [attrs@32473 a="1" b_c="\"d\]\\` + "\n" + `"] hello` + "\n" + `world`
+	if frame != expect {
+		t.Errorf("frame:\n%s\nexpected:\n%s", frame, expect)
+	}
+
+	m, err := parseSyslogMessage(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := m.params["b_c"]; v != `"d]\`+"\n" {
+		t.Errorf("param: %q", v)
+	}
+
+	frame = string(s.format(appendField(nil, "MESSAGE_ID", "0123456789abcdef0123456789abcdef"), now, nil))
+	if expect := `<158>1 2026-01-02T03:04:05.678901Z host_name app 1234 0123456789abcdef0123456789abcdef -`; frame != expect {
+		t.Errorf("frame: %q", frame)
+	}
+
+	// Time from the entry.
+	entry = appendField(nil, "SYSLOG_TIMESTAMP", "1767323045")
+	if frame := string(s.format(entry, time.Time{}, nil)); !strings.HasPrefix(frame, "<158>1 2026-01-02T03:04:05.000000Z ") {
+		t.Errorf("SYSLOG_TIMESTAMP: %q", frame)
+	}
+	entry = appendField(entry, "RECORD_REALTIME_USEC", "1767323045678901")
+	if frame := string(s.format(entry, time.Time{}, nil)); !strings.HasPrefix(frame, "<158>1 2026-01-02T03:04:05.678901Z ") {
+		t.Errorf("RECORD_REALTIME_USEC: %q", frame)
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s, err := NewSyslog(&SyslogOptions{
+		Network:  "udp",
+		Address:  conn.LocalAddr().String(),
+		Facility: FacilityDaemon,
+		SDID:     "test@32473",
+		Hostname: "testhost",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHandler(&HandlerOptions{
+		Syslog:     s,
+		Identifier: "myapp",
+		SyslogPID:  42,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(h).With("component", "db")
+
+	// The TIMESTAMP is the record time, not the send time.
+	when := time.Now().Add(-time.Hour)
+	r := slog.NewRecord(when, slog.LevelWarn, "hello", 0)
+	r.AddAttrs(slog.String("user", "alice"), slog.Group("req", "path", `/a"b]`, "n", 3), Binary("blob", []byte{0, 1}))
+	if err := logger.Handler().Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := parseSyslogMessage(string(buf[:n]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.pri != FacilityDaemon*8+4 {
+		t.Errorf("PRI: %d", m.pri)
+	}
+	if h := m.header; h[0] != "1" || h[2] != "testhost" || h[3] != "myapp" || h[4] != "42" || h[5] != "-" {
+		t.Errorf("header: %q", h)
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, m.header[1]); err != nil {
+		t.Error(err)
+	} else if !ts.Equal(when.Truncate(time.Microsecond)) {
+		t.Errorf("TIMESTAMP: %s", m.header[1])
+	}
+	if m.sdid != "test@32473" {
+		t.Errorf("SD-ID: %q", m.sdid)
+	}
+	if s := strings.Join(m.paramSeq, " "); s != "component user req.path req.n blob" {
+		t.Errorf("params: %s", s)
+	}
+	for k, v := range map[string]string{"component": "db", "user": "alice", "req.path": `/a"b]`, "req.n": "3", "blob": "AAE="} {
+		if m.params[k] != v {
+			t.Errorf("param %s: %q", k, m.params[k])
+		}
+	}
+	if !strings.HasPrefix(m.msg, "hello") {
+		t.Errorf("MSG: %q", m.msg)
+	}
+
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if err := s.Send(nil); err != ErrClosed {
+		t.Error(err)
+	}
+}
+
+func TestSyslogTCPReconnect(t *testing.T) {
+	// Reserve an address, but don't listen yet.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	s, err := NewSyslog(&SyslogOptions{
+		Network:    "tcp",
+		Address:    addr,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHandler(&HandlerOptions{Syslog: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(h)
+	for i := range 3 {
+		logger.Info("entry\nwith newline", "i", i)
+	}
+
+	time.Sleep(50 * time.Millisecond) // Let some connection attempts fail.
+
+	if l, err = net.Listen("tcp", addr); err != nil {
+		t.Skip(err) // The port was taken by someone else.
+	}
+	defer l.Close()
+
+	frames := make(chan string, 10)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+
+		r := bufio.NewReader(c)
+		for {
+			size, err := r.ReadString(' ')
+			if err != nil {
+				close(frames)
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSuffix(size, " "))
+			if err != nil {
+				frames <- "invalid length: " + size
+				close(frames)
+				return
+			}
+			b := make([]byte, n)
+			if _, err := io.ReadFull(r, b); err != nil {
+				close(frames)
+				return
+			}
+			frames <- string(b)
+		}
+	}()
+
+	for i := range 3 {
+		var frame string
+		select {
+		case frame = <-frames:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+
+		m, err := parseSyslogMessage(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.params["i"] != strconv.Itoa(i) || m.msg != fmt.Sprintf("entry\nwith newlinei=%d", i) {
+			t.Errorf("frame %d: %q", i, frame)
+		}
+	}
+
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// messageIDNamespace is the UUID namespace of message template IDs.
+var messageIDNamespace = [16]byte{0x5d, 0x1c, 0x6e, 0x3a, 0x8b, 0x24, 0x4f, 0x0e, 0x9a, 0x51, 0x27, 0xc3, 0x70, 0xd8, 0x16, 0xb9}
+
+var messageIDCache sync.Map
+
+// Msg formats a message according to a format specifier, and returns it with
+// an attribute which causes the handler to emit the template as the
+// MESSAGE_TEMPLATE field and its UUIDv5 as the MESSAGE_ID field.  The results
+// can be passed directly to the slog.Logger methods:
+//
+//	logger.Info(sjournal.Msg("user %s logged in from %s", user, addr))
+//
+// Other handlers see a "message_id" attribute.
+func Msg(template string, args ...any) (string, slog.Attr) {
+	return fmt.Sprintf(template, args...), slog.Any("message_id", messageTemplate{template, MessageID(template)})
+}
+
+// MessageID derives the MESSAGE_ID used by Msg for a template.  The ID is
+// formatted as 32 lowercase hexadecimal digits, like journald expects.
+func MessageID(template string) string {
+	if x, found := messageIDCache.Load(template); found {
+		return x.(string)
+	}
+
+	h := sha1.New()
+	h.Write(messageIDNamespace[:])
+	h.Write([]byte(template))
+
+	uuid := h.Sum(nil)[:16]
+	uuid[6] = uuid[6]&0x0f | 0x50 // Version 5.
+	uuid[8] = uuid[8]&0x3f | 0x80 // RFC 4122 variant.
+
+	id := hex.EncodeToString(uuid)
+	messageIDCache.Store(template, id)
+	return id
+}
+
+type messageTemplate struct {
+	template string
+	id       string
+}
+
+func (t messageTemplate) LogValue() slog.Value {
+	return slog.StringValue(t.id)
+}
+
+func (s *handleState) appendMessageTemplate(t messageTemplate) {
+	s.appendField("MESSAGE_ID", t.id)
+	s.appendField("MESSAGE_TEMPLATE", t.template)
+}
@@ -0,0 +1,79 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMsg(t *testing.T) {
+	recv := newTestReceiver(t)
+
+	h, err := NewHandler(&HandlerOptions{
+		Delimiter: DefaultDelimiter,
+		Socket:    recv.path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	const (
+		loginTemplate  = "user %s logged in from %s"
+		logoutTemplate = "user %s logged out"
+	)
+
+	logger := slog.New(h)
+	logger.Info(Msg(loginTemplate, "alice", "10.0.0.1"))
+	logger.Info(Msg(loginTemplate, "bob", "10.0.0.2"))
+	logger.Info(Msg(logoutTemplate, "alice"))
+	_, multiline := Msg("multi\nline")
+	logger.With(multiline).Info("preformatted", "x", 1)
+	logger.Info("plain")
+
+	ms := recv.wait(t, 5)
+
+	if s := ms[0]["MESSAGE"]; s != "user alice logged in from 10.0.0.1" {
+		t.Errorf("message: %q", s)
+	}
+	if s := ms[0]["MESSAGE_TEMPLATE"]; s != loginTemplate {
+		t.Errorf("template: %q", s)
+	}
+
+	id := ms[0]["MESSAGE_ID"]
+	if !regexp.MustCompile("^[0-9a-f]{12}5[0-9a-f]{3}[89ab][0-9a-f]{15}$").MatchString(id) {
+		t.Errorf("invalid message id: %q", id)
+	}
+	if s := ms[1]["MESSAGE_ID"]; s != id {
+		t.Errorf("message id of same template: %q", s)
+	}
+	if s := ms[2]["MESSAGE_ID"]; s == id || s != MessageID(logoutTemplate) {
+		t.Errorf("message id of different template: %q", s)
+	}
+
+	if s := ms[3]["MESSAGE"]; s != "preformatted x=1" {
+		t.Errorf("message: %q", s)
+	}
+	if s := ms[3]["MESSAGE_TEMPLATE"]; s != "multi\nline" {
+		t.Errorf("template: %q", s)
+	}
+
+	if _, found := ms[4]["MESSAGE_ID"]; found {
+		t.Error("message id without template")
+	}
+}
+
+func TestMsgOtherHandler(t *testing.T) {
+	var b bytes.Buffer
+	slog.New(slog.NewTextHandler(&b, nil)).Info(Msg("hello %s", "world"))
+
+	if s := b.String(); !strings.Contains(s, `msg="hello world" message_id=`+MessageID("hello %s")) {
+		t.Error(s)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"encoding"
+	"fmt"
+)
+
+// textCompatValue formats a value of kind Any like slog.TextHandler.  quote
+// is true if the string must be quoted regardless of its content.
+func textCompatValue(v any) (s string, quote bool) {
+	if tm, ok := v.(encoding.TextMarshaler); ok {
+		data, err := tm.MarshalText()
+		if err != nil {
+			return fmt.Sprintf("!ERROR:%v", err), false
+		}
+		return string(data), false
+	}
+	if bs, ok := byteSlice(v); ok {
+		return string(bs), true
+	}
+	return fmt.Sprintf("%+v", v), false
+}
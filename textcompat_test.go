@@ -0,0 +1,179 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+type compatStruct struct {
+	Name  string
+	Count int
+}
+
+type compatText string
+
+func (t compatText) MarshalText() ([]byte, error) {
+	if t == "" {
+		return nil, errors.New("empty")
+	}
+	return []byte("<" + t + ">"), nil
+}
+
+type compatBytes []byte
+
+type compatValuer struct{ v any }
+
+func (v compatValuer) LogValue() slog.Value { return slog.AnyValue(v.v) }
+
+var compatStrings = []string{
+	"", "plain", "with space", "a=b", `"quoted"`, `back\slash`, "multi\nline",
+	"tab\there", "\x00", "\x7f", "caf\u00e9", "nb\u00a0sp", "line\u2028sep",
+	"\xff\xfe", "\u0085", "emoji\U0001f600", "-", "{}", "[1 2]", "key:", "日本",
+}
+
+func compatKey(r *rand.Rand) string {
+	if r.IntN(8) == 0 {
+		return compatStrings[r.IntN(len(compatStrings))]
+	}
+	return string(rune('a' + r.IntN(6)))
+}
+
+func compatAttr(r *rand.Rand, depth int) slog.Attr {
+	key := compatKey(r)
+
+	locs := []*time.Location{time.UTC, time.FixedZone("X", -(3*3600 + 1800)), time.FixedZone("", 5*3600)}
+
+	switch r.IntN(17) {
+	case 0:
+		return slog.String(key, compatStrings[r.IntN(len(compatStrings))])
+	case 1:
+		return slog.Int64(key, r.Int64()-r.Int64())
+	case 2:
+		return slog.Uint64(key, r.Uint64())
+	case 3:
+		fs := []float64{0, math.Copysign(0, -1), 1.5, -1e21, 1e-7, math.NaN(), math.Inf(1), math.Inf(-1), r.Float64()}
+		return slog.Float64(key, fs[r.IntN(len(fs))])
+	case 4:
+		return slog.Bool(key, r.IntN(2) == 0)
+	case 5:
+		return slog.Duration(key, time.Duration(r.Int64N(1e12)-5e11))
+	case 6:
+		t := time.Unix(r.Int64N(4e9)-1e9, r.Int64N(1e9)).In(locs[r.IntN(len(locs))])
+		if r.IntN(10) == 0 {
+			t = time.Time{}
+		}
+		return slog.Time(key, t)
+	case 7:
+		return slog.Any(key, []byte(compatStrings[r.IntN(len(compatStrings))]))
+	case 8:
+		return slog.Any(key, compatBytes(compatStrings[r.IntN(len(compatStrings))]))
+	case 9:
+		return slog.Any(key, compatText(compatStrings[r.IntN(len(compatStrings))]))
+	case 10:
+		return slog.Any(key, netip.AddrFrom4([4]byte{10, 0, 0, byte(r.IntN(256))}))
+	case 11:
+		return slog.Any(key, fmt.Errorf("failed: %s", compatStrings[r.IntN(len(compatStrings))]))
+	case 12:
+		return slog.Any(key, compatStruct{compatStrings[r.IntN(len(compatStrings))], r.IntN(100)})
+	case 13:
+		return slog.Any(key, map[string]int{"x": r.IntN(10)})
+	case 14:
+		return slog.Any(key, nil)
+	case 15:
+		return slog.Any(key, compatValuer{r.IntN(100)})
+	default:
+		if depth > 2 {
+			return slog.String(key, "leaf")
+		}
+		if r.IntN(4) == 0 {
+			key = ""
+		}
+		var attrs []any
+		for range r.IntN(4) {
+			attrs = append(attrs, compatAttr(r, depth+1))
+		}
+		return slog.Group(key, attrs...)
+	}
+}
+
+func TestTextCompat(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+
+	for i := range 2000 {
+		var text bytes.Buffer
+		th := slog.Handler(slog.NewTextHandler(&text, nil))
+
+		h, err := NewHandler(&HandlerOptions{Delimiter: DefaultDelimiter, TextCompat: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var message string
+		h.root.sink = func(b []byte) error {
+			rangeFields(b, func(name string, value []byte) {
+				if name == "MESSAGE" {
+					message = string(value)
+				}
+			})
+			return nil
+		}
+		jh := slog.Handler(h)
+
+		for range r.IntN(3) {
+			if r.IntN(2) == 0 {
+				name := compatKey(r)
+				th = th.WithGroup(name)
+				jh = jh.WithGroup(name)
+			} else {
+				attrs := []slog.Attr{compatAttr(r, 0)}
+				th = th.WithAttrs(attrs)
+				jh = jh.WithAttrs(attrs)
+			}
+		}
+
+		rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "m", 0)
+		for range r.IntN(5) {
+			rec.AddAttrs(compatAttr(r, 0))
+		}
+
+		if err := th.Handle(context.Background(), rec.Clone()); err != nil {
+			t.Fatal(err)
+		}
+		if err := jh.Handle(context.Background(), rec.Clone()); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := strings.TrimSuffix(strings.TrimPrefix(text.String(), "level=INFO msg=m"), "\n")
+		if actual := strings.TrimPrefix(message, "m"); actual != expect {
+			t.Errorf("record %d:\ntext:     %q\njournald: %q", i, expect, actual)
+		}
+	}
+}
+
+func TestTextCompatSource(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		Delimiter:    DefaultDelimiter,
+		TextCompat:   true,
+		SourceFormat: SourceTrimPath | SourceFunction,
+	})
+
+	src := &slog.Source{Function: "main.f", File: "/src/main.go", Line: 7}
+	when := time.Date(2026, 1, 2, 3, 4, 5, 6789000, time.FixedZone("", 3600))
+	slog.New(h).Info("msg", "src", src, "t", when, "b", []byte("x"))
+
+	if s := recv.wait(t, 1)[0]["MESSAGE"]; s != `msg src=/src/main.go:7 t=2026-01-02T03:04:05.006+01:00 b="x"` {
+		t.Errorf("%q", s)
+	}
+}
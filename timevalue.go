@@ -0,0 +1,88 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// TimeValueFormat determines how time-valued attributes are rendered.
+type TimeValueFormat int
+
+const (
+	TimeLayout      TimeValueFormat = iota // TimeFormat, or time.Time.String.
+	TimeUnixSeconds                        // Integer seconds since the Unix epoch.
+	TimeUnixMilli                          // Integer milliseconds since the Unix epoch.
+	TimeUnixNano                           // Integer nanoseconds since the Unix epoch.
+)
+
+// Layouts which can be passed to Time for integer renderings.
+const (
+	LayoutUnixSeconds = "unix"      // Like TimeUnixSeconds.
+	LayoutUnixMilli   = "unixmilli" // Like TimeUnixMilli.
+	LayoutUnixNano    = "unixnano"  // Like TimeUnixNano.
+)
+
+// Time returns an attribute which the handler renders using the layout (see
+// time.Time.Format) instead of TimeFormat and TimeValueFormat.  TimeLocation
+// applies.  The layout may also be LayoutUnixSeconds, LayoutUnixMilli or
+// LayoutUnixNano.  Empty layout means the handler's format.  Other handlers
+// see a normal time attribute.
+func Time(key string, t time.Time, layout string) slog.Attr {
+	return slog.Any(key, timeValue{t, layout})
+}
+
+type timeValue struct {
+	t      time.Time
+	layout string
+}
+
+func (v timeValue) LogValue() slog.Value {
+	return slog.TimeValue(v.t)
+}
+
+// format the time using the layout, or according to the handler if the layout
+// is empty.
+func (v timeValue) format(h *Handler, cfg *config) string {
+	switch v.layout {
+	case "":
+		return h.timeValueFormat.formatTime(cfg, v.t)
+	case LayoutUnixSeconds:
+		return TimeUnixSeconds.formatTime(cfg, v.t)
+	case LayoutUnixMilli:
+		return TimeUnixMilli.formatTime(cfg, v.t)
+	case LayoutUnixNano:
+		return TimeUnixNano.formatTime(cfg, v.t)
+	}
+
+	t := v.t
+	if cfg.timeLocation != nil {
+		t = t.In(cfg.timeLocation)
+	}
+	return t.Format(v.layout)
+}
+
+// formatTime according to the format.  TimeFormat and TimeLocation apply only
+// to TimeLayout.
+func (f TimeValueFormat) formatTime(cfg *config, t time.Time) string {
+	switch f {
+	case TimeUnixSeconds:
+		return strconv.FormatInt(t.Unix(), 10)
+	case TimeUnixMilli:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case TimeUnixNano:
+		return strconv.FormatInt(t.UnixNano(), 10)
+	}
+
+	if cfg.timeLocation != nil {
+		t = t.In(cfg.timeLocation)
+	}
+	if cfg.timeFormat != "" {
+		return t.Format(cfg.timeFormat)
+	}
+	return t.String()
+}
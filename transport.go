@@ -0,0 +1,93 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+// Transport delivers encoded entries somewhere else than the journald socket.
+// The payload is an entry in the journald native protocol format; it must not
+// be retained after Send returns.  needsFile is true if the entry is larger
+// than the datagram size limit of the journald socket (see DatagramLimit), in
+// which case journald would have to receive it via a file.  Send may be called
+// concurrently.
+//
+// The default transport sends the entries to the journald socket, passing
+// large entries via files or chunking them.  Uploader and Syslog are
+// alternative transports with their own options.
+type Transport interface {
+	Send(payload []byte, needsFile bool) error
+}
+
+// journalTransport is the default transport.  It uses the sockets and the
+// destination address of the handler.
+type journalTransport struct {
+	r *root
+}
+
+func (t *journalTransport) Send(b []byte, needsFile bool) error {
+	large, err := t.send(b)
+	if large {
+		t.r.largeEntry(len(b), nil)
+	}
+	return err
+}
+
+// send an entry to one of the sockets, and report whether it was passed via
+// a file.
+func (t *journalTransport) send(b []byte) (large bool, err error) {
+	r := t.r
+	addr := r.addr.Load()
+	sock := r.socket()
+	start := r.slowSendStart()
+
+	_, _, err = sock.WriteMsgUnix(b, nil, addr)
+	if err != nil {
+		r.learnDatagramLimit(err, len(b))
+		if r.chunkSize > 0 && tooLarge(err) {
+			err = r.sendChunks(err, b, sock, addr)
+		} else {
+			large, err = sendViaFileIfTooLarge(err, b, r.largeEntryField, sock, addr)
+		}
+	}
+	r.finishSend(start, sock, addr)
+	if err != nil {
+		if r.closed.Load() && errors.Is(err, net.ErrClosed) {
+			return false, ErrClosed
+		}
+		return false, r.permissionError(err, addr.Name)
+	}
+	return large, nil
+}
+
+// sendFunc adapts the Send method of Uploader and Syslog.
+type sendFunc func([]byte) error
+
+func (f sendFunc) Send(b []byte, needsFile bool) error { return f(b) }
+
+// WriterTransport writes entries to an io.Writer in the Journal Export Format
+// (see Encoder).  It can be used for capturing the output of a handler, or
+// for feeding it to systemd-journal-remote via a pipe.
+type WriterTransport struct {
+	mu  sync.Mutex
+	enc *Encoder
+}
+
+// NewWriterTransport returns a transport which writes to w.  Writes are
+// serialized, so w doesn't need to be safe for concurrent use.
+func NewWriterTransport(w io.Writer) *WriterTransport {
+	return &WriterTransport{enc: NewEncoder(w)}
+}
+
+// Send writes an entry.  The entries are written one at a time.
+func (t *WriterTransport) Send(payload []byte, needsFile bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.enc.Encode(payload)
+}
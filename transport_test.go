@@ -0,0 +1,170 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingTransport struct {
+	mu        sync.Mutex
+	payloads  [][]byte
+	needsFile []bool
+}
+
+func (t *recordingTransport) Send(payload []byte, needsFile bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.payloads = append(t.payloads, slices.Clone(payload))
+	t.needsFile = append(t.needsFile, needsFile)
+	return nil
+}
+
+func TestTransport(t *testing.T) {
+	_, recv := newTestHandler(t, nil)
+	h, err := NewHandler(&HandlerOptions{Socket: recv.path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	tr := new(recordingTransport)
+	ht, err := NewHandler(&HandlerOptions{Socket: recv.path, Transport: tr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	// The same record via the socket and the transport.
+	r := slog.NewRecord(time.Unix(1700000000, 0), slog.LevelWarn, "hello", callerPCForTest())
+	r.AddAttrs(slog.String("data", "multi\nline"), slog.Int("n", 1))
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	if err := ht.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	recv.wait(t, 1)
+	if len(tr.payloads) != 1 || !bytes.Equal(tr.payloads[0], recv.datagrams()[0]) {
+		t.Errorf("payloads: %q", tr.payloads)
+	}
+	if tr.needsFile[0] {
+		t.Error("small entry needs file")
+	}
+	if s := ht.Stats(); s.Sent != 1 {
+		t.Errorf("stats: %+v", s)
+	}
+
+	ht.root.dgramMax.Store(100)
+	slog.New(ht).Info(strings.Repeat("x", 100))
+	if !tr.needsFile[1] {
+		t.Error("large entry doesn't need file")
+	}
+
+	if s := ht.String(); !strings.Contains(s, "transport=custom") {
+		t.Error(s)
+	}
+}
+
+func TestWriterTransport(t *testing.T) {
+	var buf bytes.Buffer
+
+	h, err := NewHandler(&HandlerOptions{
+		Delimiter: ColonDelimiter,
+		Transport: NewWriterTransport(&buf),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(h)
+	logger.Info("first", "n", 1)
+	logger.Error("second\nline", "n", 2)
+	h.Close()
+
+	dec := NewDecoder(&buf)
+	for i, expect := range []struct {
+		message  string
+		priority string
+	}{
+		{"first: n=1", "6"},
+		{"second\nline: n=2", "3"},
+	} {
+		e, err := dec.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := string(e["MESSAGE"]); s != expect.message {
+			t.Errorf("entry %d: message %q", i, s)
+		}
+		if s := string(e["PRIORITY"]); s != expect.priority {
+			t.Errorf("entry %d: priority %q", i, s)
+		}
+		if _, found := e["CODE_FILE"]; !found {
+			t.Errorf("entry %d: no CODE_FILE", i)
+		}
+	}
+	if _, err := dec.Next(); err != io.EOF {
+		t.Error(err)
+	}
+}
+
+type discardTransport struct{}
+
+func (discardTransport) Send([]byte, bool) error { return nil }
+
+func BenchmarkTransport(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram", Name: path})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadBuffer(8 << 20)
+
+	go func() {
+		buf := make([]byte, 65536)
+		for {
+			if _, err := conn.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, c := range []struct {
+		name string
+		opts HandlerOptions
+	}{
+		{"socket", HandlerOptions{Socket: path}},
+		{"discard", HandlerOptions{Transport: discardTransport{}}},
+		{"writer", HandlerOptions{Transport: NewWriterTransport(io.Discard)}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			h, err := NewHandler(&c.opts)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer h.Close()
+			logger := slog.New(h)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				logger.Info("benchmark", "key", "value")
+			}
+		})
+	}
+}
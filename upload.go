@@ -0,0 +1,364 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// UploadContentType is the media type of the Journal Export Format.
+const UploadContentType = "application/vnd.fdo.journal"
+
+// ErrUploadBufferFull is returned by Uploader.Send when the buffer limit
+// would be exceeded.
+var ErrUploadBufferFull = errors.New("sjournal: upload buffer is full")
+
+const (
+	defaultUploadBufferBytes = 1 << 20
+	defaultUploadMinBackoff  = 100 * time.Millisecond
+	defaultUploadMaxBackoff  = 30 * time.Second
+	defaultUploadLinger      = time.Second
+)
+
+type UploaderOptions struct {
+	// URL of the upload endpoint, e.g. "https://example.net:19532/upload".
+	URL string
+
+	// TLSConfig is used for HTTPS connections, unless Client is set.
+	TLSConfig *tls.Config
+
+	// Client is used for requests instead of a client created by the
+	// uploader.
+	Client *http.Client
+
+	// BufferBytes limits the total size of entries which have been accepted
+	// but not uploaded successfully.  It defaults to 1 MiB.
+	BufferBytes int
+
+	// MinBackoff and MaxBackoff bound the delay between failed upload
+	// attempts.  The delay is doubled after each consecutive failure.  They
+	// default to 100 milliseconds and 30 seconds.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Linger is how long a request is kept open waiting for more entries
+	// after the buffer has been emptied.  It defaults to one second.
+	Linger time.Duration
+
+	// Clock is used for the __REALTIME_TIMESTAMP fields.  It defaults to
+	// SystemClock.
+	Clock Clock
+}
+
+// Uploader streams entries to systemd-journal-remote (or a compatible
+// server) over HTTP.  Each request body is a stream of entries in the
+// Journal Export Format, sent using chunked transfer encoding.  Entries
+// included in a failed request are retried, so an entry may be uploaded more
+// than once.
+//
+// An uploader can be used as the transport of a Handler via
+// HandlerOptions.Uploader.
+type Uploader struct {
+	url        string
+	client     *http.Client
+	maxBytes   int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	linger     time.Duration
+	clock      Clock
+
+	mu       sync.Mutex
+	pending  [][]byte
+	inflight [][]byte // Taken by the current request.
+	bytes    int      // Size of pending and inflight entries.
+	closed   bool
+
+	notify    chan struct{} // Signaled when pending may have grown.
+	stop      chan struct{} // Closed to abort uploading.
+	stopOnce  sync.Once
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewUploader(opts *UploaderOptions) (*Uploader, error) {
+	if opts == nil || opts.URL == "" {
+		return nil, errors.New("sjournal: upload URL not specified")
+	}
+
+	client := opts.Client
+	if client == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = opts.TLSConfig
+		client = &http.Client{Transport: t}
+	}
+
+	u := &Uploader{
+		url:        opts.URL,
+		client:     client,
+		maxBytes:   opts.BufferBytes,
+		minBackoff: opts.MinBackoff,
+		maxBackoff: opts.MaxBackoff,
+		linger:     opts.Linger,
+		clock:      cmp.Or(opts.Clock, SystemClock),
+		notify:     make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if u.maxBytes <= 0 {
+		u.maxBytes = defaultUploadBufferBytes
+	}
+	if u.minBackoff <= 0 {
+		u.minBackoff = defaultUploadMinBackoff
+	}
+	if u.maxBackoff <= 0 {
+		u.maxBackoff = defaultUploadMaxBackoff
+	}
+	u.maxBackoff = max(u.maxBackoff, u.minBackoff)
+	if u.linger <= 0 {
+		u.linger = defaultUploadLinger
+	}
+
+	go u.run()
+	return u, nil
+}
+
+// Send queues an entry for uploading.  The entry consists of fields in the
+// native protocol format (which is also the export format).  A
+// __REALTIME_TIMESTAMP field is added.  The entry is copied.
+func (u *Uploader) Send(entry []byte) error {
+	e := appendExportEntry(make([]byte, 0, len(entry)+48), entry, u.clock.Now())
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.closed {
+		return ErrClosed
+	}
+	if u.bytes+len(e) > u.maxBytes {
+		return ErrUploadBufferFull
+	}
+
+	u.pending = append(u.pending, e)
+	u.bytes += len(e)
+
+	select {
+	case u.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Close stops accepting entries and uploads the buffered entries.  If the
+// context is done before that, uploading is aborted and the context error is
+// returned.  Nil context means that uploading is aborted immediately.
+func (u *Uploader) Close(ctx context.Context) error {
+	u.closeOnce.Do(func() {
+		u.mu.Lock()
+		u.closed = true
+		u.mu.Unlock()
+
+		select {
+		case u.notify <- struct{}{}:
+		default:
+		}
+	})
+
+	if ctx == nil {
+		u.abort()
+		<-u.done
+		return nil
+	}
+
+	select {
+	case <-u.done:
+		return nil
+
+	case <-ctx.Done():
+		u.abort()
+		<-u.done
+		return ctx.Err()
+	}
+}
+
+func (u *Uploader) abort() {
+	u.stopOnce.Do(func() { close(u.stop) })
+}
+
+func (u *Uploader) run() {
+	defer close(u.done)
+
+	var backoff time.Duration
+
+	for u.waitPending() {
+		if err := u.upload(); err == nil {
+			u.acknowledge()
+			backoff = 0
+			continue
+		}
+
+		u.requeue()
+
+		if backoff == 0 {
+			backoff = u.minBackoff
+		} else {
+			backoff = min(backoff*2, u.maxBackoff)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-u.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// waitPending returns false if there will be nothing more to upload.
+func (u *Uploader) waitPending() bool {
+	for {
+		u.mu.Lock()
+		n := len(u.pending)
+		closed := u.closed
+		u.mu.Unlock()
+
+		if n > 0 {
+			return true
+		}
+		if closed {
+			return false
+		}
+
+		select {
+		case <-u.notify:
+		case <-u.stop:
+			return false
+		}
+	}
+}
+
+// take the next pending entry for the current request.  It waits for the
+// linger duration if there are no pending entries.
+func (u *Uploader) take() ([]byte, bool) {
+	var timer *time.Timer
+
+	for {
+		u.mu.Lock()
+		if len(u.pending) > 0 {
+			e := u.pending[0]
+			u.pending[0] = nil
+			u.pending = u.pending[1:]
+			u.inflight = append(u.inflight, e)
+			u.mu.Unlock()
+
+			if timer != nil {
+				timer.Stop()
+			}
+			return e, true
+		}
+		closed := u.closed
+		u.mu.Unlock()
+
+		if closed {
+			return nil, false
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(u.linger)
+			defer timer.Stop()
+		}
+
+		select {
+		case <-u.notify:
+		case <-timer.C:
+			return nil, false
+		case <-u.stop:
+			return nil, false
+		}
+	}
+}
+
+func (u *Uploader) acknowledge() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, e := range u.inflight {
+		u.bytes -= len(e)
+	}
+	clear(u.inflight)
+	u.inflight = u.inflight[:0]
+}
+
+// requeue entries of a failed request in front of the pending ones.
+func (u *Uploader) requeue() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.pending = append(u.inflight, u.pending...)
+	u.inflight = nil
+}
+
+// upload entries as a single request.
+func (u *Uploader) upload() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-u.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	r, w := io.Pipe()
+	writeErr := make(chan error, 1)
+
+	go func() {
+		for {
+			e, ok := u.take()
+			if !ok {
+				writeErr <- w.Close()
+				return
+			}
+			if _, err := w.Write(e); err != nil {
+				writeErr <- err
+				return
+			}
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.url, r)
+	if err != nil {
+		r.CloseWithError(err)
+		<-writeErr
+		return err
+	}
+	req.Header.Set("Content-Type", UploadContentType)
+
+	resp, err := u.client.Do(req)
+	if err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			err = fmt.Errorf("sjournal: upload: %s", resp.Status)
+		}
+	}
+
+	// Stop the writer if the request ended prematurely.
+	r.CloseWithError(io.ErrClosedPipe)
+	if e := <-writeErr; err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testUploadServer struct {
+	mu       sync.Mutex
+	failures int // Number of requests to fail.
+	requests int
+	entries  []map[string][]byte // From successful requests.
+	errors   []string
+}
+
+func (s *testUploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var errs []string
+	if r.Method != http.MethodPost || r.URL.Path != "/upload" {
+		errs = append(errs, "request: "+r.Method+" "+r.URL.Path)
+	}
+	if ct := r.Header.Get("Content-Type"); ct != UploadContentType {
+		errs = append(errs, "content type: "+ct)
+	}
+	if !slices.Contains(r.TransferEncoding, "chunked") {
+		errs = append(errs, "not chunked")
+	}
+
+	var entries []map[string][]byte
+	d := NewDecoder(r.Body)
+	for {
+		entry, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, "decode: "+err.Error())
+			break
+		}
+		entries = append(entries, entry)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	s.errors = append(s.errors, errs...)
+
+	if s.failures > 0 {
+		s.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	s.entries = append(s.entries, entries...)
+}
+
+func TestUploader(t *testing.T) {
+	server := &testUploadServer{failures: 2}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	u, err := NewUploader(&UploaderOptions{
+		URL:        ts.URL + "/upload",
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+		Linger:     10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHandler(&HandlerOptions{
+		Delimiter: DefaultDelimiter,
+		Uploader:  u,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(h)
+	logger.Info("first", "x", 1)
+	logger.Warn("multi\nline")
+	logger.Error("third")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	for _, s := range server.errors {
+		t.Error(s)
+	}
+	if server.requests < 3 {
+		t.Errorf("%d requests", server.requests)
+	}
+
+	expect := []struct {
+		message  string
+		priority string
+	}{
+		{"first x=1", "6"},
+		{"multi\nline", "4"},
+		{"third", "3"},
+	}
+	if len(server.entries) != len(expect) {
+		t.Fatalf("%d entries", len(server.entries))
+	}
+	for i, x := range expect {
+		e := server.entries[i]
+		if s := string(e["MESSAGE"]); s != x.message {
+			t.Errorf("entry %d: message %q", i, s)
+		}
+		if s := string(e["PRIORITY"]); s != x.priority {
+			t.Errorf("entry %d: priority %q", i, s)
+		}
+		if len(e["__REALTIME_TIMESTAMP"]) == 0 || len(e["CODE_FUNC"]) == 0 {
+			t.Errorf("entry %d: %q", i, e)
+		}
+	}
+
+	if err := u.Send([]byte("MESSAGE=late\n")); err != ErrClosed {
+		t.Errorf("send after close: %v", err)
+	}
+}
+
+func TestUploaderBufferFull(t *testing.T) {
+	block := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer ts.Close()
+	defer close(block)
+
+	u, err := NewUploader(&UploaderOptions{
+		URL:         ts.URL + "/upload",
+		BufferBytes: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u.Close(nil)
+
+	entry := []byte("MESSAGE=0123456789\n")
+	var n int
+	for ; n < 10; n++ {
+		if err := u.Send(entry); err != nil {
+			if err != ErrUploadBufferFull {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+	if n == 0 || n == 10 {
+		t.Errorf("%d entries accepted", n)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+)
+
+// UTF8Policy determines how strings containing invalid UTF-8 are handled.
+// journald treats fields with invalid UTF-8 as binary data.
+type UTF8Policy int
+
+const (
+	UTF8Pass    UTF8Policy = iota // Strings are emitted as is.
+	UTF8Replace                   // Invalid sequences are replaced with U+FFFD.
+	UTF8Hex                       // Invalid strings are hex-encoded with HexPrefix.
+)
+
+// HexPrefix is prepended to hex-encoded strings by the UTF8Hex policy.
+const HexPrefix = "hex:"
+
+// apply the policy to s.  Valid strings are returned as is.
+func (p UTF8Policy) apply(s string) string {
+	if p == UTF8Pass || utf8.ValidString(s) {
+		return s
+	}
+
+	switch p {
+	case UTF8Replace:
+		return strings.ToValidUTF8(s, string(utf8.RuneError))
+
+	case UTF8Hex:
+		return HexPrefix + hex.EncodeToString([]byte(s))
+
+	default:
+		return s
+	}
+}
+
+// appendField applies the UTF-8 policy and RedactValue to the value and
+// appends it to the native fields.
+func (s *handleState) appendField(key, value string) {
+	value = s.h.utf8Policy.apply(value)
+	if s.h.redactValue != nil {
+		value = s.h.redactValue(key, value)
+	}
+	*s.fields = appendField(*s.fields, key, value)
+}
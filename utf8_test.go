@@ -0,0 +1,82 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestUTF8Policy(t *testing.T) {
+	for _, x := range []struct {
+		input   string
+		replace string
+		hex     string
+	}{
+		{"valid ä€", "valid ä€", "valid ä€"},
+		{"overlong \xc0\xaf", "overlong �", "hex:6f7665726c6f6e6720c0af"},
+		{"surrogate \xed\xa0\x80", "surrogate �", "hex:737572726f6761746520eda080"},
+		{"truncated \xe2\x82", "truncated �", "hex:7472756e636174656420e282"},
+	} {
+		if s := UTF8Pass.apply(x.input); s != x.input {
+			t.Errorf("pass %q: %q", x.input, s)
+		}
+		if s := UTF8Replace.apply(x.input); s != x.replace {
+			t.Errorf("replace %q: %q", x.input, s)
+		}
+		if s := UTF8Hex.apply(x.input); s != x.hex {
+			t.Errorf("hex %q: %q", x.input, s)
+		}
+	}
+}
+
+func TestUTF8PolicyHandler(t *testing.T) {
+	for _, x := range []struct {
+		policy  UTF8Policy
+		message string
+		field   string
+	}{
+		{UTF8Pass, "a\xe2\x82 x=\"b\\xc0\\xaf\"", "c\xed\xa0\x80"},
+		{UTF8Replace, "a� x=\"b�\"", "c�"},
+		{UTF8Hex, "hex:61e282 x=hex:62c0af", "hex:63eda080"},
+	} {
+		h, recv := newTestHandler(t, &HandlerOptions{UTF8Policy: x.policy})
+		slog.New(h).Info("a\xe2\x82", "x", "b\xc0\xaf", Field("f", "c\xed\xa0\x80"))
+
+		m := recv.wait(t, 1)[0]
+		if s := m["MESSAGE"]; s != x.message {
+			t.Errorf("policy %d: message %q", x.policy, s)
+		}
+		if s := m["F"]; s != x.field {
+			t.Errorf("policy %d: field %q", x.policy, s)
+		}
+	}
+}
+
+func TestUTF8PolicyReload(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{UTF8Policy: UTF8Hex})
+
+	// The policy is not reloaded.
+	err := h.Reload(&HandlerOptions{
+		UTF8Policy: UTF8Pass,
+		Identifier: "a\xe2\x82",
+		Fields:     map[string]string{"F": "b\xc0\xaf"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.New(h).Info("c\xed\xa0\x80")
+
+	m := recv.wait(t, 1)[0]
+	for name, expect := range map[string]string{
+		"SYSLOG_IDENTIFIER": "hex:61e282",
+		"F":                 "hex:62c0af",
+		"MESSAGE":           "hex:63eda080",
+	} {
+		if s := m[name]; s != expect {
+			t.Errorf("%s: %q", name, s)
+		}
+	}
+}
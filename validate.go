@@ -0,0 +1,207 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxSocketPathLen is the size of sockaddr_un.sun_path on Linux minus the
+// terminating null byte.
+const maxSocketPathLen = 107
+
+// ErrInvalidOption is wrapped by the errors which NewHandler and Reload return
+// for invalid options.  Multiple problems are joined into one error.
+//
+// In addition to options which are always checked, NewHandler and Reload
+// reject a Prefix containing a newline, a TimeFormat without any layout
+// elements, a Socket path which is too long, and Fields keys which are not
+// uppercase field names, unless AllowInvalid is set.
+var ErrInvalidOption = errors.New("sjournal: invalid option")
+
+func validateOptions(opts *HandlerOptions) error {
+	errs := validateConfig(opts, opts.ReservedFields)
+
+	if opts.SyslogPID < 0 {
+		errs = append(errs, invalidOption("SyslogPID", "negative value %d", opts.SyslogPID))
+	}
+
+	if opts.Facility < 0 || opts.Facility > FacilityLocal7 {
+		errs = append(errs, invalidOption("Facility", "unknown value %d", opts.Facility))
+	} else if _, found := opts.Fields["SYSLOG_FACILITY"]; found && opts.Facility > 0 {
+		errs = append(errs, invalidOption("Facility", "conflicts with SYSLOG_FACILITY in Fields"))
+	}
+
+	if opts.TimeValueFormat < TimeLayout || opts.TimeValueFormat > TimeUnixNano {
+		errs = append(errs, invalidOption("TimeValueFormat", "unknown value %d", opts.TimeValueFormat))
+	}
+
+	if opts.SourceFormat&^sourceFormatMask != 0 {
+		errs = append(errs, invalidOption("SourceFormat", "unknown flags %#x", int(opts.SourceFormat&^sourceFormatMask)))
+	}
+
+	switch opts.AnyFormat {
+	case "", "%v", "%+v", "%#v":
+	default:
+		errs = append(errs, invalidOption("AnyFormat", "unsupported format verb %q", opts.AnyFormat))
+	}
+
+	if _, err := newKeyPatterns(opts.DropKeys); err != nil {
+		errs = append(errs, invalidOption("DropKeys", "%w", err))
+	}
+	if _, err := newKeyPatterns(opts.RedactKeys); err != nil {
+		errs = append(errs, invalidOption("RedactKeys", "%w", err))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(opts.PromoteKeys)) {
+		value := opts.PromoteKeys[key]
+		name, ok := fieldName(value)
+		switch {
+		case !ok:
+			errs = append(errs, invalidOption("PromoteKeys", "invalid field name %q", value))
+		case name != value && !opts.AllowInvalid:
+			errs = append(errs, invalidOption("PromoteKeys", "field name %q is not uppercase", value))
+		case opts.ReservedFields == RejectReserved && reservedFieldName(name):
+			errs = append(errs, invalidOption("PromoteKeys", "field name %q is reserved", value))
+		}
+	}
+
+	if opts.ReservedFields < AllowReserved || opts.ReservedFields > RejectReserved {
+		errs = append(errs, invalidOption("ReservedFields", "unknown value %d", opts.ReservedFields))
+	}
+	if p := opts.ReservedPrefix; p != "" {
+		if name, ok := fieldName(p + "X"); !ok || name != p+"X" {
+			errs = append(errs, invalidOption("ReservedPrefix", "invalid field name prefix %q", p))
+		}
+	}
+
+	if opts.AttrOrder < ContextFirst || opts.AttrOrder > RecordFirst {
+		errs = append(errs, invalidOption("AttrOrder", "unknown value %d", opts.AttrOrder))
+	}
+
+	if opts.SendSockets < 0 {
+		errs = append(errs, invalidOption("SendSockets", "negative value %d", opts.SendSockets))
+	}
+
+	if opts.CaptureEntries < 0 {
+		errs = append(errs, invalidOption("CaptureEntries", "negative value %d", opts.CaptureEntries))
+	}
+	if opts.CaptureBytes < 0 {
+		errs = append(errs, invalidOption("CaptureBytes", "negative value %d", opts.CaptureBytes))
+	}
+
+	if opts.ChunkSize < 0 {
+		errs = append(errs, invalidOption("ChunkSize", "negative value %d", opts.ChunkSize))
+	}
+
+	if opts.SlowSendThreshold < 0 {
+		errs = append(errs, invalidOption("SlowSendThreshold", "negative duration %v", opts.SlowSendThreshold))
+	}
+
+	if opts.DeadLetterBytes < 0 {
+		errs = append(errs, invalidOption("DeadLetterBytes", "negative value %d", opts.DeadLetterBytes))
+	}
+
+	for i, b := range opts.Budgets {
+		if b.Bytes < 0 {
+			errs = append(errs, invalidOption("Budgets", "negative value %d at index %d", b.Bytes, i))
+		}
+		if b.Interval < 0 {
+			errs = append(errs, invalidOption("Budgets", "negative duration %v at index %d", b.Interval, i))
+		}
+	}
+	if opts.BudgetSummaryInterval < 0 {
+		errs = append(errs, invalidOption("BudgetSummaryInterval", "negative duration %v", opts.BudgetSummaryInterval))
+	}
+
+	if opts.SignatureKeyID != "" && len(opts.SignatureKey) == 0 {
+		errs = append(errs, invalidOption("SignatureKeyID", "requires SignatureKey"))
+	}
+
+	if opts.AttrMode < DefaultAttrs || opts.AttrMode > FieldAttrs {
+		errs = append(errs, invalidOption("AttrMode", "unknown value %d", opts.AttrMode))
+	}
+	for i, d := range opts.Destinations {
+		if d.Socket == "" && d.Transport == nil {
+			errs = append(errs, invalidOption("Destinations", "no Socket or Transport at index %d", i))
+		}
+		if d.AttrMode < DefaultAttrs || d.AttrMode > FieldAttrs {
+			errs = append(errs, invalidOption("Destinations", "unknown AttrMode %d at index %d", d.AttrMode, i))
+		}
+		for _, key := range slices.Sorted(maps.Keys(d.Fields)) {
+			if name, ok := fieldName(key); !ok || name != key && !opts.AllowInvalid {
+				errs = append(errs, invalidOption("Destinations", "invalid field name %q at index %d", key, i))
+			}
+		}
+	}
+
+	if opts.TextCompat {
+		if opts.QuoteStyle != GoQuote {
+			errs = append(errs, invalidOption("TextCompat", "cannot be used with QuoteStyle"))
+		}
+		if opts.AnyFormat != "" && opts.AnyFormat != "%v" {
+			errs = append(errs, invalidOption("TextCompat", "cannot be used with AnyFormat"))
+		}
+		if opts.TimeValueFormat != TimeLayout {
+			errs = append(errs, invalidOption("TextCompat", "cannot be used with TimeValueFormat"))
+		}
+	}
+
+	if opts.Syslog != nil && opts.Uploader != nil {
+		errs = append(errs, invalidOption("Syslog", "cannot be used with Uploader"))
+	}
+	if opts.Transport != nil && (opts.Uploader != nil || opts.Syslog != nil) {
+		errs = append(errs, invalidOption("Transport", "cannot be used with Uploader or Syslog"))
+	}
+
+	if !opts.AllowInvalid && len(opts.Socket) > maxSocketPathLen {
+		errs = append(errs, invalidOption("Socket", "path is longer than %d bytes", maxSocketPathLen))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateConfig checks the options which can be changed using Reload.  The
+// reserved field policy is not one of them.
+func validateConfig(opts *HandlerOptions, reserved ReservedPolicy) []error {
+	var errs []error
+
+	for _, key := range slices.Sorted(maps.Keys(opts.Fields)) {
+		name, ok := fieldName(key)
+		switch {
+		case !ok:
+			errs = append(errs, invalidOption("Fields", "invalid field name %q", key))
+		case name != key && !opts.AllowInvalid:
+			errs = append(errs, invalidOption("Fields", "field name %q is not uppercase", key))
+		case reserved == RejectReserved && reservedFieldName(name):
+			errs = append(errs, invalidOption("Fields", "field name %q is reserved", key))
+		}
+	}
+
+	if opts.AllowInvalid {
+		return errs
+	}
+
+	if strings.Contains(opts.Prefix, "\n") {
+		errs = append(errs, invalidOption("Prefix", "contains a newline"))
+	}
+
+	if layout := opts.TimeFormat; layout != "" {
+		if time.Unix(0, 0).UTC().Format(layout) == layout {
+			errs = append(errs, invalidOption("TimeFormat", "%q has no layout elements", layout))
+		}
+	}
+
+	return errs
+}
+
+func invalidOption(name, format string, args ...any) error {
+	return fmt.Errorf("%w: %s: "+format, append([]any{ErrInvalidOption, name}, args...)...)
+}
@@ -0,0 +1,84 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateOptions(t *testing.T) {
+	for _, c := range []struct {
+		opts   HandlerOptions
+		expect string
+	}{
+		{HandlerOptions{TimeFormat: "ISO"}, `sjournal: invalid option: TimeFormat: "ISO" has no layout elements`},
+		{HandlerOptions{Prefix: "a\nb"}, `sjournal: invalid option: Prefix: contains a newline`},
+		{HandlerOptions{Socket: "/" + strings.Repeat("x", 107)}, `sjournal: invalid option: Socket: path is longer than 107 bytes`},
+		{HandlerOptions{Fields: map[string]string{"unit": ""}}, `sjournal: invalid option: Fields: field name "unit" is not uppercase`},
+		{HandlerOptions{Fields: map[string]string{"_BAD": ""}}, `sjournal: invalid option: Fields: invalid field name "_BAD"`},
+		{HandlerOptions{PromoteKeys: map[string]string{"err": "error"}}, `sjournal: invalid option: PromoteKeys: field name "error" is not uppercase`},
+		{HandlerOptions{PromoteKeys: map[string]string{"id": "REQUEST.ID"}}, `sjournal: invalid option: PromoteKeys: invalid field name "REQUEST.ID"`},
+		{HandlerOptions{Fields: map[string]string{"SYSLOG_FACILITY": "3"}, ReservedFields: RejectReserved}, `sjournal: invalid option: Fields: field name "SYSLOG_FACILITY" is reserved`},
+		{HandlerOptions{PromoteKeys: map[string]string{"p": "PRIORITY"}, ReservedFields: RejectReserved}, `sjournal: invalid option: PromoteKeys: field name "PRIORITY" is reserved`},
+		{HandlerOptions{ReservedFields: -1}, `sjournal: invalid option: ReservedFields: unknown value -1`},
+		{HandlerOptions{ReservedPrefix: "x_"}, `sjournal: invalid option: ReservedPrefix: invalid field name prefix "x_"`},
+		{HandlerOptions{SyslogPID: -1}, `sjournal: invalid option: SyslogPID: negative value -1`},
+		{HandlerOptions{Facility: 24}, `sjournal: invalid option: Facility: unknown value 24`},
+		{HandlerOptions{AnyFormat: "%s"}, `sjournal: invalid option: AnyFormat: unsupported format verb "%s"`},
+		{HandlerOptions{SourceFormat: 1 << 8}, "sjournal: invalid option: SourceFormat: unknown flags 0x100"},
+		{HandlerOptions{SignatureKeyID: "k"}, "sjournal: invalid option: SignatureKeyID: requires SignatureKey"},
+		{HandlerOptions{Transport: new(recordingTransport), Syslog: new(Syslog)}, "sjournal: invalid option: Transport: cannot be used with Uploader or Syslog"},
+		{HandlerOptions{Destinations: []Destination{{}}}, "sjournal: invalid option: Destinations: no Socket or Transport at index 0"},
+		{HandlerOptions{TextCompat: true, QuoteStyle: MinimalQuote}, "sjournal: invalid option: TextCompat: cannot be used with QuoteStyle"},
+		{HandlerOptions{DropKeys: []string{"["}}, `sjournal: invalid option: DropKeys: invalid key pattern "[": syntax error in pattern`},
+		{HandlerOptions{RedactKeys: []string{"a\\"}}, `sjournal: invalid option: RedactKeys: invalid key pattern "a\\": syntax error in pattern`},
+		{
+			HandlerOptions{Prefix: "\n", Fields: map[string]string{"b": "", "a": ""}},
+			"sjournal: invalid option: Fields: field name \"a\" is not uppercase\n" +
+				"sjournal: invalid option: Fields: field name \"b\" is not uppercase\n" +
+				"sjournal: invalid option: Prefix: contains a newline",
+		},
+	} {
+		_, err := NewHandler(&c.opts)
+		if err == nil {
+			t.Errorf("%q: no error", c.expect)
+			continue
+		}
+		if !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("%q: not ErrInvalidOption: %v", c.expect, err)
+		}
+		if s := err.Error(); s != c.expect {
+			t.Errorf("error:\n%s\nexpected:\n%s", s, c.expect)
+		}
+	}
+}
+
+func TestValidateOptionsAllowInvalid(t *testing.T) {
+	h, err := NewHandler(&HandlerOptions{
+		TimeFormat:   "ISO",
+		Prefix:       "a\nb",
+		Socket:       "/" + strings.Repeat("x", 107),
+		Fields:       map[string]string{"unit": ""},
+		AllowInvalid: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+
+	if _, err := NewHandler(&HandlerOptions{SyslogPID: -1, AllowInvalid: true}); err == nil {
+		t.Error("invalid SyslogPID allowed")
+	}
+}
+
+func TestValidateReload(t *testing.T) {
+	h, _ := newTestHandler(t, nil)
+
+	if err := h.Reload(&HandlerOptions{Prefix: "\n"}); !errors.Is(err, ErrInvalidOption) {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	socketPollInterval = 10 * time.Millisecond
+	maxWaitBufferBytes = 1 << 20
+)
+
+// socketWait buffers entries until the socket appears.
+type socketWait struct {
+	timeout time.Duration
+
+	mu      sync.Mutex
+	started bool
+	over    bool     // Entries are sent directly.
+	buf     [][]byte // Entries waiting for the socket.
+	bytes   int
+	stop    chan struct{} // Closed to abort waiting.
+	done    chan struct{} // Closed when the buffer has been flushed or dropped.
+}
+
+// newSocketWait returns nil if waiting is disabled.
+func newSocketWait(timeout time.Duration) *socketWait {
+	if timeout <= 0 {
+		return nil
+	}
+	return &socketWait{
+		timeout: timeout,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// buffer the entry if the socket hasn't appeared yet.  The path is checked on
+// first use; if the socket exists, waiting ends immediately.
+func (r *root) buffer(b []byte) bool {
+	w := r.wait
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.over {
+		return false
+	}
+
+	if !w.started {
+		w.started = true
+		if socketExists(r.addr.Load().Name) {
+			w.over = true
+			close(w.done)
+			return false
+		}
+		go r.waitForSocket()
+	}
+
+	if w.bytes+len(b) > maxWaitBufferBytes {
+		r.stats.drop(dropNoSocket, entryPriority(b), 1)
+		return true
+	}
+
+	w.buf = append(w.buf, slices.Clone(b))
+	w.bytes += len(b)
+	return true
+}
+
+// waitForSocket polls the socket path and sends the buffered entries when the
+// socket appears.  If it doesn't appear in time, the entries are dropped.
+func (r *root) waitForSocket() {
+	w := r.wait
+	defer close(w.done)
+
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(socketPollInterval)
+	defer ticker.Stop()
+
+	for !socketExists(r.addr.Load().Name) {
+		select {
+		case <-ticker.C:
+
+		case <-timer.C:
+			n := w.dropAll(&r.stats, dropNoSocket)
+			fmt.Fprintf(stderr, "sjournal: socket %s did not appear within %v: %d entries dropped\n", r.addr.Load().Name, w.timeout, n)
+			return
+
+		case <-w.stop:
+			w.dropAll(&r.stats, dropClosed)
+			return
+		}
+	}
+
+	// Entries may be buffered while earlier ones are being sent.
+	for {
+		w.mu.Lock()
+		batch := w.buf
+		w.buf = nil
+		w.bytes = 0
+		if len(batch) == 0 {
+			w.over = true
+		}
+		w.mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+
+		for i, b := range batch {
+			select {
+			case <-w.stop:
+				for _, b := range batch[i:] {
+					r.stats.drop(dropClosed, entryPriority(b), 1)
+				}
+				w.dropAll(&r.stats, dropClosed)
+				return
+			default:
+			}
+
+			if err := r.sendNow(b, nil); err != nil {
+				r.deadLetter(b, err)
+				r.stats.drop(dropError, entryPriority(b), 1)
+			}
+		}
+	}
+}
+
+// dropAll buffered entries and stop buffering.  The number of dropped entries
+// is returned.
+func (w *socketWait) dropAll(s *stats, reason dropReason) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(w.buf)
+	for _, b := range w.buf {
+		s.drop(reason, entryPriority(b), 1)
+	}
+	w.buf = nil
+	w.bytes = 0
+	w.over = true
+	return n
+}
+
+// finish waiting during shutdown.  If drain is true, the buffered entries are
+// sent if the socket appears before the context is done.
+func (w *socketWait) finish(ctx context.Context, drain bool) error {
+	w.mu.Lock()
+	if !w.started {
+		w.started = true
+		w.over = true
+		close(w.done)
+	}
+	w.mu.Unlock()
+
+	var err error
+	if drain {
+		select {
+		case <-w.done:
+			return nil
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	close(w.stop)
+	<-w.done
+	return err
+}
+
+func socketExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// entryPriority extracts the priority from an encoded entry.
+func entryPriority(b []byte) int {
+	if len(b) > priorityOffset {
+		if p := int(b[priorityOffset] - '0'); p >= 0 && p < numPriorities {
+			return p
+		}
+	}
+	return priorityDebug
+}
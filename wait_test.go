@@ -0,0 +1,138 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForSocket(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		t.Run(fmt.Sprint("async=", async), func(t *testing.T) {
+			socket := path.Join(t.TempDir(), "socket")
+
+			opts := &HandlerOptions{
+				Socket:        socket,
+				WaitForSocket: 5 * time.Second,
+			}
+			if async {
+				opts.QueueSize = 100
+			}
+
+			h, err := NewHandler(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			logger := slog.New(h)
+
+			t0 := time.Now()
+			for i := range 10 {
+				logger.Info("early", "i", i)
+			}
+			if d := time.Since(t0); d > time.Second {
+				t.Errorf("logging blocked for %v", d)
+			}
+
+			time.Sleep(50 * time.Millisecond)
+			recv := newTestReceiverAt(t, socket)
+
+			for i := 10; i < 15; i++ {
+				logger.Info("late", "i", i)
+			}
+
+			ms := recv.wait(t, 15)
+			for i, m := range ms {
+				if !strings.HasSuffix(m["MESSAGE"], fmt.Sprintf("i=%d", i)) {
+					t.Errorf("entry %d: %q", i, m["MESSAGE"])
+				}
+			}
+
+			if err := h.Shutdown(context.Background()); err != nil {
+				t.Error(err)
+			}
+			if s := h.Stats(); s.Sent != 15 || len(s.Dropped) != 0 {
+				t.Errorf("stats: %+v", s)
+			}
+		})
+	}
+}
+
+func TestWaitForSocketExisting(t *testing.T) {
+	h, recv := newTestHandler(t, &HandlerOptions{
+		WaitForSocket: time.Hour,
+	})
+
+	slog.New(h).Info("hello")
+	recv.wait(t, 1)
+}
+
+func TestWaitForSocketTimeout(t *testing.T) {
+	var out bytes.Buffer
+	orig := stderr
+	defer func() { stderr = orig }()
+	stderr = &out
+
+	h, err := NewHandler(&HandlerOptions{
+		Socket:        path.Join(t.TempDir(), "socket"),
+		WaitForSocket: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	logger := slog.New(h)
+	logger.Info("a")
+	logger.Warn("b")
+
+	<-h.root.wait.done
+
+	s := h.Stats()
+	if n := s.Dropped[DropNoSocket]; n != 2 {
+		t.Errorf("dropped: %v", s.Dropped)
+	}
+	if s.DroppedByPriority[4] != 1 || s.DroppedByPriority[6] != 1 {
+		t.Errorf("dropped by priority: %v", s.DroppedByPriority)
+	}
+	if !strings.Contains(out.String(), "2 entries dropped") {
+		t.Errorf("stderr: %q", out.String())
+	}
+
+	// Entries are no longer buffered.
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "c", 0)); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestWaitForSocketClose(t *testing.T) {
+	h, err := NewHandler(&HandlerOptions{
+		Socket:        path.Join(t.TempDir(), "socket"),
+		WaitForSocket: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slog.New(h).Info("a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := h.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Error(err)
+	}
+	if n := h.Stats().Dropped[DropClosed]; n != 1 {
+		t.Errorf("dropped: %v", h.Stats().Dropped)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// maxLineLen matches journald's default LineMax.  Longer lines are split.
+const maxLineLen = 48 * 1024
+
+type WriterOptions struct {
+	// Level of lines without a priority prefix.  The default is LevelInfo.
+	Level slog.Leveler
+
+	// PriorityPrefixes causes a "<N>" prefix (as used with sd-daemon) to be
+	// parsed from the beginning of each line.  The prefix is removed and the
+	// line is logged with journald priority N (values above 7 are clamped to
+	// 7).
+	PriorityPrefixes bool
+}
+
+// Writer logs each line written to it as a record.  Partial lines are
+// buffered until the rest is written, or the writer is closed.
+type Writer struct {
+	h        *Handler
+	level    slog.Leveler
+	prefixes bool
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func NewWriter(h *Handler, opts *WriterOptions) *Writer {
+	w := &Writer{
+		h:     h,
+		level: LevelInfo,
+	}
+	if opts != nil {
+		if opts.Level != nil {
+			w.level = opts.Level
+		}
+		w.prefixes = opts.PriorityPrefixes
+	}
+	return w
+}
+
+// Write logs the complete lines.  An error is returned if handling a line
+// fails; the count includes the line.
+func (w *Writer) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := 0
+
+	for len(b) > 0 {
+		room := maxLineLen - len(w.buf)
+
+		if i := bytes.IndexByte(b, '\n'); i >= 0 && i <= room {
+			w.buf = append(w.buf, b[:i]...)
+			n += i + 1
+			b = b[i+1:]
+		} else if len(b) < room {
+			w.buf = append(w.buf, b...)
+			return n + len(b), nil
+		} else {
+			w.buf = append(w.buf, b[:room]...)
+			n += room
+			b = b[room:]
+		}
+
+		if err := w.logLine(); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// Close logs the buffered partial line, if any.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	return w.logLine()
+}
+
+func (w *Writer) logLine() error {
+	line := w.buf
+	w.buf = w.buf[:0]
+
+	level := w.level.Level()
+	priority := -1
+
+	if w.prefixes {
+		if p, rest, ok := parsePriorityPrefix(line); ok {
+			priority = p
+			level = priorityLevels[p]
+			line = rest
+		}
+	}
+
+	ctx := context.Background()
+	if !w.h.Enabled(ctx, level) {
+		return nil
+	}
+
+	r := slog.NewRecord(w.h.root.clock.Now(), level, string(line), 0)
+	if priority >= 0 {
+		r.AddAttrs(Priority(priority))
+	}
+	return w.h.Handle(ctx, r)
+}
+
+// parsePriorityPrefix parses "<N>" at the beginning of a line.
+func parsePriorityPrefix(line []byte) (int, []byte, bool) {
+	if len(line) < 3 || line[0] != '<' {
+		return 0, nil, false
+	}
+
+	p := 0
+	for i := 1; i < len(line); i++ {
+		switch c := line[i]; {
+		case c >= '0' && c <= '9':
+			if i > 10 {
+				return 0, nil, false
+			}
+			p = p*10 + int(c-'0')
+
+		case c == '>' && i > 1:
+			return min(p, priorityDebug), line[i+1:], true
+
+		default:
+			return 0, nil, false
+		}
+	}
+	return 0, nil, false
+}
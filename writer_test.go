@@ -0,0 +1,80 @@
+// Copyright 2026 Timo Savola. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sjournal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+
+	w := NewWriter(h, &WriterOptions{
+		Level:            LevelNotice,
+		PriorityPrefixes: true,
+	})
+
+	for _, s := range []string{
+		"<3>err",
+		"or\nplain\n<",
+		"6>in",
+		"fo\n<0>emerg\n<42>clamped\n<x>not a prefix\n<>empty\n",
+		"<4>partial",
+	} {
+		if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+			t.Fatal(n, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ms := recv.wait(t, 8)
+	for i, c := range []struct {
+		priority string
+		message  string
+	}{
+		{"3", "error"},
+		{"5", "plain"},
+		{"6", "info"},
+		{"0", "emerg"},
+		{"7", "clamped"},
+		{"5", "<x>not a prefix"},
+		{"5", "<>empty"},
+		{"4", "partial"},
+	} {
+		if m := ms[i]; m["PRIORITY"] != c.priority || m["MESSAGE"] != c.message {
+			t.Errorf("entry %d: PRIORITY=%s MESSAGE=%q", i, m["PRIORITY"], m["MESSAGE"])
+		}
+	}
+}
+
+func TestWriterNoPrefixes(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+
+	w := NewWriter(h, nil)
+	w.Write([]byte("<3>kept\n"))
+
+	if m := recv.wait(t, 1)[0]; m["PRIORITY"] != "6" || m["MESSAGE"] != "<3>kept" {
+		t.Errorf("PRIORITY=%s MESSAGE=%q", m["PRIORITY"], m["MESSAGE"])
+	}
+}
+
+func TestWriterLongLine(t *testing.T) {
+	h, recv := newTestHandler(t, nil)
+
+	w := NewWriter(h, nil)
+	w.Write([]byte(strings.Repeat("x", maxLineLen-1)))
+	w.Write([]byte("yz\n"))
+
+	ms := recv.wait(t, 2)
+	if s := ms[0]["MESSAGE"]; s != strings.Repeat("x", maxLineLen-1)+"y" {
+		t.Errorf("entry 0 length: %d", len(s))
+	}
+	if s := ms[1]["MESSAGE"]; s != "z" {
+		t.Errorf("entry 1: %q", s)
+	}
+}